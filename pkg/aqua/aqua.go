@@ -0,0 +1,397 @@
+// Package aqua reads tool definitions in the aqua registry format
+// (https://github.com/aquaproj/aqua-registry) and resolves a package to the
+// release asset, archive member and checksum file b downloads for a version
+// and platform.
+//
+// Only the package types that map onto b's download paths are supported:
+// github_release (a GitHub release asset) and http (a URL template).
+package aqua
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+// Prefix marks a registry package in b.yaml and on the command line, as in
+// "aqua:cli/cli".
+const Prefix = "aqua:"
+
+// Package types.
+const (
+	TypeGitHubRelease = "github_release"
+	TypeHTTP          = "http"
+)
+
+// IsRef reports whether ref names a registry package ("aqua:owner/name").
+func IsRef(ref string) bool {
+	return strings.HasPrefix(ref, Prefix)
+}
+
+// Package is one entry of a registry.yaml `packages:` list. Fields b does
+// not use (cosign, slsa_provenance, version_source, ...) are ignored.
+type Package struct {
+	Spec              `yaml:",inline"`
+	Name              string            `yaml:"name,omitempty"`
+	RepoOwner         string            `yaml:"repo_owner,omitempty"`
+	RepoName          string            `yaml:"repo_name,omitempty"`
+	Description       string            `yaml:"description,omitempty"`
+	Aliases           []Alias           `yaml:"aliases,omitempty"`
+	VersionConstraint string            `yaml:"version_constraint,omitempty"`
+	VersionOverrides  []VersionOverride `yaml:"version_overrides,omitempty"`
+}
+
+// Spec is the part of a package that version_overrides and overrides can
+// replace.
+type Spec struct {
+	Type   string `yaml:"type,omitempty"`
+	Asset  string `yaml:"asset,omitempty"`
+	URL    string `yaml:"url,omitempty"`
+	Format string `yaml:"format,omitempty"`
+	Files  []File `yaml:"files,omitempty"`
+	// Replacements map GOOS/GOARCH to the names used in the templates.
+	Replacements map[string]string `yaml:"replacements,omitempty"`
+	// Overrides apply to the first platform they match.
+	Overrides       []Override       `yaml:"overrides,omitempty"`
+	FormatOverrides []FormatOverride `yaml:"format_overrides,omitempty"`
+	// SupportedEnvs lists "os", "arch", "os/arch" or "all"; empty is all.
+	SupportedEnvs       []string  `yaml:"supported_envs,omitempty"`
+	Rosetta2            *bool     `yaml:"rosetta2,omitempty"`
+	WindowsARMEmulation *bool     `yaml:"windows_arm_emulation,omitempty"`
+	CompleteWindowsExt  *bool     `yaml:"complete_windows_ext,omitempty"`
+	Checksum            *Checksum `yaml:"checksum,omitempty"`
+	VersionPrefix       string    `yaml:"version_prefix,omitempty"`
+	NoAsset             *bool     `yaml:"no_asset,omitempty"`
+	ErrorMessage        string    `yaml:"error_message,omitempty"`
+}
+
+// File is a command a package installs; Src is its (templated) path in
+// the archive and defaults to Name.
+type File struct {
+	Name string `yaml:"name"`
+	Src  string `yaml:"src,omitempty"`
+}
+
+// Alias is another name a package is known by.
+type Alias struct {
+	Name string `yaml:"name"`
+}
+
+// Override replaces parts of the spec on the platforms it matches.
+type Override struct {
+	Spec   `yaml:",inline"`
+	GOOS   string `yaml:"goos,omitempty"`
+	GOArch string `yaml:"goarch,omitempty"`
+}
+
+// FormatOverride is the older form of an override that only sets Format.
+type FormatOverride struct {
+	GOOS   string `yaml:"goos"`
+	Format string `yaml:"format"`
+}
+
+// VersionOverride replaces parts of the spec for the versions its
+// constraint matches.
+type VersionOverride struct {
+	Spec              `yaml:",inline"`
+	VersionConstraint string `yaml:"version_constraint"`
+}
+
+// Checksum names the release file listing the assets' checksums.
+type Checksum struct {
+	Type      string `yaml:"type,omitempty"`
+	Asset     string `yaml:"asset,omitempty"`
+	URL       string `yaml:"url,omitempty"`
+	Algorithm string `yaml:"algorithm,omitempty"`
+	Enabled   *bool  `yaml:"enabled,omitempty"`
+}
+
+// FullName is the name the package is installed by: Name, or
+// "owner/repo".
+func (p *Package) FullName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.RepoOwner + "/" + p.RepoName
+}
+
+// Repo is the GitHub repository ("owner/repo") releases and versions come
+// from, or "" when the package has none.
+func (p *Package) Repo() string {
+	if p.RepoOwner == "" || p.RepoName == "" {
+		return ""
+	}
+	return p.RepoOwner + "/" + p.RepoName
+}
+
+// Command is the name of the binary the package installs: its first file,
+// or the last element of its name.
+func (p *Package) Command() string {
+	if len(p.Files) > 0 && p.Files[0].Name != "" {
+		return p.Files[0].Name
+	}
+	return path.Base(p.FullName())
+}
+
+// Names returns FullName followed by the aliases.
+func (p *Package) Names() []string {
+	names := []string{p.FullName()}
+	for _, a := range p.Aliases {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// Artifact is what a package downloads for one version and platform.
+type Artifact struct {
+	Type string
+	// Asset is the release asset (github_release) or the last element of
+	// URL (http).
+	Asset string
+	URL   string
+	// Member is the binary's path inside the archive; "" when the asset is
+	// the binary itself.
+	Member string
+	// Checksum is the release asset listing Asset's sha256, or "".
+	Checksum string
+}
+
+// Resolve renders the package for version (a release tag) and target.
+func (p *Package) Resolve(version string, target provider.Platform) (*Artifact, error) {
+	target = target.Resolve()
+	spec := p.forVersion(version)
+	if spec.ErrorMessage != "" {
+		return nil, fmt.Errorf("aqua package %s: %s", p.FullName(), strings.TrimSpace(spec.ErrorMessage))
+	}
+	if !spec.supports(target) {
+		return nil, fmt.Errorf("aqua package %s: no %s build", p.FullName(), target)
+	}
+	spec = spec.forPlatform(target)
+	if isTrue(spec.NoAsset) {
+		return nil, fmt.Errorf("aqua package %s: no asset for %s", p.FullName(), target)
+	}
+
+	arch := target.Arch
+	if arch == "arm64" && (target.OS == "darwin" && isTrue(spec.Rosetta2) || target.OS == "windows" && isTrue(spec.WindowsARMEmulation)) {
+		arch = "amd64"
+	}
+	vars := templateVars{
+		Version: version,
+		SemVer:  strings.TrimPrefix(version, spec.VersionPrefix),
+		OS:      replaced(spec.Replacements, target.OS),
+		Arch:    replaced(spec.Replacements, arch),
+		Format:  spec.Format,
+	}
+	windowsExt := target.OS == "windows" && (spec.CompleteWindowsExt == nil || *spec.CompleteWindowsExt)
+
+	a := &Artifact{Type: spec.Type}
+	var err error
+	switch spec.Type {
+	case TypeGitHubRelease:
+		if p.Repo() == "" {
+			return nil, fmt.Errorf("aqua package %s: github_release without repo_owner and repo_name", p.FullName())
+		}
+		if a.Asset, err = render(spec.Asset, vars); err != nil {
+			return nil, fmt.Errorf("aqua package %s: asset: %w", p.FullName(), err)
+		}
+	case TypeHTTP:
+		if a.URL, err = render(spec.URL, vars); err != nil {
+			return nil, fmt.Errorf("aqua package %s: url: %w", p.FullName(), err)
+		}
+		a.Asset = path.Base(a.URL)
+	default:
+		return nil, fmt.Errorf("aqua package %s: type %q is not supported", p.FullName(), spec.Type)
+	}
+	if a.Asset == "" || a.Asset == "." {
+		return nil, fmt.Errorf("aqua package %s: no asset for %s", p.FullName(), target)
+	}
+	archive := provider.DetectArchiveType(a.Asset)
+	if windowsExt && archive == "" && (spec.Format == "" || spec.Format == "raw") && !strings.HasSuffix(a.Asset, ".exe") {
+		a.Asset += ".exe"
+		a.URL = addExe(a.URL)
+	}
+
+	if isArchive(archive) {
+		file := File{Name: p.Command()}
+		if len(spec.Files) > 0 {
+			file = spec.Files[0]
+		}
+		src := file.Src
+		if src == "" {
+			src = file.Name
+		}
+		vars.Asset = a.Asset
+		vars.AssetWithoutExt = strings.TrimSuffix(a.Asset, "."+archive)
+		vars.FileName = file.Name
+		if a.Member, err = render(src, vars); err != nil {
+			return nil, fmt.Errorf("aqua package %s: files: %w", p.FullName(), err)
+		}
+		if windowsExt && path.Ext(a.Member) == "" {
+			a.Member += ".exe"
+		}
+	}
+
+	if c := spec.Checksum; c != nil && c.Type == TypeGitHubRelease && c.Asset != "" &&
+		(c.Enabled == nil || *c.Enabled) && (c.Algorithm == "" || c.Algorithm == "sha256") {
+		vars.Asset = a.Asset
+		if a.Checksum, err = render(c.Asset, vars); err != nil {
+			return nil, fmt.Errorf("aqua package %s: checksum: %w", p.FullName(), err)
+		}
+	}
+	return a, nil
+}
+
+// forVersion returns the spec for version: the package's own when its
+// version_constraint holds, else that of the first matching
+// version_overrides entry. An unknown version uses the package's own.
+func (p *Package) forVersion(version string) Spec {
+	if version == "" || p.VersionConstraint == "" || matchVersion(p.VersionConstraint, version) {
+		return p.Spec
+	}
+	for _, o := range p.VersionOverrides {
+		if matchVersion(o.VersionConstraint, version) {
+			return p.Spec.merge(o.Spec)
+		}
+	}
+	return p.Spec
+}
+
+// forPlatform applies the first override matching target, and the format
+// overrides.
+func (s Spec) forPlatform(target provider.Platform) Spec {
+	for _, fo := range s.FormatOverrides {
+		if fo.GOOS == target.OS {
+			s.Format = fo.Format
+			break
+		}
+	}
+	for _, o := range s.Overrides {
+		if (o.GOOS == "" || o.GOOS == target.OS) && (o.GOArch == "" || o.GOArch == target.Arch) {
+			return s.merge(o.Spec)
+		}
+	}
+	return s
+}
+
+// merge returns s with the fields o sets replaced.
+func (s Spec) merge(o Spec) Spec {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&s.Type, o.Type)
+	set(&s.Asset, o.Asset)
+	set(&s.URL, o.URL)
+	set(&s.Format, o.Format)
+	set(&s.VersionPrefix, o.VersionPrefix)
+	set(&s.ErrorMessage, o.ErrorMessage)
+	if o.Files != nil {
+		s.Files = o.Files
+	}
+	if o.Replacements != nil {
+		s.Replacements = o.Replacements
+	}
+	if o.Overrides != nil {
+		s.Overrides = o.Overrides
+	}
+	if o.FormatOverrides != nil {
+		s.FormatOverrides = o.FormatOverrides
+	}
+	if o.SupportedEnvs != nil {
+		s.SupportedEnvs = o.SupportedEnvs
+	}
+	if o.Rosetta2 != nil {
+		s.Rosetta2 = o.Rosetta2
+	}
+	if o.WindowsARMEmulation != nil {
+		s.WindowsARMEmulation = o.WindowsARMEmulation
+	}
+	if o.CompleteWindowsExt != nil {
+		s.CompleteWindowsExt = o.CompleteWindowsExt
+	}
+	if o.Checksum != nil {
+		s.Checksum = o.Checksum
+	}
+	if o.NoAsset != nil {
+		s.NoAsset = o.NoAsset
+	}
+	return s
+}
+
+func (s Spec) supports(target provider.Platform) bool {
+	if len(s.SupportedEnvs) == 0 {
+		return true
+	}
+	for _, env := range s.SupportedEnvs {
+		if env == "all" || env == target.OS || env == target.Arch || env == target.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// templateVars are the fields aqua templates use.
+type templateVars struct {
+	Version, SemVer, OS, Arch, Format string
+	// Set for files[].src and checksum.asset
+	Asset, AssetWithoutExt, FileName string
+}
+
+// templateFuncs are the template functions registry.yaml files use, with
+// sprig's argument order.
+var templateFuncs = template.FuncMap{
+	"trimV":      func(s string) string { return strings.TrimPrefix(s, "v") },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trim":       strings.TrimSpace,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+}
+
+func render(text string, vars templateVars) (string, error) {
+	tmpl, err := template.New("aqua").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func replaced(replacements map[string]string, s string) string {
+	if r, ok := replacements[s]; ok {
+		return r
+	}
+	return s
+}
+
+func isArchive(archiveType string) bool {
+	return archiveType == "zip" || strings.HasPrefix(archiveType, "tar.")
+}
+
+func addExe(url string) string {
+	if url == "" {
+		return ""
+	}
+	return url + ".exe"
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,230 @@
+package aqua
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+// ghRegistry is the cli/cli entry of the aqua registry, trimmed.
+const ghRegistry = `
+packages:
+  - type: github_release
+    repo_owner: cli
+    repo_name: cli
+    description: GitHub’s official command line tool
+    version_constraint: semver(">= 2.0.0")
+    asset: gh_{{trimV .Version}}_{{.OS}}_{{.Arch}}.{{.Format}}
+    format: tar.gz
+    files:
+      - name: gh
+        src: gh_{{trimV .Version}}_{{.OS}}_{{.Arch}}/bin/gh
+    replacements:
+      darwin: macOS
+    overrides:
+      - goos: windows
+        format: zip
+        files:
+          - name: gh
+            src: bin/gh
+      - goos: darwin
+        format: zip
+    checksum:
+      type: github_release
+      asset: gh_{{trimV .Version}}_checksums.txt
+      algorithm: sha256
+    supported_envs:
+      - darwin
+      - linux
+      - amd64
+    version_overrides:
+      - version_constraint: semver("< 2.0.0")
+        asset: gh_{{trimV .Version}}_{{.OS}}_{{.Arch}}.{{.Format}}
+        replacements:
+          darwin: macOS
+          amd64: 64bit
+        checksum:
+          enabled: false
+`
+
+func parseOne(t *testing.T, data string) *Package {
+	t.Helper()
+	pkgs, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 {
+		t.Fatalf("parsed %d packages, want 1", len(pkgs))
+	}
+	return pkgs[0]
+}
+
+func TestPackage_Names(t *testing.T) {
+	p := parseOne(t, ghRegistry)
+	if p.FullName() != "cli/cli" || p.Repo() != "cli/cli" || p.Command() != "gh" {
+		t.Errorf("FullName=%q Repo=%q Command=%q", p.FullName(), p.Repo(), p.Command())
+	}
+
+	p = parseOne(t, `
+packages:
+  - type: http
+    name: kubernetes/kubectl
+    url: https://dl.k8s.io/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl
+    aliases:
+      - name: kubectl
+`)
+	if p.FullName() != "kubernetes/kubectl" || p.Repo() != "" || p.Command() != "kubectl" {
+		t.Errorf("FullName=%q Repo=%q Command=%q", p.FullName(), p.Repo(), p.Command())
+	}
+	if names := p.Names(); len(names) != 2 || names[1] != "kubectl" {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestPackage_Resolve(t *testing.T) {
+	gh := parseOne(t, ghRegistry)
+	tests := []struct {
+		name     string
+		pkg      *Package
+		version  string
+		platform string
+		want     Artifact
+		wantErr  string
+	}{
+		{
+			name: "linux", pkg: gh, version: "v2.40.1", platform: "linux/amd64",
+			want: Artifact{
+				Type:     TypeGitHubRelease,
+				Asset:    "gh_2.40.1_linux_amd64.tar.gz",
+				Member:   "gh_2.40.1_linux_amd64/bin/gh",
+				Checksum: "gh_2.40.1_checksums.txt",
+			},
+		},
+		{
+			name: "replacement and override", pkg: gh, version: "v2.40.1", platform: "darwin/arm64",
+			want: Artifact{
+				Type:     TypeGitHubRelease,
+				Asset:    "gh_2.40.1_macOS_arm64.zip",
+				Member:   "gh_2.40.1_macOS_arm64/bin/gh",
+				Checksum: "gh_2.40.1_checksums.txt",
+			},
+		},
+		{
+			name: "windows gets .exe", pkg: gh, version: "v2.40.1", platform: "windows/amd64",
+			want: Artifact{
+				Type:     TypeGitHubRelease,
+				Asset:    "gh_2.40.1_windows_amd64.zip",
+				Member:   "bin/gh.exe",
+				Checksum: "gh_2.40.1_checksums.txt",
+			},
+		},
+		{
+			name: "version override", pkg: gh, version: "v1.14.0", platform: "linux/amd64",
+			want: Artifact{
+				Type:   TypeGitHubRelease,
+				Asset:  "gh_1.14.0_linux_64bit.tar.gz",
+				Member: "gh_1.14.0_linux_64bit/bin/gh",
+			},
+		},
+		{
+			name: "unsupported platform", pkg: gh, version: "v2.40.1", platform: "windows/arm64",
+			wantErr: "aqua package cli/cli: no windows/arm64 build",
+		},
+		{
+			name: "rosetta2 and raw", version: "v0.5.0", platform: "darwin/arm64",
+			pkg: &Package{RepoOwner: "o", RepoName: "tool", Spec: Spec{
+				Type: TypeGitHubRelease, Asset: "tool-{{.OS}}-{{.Arch}}", Format: "raw", Rosetta2: ptr(true),
+			}},
+			want: Artifact{Type: TypeGitHubRelease, Asset: "tool-darwin-amd64"},
+		},
+		{
+			name: "raw on windows", version: "v0.5.0", platform: "windows/amd64",
+			pkg: &Package{RepoOwner: "o", RepoName: "tool", Spec: Spec{
+				Type: TypeGitHubRelease, Asset: "tool-{{.OS}}-{{.Arch}}", Format: "raw",
+			}},
+			want: Artifact{Type: TypeGitHubRelease, Asset: "tool-windows-amd64.exe"},
+		},
+		{
+			name: "http", version: "v1.30.0", platform: "linux/arm64",
+			pkg: &Package{Name: "kubernetes/kubectl", Spec: Spec{
+				Type: TypeHTTP, URL: "https://dl.k8s.io/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl",
+			}},
+			want: Artifact{Type: TypeHTTP, Asset: "kubectl", URL: "https://dl.k8s.io/v1.30.0/bin/linux/arm64/kubectl"},
+		},
+		{
+			name: "version prefix", version: "cli-v1.2.0", platform: "linux/amd64",
+			pkg: &Package{RepoOwner: "o", RepoName: "mono", Spec: Spec{
+				Type: TypeGitHubRelease, VersionPrefix: "cli-", Asset: "cli_{{trimV .SemVer}}_{{.OS}}.tar.gz",
+				Files: []File{{Name: "cli"}},
+			}},
+			want: Artifact{Type: TypeGitHubRelease, Asset: "cli_1.2.0_linux.tar.gz", Member: "cli"},
+		},
+		{
+			name: "unsupported type", version: "v1.0.0", platform: "linux/amd64",
+			pkg:     &Package{RepoOwner: "o", RepoName: "tool", Spec: Spec{Type: "go_install"}},
+			wantErr: `type "go_install" is not supported`,
+		},
+		{
+			name: "error message", version: "v1.0.0", platform: "linux/amd64",
+			pkg: &Package{RepoOwner: "o", RepoName: "tool", Spec: Spec{Type: TypeGitHubRelease, Asset: "tool"},
+				VersionConstraint: "false",
+				VersionOverrides: []VersionOverride{
+					{VersionConstraint: "true", Spec: Spec{ErrorMessage: "tool moved to o/tool2\n"}},
+				}},
+			wantErr: "aqua package o/tool: tool moved to o/tool2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform, err := provider.ParsePlatform(tt.platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tt.pkg.Resolve(tt.version, platform)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("Resolve() = %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"true", "v1.0.0", true},
+		{"false", "v1.0.0", false},
+		{`semver(">= 2.0.0")`, "v2.1.0", true},
+		{`semver(">= 2.0.0")`, "v1.9.9", false},
+		{`semver(">= 1.0.0, < 2.0.0")`, "v1.5.0", true},
+		{`Version == "v1.0.0"`, "v1.0.0", true},
+		{`Version != "v1.0.0"`, "v1.0.0", false},
+		{`Version == "v1.0.0" || semver(">= 3.0.0")`, "v3.1.0", true},
+		{`Version == "v1.0.0" or semver(">= 3.0.0")`, "v2.0.0", false},
+		{`semver(">= 1.0.0") and semver("< 1.2.0")`, "v1.1.0", true},
+		{`semver(">= 1.0.0") && semver("< 1.2.0")`, "v1.2.0", false},
+		{`semverWithVersion(">= 1.0.0", trimPrefix(Version, "cli-"))`, "cli-v1.3.0", true},
+		{`Version in ["v1.0.0"]`, "v1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := matchVersion(tt.constraint, tt.version); got != tt.want {
+			t.Errorf("matchVersion(%q, %q) = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
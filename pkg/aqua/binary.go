@@ -0,0 +1,68 @@
+package aqua
+
+import (
+	"fmt"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// Binary compiles the package into a binary named after its command. A
+// github_release package downloads the release asset it names, verified
+// against its checksum file; an http package downloads its URL, with the
+// versions of its repository if it has one.
+func (p *Package) Binary() *binary.Binary {
+	b := &binary.Binary{
+		Name:    p.Command(),
+		Package: Prefix + p.FullName(),
+		TarFileF: func(b *binary.Binary) (string, error) {
+			a, err := p.Resolve(b.Version, b.Platform)
+			if err != nil {
+				return "", err
+			}
+			return a.Member, nil
+		},
+	}
+	if p.Type == TypeHTTP {
+		b.GitHubRepo = p.Repo()
+		b.VersionF = binary.GithubLatest
+		if b.GitHubRepo == "" {
+			b.VersionF = func(*binary.Binary) (string, error) {
+				return "", fmt.Errorf("aqua package %s has no repository to look its versions up in; pin a version", p.FullName())
+			}
+		}
+		b.URLF = func(b *binary.Binary) (string, error) {
+			a, err := p.Resolve(b.Version, b.Platform)
+			if err != nil {
+				return "", err
+			}
+			// The format can differ by platform and version.
+			b.IsDynamic = provider.DetectArchiveType(a.Asset) != ""
+			return a.URL, nil
+		}
+		return b
+	}
+
+	ref := "github.com/" + p.Repo()
+	b.AutoDetect = true
+	b.ProviderRef = ref
+	b.ProviderType = (&provider.GitHub{}).Name()
+	b.VersionF = func(b *binary.Binary) (string, error) {
+		return provider.LatestInChannel(&provider.GitHub{}, ref, b.Channel)
+	}
+	b.AssetF = func(b *binary.Binary) (string, error) {
+		a, err := p.Resolve(b.Version, b.Platform)
+		if err != nil {
+			return "", err
+		}
+		return a.Asset, nil
+	}
+	b.ChecksumF = func(b *binary.Binary) (string, error) {
+		a, err := p.Resolve(b.Version, b.Platform)
+		if err != nil {
+			return "", err
+		}
+		return a.Checksum, nil
+	}
+	return b
+}
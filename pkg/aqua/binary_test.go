@@ -0,0 +1,44 @@
+package aqua
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/provider"
+)
+
+func TestPackage_Binary(t *testing.T) {
+	gh := parseOne(t, ghRegistry).Binary()
+	if gh.Name != "gh" || gh.Package != "aqua:cli/cli" || !gh.AutoDetect || gh.ProviderRef != "github.com/cli/cli" || gh.ProviderType != "github" {
+		t.Fatalf("Binary() = %+v", gh)
+	}
+	gh.Version, gh.Platform = "v2.40.1", provider.Platform{OS: "linux", Arch: "arm64"}
+	for name, f := range map[string]binary.Callback{
+		"gh_2.40.1_linux_arm64.tar.gz": gh.AssetF,
+		"gh_2.40.1_checksums.txt":      gh.ChecksumF,
+		"gh_2.40.1_linux_arm64/bin/gh": gh.TarFileF,
+	} {
+		if got, err := f(gh); err != nil || got != name {
+			t.Errorf("got %q, %v, want %q", got, err, name)
+		}
+	}
+	gh.Platform = provider.Platform{OS: "windows", Arch: "arm64"}
+	if _, err := gh.AssetF(gh); err == nil || !strings.Contains(err.Error(), "no windows/arm64 build") {
+		t.Errorf("unsupported platform: %v", err)
+	}
+
+	kubectl := (&Package{Name: "kubernetes/kubectl", Spec: Spec{
+		Type: TypeHTTP, URL: "https://dl.k8s.io/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl.tar.gz",
+	}}).Binary()
+	if kubectl.AutoDetect || kubectl.Name != "kubectl" || kubectl.Package != "aqua:kubernetes/kubectl" {
+		t.Fatalf("Binary() = %+v", kubectl)
+	}
+	kubectl.Version, kubectl.Platform = "v1.30.0", provider.Platform{OS: "linux", Arch: "amd64"}
+	if url, err := kubectl.URLF(kubectl); err != nil || url != "https://dl.k8s.io/v1.30.0/bin/linux/amd64/kubectl.tar.gz" || !kubectl.IsDynamic {
+		t.Errorf("URLF() = %q, %v (dynamic %v)", url, err, kubectl.IsDynamic)
+	}
+	if _, err := kubectl.VersionF(kubectl); err == nil || !strings.Contains(err.Error(), "pin a version") {
+		t.Errorf("VersionF() without a repository: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package aqua
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fentas/b/pkg/semver"
+)
+
+// version_constraint atoms b understands. The registry writes them in the
+// expr language; anything else never matches.
+var (
+	semverAtom      = regexp.MustCompile(`^semver\(\s*"([^"]*)"\s*\)$`)
+	semverWithAtom  = regexp.MustCompile(`^semverWithVersion\(\s*"([^"]*)"\s*,\s*trimPrefix\(\s*Version\s*,\s*"([^"]*)"\s*\)\s*\)$`)
+	versionCompAtom = regexp.MustCompile(`^Version\s*(==|!=)\s*"([^"]*)"$`)
+)
+
+// matchVersion evaluates a version_constraint for version: "true",
+// "false", semver("<range>"), semverWithVersion("<range>",
+// trimPrefix(Version, "<prefix>")) and Version == / != "<tag>", joined by
+// "or"/"||" and "and"/"&&".
+func matchVersion(constraint, version string) bool {
+	for _, alt := range splitOp(constraint, "||", " or ") {
+		all := true
+		for _, atom := range splitOp(alt, "&&", " and ") {
+			if !matchAtom(strings.TrimSpace(atom), version) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAtom(atom, version string) bool {
+	if strings.HasPrefix(atom, "(") && strings.HasSuffix(atom, ")") {
+		atom = strings.TrimSpace(atom[1 : len(atom)-1])
+	}
+	switch atom {
+	case "true":
+		return true
+	case "false", "":
+		return false
+	}
+	if m := semverAtom.FindStringSubmatch(atom); m != nil {
+		return matchRange(m[1], version)
+	}
+	if m := semverWithAtom.FindStringSubmatch(atom); m != nil {
+		return matchRange(m[1], strings.TrimPrefix(version, m[2]))
+	}
+	if m := versionCompAtom.FindStringSubmatch(atom); m != nil {
+		return (version == m[2]) == (m[1] == "==")
+	}
+	return false
+}
+
+func matchRange(constraint, version string) bool {
+	c, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, ok := semver.Parse(version)
+	return ok && c.Check(v)
+}
+
+// splitOp splits s at either spelling of an operator.
+func splitOp(s, symbol, word string) []string {
+	return strings.Split(strings.ReplaceAll(s, word, symbol), symbol)
+}
@@ -0,0 +1,301 @@
+package aqua
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fentas/b/pkg/gitcache"
+	"github.com/fentas/b/pkg/offline"
+)
+
+// RegistryEnv lists extra registries, separated like PATH.
+const RegistryEnv = "B_AQUA_REGISTRY"
+
+// DefaultRegistry is where aqua: refs are looked up when no registry is
+// configured.
+const DefaultRegistry = "github.com/aquaproj/aqua-registry"
+
+// Registry is the content of a registry.yaml file.
+type Registry struct {
+	Packages []*Package `yaml:"packages"`
+}
+
+// Parse reads the packages of a registry.yaml file.
+func Parse(data []byte) ([]*Package, error) {
+	var r Registry
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r.Packages, nil
+}
+
+// EnvPaths returns the registries listed in $B_AQUA_REGISTRY.
+func EnvPaths() []string {
+	var refs []string
+	for _, ref := range filepath.SplitList(os.Getenv(RegistryEnv)) {
+		if ref != "" {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Index looks packages up in registries. A registry is a registry.yaml
+// file, a directory (a registry checkout, or any tree of registry.yaml
+// files), or a git repository ("github.com/aquaproj/aqua-registry@v4.300.0",
+// "git:///path/to/repo") read through the git cache. Registries are opened
+// on first use; a later one wins over an earlier one.
+type Index struct {
+	// Dir is what relative registry paths are joined against.
+	Dir string
+	// CacheRoot is the git cache; empty is gitcache.DefaultCacheRoot().
+	CacheRoot string
+	Refs      []string
+
+	mu      sync.Mutex
+	sources []*source
+}
+
+// NewIndex returns an index over refs, relative paths resolved against dir.
+func NewIndex(dir string, refs []string) *Index {
+	return &Index{Dir: dir, Refs: refs}
+}
+
+// Find returns the package named name or aliased to it.
+func (x *Index) Find(name string) (*Package, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	sources, err := x.open()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(sources) - 1; i >= 0; i-- {
+		s := sources[i]
+		// A registry checkout keeps each package in pkgs/<name>/, which
+		// saves parsing all of them.
+		if data, err := s.readFile(path.Join("pkgs", name, "registry.yaml")); err == nil && s.files == nil {
+			pkgs, err := Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: pkgs/%s/registry.yaml: %w", s.ref, name, err)
+			}
+			if p := find(pkgs, name); p != nil {
+				return p, nil
+			}
+		}
+		pkgs, err := s.all()
+		if err != nil {
+			return nil, err
+		}
+		if p := find(pkgs, name); p != nil {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("aqua package %s not found in %s", name, strings.Join(x.Refs, ", "))
+}
+
+// Packages returns the packages of every registry, sorted by name.
+func (x *Index) Packages() ([]*Package, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	sources, err := x.open()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*Package)
+	for _, s := range sources {
+		pkgs, err := s.all()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pkgs {
+			byName[p.FullName()] = p
+		}
+	}
+	out := make([]*Package, 0, len(byName))
+	for _, p := range byName {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FullName() < out[j].FullName() })
+	return out, nil
+}
+
+// open opens the registries; x.mu is held.
+func (x *Index) open() ([]*source, error) {
+	if x.sources != nil {
+		return x.sources, nil
+	}
+	sources := make([]*source, 0, len(x.Refs))
+	for _, ref := range x.Refs {
+		s, err := x.openSource(ref)
+		if err != nil {
+			return nil, fmt.Errorf("aqua registry %s: %w", ref, err)
+		}
+		sources = append(sources, s)
+	}
+	x.sources = sources
+	return sources, nil
+}
+
+func (x *Index) openSource(ref string) (*source, error) {
+	local := ref
+	if !filepath.IsAbs(local) && x.Dir != "" {
+		local = filepath.Join(x.Dir, local)
+	}
+	info, err := os.Stat(local)
+	switch {
+	case err == nil && info.IsDir():
+		return &source{ref: ref, fsys: os.DirFS(local)}, nil
+	case err == nil:
+		return &source{ref: ref, fsys: os.DirFS(filepath.Dir(local)), files: []string{filepath.Base(local)}}, nil
+	case strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../"):
+		return nil, err
+	}
+
+	resolved := gitcache.ResolveGitURL(ref, x.Dir)
+	version := gitcache.RefVersion(ref)
+	if resolved.IsLocal {
+		commit, err := gitcache.ResolveLocalRef(resolved.URL, version)
+		if err != nil {
+			return nil, err
+		}
+		return &source{ref: ref, repo: resolved.URL, commit: commit}, nil
+	}
+
+	root := x.CacheRoot
+	if root == "" {
+		root = gitcache.DefaultCacheRoot()
+	}
+	base := gitcache.RefBase(ref)
+	dir := gitcache.CacheDir(root, base)
+	if offline.Enabled() {
+		if version == "" {
+			version = "HEAD"
+		}
+		commit, err := gitcache.ResolveLocalRef(dir, version)
+		if err != nil {
+			return nil, offline.Missing(ref, "not in the git cache (%s)", root)
+		}
+		return &source{ref: ref, repo: dir, commit: commit}, nil
+	}
+	commit, err := gitcache.ResolveRefAuth(resolved.URL, version, resolved.AuthHeader)
+	if err != nil {
+		return nil, err
+	}
+	if err := gitcache.EnsureCloneAuth(root, base, resolved.URL, resolved.AuthHeader); err != nil {
+		return nil, fmt.Errorf("cloning %s: %w", resolved.URL, err)
+	}
+	if err := gitcache.FetchAuth(root, base, commit, resolved.AuthHeader); err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", commit, err)
+	}
+	return &source{ref: ref, repo: dir, commit: commit}, nil
+}
+
+// source is one opened registry: a directory (fsys) or a git commit.
+type source struct {
+	ref  string
+	fsys fs.FS
+	// files, when set, are the only registry files of fsys.
+	files        []string
+	repo, commit string
+
+	pkgs []*Package
+}
+
+func (s *source) readFile(name string) ([]byte, error) {
+	if s.fsys != nil {
+		return fs.ReadFile(s.fsys, name)
+	}
+	return gitcache.ShowFileDir(s.repo, s.commit, name)
+}
+
+// registryFiles lists the registry.yaml files: the aggregated one at the
+// root when there is one, else all of them.
+func (s *source) registryFiles() ([]string, error) {
+	if s.files != nil {
+		return s.files, nil
+	}
+	var names []string
+	if s.fsys != nil {
+		err := fs.WalkDir(s.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && strings.HasPrefix(d.Name(), ".") && name != "." {
+				return fs.SkipDir
+			}
+			if !d.IsDir() && d.Name() == "registry.yaml" {
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		entries, err := gitcache.ListTreeWithModesDir(s.repo, s.commit)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if path.Base(e.Path) == "registry.yaml" {
+				names = append(names, e.Path)
+			}
+		}
+	}
+	for _, name := range names {
+		if name == "registry.yaml" {
+			return []string{name}, nil
+		}
+	}
+	return names, nil
+}
+
+// all parses every package of the registry, once.
+func (s *source) all() ([]*Package, error) {
+	if s.pkgs != nil {
+		return s.pkgs, nil
+	}
+	files, err := s.registryFiles()
+	if err != nil {
+		return nil, err
+	}
+	pkgs := []*Package{}
+	for _, name := range files {
+		data, err := s.readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", s.ref, name, err)
+		}
+		pkgs = append(pkgs, parsed...)
+	}
+	s.pkgs = pkgs
+	return pkgs, nil
+}
+
+// find returns the package named name, or else the one aliased to it.
+func find(pkgs []*Package, name string) *Package {
+	for _, p := range pkgs {
+		if p.FullName() == name {
+			return p
+		}
+	}
+	for _, p := range pkgs {
+		for _, a := range p.Aliases {
+			if a.Name == name {
+				return p
+			}
+		}
+	}
+	return nil
+}
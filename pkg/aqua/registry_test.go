@@ -0,0 +1,153 @@
+package aqua
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/gitcache"
+	"github.com/fentas/b/pkg/offline"
+)
+
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pkgYAML is a registry.yaml with one github_release package.
+func pkgYAML(owner, name, asset string) string {
+	return "packages:\n  - type: github_release\n    repo_owner: " + owner + "\n    repo_name: " + name +
+		"\n    asset: " + asset + "\n    aliases:\n      - name: " + name + "-alias\n"
+}
+
+// registryDir lays out a registry checkout: pkgs/<owner>/<name>/registry.yaml.
+func registryDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "pkgs", "cli", "cli", "registry.yaml"), pkgYAML("cli", "cli", "gh.tar.gz"))
+	writeFile(t, filepath.Join(dir, "pkgs", "jqlang", "jq", "registry.yaml"), pkgYAML("jqlang", "jq", "jq"))
+	writeFile(t, filepath.Join(dir, ".github", "registry.yaml"), "not: a registry")
+	return dir
+}
+
+func TestIndex_Find(t *testing.T) {
+	dir := registryDir(t)
+	local := filepath.Join(t.TempDir(), "local.yaml")
+	writeFile(t, local, pkgYAML("cli", "cli", "gh-patched.tar.gz"))
+
+	tests := []struct {
+		name      string
+		refs      []string
+		pkg       string
+		wantAsset string
+		wantErr   string
+	}{
+		{name: "checkout", refs: []string{dir}, pkg: "cli/cli", wantAsset: "gh.tar.gz"},
+		{name: "alias", refs: []string{dir}, pkg: "jq-alias", wantAsset: "jq"},
+		{name: "later wins", refs: []string{dir, local}, pkg: "cli/cli", wantAsset: "gh-patched.tar.gz"},
+		{name: "falls back", refs: []string{local, dir}, pkg: "jqlang/jq", wantAsset: "jq"},
+		{name: "relative", refs: []string{"./local.yaml"}, pkg: "cli/cli", wantAsset: "gh-patched.tar.gz"},
+		{name: "unknown", refs: []string{dir}, pkg: "no/such", wantErr: "aqua package no/such not found in " + dir},
+		{name: "missing registry", refs: []string{"./nope"}, pkg: "cli/cli", wantErr: "aqua registry ./nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewIndex(filepath.Dir(local), tt.refs).Find(tt.pkg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Find() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Asset != tt.wantAsset {
+				t.Errorf("Find() asset = %q, want %q", p.Asset, tt.wantAsset)
+			}
+		})
+	}
+}
+
+func TestIndex_Packages(t *testing.T) {
+	dir := registryDir(t)
+	pkgs, err := NewIndex("", []string{dir}).Packages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range pkgs {
+		names = append(names, p.FullName())
+	}
+	if strings.Join(names, ",") != "cli/cli,jqlang/jq" {
+		t.Errorf("Packages() = %v", names)
+	}
+
+	// The aggregated registry.yaml at the root stands for all the others.
+	writeFile(t, filepath.Join(dir, "registry.yaml"), pkgYAML("derailed", "k9s", "k9s.tar.gz"))
+	pkgs, err = NewIndex("", []string{dir}).Packages()
+	if err != nil || len(pkgs) != 1 || pkgs[0].FullName() != "derailed/k9s" {
+		t.Errorf("Packages() = %v, %v", pkgs, err)
+	}
+}
+
+func TestIndex_Git(t *testing.T) {
+	work := registryDir(t)
+	git(t, "init", "-q", work)
+	git(t, "-C", work, "config", "user.email", "t@t.com")
+	git(t, "-C", work, "config", "user.name", "T")
+	git(t, "-C", work, "add", "-A")
+	git(t, "-C", work, "commit", "-q", "--no-gpg-sign", "-m", "v1")
+	git(t, "-C", work, "tag", "v1")
+	writeFile(t, filepath.Join(work, "pkgs", "cli", "cli", "registry.yaml"), pkgYAML("cli", "cli", "gh-v2.tar.gz"))
+	git(t, "-C", work, "commit", "-q", "--no-gpg-sign", "-am", "v2")
+
+	for ref, want := range map[string]string{
+		"git://" + work:         "gh-v2.tar.gz",
+		"git://" + work + "@v1": "gh.tar.gz",
+	} {
+		x := NewIndex("", []string{ref})
+		p, err := x.Find("cli/cli")
+		if err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		}
+		if p.Asset != want {
+			t.Errorf("%s: asset %q, want %q", ref, p.Asset, want)
+		}
+		if pkgs, err := x.Packages(); err != nil || len(pkgs) != 2 {
+			t.Errorf("%s: Packages() = %v, %v", ref, pkgs, err)
+		}
+	}
+
+	// A remote registry is read from the git cache, which is all there is
+	// offline.
+	root := t.TempDir()
+	git(t, "clone", "-q", "--bare", work, gitcache.CacheDir(root, DefaultRegistry))
+	offline.Set(true)
+	t.Cleanup(func() { offline.Set(false) })
+	x := &Index{CacheRoot: root, Refs: []string{DefaultRegistry}}
+	if p, err := x.Find("cli/cli"); err != nil || p.Asset != "gh-v2.tar.gz" {
+		t.Errorf("cached: %+v, %v", p, err)
+	}
+	x = &Index{CacheRoot: t.TempDir(), Refs: []string{DefaultRegistry}}
+	if _, err := x.Find("cli/cli"); !errors.Is(err, offline.ErrOffline) {
+		t.Errorf("uncached offline: %v", err)
+	}
+}
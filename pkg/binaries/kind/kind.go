@@ -1,37 +0,0 @@
-package kind
-
-import (
-	"context"
-	"fmt"
-	"runtime"
-	"strings"
-
-	"github.com/fentas/b/pkg/binaries"
-	"github.com/fentas/b/pkg/binary"
-)
-
-func Binary(options *binaries.BinaryOptions) *binary.Binary {
-	if options == nil {
-		options = &binaries.BinaryOptions{
-			Context: context.Background(),
-		}
-	}
-	return &binary.Binary{
-		Context:    options.Context,
-		Envs:       options.Envs,
-		Tracker:    options.Tracker,
-		Version:    options.Version,
-		Name:       "kind",
-		GitHubRepo: "kubernetes-sigs/kind",
-		GitHubFile: fmt.Sprintf("kind-%s-%s", runtime.GOOS, runtime.GOARCH),
-		VersionF:   binary.GithubLatest,
-		IsTarGz:    false,
-		VersionLocalF: func(b *binary.Binary) (string, error) {
-			s, err := b.Exec("version")
-			if err != nil {
-				return "", err
-			}
-			return strings.Split(s, " ")[1], nil
-		},
-	}
-}
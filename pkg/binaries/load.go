@@ -0,0 +1,151 @@
+package binaries
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fentas/b/pkg/binary"
+	"gopkg.in/yaml.v3"
+)
+
+// PresetsEnv lists extra preset files or directories, separated like PATH.
+const PresetsEnv = "B_PRESETS"
+
+//go:embed presets/*.yaml
+var builtin embed.FS
+
+// Builtin returns the presets shipped with b, sorted by name.
+func Builtin() ([]*Preset, error) {
+	return loadFS(builtin, "presets")
+}
+
+// Parse reads presets from YAML. A document may hold several presets
+// separated by "---".
+func Parse(data []byte) ([]*Preset, error) {
+	var presets []*Preset
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	for {
+		var p Preset
+		err := dec.Decode(&p)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		presets = append(presets, &p)
+	}
+	return presets, nil
+}
+
+// Load reads presets from a file, or from every *.yaml / *.yml file in a
+// directory.
+func Load(file string) ([]*Preset, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadFS(os.DirFS(file), ".")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	presets, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return presets, nil
+}
+
+// LoadPaths loads presets from each path in order; relative paths are
+// joined against dir. Later presets replace earlier ones of the same name.
+func LoadPaths(dir string, paths []string) ([]*Preset, error) {
+	var all []*Preset
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) && dir != "" {
+			p = filepath.Join(dir, p)
+		}
+		presets, err := Load(p)
+		if err != nil {
+			return nil, fmt.Errorf("loading presets: %w", err)
+		}
+		all = Merge(all, presets)
+	}
+	return all, nil
+}
+
+// EnvPaths returns the paths listed in $B_PRESETS.
+func EnvPaths() []string {
+	return filepath.SplitList(os.Getenv(PresetsEnv))
+}
+
+// Merge returns base with overrides applied: a preset replaces the one of
+// the same name in place, new names are appended.
+func Merge(base, overrides []*Preset) []*Preset {
+	out := append([]*Preset(nil), base...)
+	for _, p := range overrides {
+		replaced := false
+		for i := range out {
+			if out[i].Name == p.Name {
+				out[i], replaced = p, true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Binaries compiles presets into binaries sharing options.
+func Binaries(options *BinaryOptions, presets []*Preset) []*binary.Binary {
+	out := make([]*binary.Binary, 0, len(presets))
+	for _, p := range presets {
+		out = append(out, p.Binary(options))
+	}
+	return out
+}
+
+func loadFS(fsys fs.FS, dir string) ([]*Preset, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var all []*Preset
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !(strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")) {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		presets, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		all = Merge(all, presets)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all, nil
+}
@@ -0,0 +1,156 @@
+package binaries
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPresets = `name: one
+repo: org/one
+file: one-{{.OS}}
+---
+name: two
+url: https://dl.example.com/two/{{.Version}}/two
+latest:
+  url: https://dl.example.com/two/latest
+`
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(presets []*Preset) string {
+	var out []string
+	for _, p := range presets {
+		out = append(out, p.Name)
+	}
+	return strings.Join(out, ",")
+}
+
+func TestParse(t *testing.T) {
+	presets, err := Parse([]byte(testPresets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(presets); got != "one,two" {
+		t.Errorf("names = %s", got)
+	}
+	if presets[1].Latest == nil || presets[1].Latest.URL != "https://dl.example.com/two/latest" {
+		t.Errorf("latest = %+v", presets[1].Latest)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"unknown field", "name: a\nrepo: o/a\nfile: a\nfiel: typo\n", "fiel"},
+		{"invalid preset", "name: a\n", "needs url"},
+		{"bad yaml", "name: [\n", "yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "multi.yaml"), testPresets)
+	writeFile(t, filepath.Join(dir, "dir", "b.yml"), "name: b\nrepo: org/b\nfile: b\n")
+	writeFile(t, filepath.Join(dir, "dir", "a.yaml"), "name: a\nrepo: org/a\nfile: a\n")
+	writeFile(t, filepath.Join(dir, "dir", "README.md"), "not a preset")
+	writeFile(t, filepath.Join(dir, "bad.yaml"), "name: bad\n")
+
+	presets, err := Load(filepath.Join(dir, "multi.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(presets); got != "one,two" {
+		t.Errorf("file: %s", got)
+	}
+
+	presets, err = Load(filepath.Join(dir, "dir"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(presets); got != "a,b" {
+		t.Errorf("dir: %s", got)
+	}
+
+	if _, err := Load(filepath.Join(dir, "bad.yaml")); err == nil || !strings.Contains(err.Error(), "bad.yaml") {
+		t.Errorf("invalid file: err = %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestLoadPaths(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.yaml"), testPresets)
+	writeFile(t, filepath.Join(dir, "override.yaml"), "name: one\nrepo: fork/one\nfile: one\n")
+
+	presets, err := LoadPaths(dir, []string{"base.yaml", "", filepath.Join(dir, "override.yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(presets); got != "one,two" {
+		t.Fatalf("names = %s", got)
+	}
+	if presets[0].Repo != "fork/one" {
+		t.Errorf("override not applied: %s", presets[0].Repo)
+	}
+
+	if _, err := LoadPaths(dir, []string{"missing.yaml"}); err == nil || !strings.Contains(err.Error(), "loading presets") {
+		t.Errorf("missing: err = %v", err)
+	}
+}
+
+func TestEnvPaths(t *testing.T) {
+	t.Setenv(PresetsEnv, "")
+	if got := EnvPaths(); len(got) != 0 {
+		t.Errorf("empty env: %v", got)
+	}
+	t.Setenv(PresetsEnv, "a.yaml"+string(os.PathListSeparator)+"dir")
+	if got := EnvPaths(); len(got) != 2 || got[0] != "a.yaml" || got[1] != "dir" {
+		t.Errorf("EnvPaths = %v", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := []*Preset{{Name: "a", Repo: "o/a"}, {Name: "b"}}
+	got := Merge(base, []*Preset{{Name: "c"}, {Name: "a", Repo: "fork/a"}})
+	if names(got) != "a,b,c" {
+		t.Errorf("names = %s", names(got))
+	}
+	if got[0].Repo != "fork/a" {
+		t.Errorf("a not replaced: %s", got[0].Repo)
+	}
+	if base[0].Repo != "o/a" {
+		t.Error("base modified")
+	}
+}
+
+func TestBinaries(t *testing.T) {
+	presets, err := Parse([]byte(testPresets))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bins := Binaries(&BinaryOptions{Version: "v1"}, presets)
+	if len(bins) != 2 || bins[0].Name != "one" || bins[1].Name != "two" || bins[1].Version != "v1" {
+		t.Errorf("unexpected binaries: %+v", bins)
+	}
+}
@@ -0,0 +1,263 @@
+package binaries
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// Archive types a preset can declare.
+const (
+	ArchiveRaw    = "raw"
+	ArchiveTarGz  = "tar.gz"
+	ArchiveTarXz  = "tar.xz"
+	ArchiveTarBz2 = "tar.bz2"
+	ArchiveTarZst = "tar.zst"
+	ArchiveZip    = "zip"
+	// A single compressed file, e.g. tool-linux-amd64.gz.
+	ArchiveGz  = "gz"
+	ArchiveXz  = "xz"
+	ArchiveBz2 = "bz2"
+	ArchiveZst = "zst"
+	// ArchiveAuto picks the type from the download URL's extension.
+	ArchiveAuto = "auto"
+)
+
+// goos and goarch are the host platform presets render for unless the
+// binary targets another one; tests override them.
+var (
+	goos   = runtime.GOOS
+	goarch = runtime.GOARCH
+)
+
+// Preset is a declarative binary definition, loaded from YAML:
+//
+//	name: kubeseal
+//	repo: bitnami-labs/sealed-secrets
+//	file: kubeseal-{{trimPrefix "v" .Version}}-{{.OS}}-{{.Arch}}.tar.gz
+//	archive: tar.gz
+//	versionCmd:
+//	  args: [--version]
+//	  regex: (\S+)$
+//	  prefix: v
+//
+// Templates use the same data and funcs as templated http(s):// refs:
+// {{.Version}}, {{.OS}}, {{.Arch}}, trimPrefix, trimSuffix, replace, lower
+// and upper.
+type Preset struct {
+	Name string `yaml:"name"`
+	// Repo is the GitHub repository ("owner/repo"). It is the default
+	// version source (its latest release) and, with File, the download.
+	Repo string `yaml:"repo,omitempty"`
+	// File is the release asset name template, downloaded from Repo's
+	// release for the version.
+	File string `yaml:"file,omitempty"`
+	// URL is a full download URL template, for binaries not published as
+	// GitHub release assets. Takes precedence over File.
+	URL string `yaml:"url,omitempty"`
+	// OS and Arch map GOOS/GOARCH to the names used in File/URL.
+	OS   map[string]string `yaml:"os,omitempty"`
+	Arch map[string]string `yaml:"arch,omitempty"`
+	// Platforms restricts the preset to "os" or "os/arch" entries; empty
+	// means every platform.
+	Platforms []string `yaml:"platforms,omitempty"`
+	// Latest is the version source when it isn't Repo's latest release.
+	Latest *provider.HTTPLatest `yaml:"latest,omitempty"`
+	// Archive is raw (default), tar.gz, tar.xz, tar.bz2, tar.zst, zip,
+	// gz, xz, bz2, zst, or auto.
+	Archive string `yaml:"archive,omitempty"`
+	// Member is the (templated) name of the binary inside the archive;
+	// defaults to Name.
+	Member string `yaml:"member,omitempty"`
+	// VersionCmd detects the installed version.
+	VersionCmd *VersionCmd `yaml:"versionCmd,omitempty"`
+}
+
+// VersionCmd runs the installed binary and extracts its version.
+type VersionCmd struct {
+	// Args are passed to the binary; defaults to ["--version"].
+	Args []string `yaml:"args,omitempty"`
+	// Env is added to the binary's environment.
+	Env map[string]string `yaml:"env,omitempty"`
+	// Regex extracts the version from the output: the first capture group,
+	// or the whole match. A match is accepted even if the command exits
+	// non-zero. Without Regex the trimmed output is the version.
+	Regex string `yaml:"regex,omitempty"`
+	// Prefix is prepended when the detected version lacks it (e.g. "v",
+	// so "1.2.0" compares equal to the "v1.2.0" release tag).
+	Prefix string `yaml:"prefix,omitempty"`
+}
+
+// Validate reports configuration errors: missing fields, unknown archive
+// types, and templates or regexes that don't parse.
+func (p *Preset) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("preset without name")
+	}
+	if p.URL == "" && (p.Repo == "" || p.File == "") {
+		return fmt.Errorf("preset %s: needs url, or repo and file", p.Name)
+	}
+	if p.Repo == "" && (p.Latest == nil || p.Latest.URL == "") {
+		return fmt.Errorf("preset %s: needs repo or latest.url as version source", p.Name)
+	}
+	switch p.Archive {
+	case "", ArchiveRaw, ArchiveTarGz, ArchiveTarXz, ArchiveTarBz2, ArchiveTarZst, ArchiveZip,
+		ArchiveGz, ArchiveXz, ArchiveBz2, ArchiveZst, ArchiveAuto:
+	default:
+		return fmt.Errorf("preset %s: unknown archive type %q", p.Name, p.Archive)
+	}
+	vars := p.vars("v0.0.0", platform(nil))
+	for _, tmpl := range []string{p.URL, p.File, p.Member} {
+		if _, err := provider.RenderTemplate(tmpl, vars); err != nil {
+			return fmt.Errorf("preset %s: %w", p.Name, err)
+		}
+	}
+	if p.Latest != nil && p.Latest.Regex != "" {
+		if _, err := regexp.Compile(p.Latest.Regex); err != nil {
+			return fmt.Errorf("preset %s: latest regex: %w", p.Name, err)
+		}
+	}
+	if p.VersionCmd != nil && p.VersionCmd.Regex != "" {
+		if _, err := regexp.Compile(p.VersionCmd.Regex); err != nil {
+			return fmt.Errorf("preset %s: versionCmd regex: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Binary compiles the preset into a binary.Binary. The preset must be
+// valid (see Validate).
+func (p *Preset) Binary(options *BinaryOptions) *binary.Binary {
+	if options == nil {
+		options = &BinaryOptions{}
+	}
+	b := &binary.Binary{
+		Context:       options.Context,
+		Envs:          options.Envs,
+		Tracker:       options.Tracker,
+		Name:          p.Name,
+		GitHubRepo:    p.Repo,
+		VersionF:      binary.GithubLatest,
+		VersionLocalF: p.versionLocal,
+	}
+	b.SetVersion(options.Version)
+	switch {
+	case p.URL != "":
+		b.URLF = p.render(p.URL)
+	default:
+		b.GitHubFileF = p.render(p.File)
+	}
+	if p.Latest != nil && p.Latest.URL != "" {
+		latest := *p.Latest
+		b.VersionF = func(b *binary.Binary) (string, error) {
+			return latest.Resolve(p.vars("", platform(b)))
+		}
+	}
+	switch p.Archive {
+	case ArchiveTarGz:
+		b.IsTarGz = true
+	case ArchiveTarXz:
+		b.IsTarXz = true
+	case ArchiveTarBz2:
+		b.IsTarBz2 = true
+	case ArchiveTarZst:
+		b.IsTarZst = true
+	case ArchiveZip:
+		b.IsZip = true
+	case ArchiveGz, ArchiveXz, ArchiveBz2, ArchiveZst:
+		b.Compression = p.Archive
+	case ArchiveAuto:
+		b.IsDynamic = true
+	}
+	if p.Member != "" {
+		b.TarFileF = p.render(p.Member)
+	}
+	return b
+}
+
+// render returns a callback rendering tmpl for the binary's version.
+func (p *Preset) render(tmpl string) binary.Callback {
+	return func(b *binary.Binary) (string, error) {
+		target := platform(b)
+		if !p.supported(target) {
+			return "", fmt.Errorf("no %s build for %s", target, p.Name)
+		}
+		return provider.RenderTemplate(tmpl, p.vars(b.Version, target))
+	}
+}
+
+// platform is the platform b is installed for, goos/goarch unless it
+// targets another one.
+func platform(b *binary.Binary) provider.Platform {
+	target := provider.Platform{OS: goos, Arch: goarch}
+	if b != nil && b.Platform.OS != "" {
+		target.OS = b.Platform.OS
+	}
+	if b != nil && b.Platform.Arch != "" {
+		target.Arch = b.Platform.Arch
+	}
+	return target
+}
+
+func (p *Preset) vars(version string, target provider.Platform) provider.HTTPVars {
+	cfg := &provider.HTTPConfig{OS: p.OS, Arch: p.Arch}
+	return cfg.Vars(version, target.OS, target.Arch)
+}
+
+func (p *Preset) supported(target provider.Platform) bool {
+	if len(p.Platforms) == 0 {
+		return true
+	}
+	return slices.Contains(p.Platforms, target.OS) || slices.Contains(p.Platforms, target.String())
+}
+
+// versionLocal runs the VersionCmd against the installed binary.
+func (p *Preset) versionLocal(b *binary.Binary) (string, error) {
+	vc := p.VersionCmd
+	if vc == nil {
+		vc = &VersionCmd{}
+	}
+	args := vc.Args
+	if len(args) == 0 {
+		args = []string{"--version"}
+	}
+	if len(vc.Env) > 0 {
+		// Copy so the shared options map isn't mutated
+		envs := maps.Clone(b.Envs)
+		if envs == nil {
+			envs = make(map[string]string, len(vc.Env))
+		}
+		maps.Copy(envs, vc.Env)
+		b.Envs = envs
+	}
+
+	out, err := b.Exec(args...)
+	version := out
+	if vc.Regex != "" {
+		m := regexp.MustCompile(vc.Regex).FindStringSubmatch(out)
+		switch {
+		case m == nil && err != nil:
+			return "", err
+		case m == nil:
+			return "", fmt.Errorf("%s version not found in %q", p.Name, out)
+		case len(m) > 1:
+			version = m[1]
+		default:
+			version = m[0]
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	version = strings.TrimSpace(version)
+	if version != "" && vc.Prefix != "" && !strings.HasPrefix(version, vc.Prefix) {
+		version = vc.Prefix + version
+	}
+	return version, nil
+}
@@ -0,0 +1,289 @@
+package binaries
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// withPlatform renders presets for goos/goarch for the rest of the test.
+func withPlatform(t *testing.T, pOS, pArch string) {
+	t.Helper()
+	oldOS, oldArch := goos, goarch
+	goos, goarch = pOS, pArch
+	t.Cleanup(func() { goos, goarch = oldOS, oldArch })
+}
+
+func builtinPreset(t *testing.T, name string) *Preset {
+	t.Helper()
+	presets, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range presets {
+		if p.Name == name {
+			return p
+		}
+	}
+	t.Fatalf("no builtin preset %s", name)
+	return nil
+}
+
+func TestBuiltin(t *testing.T) {
+	presets, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"argocd", "argsh", "b", "cilium", "clusterctl", "curl", "docker-compose",
+		"gh", "hcloud", "hubble", "jq", "k9s", "khelm", "kind", "kubectl",
+		"kubectl-cnpg", "kubelogin", "kubeseal", "kustomize", "mkcert", "packer",
+		"renvsubst", "sops", "ssh-to-age", "stern", "tilt", "yq",
+	}
+	if len(presets) != len(want) {
+		t.Fatalf("got %d presets, want %d", len(presets), len(want))
+	}
+	for i, p := range presets {
+		if p.Name != want[i] {
+			t.Errorf("preset %d = %s, want %s", i, p.Name, want[i])
+		}
+	}
+}
+
+func TestPreset_Render(t *testing.T) {
+	withPlatform(t, "linux", "amd64")
+
+	tests := []struct {
+		name    string
+		version string
+		github  string // GitHubFileF result
+		url     string // URLF result
+		member  string // TarFileF result
+	}{
+		{name: "jq", version: "v1.0.0", github: "jq-linux-amd64"},
+		{name: "tilt", version: "v1.0.0", github: "tilt.1.0.0.linux.x86_64.tar.gz"},
+		{name: "k9s", version: "v1.0.0", github: "k9s_Linux_amd64.tar.gz"},
+		{name: "yq", version: "v4.0.0", github: "yq_linux_amd64.tar.gz", member: "yq_linux_amd64"},
+		{name: "renvsubst", version: "v0.1.0", github: "renvsubst-v0.1.0-x86_64-unknown-linux-musl.tar.gz"},
+		{name: "kubectl", version: "v1.30.0", url: "https://storage.googleapis.com/kubernetes-release/release/v1.30.0/bin/linux/amd64/kubectl"},
+		{name: "kustomize", version: "v5.0.0", url: "https://github.com/kubernetes-sigs/kustomize/releases/download/kustomize%2Fv5.0.0/kustomize_v5.0.0_linux_amd64.tar.gz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := builtinPreset(t, tt.name).Binary(nil)
+			b.Version = tt.version
+			check := func(field string, f binary.Callback, want string) {
+				t.Helper()
+				if want == "" {
+					if f != nil {
+						t.Errorf("%s set, want nil", field)
+					}
+					return
+				}
+				if f == nil {
+					t.Fatalf("%s is nil", field)
+				}
+				got, err := f(b)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("%s = %q, want %q", field, got, want)
+				}
+			}
+			check("GitHubFileF", b.GitHubFileF, tt.github)
+			check("URLF", b.URLF, tt.url)
+			check("TarFileF", b.TarFileF, tt.member)
+		})
+	}
+}
+
+func TestPreset_Binary(t *testing.T) {
+	p := &Preset{
+		Name:    "tool",
+		Repo:    "org/tool",
+		File:    "tool.zip",
+		Archive: ArchiveZip,
+	}
+	b := p.Binary(&BinaryOptions{Version: "v2", Envs: map[string]string{"A": "1"}})
+	if b.Name != "tool" || b.GitHubRepo != "org/tool" || b.Version != "v2" || b.Envs["A"] != "1" {
+		t.Errorf("unexpected binary: %+v", b)
+	}
+	if !b.IsZip || b.IsTarGz || b.IsTarXz || b.IsDynamic {
+		t.Errorf("archive flags: zip=%v targz=%v tarxz=%v dynamic=%v", b.IsZip, b.IsTarGz, b.IsTarXz, b.IsDynamic)
+	}
+	if b.VersionF == nil || b.VersionLocalF == nil {
+		t.Error("version callbacks not set")
+	}
+
+	flags := map[string]func(*binary.Binary) bool{
+		ArchiveTarGz:  func(b *binary.Binary) bool { return b.IsTarGz },
+		ArchiveTarXz:  func(b *binary.Binary) bool { return b.IsTarXz },
+		ArchiveTarBz2: func(b *binary.Binary) bool { return b.IsTarBz2 },
+		ArchiveTarZst: func(b *binary.Binary) bool { return b.IsTarZst },
+		ArchiveGz:     func(b *binary.Binary) bool { return b.Compression == "gz" },
+		ArchiveZst:    func(b *binary.Binary) bool { return b.Compression == "zst" },
+		ArchiveAuto:   func(b *binary.Binary) bool { return b.IsDynamic },
+	}
+	for archive, isSet := range flags {
+		p.Archive = archive
+		if !isSet(p.Binary(nil)) {
+			t.Errorf("archive %s: flag not set", archive)
+		}
+	}
+}
+
+func TestPreset_Platforms(t *testing.T) {
+	p := builtinPreset(t, "renvsubst")
+	tests := []struct {
+		os, arch string
+		want     string
+		wantErr  bool
+	}{
+		{"darwin", "arm64", "renvsubst-v1-x86_64-apple-darwin.tar.gz", false},
+		{"linux", "arm", "renvsubst-v1-armv7-unknown-linux-musleabihf.tar.gz", false},
+		{"linux", "arm64", "", true},
+		{"windows", "amd64", "", true},
+	}
+	for _, tt := range tests {
+		withPlatform(t, tt.os, tt.arch)
+		b := p.Binary(nil)
+		b.Version = "v1"
+		got, err := b.GitHubFileF(b)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "no "+tt.os+"/"+tt.arch+" build") {
+				t.Errorf("%s/%s: err = %v, want unsupported platform", tt.os, tt.arch, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s/%s: %q, want %q", tt.os, tt.arch, got, tt.want)
+		}
+	}
+}
+
+func TestPreset_TargetPlatform(t *testing.T) {
+	withPlatform(t, "linux", "amd64")
+	b := builtinPreset(t, "k9s").Binary(nil)
+	b.Version = "v1.0.0"
+	b.Platform = provider.Platform{OS: "darwin", Arch: "arm64"}
+	got, err := b.GitHubFileF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "k9s_Darwin_arm64.tar.gz" {
+		t.Errorf("GitHubFileF() for darwin/arm64 = %q", got)
+	}
+
+	// Only the target's arch given: the host's OS is kept.
+	b.Platform = provider.Platform{Arch: "arm64"}
+	if got, _ := b.GitHubFileF(b); got != "k9s_Linux_arm64.tar.gz" {
+		t.Errorf("GitHubFileF() for arm64 = %q", got)
+	}
+
+	b = builtinPreset(t, "renvsubst").Binary(nil)
+	b.Version = "v1"
+	b.Platform = provider.Platform{OS: "windows", Arch: "amd64"}
+	if _, err := b.GitHubFileF(b); err == nil || !strings.Contains(err.Error(), "no windows/amd64 build") {
+		t.Errorf("GitHubFileF() for an unsupported target: err = %v", err)
+	}
+}
+
+func TestPreset_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		preset  Preset
+		wantErr string
+	}{
+		{"ok repo", Preset{Name: "a", Repo: "o/a", File: "a"}, ""},
+		{"ok url", Preset{Name: "a", URL: "https://x/a", Latest: &provider.HTTPLatest{URL: "https://x/v"}}, ""},
+		{"no name", Preset{Repo: "o/a", File: "a"}, "without name"},
+		{"no download", Preset{Name: "a", Repo: "o/a"}, "needs url"},
+		{"no version source", Preset{Name: "a", URL: "https://x/a"}, "version source"},
+		{"bad archive", Preset{Name: "a", Repo: "o/a", File: "a", Archive: "rar"}, "unknown archive"},
+		{"bad template", Preset{Name: "a", Repo: "o/a", File: "a-{{.Nope}}"}, "rendering template"},
+		{"bad member", Preset{Name: "a", Repo: "o/a", File: "a", Member: "{{"}, "parsing template"},
+		{"bad latest regex", Preset{Name: "a", Repo: "o/a", File: "a", Latest: &provider.HTTPLatest{URL: "u", Regex: "("}}, "latest regex"},
+		{"bad version regex", Preset{Name: "a", Repo: "o/a", File: "a", VersionCmd: &VersionCmd{Regex: "("}}, "versionCmd regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.preset.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPreset_VersionLocal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script binaries")
+	}
+	dir := t.TempDir()
+	script := func(t *testing.T, body string) string {
+		t.Helper()
+		file := filepath.Join(dir, strings.ReplaceAll(t.Name(), "/", "_"))
+		if err := os.WriteFile(file, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	tests := []struct {
+		name    string
+		script  string
+		cmd     *VersionCmd
+		want    string
+		wantErr bool
+	}{
+		{"plain output", `[ "$1" = --version ] && echo " 1.2.3 "`, nil, "1.2.3", false},
+		{"prefix added", `echo 1.2.3`, &VersionCmd{Prefix: "v"}, "v1.2.3", false},
+		{"prefix kept", `echo v1.2.3`, &VersionCmd{Prefix: "v"}, "v1.2.3", false},
+		{"custom args", `[ "$1" = version ] && [ "$2" = -s ] && echo ok`, &VersionCmd{Args: []string{"version", "-s"}}, "ok", false},
+		{"regex group", `echo "tool version v2.0.1 (abc)"`, &VersionCmd{Regex: `version (\S+)`}, "v2.0.1", false},
+		{"regex match", `echo "v3.1.0, built today"`, &VersionCmd{Regex: `^[^,]*`}, "v3.1.0", false},
+		{"regex despite exit code", "echo v4.0.0; exit 3", &VersionCmd{Regex: `v\S+`}, "v4.0.0", false},
+		{"regex no match", `echo nothing`, &VersionCmd{Regex: `v\d+`}, "", true},
+		{"exit code", "echo v1; exit 1", nil, "", true},
+		{"env", `echo "$TOOL_FLAG"`, &VersionCmd{Env: map[string]string{"TOOL_FLAG": "v5"}}, "v5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Preset{Name: "tool", VersionCmd: tt.cmd}
+			shared := map[string]string{"SHARED": "1"}
+			b := p.Binary(&BinaryOptions{Envs: shared})
+			b.File = script(t, tt.script)
+			got, err := b.VersionLocalF(b)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("version = %q, want %q", got, tt.want)
+			}
+			if len(shared) != 1 {
+				t.Errorf("shared envs mutated: %v", shared)
+			}
+		})
+	}
+}
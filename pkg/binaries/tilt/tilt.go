@@ -1,50 +0,0 @@
-package tilt
-
-import (
-	"context"
-	"fmt"
-	"runtime"
-	"strings"
-
-	"github.com/fentas/b/pkg/binaries"
-	"github.com/fentas/b/pkg/binary"
-)
-
-func Binary(options *binaries.BinaryOptions) *binary.Binary {
-	if options == nil {
-		options = &binaries.BinaryOptions{
-			Context: context.Background(),
-		}
-	}
-	return &binary.Binary{
-		Context:    options.Context,
-		Envs:       options.Envs,
-		Tracker:    options.Tracker,
-		Version:    options.Version,
-		GitHubRepo: "windmilleng/tilt",
-		GitHubFileF: func(b *binary.Binary) (string, error) {
-			return fmt.Sprintf("tilt.%s.%s.%s.tar.gz",
-				b.Version[1:],
-				runtime.GOOS,
-				binaries.Arch(runtime.GOARCH),
-			), nil
-		},
-		Name:     "tilt",
-		VersionF: binary.GithubLatest,
-		IsTarGz:  true,
-		VersionLocalF: func(b *binary.Binary) (string, error) {
-			// tilt's analytics init calls getent which may not be in
-			// the clean env. Disable analytics so the version check
-			// doesn't fail on minimal environments.
-			if b.Envs == nil {
-				b.Envs = map[string]string{}
-			}
-			b.Envs["TILT_DISABLE_ANALYTICS"] = "1"
-			s, err := b.Exec("version")
-			if err != nil {
-				return "", err
-			}
-			return strings.Split(s, ",")[0], nil
-		},
-	}
-}
@@ -17,6 +17,10 @@ func (b *Binary) LocalBinary(remote bool) *LocalBinary {
 	if b.VersionLocalF != nil {
 		version, _ = b.VersionLocalF(b)
 	}
+	enforced := b.Version
+	if b.Constraint != "" {
+		enforced = b.Constraint
+	}
 	file := b.BinaryPath()
 	if !b.BinaryExists() {
 		file = ""
@@ -27,7 +31,8 @@ func (b *Binary) LocalBinary(remote bool) *LocalBinary {
 		File:     file,
 		Version:  version,
 		Latest:   latest,
-		Enforced: b.Version,
+		Enforced: enforced,
+		Channel:  string(b.Channel),
 	}
 }
 
@@ -41,7 +46,7 @@ func (b *Binary) BinaryPath() string {
 		name = b.Name
 	}
 	path := path.GetBinaryPath()
-	b.File = filepath.Join(path, name)
+	b.File = filepath.Join(path, b.Platform.Exe(name))
 	return b.File
 }
 
@@ -54,14 +59,28 @@ func (b *Binary) BinaryExists() bool {
 	return err == nil
 }
 
+// Installed reports whether the binary and everything installed with it —
+// companions, paths copied out of an image, the tree of layout: tree —
+// are in place, built as b.yaml asks for.
+func (b *Binary) Installed() bool {
+	return !b.Rebuild && b.BinaryExists() && b.CompanionsExist() && b.PathsExist() && b.TreeExists()
+}
+
 func (b *Binary) EnsureBinary(update bool) error {
-	if b.BinaryExists() {
+	if b.Installed() {
 		if !update {
+			b.pinInstalled()
 			return nil
 		}
+		if err := b.ResolveConstraint(); err != nil {
+			return err
+		}
+		if err := b.ResolveQuery(); err != nil {
+			return err
+		}
 		local := b.LocalBinary(true)
 
-		if local.Version == local.Enforced || local.Enforced == "" && local.Latest == local.Version {
+		if local.Version == b.Version || b.Version == "" && local.Latest == local.Version {
 			return nil
 		}
 	}
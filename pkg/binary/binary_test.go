@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,10 +14,14 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
 	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 )
 
 // --- test helpers ---
@@ -28,6 +33,7 @@ type tarEntry struct {
 	name    string
 	mode    int64
 	content []byte
+	link    string // symlink target; makes the entry a symlink
 }
 
 func makeTarGz(t *testing.T, entries ...tarEntry) *bytes.Buffer {
@@ -42,6 +48,9 @@ func makeTarGz(t *testing.T, entries ...tarEntry) *bytes.Buffer {
 			Size:     int64(len(e.content)),
 			Typeflag: tar.TypeReg,
 		}
+		if e.link != "" {
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		}
 		if err := tw.WriteHeader(hdr); err != nil {
 			t.Fatalf("tar WriteHeader %q: %v", e.name, err)
 		}
@@ -88,6 +97,9 @@ func TestGetFileExtensionFromURL(t *testing.T) {
 	}{
 		{"https://example.com/foo.tar.gz", "tar.gz", false},
 		{"https://example.com/foo.tar.xz", "tar.xz", false},
+		{"https://example.com/foo.tar.bz2", "tar.bz2", false},
+		{"https://example.com/foo.tar.zst", "tar.zst", false},
+		{"https://example.com/foo.gz", "gz", false},
 		{"https://example.com/foo.zip", "zip", false},
 		{"https://example.com/foo.bin", "bin", false},
 		{"https://example.com/noext", "", true},
@@ -331,6 +343,23 @@ func TestBinary_Cmd_Exec(t *testing.T) {
 	}
 }
 
+func TestBinary_OtherPlatform(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("PATH_BIN", tmp)
+	b := &Binary{Name: "tool", Platform: provider.Platform{OS: "windows", Arch: "arm64"}}
+	if got := b.BinaryPath(); got != filepath.Join(tmp, "tool.exe") {
+		t.Errorf("BinaryPath() = %q, want tool.exe", got)
+	}
+	if _, err := b.Exec("--version"); err == nil || !strings.Contains(err.Error(), "windows/arm64 binary") {
+		t.Errorf("Exec() error = %v, want a refusal", err)
+	}
+
+	b = &Binary{Name: "tool", Version: "v1", Layout: LayoutTree, Entrypoint: "bin/tool", Platform: provider.Platform{OS: "windows"}}
+	if err := b.extractTree(bytes.NewReader(nil), "gz"); err == nil || !strings.Contains(err.Error(), "Windows") {
+		t.Errorf("extractTree() error = %v, want windows rejected", err)
+	}
+}
+
 func exeLookup(name string) (string, error) {
 	return exec.LookPath(name)
 }
@@ -583,6 +612,38 @@ func TestExtractFromZipAuto_PicksLargest(t *testing.T) {
 	}
 }
 
+func TestExtractAuto_WindowsTarget(t *testing.T) {
+	windows := provider.Platform{OS: "windows", Arch: "amd64"}
+
+	// Windows archives carry no exec bits; the .exe name decides.
+	tgz := makeTarGz(t,
+		tarEntry{name: "tool.exe", mode: 0644, content: []byte("exe")},
+		tarEntry{name: "README.md", mode: 0644, content: []byte("a much longer readme")},
+	)
+	tmp := t.TempDir()
+	b := &Binary{Name: "tool", File: filepath.Join(tmp, "tool.exe"), Platform: windows}
+	if err := b.extractFromTarAuto(tgz, "gz"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(b.File); string(data) != "exe" {
+		t.Errorf("tar: got %q, want the .exe", data)
+	}
+
+	zbuf := makeZip(t, map[string][]byte{"tool.exe": []byte("exe"), "LICENSE": []byte("a much longer license")})
+	b = &Binary{Name: "other", File: filepath.Join(tmp, "other.exe"), Platform: windows}
+	if err := b.extractFromZipAuto(zbuf); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(b.File); string(data) != "exe" {
+		t.Errorf("zip: got %q, want the .exe", data)
+	}
+
+	noExe := makeZip(t, map[string][]byte{"tool": []byte("bin")})
+	if err := b.extractFromZipAuto(noExe); err == nil {
+		t.Error("expected error for a windows zip without an .exe")
+	}
+}
+
 func TestGithubURL(t *testing.T) {
 	b := &Binary{GitHubRepo: "foo/bar", GitHubFile: "file", Version: "v1"}
 	u, err := b.githubURL()
@@ -627,6 +688,11 @@ func TestDownloadAsset_TarGz(t *testing.T) {
 }
 
 func TestDownloadAsset_HTTPError(t *testing.T) {
+	noRetries := 0
+	if err := httpclient.Set(&httpclient.Config{Retries: &noRetries}, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = httpclient.Set(nil, "") })
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 	}))
@@ -783,3 +849,232 @@ func TestBinary_DownloadBinary_Switch(t *testing.T) {
 		t.Errorf("%v", err)
 	}
 }
+
+func TestDownloadAsset_ChecksumVerified(t *testing.T) {
+	payload := []byte("raw-bin")
+	sum := fmt.Sprintf("%x", sha256.Sum256(payload))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/checksums.txt":
+			fmt.Fprintf(w, "%s  foo.bin\n", sum)
+		default:
+			_, _ = w.Write(payload)
+		}
+	}))
+	defer srv.Close()
+	tmp := t.TempDir()
+	b := &Binary{
+		Name:             "foo",
+		File:             filepath.Join(tmp, "foo"),
+		ResolvedChecksum: &provider.Asset{URL: srv.URL + "/checksums.txt", Name: "checksums.txt"},
+	}
+	if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/foo.bin", Name: "foo.bin"}); err != nil {
+		t.Fatal(err)
+	}
+	if b.AssetSHA256 != sum {
+		t.Errorf("AssetSHA256 = %q, want %q", b.AssetSHA256, sum)
+	}
+	if data, _ := os.ReadFile(b.File); string(data) != "raw-bin" {
+		t.Errorf("got %q", data)
+	}
+}
+
+func TestDownloadAsset_ChecksumMismatchRefusesInstall(t *testing.T) {
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte("expected")))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/foo.bin.sha256":
+			fmt.Fprintln(w, sum)
+		default:
+			_, _ = w.Write([]byte("tampered"))
+		}
+	}))
+	defer srv.Close()
+	tmp := t.TempDir()
+	b := &Binary{
+		Name:             "foo",
+		File:             filepath.Join(tmp, "foo"),
+		ResolvedChecksum: &provider.Asset{URL: srv.URL + "/foo.bin.sha256", Name: "foo.bin.sha256"},
+	}
+	err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/foo.bin", Name: "foo.bin"})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	if _, statErr := os.Stat(b.File); !os.IsNotExist(statErr) {
+		t.Error("binary must not be written on checksum mismatch")
+	}
+	if b.AssetSHA256 != "" {
+		t.Errorf("AssetSHA256 = %q, want empty", b.AssetSHA256)
+	}
+}
+
+func TestDownloadAsset_ChecksumFetchError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	b := &Binary{
+		Name:             "foo",
+		File:             filepath.Join(t.TempDir(), "foo"),
+		ResolvedChecksum: &provider.Asset{URL: srv.URL + "/checksums.txt", Name: "checksums.txt"},
+	}
+	if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/foo.bin", Name: "foo.bin"}); err == nil {
+		t.Error("expected error when the checksum file can't be fetched")
+	}
+}
+
+func TestExtractFromTarAuto_ArchiveMember(t *testing.T) {
+	// The configured member wins over name match, size, and mode bits.
+	buf := makeTarGz(t,
+		tarEntry{name: "./tool-1.0/foo", mode: 0755, content: []byte("decoy-with-name-match")},
+		tarEntry{name: "./tool-1.0/bin/foo", mode: 0644, content: []byte("bin")},
+	)
+	b := &Binary{Name: "foo", File: filepath.Join(t.TempDir(), "foo"), ArchiveMember: "tool-1.0/bin/foo"}
+	if err := b.extractFromTarAuto(buf, "gz"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(b.File)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "bin" {
+		t.Errorf("got %q", data)
+	}
+
+	buf = makeTarGz(t, tarEntry{name: "foo", mode: 0755, content: []byte("bin")})
+	b.ArchiveMember = "bin/foo"
+	if err := b.extractFromTarAuto(buf, "gz"); err == nil || !strings.Contains(err.Error(), "bin/foo not found") {
+		t.Errorf("expected member not found, got %v", err)
+	}
+}
+
+func TestExtractFromZipAuto_ArchiveMember(t *testing.T) {
+	buf := makeZip(t, map[string][]byte{"foo": []byte("decoy"), "dist/foo.exe": []byte("bin")})
+	b := &Binary{Name: "foo", File: filepath.Join(t.TempDir(), "foo"), ArchiveMember: "dist/foo.exe"}
+	if err := b.extractFromZipAuto(buf); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(b.File)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "bin" {
+		t.Errorf("got %q", data)
+	}
+
+	buf = makeZip(t, map[string][]byte{"foo": []byte("bin")})
+	b.ArchiveMember = "dist/foo"
+	if err := b.extractFromZipAuto(buf); err == nil {
+		t.Error("expected error for missing member")
+	}
+}
+
+func TestBinary_DownloadViaProvider_HTTPTemplate(t *testing.T) {
+	archive := makeTarGz(t,
+		tarEntry{name: "tool-1.2.3/LICENSE", mode: 0644, content: []byte("license text that is longer")},
+		tarEntry{name: "tool-1.2.3/bin/tool", mode: 0755, content: []byte("tool-bin")},
+	).Bytes()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tool/latest.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v1.2.3"}`))
+	})
+	mux.HandleFunc(fmt.Sprintf("/tool/v1.2.3/tool_1.2.3_%s_x86_64.tar.gz", runtime.GOOS), func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	b := &Binary{
+		Name:        "tool",
+		File:        filepath.Join(t.TempDir(), "tool"),
+		AutoDetect:  true,
+		ProviderRef: srv.URL + `/tool/{{.Version}}/tool_{{trimPrefix "v" .Version}}_{{.OS}}_{{.Arch}}.tar.gz`,
+		HTTP: &provider.HTTPConfig{
+			Arch:   map[string]string{runtime.GOARCH: "x86_64"},
+			Latest: &provider.HTTPLatest{URL: srv.URL + "/tool/latest.json", JSON: "tag_name"},
+			Member: `tool-{{trimPrefix "v" .Version}}/bin/tool`,
+		},
+	}
+	if err := b.downloadViaProvider(); err != nil {
+		t.Fatalf("downloadViaProvider: %v", err)
+	}
+	if b.Version != "v1.2.3" {
+		t.Errorf("Version = %q, want v1.2.3", b.Version)
+	}
+	data, err := os.ReadFile(b.File)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "tool-bin" {
+		t.Errorf("got %q", data)
+	}
+
+	// A signature policy can't be satisfied by a bare URL.
+	b.Verify = &signature.Config{Minisign: &signature.Minisign{Key: "RWQ"}}
+	if err := b.downloadViaProvider(); err == nil || !strings.Contains(err.Error(), "not supported for http refs") {
+		t.Errorf("expected verify refusal, got %v", err)
+	}
+}
+
+func TestDownloadViaProvider_AssetF(t *testing.T) {
+	archive := makeTarGz(t,
+		tarEntry{name: "tool-1.0/bin/tool", mode: 0755, content: []byte("tool-bin")},
+		tarEntry{name: "tool-1.0/bin/helper", mode: 0755, content: []byte("a larger helper binary")},
+	).Bytes()
+	sums := fmt.Sprintf("%x  tool_linux.tar.gz\n", sha256.Sum256(archive))
+
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/org/tool/releases/tags/v1.0", func(w http.ResponseWriter, r *http.Request) {
+		var assets []string
+		for _, name := range []string{"tool_linux_amd64.tar.gz", "tool_linux.tar.gz", "SUMS"} {
+			assets = append(assets, fmt.Sprintf(`{"name":%q,"browser_download_url":"%s/dl/%s"}`, name, srv.URL, name))
+		}
+		fmt.Fprintf(w, `{"tag_name":"v1.0","assets":[%s]}`, strings.Join(assets, ","))
+	})
+	mux.HandleFunc("/dl/tool_linux.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archive)
+	})
+	mux.HandleFunc("/dl/SUMS", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sums))
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	if err := hosts.Set(map[string]*hosts.Host{
+		"gitea.test": {Type: hosts.Gitea, API: srv.URL + "/api/v1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = hosts.Set(nil) })
+
+	named := func(name string) Callback {
+		return func(*Binary) (string, error) { return name, nil }
+	}
+	b := &Binary{
+		Name:        "tool",
+		Version:     "v1.0",
+		File:        filepath.Join(t.TempDir(), "tool"),
+		AutoDetect:  true,
+		ProviderRef: "gitea.test/org/tool",
+		AssetF:      named("tool_linux.tar.gz"),
+		ChecksumF:   named("SUMS"),
+		TarFileF:    named("tool-1.0/bin/tool"),
+	}
+	if err := b.downloadViaProvider(); err != nil {
+		t.Fatalf("downloadViaProvider: %v", err)
+	}
+	if data, _ := os.ReadFile(b.File); string(data) != "tool-bin" {
+		t.Errorf("installed %q, want the named member", data)
+	}
+	if b.Asset == nil || b.Asset.Name != "tool_linux.tar.gz" {
+		t.Errorf("Asset = %+v, want the named one", b.Asset)
+	}
+	if b.AssetSHA256 == "" || b.ResolvedChecksum == nil || b.ResolvedChecksum.Name != "SUMS" {
+		t.Errorf("not verified against the named checksum file: sha=%q checksum=%+v", b.AssetSHA256, b.ResolvedChecksum)
+	}
+
+	b.File, b.AssetF = filepath.Join(t.TempDir(), "tool"), named("tool_darwin.tar.gz")
+	if err := b.downloadViaProvider(); err == nil || !strings.Contains(err.Error(), `no asset "tool_darwin.tar.gz" among 3 assets`) {
+		t.Errorf("missing named asset: %v", err)
+	}
+}
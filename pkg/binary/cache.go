@@ -0,0 +1,85 @@
+package binary
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/offline"
+)
+
+// source is a release asset ready to be verified and extracted: either a
+// blob from the shared cache or a download staged by fetch.
+type source struct {
+	*os.File
+	url  string
+	blob string // cache path; "" while the content is only staged
+
+	// byURL: the URL names fixed content, so the cache may answer for it
+	// by URL alone.
+	byURL bool
+}
+
+// open returns the content of url, from the blob cache (see
+// pkg/blobcache) when it holds it and downloaded otherwise. want, the
+// asset's sha256 from a checksum file, lets a blob fetched under any URL
+// serve. Without it only URLs that carry the version are looked up by
+// URL; anything else ("…/latest/download/tool") may change under the
+// same name.
+func (b *Binary) open(url, name string, size int64, want string, statusErr func(*http.Response) error) (*source, error) {
+	src := &source{
+		url:   url,
+		byURL: want != "" || (b.Version != "" && strings.Contains(url, b.Version)),
+	}
+	if src.byURL {
+		if blob, ok := blobcache.Lookup(url, want); ok {
+			if f, err := os.Open(blob); err == nil {
+				if b.Tracker != nil {
+					b.Tracker.UpdateMessage(fmt.Sprintf("Using cached %s", name))
+				}
+				src.File, src.blob = f, blob
+				return src, nil
+			}
+		}
+	}
+	if offline.Enabled() {
+		return nil, offline.Missing(b.artifact(), "%s is not in the download cache", name)
+	}
+	f, err := b.fetch(url, name, size, statusErr)
+	if err != nil {
+		return nil, err
+	}
+	src.File = f
+	return src, nil
+}
+
+// artifact names b for offline errors: "kubectl@v1.31.0".
+func (b *Binary) artifact() string {
+	if b.Version == "" {
+		return b.Name
+	}
+	return b.Name + "@" + b.Version
+}
+
+// cache adds a staged download to the blob cache once it checked out and
+// returns its blob, or "" when the cache is off or the asset can't be
+// keyed. A cache that fails to take it is not an install error.
+func (s *source) cache() string {
+	if s.blob == "" && s.byURL && blobcache.Root() != "" {
+		if blob, err := blobcache.Put(s.url, s.Name()); err == nil {
+			s.blob = blob
+		}
+	}
+	return s.blob
+}
+
+// close releases the source, removing it if it was staged.
+func (s *source) close() {
+	if s.Name() == s.blob {
+		s.Close()
+		return
+	}
+	discard(s.File)
+}
@@ -0,0 +1,202 @@
+package binary
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+)
+
+func tarGz(t *testing.T, name, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// TestDownloadAsset_BlobCache: a second project installing the same asset
+// is served from the blob cache without a request.
+func TestDownloadAsset_BlobCache(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   []byte
+		want   string
+		cached bool
+	}{
+		{name: "raw", path: "/v1.0.0/tool-linux", body: []byte("binary"), want: "binary", cached: true},
+		{name: "archive", path: "/v1.0.0/tool.tar.gz", body: tarGz(t, "tool", "from tar"), want: "from tar", cached: true},
+		{name: "unversioned url", path: "/latest/tool-linux", body: []byte("binary"), want: "binary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobcache.SetRoot(t.TempDir())
+			t.Cleanup(func() { blobcache.SetRoot("") })
+
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+			asset := &provider.Asset{URL: srv.URL + tt.path, Name: filepath.Base(tt.path)}
+
+			for range 2 {
+				dir := t.TempDir()
+				b := &Binary{Name: "tool", Version: "v1.0.0", File: filepath.Join(dir, "tool")}
+				if err := b.downloadAsset(asset); err != nil {
+					t.Fatal(err)
+				}
+				got, err := os.ReadFile(b.File)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("binary = %q, want %q", got, tt.want)
+				}
+				if left := leftovers(t, dir, "tool"); len(left) > 0 {
+					t.Errorf("left behind: %v", left)
+				}
+			}
+			want := int32(2)
+			if tt.cached {
+				want = 1
+			}
+			if got := requests.Load(); got != want {
+				t.Errorf("server saw %d requests, want %d", got, want)
+			}
+		})
+	}
+}
+
+// TestDownloadAsset_BlobCacheByChecksum: a blob cached from one URL serves
+// another when the checksum file names the same digest.
+func TestDownloadAsset_BlobCacheByChecksum(t *testing.T) {
+	blobcache.SetRoot(t.TempDir())
+	t.Cleanup(func() { blobcache.SetRoot("") })
+
+	const content = "binary"
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+	src := filepath.Join(t.TempDir(), "dl")
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blobcache.Put("https://mirror.example.com/tool", src); err != nil {
+		t.Fatal(err)
+	}
+
+	var assetRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/checksums.txt" {
+			_, _ = w.Write([]byte(sum + "  tool\n"))
+			return
+		}
+		assetRequests.Add(1)
+		_, _ = w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	b := &Binary{
+		Name:             "tool",
+		File:             filepath.Join(t.TempDir(), "tool"),
+		ResolvedChecksum: &provider.Asset{URL: srv.URL + "/checksums.txt", Name: "checksums.txt"},
+	}
+	if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/tool", Name: "tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := assetRequests.Load(); n != 0 {
+		t.Errorf("asset downloaded %d times, want served from the cache", n)
+	}
+	if b.AssetSHA256 != sum {
+		t.Errorf("AssetSHA256 = %q, want %q", b.AssetSHA256, sum)
+	}
+}
+
+// TestDownloadBinary_Offline: offline, the asset pinned by b.lock is served
+// from the blob cache, by URL or by its locked sha256, as is the locked
+// binary itself; anything else fails with an offline error without a
+// request.
+func TestDownloadBinary_Offline(t *testing.T) {
+	const content = "binary"
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+
+	tests := []struct {
+		name    string
+		asset   *provider.Asset
+		sha256  string
+		locked  string
+		missing bool
+	}{
+		{name: "cached url", asset: &provider.Asset{Name: "tool", URL: "https://example.com/v1.0.0/tool"}},
+		{name: "cached digest", asset: &provider.Asset{Name: "tool", URL: "https://mirror.example.com/tool"}, sha256: sum},
+		{name: "not cached", asset: &provider.Asset{Name: "tool", URL: "https://example.com/v2.0.0/tool"}, missing: true},
+		{name: "digest not cached", asset: &provider.Asset{Name: "tool", URL: "https://example.com/v1.0.0/tool"}, sha256: fmt.Sprintf("%x", sha256.Sum256([]byte("other"))), missing: true},
+		{name: "no asset in b.lock", missing: true},
+		{name: "locked binary", locked: sum},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobcache.SetRoot(t.TempDir())
+			offline.Set(true)
+			t.Cleanup(func() {
+				blobcache.SetRoot("")
+				offline.Set(false)
+			})
+			src := filepath.Join(t.TempDir(), "dl")
+			if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := blobcache.Put("https://example.com/v1.0.0/tool", src); err != nil {
+				t.Fatal(err)
+			}
+
+			b := &Binary{
+				Name:              "tool",
+				Version:           "v1.0.0",
+				AutoDetect:        true,
+				ProviderRef:       "github.com/org/tool",
+				File:              filepath.Join(t.TempDir(), "tool"),
+				ResolvedAsset:     tt.asset,
+				LockedAssetSHA256: tt.sha256,
+				LockedSHA256:      tt.locked,
+			}
+			err := b.downloadBinary()
+			if tt.missing {
+				if !errors.Is(err, offline.ErrOffline) {
+					t.Fatalf("downloadBinary() error = %v, want an offline error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(b.File); string(got) != content {
+				t.Errorf("binary = %q, want %q", got, content)
+			}
+			if b.AssetSHA256 != tt.sha256 {
+				t.Errorf("AssetSHA256 = %q, want %q", b.AssetSHA256, tt.sha256)
+			}
+		})
+	}
+}
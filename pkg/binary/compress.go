@@ -0,0 +1,51 @@
+package binary
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/fentas/b/pkg/provider"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// decompress wraps stream in a reader for compression: "gz", "xz", "bz2"
+// or "zst", as in the tar.* and single-file archive types of
+// provider.DetectArchiveType.
+func decompress(stream io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case "gz":
+		return gzip.NewReader(stream)
+	case "xz":
+		r, err := xz.NewReader(stream)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(r), nil
+	case "bz2":
+		return io.NopCloser(bzip2.NewReader(stream)), nil
+	case "zst":
+		d, err := zstd.NewReader(stream)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unknown compression type: %s", compression)
+}
+
+// extractCompressed writes the single compressed file in stream (e.g.
+// tool-linux-amd64.gz) to b.File.
+func (b *Binary) extractCompressed(stream io.Reader, compression string) error {
+	if err := b.noArchive(b.Name + "." + compression); err != nil {
+		return err
+	}
+	r, err := decompress(stream, compression)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return provider.WriteExecutable(b.File, r)
+}
@@ -0,0 +1,205 @@
+package binary
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+// The standard library has no bzip2 writer; these were made with
+// Python's bz2 module.
+const (
+	// bzip2 of "bz2-binary".
+	bz2Binary = "425a6839314159265359f8620aac000002198000021000302110302000220698420c988c8844dc1e2ee48a70a121f0c41558"
+	// bzip2 of a tar holding foo (0755) with "tbz2-binary".
+	bz2Tar = "425a6839314159265359e4af57990000707b80ca80008040027f80000271219e30080820005446a9ea34d0c4d0d190f4d02494c8d340d000d07dc44a1083c7a1087758c09594d4810c0c4af74190f611a182382f326cbb6e82a9339fc9ea212adad5d7700115c5dc914e1424392bd5e640"
+)
+
+// makeTar builds an uncompressed tar holding a single executable.
+func makeTar(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("tar WriteHeader %q: %v", name, err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatalf("tar Write %q: %v", name, err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// compress compresses data as "gz", "xz" or "zst"; "bz2" is decoded from
+// the canned hex fixture passed as data.
+func compress(t *testing.T, compression string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch compression {
+	case "gz":
+		w = gzip.NewWriter(&buf)
+	case "xz":
+		w, err = xz.NewWriter(&buf)
+	case "zst":
+		w, err = zstd.NewWriter(&buf)
+	case "bz2":
+		raw, err := hex.DecodeString(string(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	default:
+		t.Fatalf("unknown compression %q", compression)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	for _, compression := range []string{"gz", "xz", "bz2", "zst"} {
+		t.Run(compression, func(t *testing.T) {
+			want := []byte("bz2-binary")
+			data := want
+			if compression == "bz2" {
+				data = []byte(bz2Binary)
+			}
+			r, err := decompress(bytes.NewReader(compress(t, compression, data)), compression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("decompress() = %q, want %q", got, want)
+			}
+		})
+	}
+	if _, err := decompress(bytes.NewReader(nil), "rar"); err == nil {
+		t.Error("decompress() with an unknown compression succeeded")
+	}
+}
+
+func TestDownloadAsset_Compressed(t *testing.T) {
+	tests := []struct {
+		asset string
+		body  func(t *testing.T) []byte
+		want  string
+	}{
+		{"tool-linux-amd64.gz", func(t *testing.T) []byte { return compress(t, "gz", []byte("gz-binary")) }, "gz-binary"},
+		{"tool-linux-amd64.xz", func(t *testing.T) []byte { return compress(t, "xz", []byte("xz-binary")) }, "xz-binary"},
+		{"tool-linux-amd64.bz2", func(t *testing.T) []byte { return compress(t, "bz2", []byte(bz2Binary)) }, "bz2-binary"},
+		{"tool-linux-amd64.zst", func(t *testing.T) []byte { return compress(t, "zst", []byte("zst-binary")) }, "zst-binary"},
+		{"tool-linux-amd64.tar.bz2", func(t *testing.T) []byte { return compress(t, "bz2", []byte(bz2Tar)) }, "tbz2-binary"},
+		{"tool-linux-amd64.tar.zst", func(t *testing.T) []byte {
+			return compress(t, "zst", makeTar(t, "foo", []byte("tzst-binary")))
+		}, "tzst-binary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.asset, func(t *testing.T) {
+			body := tt.body(t)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+			b := &Binary{Name: "foo", File: filepath.Join(t.TempDir(), "foo")}
+			if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/" + tt.asset, Name: tt.asset}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(b.File)
+			if err != nil || string(data) != tt.want {
+				t.Errorf("extracted content = %q err=%v, want %q", data, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadPreset_Compressed(t *testing.T) {
+	tests := []struct {
+		name string
+		bin  *Binary
+		ext  string
+		body func(t *testing.T) []byte
+		want string
+	}{
+		{
+			name: "tar.bz2",
+			bin:  &Binary{IsTarBz2: true},
+			body: func(t *testing.T) []byte { return compress(t, "bz2", []byte(bz2Tar)) },
+			want: "tbz2-binary",
+		},
+		{
+			name: "tar.zst",
+			bin:  &Binary{IsTarZst: true},
+			body: func(t *testing.T) []byte { return compress(t, "zst", makeTar(t, "foo", []byte("tzst-binary"))) },
+			want: "tzst-binary",
+		},
+		{
+			name: "single xz",
+			bin:  &Binary{Compression: "xz"},
+			body: func(t *testing.T) []byte { return compress(t, "xz", []byte("xz-binary")) },
+			want: "xz-binary",
+		},
+		{
+			name: "dynamic tzst",
+			bin:  &Binary{IsDynamic: true},
+			ext:  ".tzst",
+			body: func(t *testing.T) []byte { return compress(t, "zst", makeTar(t, "foo", []byte("tzst-binary"))) },
+			want: "tzst-binary",
+		},
+		{
+			name: "dynamic bz2",
+			bin:  &Binary{IsDynamic: true},
+			ext:  ".bz2",
+			body: func(t *testing.T) []byte { return compress(t, "bz2", []byte(bz2Binary)) },
+			want: "bz2-binary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+			b := tt.bin
+			b.Name = "foo"
+			b.Version = "v1"
+			b.File = filepath.Join(t.TempDir(), "foo")
+			b.URL = srv.URL + "/foo" + tt.ext
+			if err := b.downloadPreset(); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(b.File)
+			if err != nil || string(data) != tt.want {
+				t.Errorf("extracted content = %q err=%v, want %q", data, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package binary
+
+import (
+	"fmt"
+
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/semver"
+)
+
+// SetVersion sets the version to install. A range such as "~1.7" or
+// ">=1.28 <1.31" is kept in Constraint and resolved against the provider's
+// releases on the next install; anything else pins Version exactly.
+func (b *Binary) SetVersion(v string) {
+	if semver.IsConstraint(v) {
+		b.Constraint = v
+		b.Version = ""
+		return
+	}
+	b.Constraint = ""
+	b.Version = v
+}
+
+// ResolveConstraint sets Version to the highest release satisfying
+// Constraint. It is a no-op without a constraint or once Version is set.
+func (b *Binary) ResolveConstraint() error {
+	if b.Constraint == "" || b.Version != "" {
+		return nil
+	}
+	c, err := semver.ParseConstraint(b.Constraint)
+	if err != nil {
+		return fmt.Errorf("%s: %w", b.Name, err)
+	}
+	lister, ref, err := b.ReleaseLister()
+	if err != nil {
+		return fmt.Errorf("%s: version constraint %q: %w", b.Name, b.Constraint, err)
+	}
+	releases, err := provider.ListAll(lister, ref)
+	if err != nil {
+		return fmt.Errorf("%s: listing versions: %w", b.Name, err)
+	}
+	var versions []string
+	for _, r := range releases {
+		if !r.Draft {
+			versions = append(versions, r.Version)
+		}
+	}
+	version, ok := c.Highest(versions)
+	if !ok {
+		return fmt.Errorf("%s: no release satisfies %q among %d versions", b.Name, b.Constraint, len(versions))
+	}
+	b.Version = version
+	return nil
+}
+
+// ResolveQuery sets Version of a go:// binary to the module version its
+// query — latest (no version), a branch or a commit — stands for, keeping
+// a branch or commit in Query. Offline, the version is left to b.lock.
+func (b *Binary) ResolveQuery() error {
+	if !b.AutoDetect || b.Constraint != "" || provider.IsModuleVersion(b.Version) || offline.Enabled() {
+		return nil
+	}
+	p, err := provider.Detect(b.ProviderRef)
+	if err != nil {
+		return nil
+	}
+	g, ok := p.(*provider.GoInstall)
+	if !ok {
+		return nil
+	}
+	query := b.Version
+	if query == "" || query == "latest" {
+		query = "latest"
+	} else {
+		b.Query = query
+	}
+	version, err := g.Resolve(b.ProviderRef, query)
+	if err != nil {
+		return fmt.Errorf("%s: %w", b.Name, err)
+	}
+	b.Version = version
+	return nil
+}
+
+// pinInstalled records the installed version as the resolved one when it
+// satisfies Constraint, so a kept binary is locked at what is on disk.
+func (b *Binary) pinInstalled() {
+	if b.Constraint == "" || b.Version != "" || b.VersionLocalF == nil {
+		return
+	}
+	local, err := b.VersionLocalF(b)
+	if err != nil {
+		return
+	}
+	if Satisfies(b.Constraint, local) {
+		b.Version = local
+	}
+}
+
+// Satisfies reports whether version is within the range constraint.
+func Satisfies(constraint, version string) bool {
+	c, err := semver.ParseConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	v, ok := semver.Parse(version)
+	return ok && c.Check(v)
+}
+
+// ReleaseLister returns the provider listing this binary's releases and
+// the ref to list. Presets list the releases of their GitHub repository.
+func (b *Binary) ReleaseLister() (provider.ReleaseLister, string, error) {
+	ref := b.ProviderRef
+	if !b.AutoDetect {
+		if b.GitHubRepo == "" {
+			return nil, "", fmt.Errorf("listing versions needs a GitHub release preset or a provider ref")
+		}
+		ref = "github.com/" + b.GitHubRepo
+	}
+	p, err := provider.Detect(ref)
+	if err != nil {
+		return nil, "", err
+	}
+	lister, ok := p.(provider.ReleaseLister)
+	if !ok {
+		return nil, "", fmt.Errorf("%s refs can't list versions", p.Name())
+	}
+	return lister, ref, nil
+}
@@ -0,0 +1,167 @@
+package binary
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/hosts"
+)
+
+// giteaReleases serves a Gitea API for org/tool with the given release tags
+// on a configured host, returning the ref to use. Every release ships a
+// single raw binary whose content is its tag.
+func giteaReleases(t *testing.T, tags ...string) string {
+	t.Helper()
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/org/tool/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") != "1" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		var list []string
+		for _, tag := range tags {
+			list = append(list, fmt.Sprintf(`{"tag_name":%q}`, tag))
+		}
+		_, _ = w.Write([]byte("[" + strings.Join(list, ",") + "]"))
+	})
+	mux.HandleFunc("/api/v1/repos/org/tool/releases/tags/", func(w http.ResponseWriter, r *http.Request) {
+		tag := strings.TrimPrefix(r.URL.Path, "/api/v1/repos/org/tool/releases/tags/")
+		asset := fmt.Sprintf("tool_%s_%s", runtime.GOOS, runtime.GOARCH)
+		fmt.Fprintf(w, `{"tag_name":%q,"assets":[{"name":%q,"browser_download_url":"%s/dl/%s"}]}`, tag, asset, srv.URL, tag)
+	})
+	mux.HandleFunc("/dl/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.TrimPrefix(r.URL.Path, "/dl/")))
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	if err := hosts.Set(map[string]*hosts.Host{
+		"gitea.test": {Type: hosts.Gitea, API: srv.URL + "/api/v1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = hosts.Set(nil) })
+	return "gitea.test/org/tool"
+}
+
+func TestBinary_SetVersion(t *testing.T) {
+	b := &Binary{}
+	b.SetVersion("~1.7")
+	if b.Constraint != "~1.7" || b.Version != "" {
+		t.Errorf("range: Version=%q Constraint=%q", b.Version, b.Constraint)
+	}
+	b.SetVersion("v1.7.1")
+	if b.Constraint != "" || b.Version != "v1.7.1" {
+		t.Errorf("exact: Version=%q Constraint=%q", b.Version, b.Constraint)
+	}
+}
+
+func TestBinary_ResolveConstraint(t *testing.T) {
+	ref := giteaReleases(t, "v1.8.0", "v1.7.2", "v1.7.1", "v1.6.0")
+
+	tests := []struct {
+		constraint string
+		want       string
+		wantErr    string
+	}{
+		{"~1.7", "v1.7.2", ""},
+		{">=1.6 <1.8", "v1.7.2", ""},
+		{"^1.6", "v1.8.0", ""},
+		{"^2", "", `no release satisfies "^2" among 4 versions`},
+		{">=abc", "", "not a version"},
+	}
+	for _, tt := range tests {
+		b := &Binary{Name: "tool", AutoDetect: true, ProviderRef: ref, Constraint: tt.constraint}
+		err := b.ResolveConstraint()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: err = %v, want %q", tt.constraint, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.constraint, err)
+			continue
+		}
+		if b.Version != tt.want {
+			t.Errorf("%q resolved to %q, want %q", tt.constraint, b.Version, tt.want)
+		}
+	}
+
+	// Already resolved: no listing, Version kept.
+	b := &Binary{Name: "tool", AutoDetect: true, ProviderRef: ref, Constraint: "~1.7", Version: "v1.7.1"}
+	if err := b.ResolveConstraint(); err != nil || b.Version != "v1.7.1" {
+		t.Errorf("resolved binary changed: %q, %v", b.Version, err)
+	}
+}
+
+func TestBinary_ResolveConstraint_Unlistable(t *testing.T) {
+	for _, b := range []*Binary{
+		{Name: "tool", AutoDetect: true, ProviderRef: "https://dl.example.com/{{.Version}}/tool", Constraint: "^1"},
+		{Name: "tool", URL: "https://example.com/tool", Constraint: "^1"},
+	} {
+		if err := b.ResolveConstraint(); err == nil {
+			t.Errorf("%+v: expected error", b)
+		}
+	}
+}
+
+func TestBinary_EnsureBinary_Constraint(t *testing.T) {
+	ref := giteaReleases(t, "v1.8.0", "v1.7.2", "v1.7.1")
+	tmp := t.TempDir()
+	t.Setenv("PATH_BIN", tmp)
+	file := filepath.Join(tmp, "tool")
+	local := func(b *Binary) (string, error) {
+		data, err := os.ReadFile(file)
+		return string(data), err
+	}
+
+	// Missing: installs the highest match.
+	b := &Binary{Name: "tool", AutoDetect: true, ProviderRef: ref, Constraint: "~1.7", VersionLocalF: local}
+	if err := b.EnsureBinary(false); err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != "v1.7.2" {
+		t.Errorf("Version = %q, want v1.7.2", b.Version)
+	}
+	if got, _ := local(b); got != "v1.7.2" {
+		t.Errorf("installed %q", got)
+	}
+
+	// Present and satisfying, no update: kept and pinned at what's on disk.
+	if err := os.WriteFile(file, []byte("v1.7.1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	b = &Binary{Name: "tool", AutoDetect: true, ProviderRef: ref, Constraint: "~1.7", VersionLocalF: local}
+	if err := b.EnsureBinary(false); err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != "v1.7.1" {
+		t.Errorf("Version = %q, want the installed v1.7.1", b.Version)
+	}
+	if got := b.LocalBinary(false).Enforced; got != "~1.7" {
+		t.Errorf("Enforced = %q, want the range", got)
+	}
+
+	// Update moves to the highest match.
+	b = &Binary{Name: "tool", AutoDetect: true, ProviderRef: ref, Constraint: "~1.7", VersionLocalF: local}
+	if err := b.EnsureBinary(true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := local(b); got != "v1.7.2" {
+		t.Errorf("after update installed %q, want v1.7.2", got)
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	if !Satisfies("~1.7", "v1.7.3") || Satisfies("~1.7", "v1.8.0") || Satisfies("~1.7", "unknown") || Satisfies(">=abc", "1.0.0") {
+		t.Error("Satisfies")
+	}
+}
@@ -4,18 +4,21 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"bytes"
-	"compress/gzip"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/offline"
 	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 	"github.com/fentas/goodies/progress"
-	"github.com/ulikunitz/xz"
 )
 
 func (b *Binary) githubURL() (string, error) {
@@ -27,29 +30,38 @@ func (b *Binary) githubURL() (string, error) {
 	return fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", b.GitHubRepo, b.Version, file), err
 }
 
-// extractSingleFileFromTar extracts a single file from a tar archive.
-// compression can be "gz" or "xz".
+// extractSingleFileFromTar extracts a single file from a tar archive, and
+// b's companions (see Binary.Extract) along with it.
+// compression can be "gz", "xz", "bz2" or "zst".
 func (b *Binary) extractSingleFileFromTar(stream io.Reader, compression string) error {
-	var tarReader *tar.Reader
+	r, err := decompress(stream, compression)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tarReader := tar.NewReader(r)
 
-	switch compression {
-	case "gz":
-		gzipReader, err := gzip.NewReader(stream)
-		if err != nil {
-			return err
-		}
-		defer gzipReader.Close()
-		tarReader = tar.NewReader(gzipReader)
-	case "xz":
-		xzReader, err := xz.NewReader(stream)
-		if err != nil {
-			return err
+	isBinary := func(name string) (bool, error) {
+		switch filepath.Base(name) {
+		case b.Name, b.Platform.Exe(b.Name):
+		case strings.Split(b.GitHubFile, ".")[0]:
+		case b.TarFile:
+		default:
+			if b.TarFileF == nil {
+				return false, nil
+			}
+			member, err := b.TarFileF(b)
+			if err != nil {
+				return false, err
+			}
+			return isArchiveMember(name, member), nil
 		}
-		tarReader = tar.NewReader(xzReader)
-	default:
-		return fmt.Errorf("Unknown compression type: %s", compression)
+		return true, nil
 	}
 
+	x := b.extractor()
+	defer x.discard()
+	found := false
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -62,35 +74,34 @@ func (b *Binary) extractSingleFileFromTar(stream io.Reader, compression string)
 			continue
 		}
 
-		switch filepath.Base(header.Name) {
-		case b.Name:
-		case strings.Split(b.GitHubFile, ".")[0]:
-		case b.TarFile:
-		default:
-			if b.TarFileF == nil {
-				continue
-			}
-			name, err := b.TarFileF(b)
+		if !found {
+			ok, err := isBinary(header.Name)
 			if err != nil {
 				return err
 			}
-			if header.Name != name {
+			if ok {
+				if err := provider.WriteExecutable(b.File, tarReader); err != nil {
+					return err
+				}
+				found = true
+				if x.done() {
+					break
+				}
 				continue
 			}
 		}
-
-		file, err := os.OpenFile(b.File, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-		if _, err = io.Copy(file, tarReader); err != nil {
+		if err := x.write(header.Name, tarReader); err != nil {
 			return err
 		}
-		return os.Chmod(b.File, 0755)
 	}
 
-	return fmt.Errorf("file %s not found", b.Name)
+	if !found {
+		return fmt.Errorf("file %s not found", b.Name)
+	}
+	if err := x.check(); err != nil {
+		return err
+	}
+	return x.commit()
 }
 
 func (b *Binary) extractSingleFileFromZip(stream io.Reader) error {
@@ -104,43 +115,87 @@ func (b *Binary) extractSingleFileFromZip(stream io.Reader) error {
 		return err
 	}
 
+	var member string
+	if b.TarFileF != nil {
+		if member, err = b.TarFileF(b); err != nil {
+			return err
+		}
+	}
 	for _, file := range zipReader.File {
-		if file.Name == b.Name {
-			zippedFile, err := file.Open()
-			if err != nil {
+		if file.Name == b.Name || file.Name == b.Platform.Exe(b.Name) || member != "" && isArchiveMember(file.Name, member) {
+			x := b.extractor()
+			defer x.discard()
+			if err := x.writeZip(zipReader); err != nil {
 				return err
 			}
-			defer zippedFile.Close()
-
-			bfile, err := os.OpenFile(b.File, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-			if err != nil {
+			if err := x.check(); err != nil {
 				return err
 			}
-			defer bfile.Close()
-
-			var reader io.Reader = zippedFile
-			if b.Writer != nil {
-				b.Tracker = b.Writer.AddTracker(fmt.Sprintf("Extracting %s", b.Name), int64(file.UncompressedSize64))
-				reader = progress.NewReader(zippedFile, b.Tracker)
-				defer b.Tracker.MarkAsDone()
-			}
-
-			if _, err = io.Copy(bfile, reader); err != nil {
+			if err := b.writeFromZip(file); err != nil {
 				return err
 			}
-			return os.Chmod(b.File, 0755)
+			return x.commit()
 		}
 	}
 
 	return fmt.Errorf("file %s not found", b.Name)
 }
 
+// writeFromZip writes the zipped file to b.File.
+func (b *Binary) writeFromZip(file *zip.File) error {
+	zippedFile, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer zippedFile.Close()
+
+	var reader io.Reader = zippedFile
+	if b.Writer != nil {
+		b.Tracker = b.Writer.AddTracker(fmt.Sprintf("Extracting %s", b.Name), int64(file.UncompressedSize64))
+		reader = progress.NewReader(zippedFile, b.Tracker)
+		defer b.Tracker.MarkAsDone()
+	}
+
+	return provider.WriteExecutable(b.File, reader)
+}
+
 func (b *Binary) downloadBinary() error {
+	if err := b.ResolveConstraint(); err != nil {
+		return err
+	}
+
+	// Offline, the very binary b.lock recorded may be in the download
+	// cache (`b bundle import` puts it there); it needs no provider.
+	// A tree's shim is not what needs caching.
+	if offline.Enabled() && b.LockedSHA256 != "" && b.Layout != LayoutTree {
+		if blobs, ok := b.lockedBlobs(); ok {
+			if b.Tracker != nil {
+				b.Tracker.UpdateMessage("Using cached binary")
+			}
+			for dest, blob := range blobs {
+				if err := blobcache.Materialize(blob, dest); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
 	// Provider-based auto-detection path
 	if b.AutoDetect {
 		return b.downloadViaProvider()
 	}
 
+	if !b.Verify.IsZero() {
+		return fmt.Errorf("%s: signature verification is only supported for release and oci:// refs", b.Name)
+	}
+	if !b.Build.IsZero() {
+		return fmt.Errorf("%s: build options only apply to go:// refs", b.Name)
+	}
+	if b.Runtime != "" || len(b.Paths) > 0 {
+		return fmt.Errorf("%s: runtime and paths only apply to docker:// and oci:// refs", b.Name)
+	}
+
 	// Legacy preset path
 	return b.downloadPreset()
 }
@@ -157,24 +212,57 @@ func (b *Binary) downloadViaProvider() error {
 		return err
 	}
 
-	// Special providers that compile/extract directly
+	// Special providers that compile/extract/download directly. Only oci://
+	// images carry signatures (cosign); the others build, copy, or fetch a
+	// single URL with nothing b can verify, so a `verify:` policy refuses
+	// them.
+	switch p.(type) {
+	case *provider.GoInstall, *provider.Docker, *provider.Git, *provider.HTTP:
+		if !b.Verify.IsZero() {
+			return fmt.Errorf("%s: signature verification is not supported for %s refs", b.ProviderRef, p.Name())
+		}
+	}
+	if _, ok := p.(*provider.GoInstall); !ok && !b.Build.IsZero() {
+		return fmt.Errorf("%s: build options only apply to go:// refs", b.ProviderRef)
+	}
+	if _, ok := p.(*provider.Docker); !ok && b.Runtime != "" {
+		return fmt.Errorf("%s: runtime only applies to docker:// refs", b.ProviderRef)
+	}
+	switch p.(type) {
+	case *provider.Docker, *provider.OCI:
+	default:
+		if len(b.Paths) > 0 {
+			return fmt.Errorf("%s: paths only apply to docker:// and oci:// refs", b.ProviderRef)
+		}
+	}
 	switch pt := p.(type) {
 	case *provider.GoInstall:
-		path, err := pt.Install(b.ProviderRef, b.Version, destDir)
+		if err := b.ResolveQuery(); err != nil {
+			return err
+		}
+		path, err := pt.Install(b.ProviderRef, b.Version, destDir, b.Platform, b.Build)
 		if err != nil {
 			return err
 		}
 		b.File = path
 		return nil
 	case *provider.Docker:
-		path, err := pt.Install(b.ProviderRef, b.Version, destDir, nil)
+		path, err := pt.Install(b.ProviderRef, b.Version, destDir, nil, b.Platform, b.Runtime, b.Paths)
 		if err != nil {
 			return err
 		}
 		b.File = path
 		return nil
 	case *provider.OCI:
-		path, err := pt.Install(b.ProviderRef, b.Version, destDir)
+		version := b.Version
+		if !b.Verify.IsZero() {
+			// Install from the verified digest, not the tag, so a tag
+			// moved after verification can't swap the image.
+			if version, err = b.verifyImage(pt); err != nil {
+				return err
+			}
+		}
+		path, err := pt.Install(b.ProviderRef, version, destDir, b.Platform, b.Paths)
 		if err != nil {
 			return err
 		}
@@ -187,17 +275,41 @@ func (b *Binary) downloadViaProvider() error {
 		}
 		b.File = path
 		return nil
+	case *provider.HTTP:
+		if b.Version == "" {
+			if b.Version, err = pt.Latest(b.ProviderRef, b.HTTP); err != nil {
+				return err
+			}
+		}
+		asset, err := pt.Resolve(b.ProviderRef, b.Version, b.HTTP, b.Platform)
+		if err != nil {
+			return err
+		}
+		if b.ArchiveMember, err = b.HTTP.RenderMember(b.Version, b.Platform); err != nil {
+			return err
+		}
+		b.ResolvedChecksum = nil
+		return b.downloadAsset(asset)
 	}
 
-	// If asset was pre-resolved (e.g. via interactive prompt before download),
-	// skip all provider API calls entirely.
+	if b.TarFileF != nil {
+		if b.ArchiveMember, err = b.TarFileF(b); err != nil {
+			return err
+		}
+	}
+
+	// If asset was pre-resolved (e.g. via interactive prompt before download,
+	// or from b.lock when offline), skip all provider API calls entirely.
 	if b.ResolvedAsset != nil {
 		return b.downloadAsset(b.ResolvedAsset)
 	}
+	if offline.Enabled() {
+		return offline.Missing(b.artifact(), "no asset recorded in b.lock")
+	}
 
 	// Release-based providers (GitHub, GitLab, Gitea)
 	if b.Version == "" {
-		b.Version, err = p.LatestVersion(b.ProviderRef)
+		b.Version, err = provider.LatestInChannel(p, b.ProviderRef, b.Channel)
 		if err != nil {
 			return err
 		}
@@ -208,9 +320,10 @@ func (b *Binary) downloadViaProvider() error {
 		return err
 	}
 
-	repoName := provider.BinaryName(b.ProviderRef)
-	candidates := provider.MatchAssets(release.Assets, repoName, b.AssetFilter)
-
+	candidates, err := b.MatchAssets(release.Assets)
+	if err != nil {
+		return err
+	}
 	if len(candidates) == 0 {
 		if b.AssetFilter != "" {
 			return fmt.Errorf("%s@%s: no matching asset for filter %q among %d assets", b.ProviderRef, b.Version, b.AssetFilter, len(release.Assets))
@@ -220,84 +333,171 @@ func (b *Binary) downloadViaProvider() error {
 
 	// If there are multiple candidates with the same top score, let the caller
 	// handle interactive selection via SelectAsset callback.
+	asset := candidates[0].Asset
 	if len(candidates) > 1 && candidates[0].Score == candidates[1].Score && b.SelectAsset != nil {
-		asset, err := b.SelectAsset(candidates)
+		asset, err = b.SelectAsset(candidates)
 		if err != nil {
 			return err
 		}
-		return b.downloadAsset(asset)
 	}
 
-	return b.downloadAsset(candidates[0].Asset)
+	b.ResolveCompanions(release.Assets, asset)
+	return b.downloadAsset(asset)
+}
+
+// MatchAssets returns the release assets that may be b's for its platform,
+// best first: the one AssetF names, or else those auto-detection scores.
+func (b *Binary) MatchAssets(assets []provider.Asset) ([]provider.Scored, error) {
+	if b.AssetF == nil {
+		return provider.MatchAssetsFor(b.Platform, assets, provider.BinaryName(b.ProviderRef), b.AssetFilter), nil
+	}
+	name, err := b.AssetF(b)
+	if err != nil {
+		return nil, err
+	}
+	if a := findAsset(assets, name); a != nil {
+		return []provider.Scored{{Asset: a}}, nil
+	}
+	return nil, fmt.Errorf("%s@%s: no asset %q among %d assets", b.ProviderRef, b.Version, name, len(assets))
+}
+
+func findAsset(assets []provider.Asset, name string) *provider.Asset {
+	for i := range assets {
+		if assets[i].Name == name {
+			return &assets[i]
+		}
+	}
+	return nil
 }
 
 // downloadAsset downloads a release asset and extracts the binary if archived.
+// The asset is staged in a temp file (see fetch) and b.File is only replaced
+// once the download, its checks and the extraction all succeeded. When
+// b.ResolvedChecksum is set, the asset is verified against the upstream
+// checksum first; a mismatch aborts the install. With a `verify:` policy the
+// signature over the asset (or over the checksum manifest listing it) must
+// verify as well. Offline or b.Locked, b.LockedAssetSHA256 stands in for
+// both: it is the digest of the asset that verified when b.lock was
+// written.
 func (b *Binary) downloadAsset(asset *provider.Asset) error {
-	resp, err := http.Get(asset.URL)
-	if err != nil {
-		return err
+	b.AssetSHA256 = ""
+	b.Signatures = nil
+	b.Asset = asset
+	locked := (offline.Enabled() || b.Locked) && b.LockedAssetSHA256 != ""
+	verify := !b.Verify.IsZero() && !locked
+	if verify && offline.Enabled() {
+		return offline.Missing(b.artifact(), "verifying its signature needs the network")
+	}
+	if verify && b.SignedAsset == nil {
+		return fmt.Errorf("%s: no signature published (verify: requires %s)",
+			asset.Name, strings.Join(b.Verify.Verifiers(), ", "))
+	}
+	signsChecksum := verify && b.ResolvedChecksum != nil && b.SignedAsset.Name == b.ResolvedChecksum.Name
+
+	var (
+		want     string
+		wantFrom string
+		sigs     []signature.Result
+	)
+	if locked {
+		want, wantFrom = b.LockedAssetSHA256, "b.lock"
+	} else if b.ResolvedChecksum != nil {
+		wantFrom = b.ResolvedChecksum.Name
+		data, err := fetchCompanion(b.ResolvedChecksum)
+		if err != nil {
+			return fmt.Errorf("fetching checksums %s: %w", b.ResolvedChecksum.Name, err)
+		}
+		if signsChecksum {
+			if sigs, err = b.verifySignature(b.ResolvedChecksum, data); err != nil {
+				return err
+			}
+		}
+		// A manifest that doesn't list the asset leaves nothing to verify
+		// it against; refuse rather than install it unchecked.
+		want, err = provider.ParseChecksum(data, asset.Name)
+		if errors.Is(err, provider.ErrNotListed) {
+			signed := ""
+			if signsChecksum {
+				signed = "signed "
+			}
+			return fmt.Errorf("%s: not listed in %schecksums %s", asset.Name, signed, b.ResolvedChecksum.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("parsing checksums %s: %w", b.ResolvedChecksum.Name, err)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	src, err := b.open(asset.URL, asset.Name, asset.Size, want, func(resp *http.Response) error {
 		return fmt.Errorf("HTTP %d downloading %s", resp.StatusCode, asset.Name)
+	})
+	if err != nil {
+		return err
 	}
+	defer src.close()
 
-	var reader io.ReadCloser = resp.Body
-	if b.Tracker != nil {
-		b.Tracker.UpdateMessage(fmt.Sprintf("Downloading %s", asset.Name))
-		b.Tracker.UpdateTotal(resp.ContentLength)
-		reader = io.NopCloser(progress.NewReader(resp.Body, b.Tracker))
+	var reader io.Reader = src
+	if want != "" || (verify && !signsChecksum) {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if want != "" {
+			if err := provider.VerifyChecksum(data, want); err != nil {
+				return fmt.Errorf("%s: %w (from %s)", asset.Name, err, wantFrom)
+			}
+		}
+		if verify && !signsChecksum {
+			if sigs, err = b.verifySignature(asset, data); err != nil {
+				return err
+			}
+		}
+		b.AssetSHA256 = fmt.Sprintf("%x", sha256.Sum256(data))
+		b.Signatures = sigs
+		reader = bytes.NewReader(data)
 	}
 
 	archiveType := provider.DetectArchiveType(asset.Name)
+	if b.Layout == LayoutTree {
+		if err := b.extractTree(reader, archiveType); err != nil {
+			return err
+		}
+		src.cache()
+		return nil
+	}
 	switch archiveType {
-	case "tar.gz":
-		return b.extractFromTarAuto(reader, "gz")
-	case "tar.xz":
-		return b.extractFromTarAuto(reader, "xz")
+	case "tar.gz", "tar.xz", "tar.bz2", "tar.zst":
+		err = b.extractFromTarAuto(reader, strings.TrimPrefix(archiveType, "tar."))
 	case "zip":
-		return b.extractFromZipAuto(reader)
+		err = b.extractFromZipAuto(reader)
+	case "gz", "xz", "bz2", "zst":
+		err = b.extractCompressed(reader, archiveType)
 	default:
-		// Raw binary (no archive)
-		file, err := os.OpenFile(b.File, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-		if err != nil {
+		// Raw binary (no archive): link the cached blob into place
+		if err := b.noArchive(asset.Name); err != nil {
 			return err
 		}
-		_, copyErr := io.Copy(file, reader)
-		closeErr := file.Close()
-		if copyErr != nil {
-			return copyErr
-		}
-		if closeErr != nil {
-			return closeErr
+		if blob := src.cache(); blob != "" {
+			return blobcache.Materialize(blob, b.File)
 		}
-		return os.Chmod(b.File, 0755)
+		return provider.WriteExecutable(b.File, reader)
 	}
+	// Only archives that extracted are worth keeping
+	if err == nil {
+		src.cache()
+	}
+	return err
 }
 
 // extractFromTarAuto extracts the best-matching binary from a tar archive
-// using heuristic detection (name match > largest executable > only executable).
+// using heuristic detection (name match > largest executable > only executable),
+// and b's companions along with it.
 func (b *Binary) extractFromTarAuto(stream io.Reader, compression string) error {
-	var tarReader *tar.Reader
-
-	switch compression {
-	case "gz":
-		gzipReader, err := gzip.NewReader(stream)
-		if err != nil {
-			return err
-		}
-		defer gzipReader.Close()
-		tarReader = tar.NewReader(gzipReader)
-	case "xz":
-		xzReader, err := xz.NewReader(stream)
-		if err != nil {
-			return err
-		}
-		tarReader = tar.NewReader(xzReader)
-	default:
-		return fmt.Errorf("unknown compression type: %s", compression)
+	r, err := decompress(stream, compression)
+	if err != nil {
+		return err
 	}
+	defer r.Close()
+	tarReader := tar.NewReader(r)
 
 	type candidate struct {
 		name string
@@ -306,6 +506,9 @@ func (b *Binary) extractFromTarAuto(stream io.Reader, compression string) error
 	}
 	var candidates []candidate
 	var nameMatch *candidate
+	x := b.extractor()
+	defer x.discard()
+	memberFound := false
 
 	for {
 		header, err := tarReader.Next()
@@ -319,8 +522,31 @@ func (b *Binary) extractFromTarAuto(stream io.Reader, compression string) error
 			continue
 		}
 
+		// Companions are staged as they come and never picked
+		if x.wants(header.Name) {
+			if err := x.write(header.Name, tarReader); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// An explicit member wins regardless of its mode bits
+		if b.ArchiveMember != "" {
+			if memberFound || !isArchiveMember(header.Name, b.ArchiveMember) {
+				continue
+			}
+			if err := provider.WriteExecutable(b.File, tarReader); err != nil {
+				return err
+			}
+			memberFound = true
+			if x.done() {
+				break
+			}
+			continue
+		}
+
 		// Skip non-executable files
-		if header.Mode&0111 == 0 {
+		if b.windows() && !isExe(header.Name) || !b.windows() && header.Mode&0111 == 0 {
 			continue
 		}
 
@@ -337,11 +563,21 @@ func (b *Binary) extractFromTarAuto(stream io.Reader, compression string) error
 		candidates = append(candidates, c)
 
 		base := filepath.Base(header.Name)
-		if base == b.Name {
+		if base == b.Name || base == b.Platform.Exe(b.Name) {
 			nameMatch = &candidates[len(candidates)-1]
 		}
 	}
 
+	if b.ArchiveMember != "" {
+		if !memberFound {
+			return fmt.Errorf("%s not found in archive for %s", b.ArchiveMember, b.Name)
+		}
+		if err := x.check(); err != nil {
+			return err
+		}
+		return x.commit()
+	}
+
 	var chosen *candidate
 	if nameMatch != nil {
 		chosen = nameMatch
@@ -360,14 +596,17 @@ func (b *Binary) extractFromTarAuto(stream io.Reader, compression string) error
 	if chosen == nil {
 		return fmt.Errorf("no executable found in archive for %s", b.Name)
 	}
-
-	if err := os.WriteFile(b.File, chosen.data, 0755); err != nil {
+	if err := x.check(); err != nil {
 		return err
 	}
-	return nil
+	if err := provider.WriteExecutable(b.File, bytes.NewReader(chosen.data)); err != nil {
+		return err
+	}
+	return x.commit()
 }
 
-// extractFromZipAuto extracts the best-matching binary from a zip archive.
+// extractFromZipAuto extracts the best-matching binary from a zip archive,
+// and b's companions along with it.
 func (b *Binary) extractFromZipAuto(stream io.Reader) error {
 	zipData, err := io.ReadAll(stream)
 	if err != nil {
@@ -385,20 +624,37 @@ func (b *Binary) extractFromZipAuto(stream io.Reader) error {
 	}
 	var candidates []candidate
 	var nameMatch *candidate
+	x := b.extractor()
+	defer x.discard()
 
 	for _, f := range zipReader.File {
-		if f.FileInfo().IsDir() {
+		if f.FileInfo().IsDir() || x.wants(f.Name) {
+			continue
+		}
+		if b.ArchiveMember != "" {
+			if isArchiveMember(f.Name, b.ArchiveMember) {
+				candidates = []candidate{{name: f.Name, file: f}}
+				nameMatch = &candidates[0]
+				break
+			}
+			continue
+		}
+		// In zip files, we can't reliably check execute bit, so include all
+		// regular files — but only .exe ones for Windows
+		if b.windows() && !isExe(f.Name) {
 			continue
 		}
-		// In zip files, we can't reliably check execute bit, so include all regular files
 		c := candidate{name: f.Name, file: f}
 		candidates = append(candidates, c)
 
 		base := filepath.Base(f.Name)
-		if base == b.Name {
+		if base == b.Name || base == b.Platform.Exe(b.Name) {
 			nameMatch = &candidates[len(candidates)-1]
 		}
 	}
+	if b.ArchiveMember != "" && nameMatch == nil {
+		return fmt.Errorf("%s not found in archive for %s", b.ArchiveMember, b.Name)
+	}
 
 	var chosen *candidate
 	if nameMatch != nil {
@@ -418,6 +674,12 @@ func (b *Binary) extractFromZipAuto(stream io.Reader) error {
 	if chosen == nil {
 		return fmt.Errorf("no file found in archive for %s", b.Name)
 	}
+	if err := x.writeZip(zipReader); err != nil {
+		return err
+	}
+	if err := x.check(); err != nil {
+		return err
+	}
 
 	rc, err := chosen.file.Open()
 	if err != nil {
@@ -425,19 +687,29 @@ func (b *Binary) extractFromZipAuto(stream io.Reader) error {
 	}
 	defer rc.Close()
 
-	outFile, err := os.OpenFile(b.File, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
+	if err := provider.WriteExecutable(b.File, rc); err != nil {
 		return err
 	}
+	return x.commit()
+}
 
-	if _, err := io.Copy(outFile, rc); err != nil {
-		outFile.Close()
-		return err
-	}
-	if err := outFile.Close(); err != nil {
-		return err
+// windows reports whether b is installed for Windows, where executables
+// are told by their .exe suffix rather than by mode bits.
+func (b *Binary) windows() bool {
+	return b.Platform.Resolve().OS == "windows"
+}
+
+func isExe(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".exe")
+}
+
+// isArchiveMember reports whether the archive entry name is the configured
+// member path, ignoring a leading "./" and redundant separators.
+func isArchiveMember(name, member string) bool {
+	clean := func(p string) string {
+		return path.Clean(strings.TrimPrefix(strings.TrimPrefix(p, "./"), "/"))
 	}
-	return os.Chmod(b.File, 0755)
+	return clean(name) == clean(member)
 }
 
 // downloadPreset is the original preset-based download path.
@@ -468,44 +740,12 @@ func (b *Binary) downloadPreset() error {
 	if err != nil {
 		return err
 	}
-	resp, err := http.Get(url)
+	b.Asset = &provider.Asset{Name: b.Name, URL: url}
+	staged, err := b.open(url, b.Name, 0, "", b.presetStatusError)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	// Check HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		switch resp.StatusCode {
-		case http.StatusNotFound:
-			var latest string
-			if b.VersionF != nil {
-				latest, _ = b.VersionF(b)
-			}
-			// A 404 on the download URL means the requested version+platform
-			// asset doesn't exist. If the requested version differs from the
-			// latest, the version is the likely culprit; otherwise the version
-			// is fine and there's simply no asset for this OS/arch.
-			if latest != "" && latest != b.Version {
-				return fmt.Errorf("%s %s not found (latest: %s)", b.Name, b.Version, latest)
-			}
-			return fmt.Errorf("%s %s not found for %s/%s", b.Name, b.Version, runtime.GOOS, runtime.GOARCH)
-		case http.StatusForbidden:
-		case http.StatusUnauthorized:
-			return fmt.Errorf("Unauthorized")
-		case http.StatusTooManyRequests:
-			return fmt.Errorf("Rate limited")
-		default:
-			return fmt.Errorf("HTTP error %d", resp.StatusCode)
-		}
-	}
-
-	reader := resp.Body
-	if b.Tracker != nil {
-		b.Tracker.UpdateMessage(fmt.Sprintf("Downloading %s", b.Name))
-		b.Tracker.UpdateTotal(resp.ContentLength)
-		reader = progress.NewReader(resp.Body, b.Tracker)
-	}
+	defer staged.close()
 
 	// Checks file extension
 	if b.IsDynamic {
@@ -514,39 +754,74 @@ func (b *Binary) downloadPreset() error {
 			return err
 		}
 		switch extension {
-		case "tar.gz":
+		case "tar.gz", "tgz":
 			b.IsTarGz = true
-		case "tar.xz":
+		case "tar.xz", "txz":
 			b.IsTarXz = true
+		case "tar.bz2", "tbz2":
+			b.IsTarBz2 = true
+		case "tar.zst", "tzst":
+			b.IsTarZst = true
 		case "zip":
 			b.IsZip = true
+		case "gz", "xz", "bz2", "zst":
+			b.Compression = extension
 		default:
 			return fmt.Errorf("Unknown file extension: %s", extension)
 		}
 	}
 
-	if b.IsTarGz {
-		return b.extractSingleFileFromTar(reader, "gz")
-	}
-	if b.IsTarXz {
-		return b.extractSingleFileFromTar(reader, "xz")
+	switch {
+	case b.Layout == LayoutTree:
+		err = b.extractTree(staged, b.presetArchiveType())
+	case b.IsTarGz:
+		err = b.extractSingleFileFromTar(staged, "gz")
+	case b.IsTarXz:
+		err = b.extractSingleFileFromTar(staged, "xz")
+	case b.IsTarBz2:
+		err = b.extractSingleFileFromTar(staged, "bz2")
+	case b.IsTarZst:
+		err = b.extractSingleFileFromTar(staged, "zst")
+	case b.IsZip:
+		err = b.extractSingleFileFromZip(staged)
+	case b.Compression != "":
+		err = b.extractCompressed(staged, b.Compression)
+	default:
+		if err := b.noArchive(url); err != nil {
+			return err
+		}
+		if blob := staged.cache(); blob != "" {
+			return blobcache.Materialize(blob, b.File)
+		}
+		return provider.WriteExecutable(b.File, staged)
 	}
-	if b.IsZip {
-		return b.extractSingleFileFromZip(reader)
+	if err == nil {
+		staged.cache()
 	}
+	return err
+}
 
-	file, err := os.OpenFile(b.File, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		return err
-	}
-	_, copyErr := io.Copy(file, reader)
-	closeErr := file.Close()
-	if copyErr != nil {
-		return copyErr
-	}
-	if closeErr != nil {
-		return closeErr
+// presetStatusError explains a failed preset download.
+func (b *Binary) presetStatusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		var latest string
+		if b.VersionF != nil {
+			latest, _ = b.VersionF(b)
+		}
+		// A 404 on the download URL means the requested version+platform
+		// asset doesn't exist. If the requested version differs from the
+		// latest, the version is the likely culprit; otherwise the version
+		// is fine and there's simply no asset for this OS/arch.
+		if latest != "" && latest != b.Version {
+			return fmt.Errorf("%s %s not found (latest: %s)", b.Name, b.Version, latest)
+		}
+		return fmt.Errorf("%s %s not found for %s", b.Name, b.Version, b.Platform)
+	case http.StatusForbidden, http.StatusUnauthorized:
+		return fmt.Errorf("Unauthorized")
+	case http.StatusTooManyRequests:
+		return fmt.Errorf("Rate limited")
+	default:
+		return fmt.Errorf("HTTP error %d", resp.StatusCode)
 	}
-
-	return os.Chmod(b.File, 0755)
 }
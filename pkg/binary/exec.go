@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+
+	"github.com/fentas/b/pkg/provider"
 )
 
 func (b *Binary) Env() []string {
@@ -31,6 +33,9 @@ func (b *Binary) Cmd(args ...string) *exec.Cmd {
 }
 
 func (b *Binary) Exec(args ...string) (string, error) {
+	if !b.Platform.IsHost() {
+		return "", fmt.Errorf("%s is a %s binary, it can't run on %s", b.Name, b.Platform, provider.HostPlatform())
+	}
 	cmd := b.Cmd(args...)
 	if cmd == nil {
 		return "", fmt.Errorf("binary %s does not exist", b.Name)
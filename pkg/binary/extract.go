@@ -0,0 +1,241 @@
+package binary
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fentas/b/pkg/blobcache"
+)
+
+// Extract is a file installed from a release archive next to the binary
+// itself, e.g. kubectl-convert or hubble. In b.yaml it is either the
+// member's name or a mapping that renames it:
+//
+//	extract:
+//	  - kubectl-convert
+//	  - member: bin/hubble
+//	    as: hb
+type Extract struct {
+	// Member is the archive entry: a path inside the archive, or a bare
+	// file name matched in any directory.
+	Member string `json:"member" yaml:"member"`
+	// As is the installed file name; defaults to the member's base name.
+	As string `json:"as,omitempty" yaml:"as,omitempty"`
+}
+
+// UnmarshalYAML accepts the member name alone or the {member, as} mapping.
+func (e *Extract) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var member string
+	if err := unmarshal(&member); err == nil {
+		*e = Extract{Member: member}
+	} else {
+		type plain Extract
+		if err := unmarshal((*plain)(e)); err != nil {
+			return err
+		}
+	}
+	if e.Member == "" {
+		return fmt.Errorf("extract: member is required")
+	}
+	if name := e.Name(); name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("extract: %q is not a file name", name)
+	}
+	return nil
+}
+
+// MarshalYAML writes e back in the short form when it isn't renamed.
+func (e Extract) MarshalYAML() (interface{}, error) {
+	if e.As == "" {
+		return e.Member, nil
+	}
+	type plain Extract
+	return plain(e), nil
+}
+
+// Name is the file name e is installed as.
+func (e Extract) Name() string {
+	if e.As != "" {
+		return e.As
+	}
+	return path.Base(e.Member)
+}
+
+// matches reports whether the archive entry name is e's member.
+func (e Extract) matches(name string) bool {
+	if strings.Contains(e.Member, "/") {
+		return isArchiveMember(name, e.Member)
+	}
+	return path.Base(name) == e.Member
+}
+
+// Companions returns the Extract entries installed as files of their own;
+// an entry naming the binary itself is the binary.
+func (b *Binary) Companions() []Extract {
+	self := filepath.Base(b.BinaryPath())
+	var extra []Extract
+	for _, e := range b.Extract {
+		if e.Name() != self {
+			extra = append(extra, e)
+		}
+	}
+	return extra
+}
+
+// CompanionsExist reports whether every companion is installed.
+func (b *Binary) CompanionsExist() bool {
+	for _, e := range b.Companions() {
+		if _, err := os.Stat(b.ExtractPath(e)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtractPath is where e is installed: in the binary's directory.
+func (b *Binary) ExtractPath(e Extract) string {
+	return filepath.Join(filepath.Dir(b.BinaryPath()), e.Name())
+}
+
+// lockedBlobs finds the binary and its companions in the download cache
+// by the digests b.lock recorded, keyed by where they are installed. It
+// fails unless all of them are there.
+func (b *Binary) lockedBlobs() (map[string]string, bool) {
+	blob, ok := blobcache.Lookup("", b.LockedSHA256)
+	if !ok {
+		return nil, false
+	}
+	blobs := map[string]string{b.BinaryPath(): blob}
+	for _, e := range b.Companions() {
+		sum := b.LockedExtract[e.Name()]
+		if sum == "" {
+			return nil, false
+		}
+		if blobs[b.ExtractPath(e)], ok = blobcache.Lookup("", sum); !ok {
+			return nil, false
+		}
+	}
+	return blobs, true
+}
+
+// noArchive fails when b has companions to extract but its download, name,
+// is a single file.
+func (b *Binary) noArchive(name string) error {
+	if len(b.Companions()) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s: extract needs a tar or zip archive, %s is a single file", b.Name, path.Base(name))
+}
+
+// extractor installs b's companions while an archive is walked. They are
+// staged in temp files next to where they go and only moved into place by
+// commit, once the binary is found, so a failed install leaves none behind.
+type extractor struct {
+	b       *Binary
+	pending []Extract
+	staged  map[string]string // install path to temp file
+}
+
+func (b *Binary) extractor() *extractor {
+	return &extractor{b: b, pending: b.Companions(), staged: make(map[string]string)}
+}
+
+// wants reports whether the archive entry name is a companion not yet
+// installed.
+func (x *extractor) wants(name string) bool {
+	return x.find(name) >= 0
+}
+
+func (x *extractor) find(name string) int {
+	for i, e := range x.pending {
+		if e.matches(name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// write stages the archive entry name from r.
+func (x *extractor) write(name string, r io.Reader) error {
+	i := x.find(name)
+	if i < 0 {
+		return nil
+	}
+	e := x.pending[i]
+	x.pending = append(x.pending[:i], x.pending[i+1:]...)
+	dest := x.b.ExtractPath(e)
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	x.staged[dest] = tmp.Name()
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// commit moves the staged companions into place.
+func (x *extractor) commit() error {
+	for _, dest := range slices.Sorted(maps.Keys(x.staged)) {
+		tmp := x.staged[dest]
+		if err := os.Chmod(tmp, 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, dest); err != nil {
+			return fmt.Errorf("moving %s into place: %w", filepath.Base(dest), err)
+		}
+		delete(x.staged, dest)
+	}
+	return nil
+}
+
+// discard removes the companions staged but not committed.
+func (x *extractor) discard() {
+	for _, tmp := range x.staged {
+		_ = os.Remove(tmp)
+	}
+}
+
+// writeZip installs the companions found in zr.
+func (x *extractor) writeZip(zr *zip.Reader) error {
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !x.wants(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = x.write(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// done reports whether every companion was installed.
+func (x *extractor) done() bool {
+	return len(x.pending) == 0
+}
+
+// check fails for the companions the archive didn't contain.
+func (x *extractor) check() error {
+	if x.done() {
+		return nil
+	}
+	members := make([]string, len(x.pending))
+	for i, e := range x.pending {
+		members[i] = e.Member
+	}
+	return fmt.Errorf("%s not found in archive for %s", strings.Join(members, ", "), x.b.Name)
+}
@@ -0,0 +1,270 @@
+package binary
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+)
+
+func TestExtract_YAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Extract
+		wantErr string
+	}{
+		{name: "names", in: "[kubectl-convert, hubble]", want: []Extract{{Member: "kubectl-convert"}, {Member: "hubble"}}},
+		{name: "rename", in: "[{member: bin/hubble, as: hb}]", want: []Extract{{Member: "bin/hubble", As: "hb"}}},
+		{name: "no member", in: "[{as: hb}]", wantErr: "member is required"},
+		{name: "as a path", in: "[{member: hubble, as: ../hb}]", wantErr: "not a file name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Extract
+			err := yaml.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unmarshal() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Unmarshal() = %v, want %v", got, tt.want)
+			}
+			out, err := yaml.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var again []Extract
+			if err := yaml.Unmarshal(out, &again); err != nil || fmt.Sprint(again) != fmt.Sprint(tt.want) {
+				t.Errorf("round trip = %v (%v) from %q", again, err, out)
+			}
+		})
+	}
+}
+
+func TestExtract_Name(t *testing.T) {
+	tests := []struct {
+		e    Extract
+		want string
+	}{
+		{Extract{Member: "hubble"}, "hubble"},
+		{Extract{Member: "./bin/hubble"}, "hubble"},
+		{Extract{Member: "bin/hubble", As: "hb"}, "hb"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.Name(); got != tt.want {
+			t.Errorf("%+v.Name() = %q, want %q", tt.e, got, tt.want)
+		}
+	}
+}
+
+func TestBinary_Companions(t *testing.T) {
+	b := &Binary{Name: "kubectl", File: "/bin/kubectl", Extract: []Extract{{Member: "kubectl"}, {Member: "kubectl-convert"}}}
+	got := b.Companions()
+	if len(got) != 1 || got[0].Member != "kubectl-convert" {
+		t.Errorf("Companions() = %v, want only kubectl-convert", got)
+	}
+	if p := b.ExtractPath(got[0]); p != "/bin/kubectl-convert" {
+		t.Errorf("ExtractPath() = %q", p)
+	}
+}
+
+func TestDownloadAsset_Extract(t *testing.T) {
+	tgz := makeTarGz(t,
+		tarEntry{name: "cilium", mode: 0755, content: []byte("cilium")},
+		tarEntry{name: "bin/hubble", mode: 0755, content: []byte("hubble")},
+		tarEntry{name: "README.md", mode: 0644, content: []byte("docs")},
+	)
+	zipped := makeZip(t, map[string][]byte{
+		"cilium":     []byte("cilium"),
+		"bin/hubble": []byte("hubble"),
+		"README.md":  []byte("docs"),
+	})
+	tests := []struct {
+		name    string
+		asset   string
+		body    []byte
+		member  string
+		extract []Extract
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:    "tar by name",
+			asset:   "cilium.tar.gz",
+			body:    tgz.Bytes(),
+			extract: []Extract{{Member: "hubble"}},
+			want:    map[string]string{"cilium": "cilium", "hubble": "hubble"},
+		},
+		{
+			name:    "tar renamed, binary listed",
+			asset:   "cilium.tar.gz",
+			body:    tgz.Bytes(),
+			extract: []Extract{{Member: "cilium"}, {Member: "bin/hubble", As: "hb"}, {Member: "README.md"}},
+			want:    map[string]string{"cilium": "cilium", "hb": "hubble", "README.md": "docs"},
+		},
+		{
+			name:    "tar with archive member",
+			asset:   "cilium.tar.gz",
+			body:    tgz.Bytes(),
+			member:  "cilium",
+			extract: []Extract{{Member: "bin/hubble"}},
+			want:    map[string]string{"cilium": "cilium", "hubble": "hubble"},
+		},
+		{
+			name:    "zip",
+			asset:   "cilium.zip",
+			body:    zipped.Bytes(),
+			extract: []Extract{{Member: "hubble", As: "hb"}},
+			want:    map[string]string{"cilium": "cilium", "hb": "hubble"},
+		},
+		{
+			name:    "not in the archive",
+			asset:   "cilium.tar.gz",
+			body:    tgz.Bytes(),
+			extract: []Extract{{Member: "hubble"}, {Member: "cilium-dbg"}},
+			wantErr: "cilium-dbg not found in archive for cilium",
+		},
+		{
+			name:    "zip, not in the archive",
+			asset:   "cilium.zip",
+			body:    zipped.Bytes(),
+			extract: []Extract{{Member: "hubble"}, {Member: "cilium-dbg"}},
+			wantErr: "cilium-dbg not found in archive for cilium",
+		},
+		{
+			name:    "archive member not in the archive",
+			asset:   "cilium.tar.gz",
+			body:    tgz.Bytes(),
+			member:  "cilium-agent",
+			extract: []Extract{{Member: "bin/hubble"}},
+			wantErr: "cilium-agent not found in archive for cilium",
+		},
+		{
+			name:    "single file",
+			asset:   "cilium-linux-amd64",
+			body:    []byte("cilium"),
+			extract: []Extract{{Member: "hubble"}},
+			wantErr: "extract needs a tar or zip archive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+			dir := t.TempDir()
+			b := &Binary{Name: "cilium", File: filepath.Join(dir, "cilium"), ArchiveMember: tt.member, Extract: tt.extract}
+			err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/" + tt.asset, Name: tt.asset})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("downloadAsset() error = %v, want %q", err, tt.wantErr)
+				}
+				if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+					t.Errorf("left %v behind despite the failure", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(data) != content {
+					t.Errorf("%s = %q err=%v, want %q", name, data, err, content)
+				}
+			}
+			if !b.CompanionsExist() {
+				t.Error("CompanionsExist() = false after the install")
+			}
+		})
+	}
+}
+
+func TestDownloadPreset_Extract(t *testing.T) {
+	tgz := makeTarGz(t,
+		tarEntry{name: "kubectl-convert", mode: 0755, content: []byte("convert")},
+		tarEntry{name: "kubectl", mode: 0755, content: []byte("kubectl")},
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(tgz.Bytes())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	b := &Binary{
+		Name:    "kubectl",
+		File:    filepath.Join(dir, "kubectl"),
+		URL:     srv.URL + "/kubectl.tar.gz",
+		Version: "v1",
+		IsTarGz: true,
+		Extract: []Extract{{Member: "kubectl-convert", As: "kc"}},
+	}
+	if err := b.downloadPreset(); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"kubectl": "kubectl", "kc": "convert"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != content {
+			t.Errorf("%s = %q err=%v, want %q", name, data, err, content)
+		}
+	}
+}
+
+func TestDownloadBinary_OfflineCompanions(t *testing.T) {
+	blobcache.SetRoot(t.TempDir())
+	offline.Set(true)
+	t.Cleanup(func() {
+		blobcache.SetRoot("")
+		offline.Set(false)
+	})
+	put := func(content string) string {
+		src := filepath.Join(t.TempDir(), "dl")
+		if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		blob, err := blobcache.Put("bundle://"+content, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filepath.Base(blob)
+	}
+	binSum, hubbleSum := put("cilium"), put("hubble")
+
+	dir := t.TempDir()
+	b := &Binary{
+		Name:          "cilium",
+		Version:       "v1.0.0",
+		File:          filepath.Join(dir, "cilium"),
+		Extract:       []Extract{{Member: "hubble"}},
+		LockedSHA256:  binSum,
+		LockedExtract: map[string]string{"hubble": hubbleSum},
+	}
+	if err := b.downloadBinary(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "hubble")); string(data) != "hubble" {
+		t.Errorf("hubble = %q", data)
+	}
+
+	// A companion b.lock has no digest for can't come from the cache.
+	b.LockedExtract = nil
+	if _, ok := b.lockedBlobs(); ok {
+		t.Error("lockedBlobs() without the companion's digest succeeded")
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/b/pkg/provider"
 )
 
 const (
@@ -16,14 +18,17 @@ const (
 
 var (
 	// List of special extensions to try
-	Extensions = []string{"tar.gz", "tar.xz"}
+	Extensions = []string{"tar.gz", "tar.xz", "tar.bz2", "tar.zst"}
 )
 
 func GithubLatest(b *Binary) (string, error) {
 	if b.GitHubRepo == "" {
 		return b.Version, fmt.Errorf("GitHubRepo is not set")
 	}
-	resp, err := http.Get(fmt.Sprintf(GithubLatestURL, b.GitHubRepo))
+	if b.Channel != "" && b.Channel != provider.ChannelStable {
+		return provider.LatestInChannel(&provider.GitHub{}, "github.com/"+b.GitHubRepo, b.Channel)
+	}
+	resp, err := httpclient.Get(fmt.Sprintf(GithubLatestURL, b.GitHubRepo))
 	if err != nil {
 		return b.Version, err
 	}
@@ -33,7 +38,7 @@ func GithubLatest(b *Binary) (string, error) {
 }
 
 func GetBody(url string) (string, error) {
-	resp, err := http.Get(url)
+	resp, err := httpclient.Get(url)
 	if err != nil {
 		return "", err
 	}
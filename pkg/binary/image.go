@@ -0,0 +1,30 @@
+package binary
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+// ValidateImage checks the runtime and paths settings of lb.
+func (lb *LocalBinary) ValidateImage() error {
+	if err := provider.ValidateRuntime(lb.Runtime); err != nil {
+		return err
+	}
+	return provider.ValidatePaths(lb.Paths)
+}
+
+// PathsExist reports whether every path copied out of an image next to
+// the binary is installed.
+func (b *Binary) PathsExist() bool {
+	binPath := b.BinaryPath()
+	_, extra := provider.SplitPaths(filepath.Base(binPath), b.Paths)
+	for _, p := range extra {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(binPath), path.Base(p))); err != nil {
+			return false
+		}
+	}
+	return true
+}
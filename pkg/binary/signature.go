@@ -0,0 +1,108 @@
+package binary
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
+)
+
+// ResolveCompanions records the checksum and signature files published next
+// to asset in the same release. With a `verify:` policy, signatures over
+// the asset itself win; otherwise a signed checksum manifest is used, and
+// the asset is then tied to the signature through its listed digest.
+func (b *Binary) ResolveCompanions(assets []provider.Asset, asset *provider.Asset) {
+	b.ResolvedChecksum = provider.FindChecksumAsset(assets, asset.Name)
+	if b.ChecksumF != nil {
+		if name, err := b.ChecksumF(b); err == nil && name != "" {
+			if named := findAsset(assets, name); named != nil {
+				b.ResolvedChecksum = named
+			}
+		}
+	}
+	b.SignedAsset, b.ResolvedSignatures = nil, nil
+	if b.Verify.IsZero() {
+		return
+	}
+	suffixes := b.Verify.Companions()
+	if sigs := provider.FindSignatureAssets(assets, asset.Name, suffixes); sigs != nil {
+		b.SignedAsset, b.ResolvedSignatures = asset, sigs
+		return
+	}
+	// Prefer the checksum file already picked, then any other manifest —
+	// a release may ship "<asset>.sha256" unsigned next to a signed
+	// "checksums.txt".
+	manifests := provider.ChecksumManifests(assets)
+	if b.ResolvedChecksum != nil {
+		manifests = append([]*provider.Asset{b.ResolvedChecksum}, manifests...)
+	}
+	for _, m := range manifests {
+		if sigs := provider.FindSignatureAssets(assets, m.Name, suffixes); sigs != nil {
+			b.ResolvedChecksum, b.SignedAsset, b.ResolvedSignatures = m, m, sigs
+			return
+		}
+	}
+}
+
+// verifySignature checks data (the content of signed) against the resolved
+// signature companions.
+func (b *Binary) verifySignature(signed *provider.Asset, data []byte) ([]signature.Result, error) {
+	material := make(signature.Material, len(b.ResolvedSignatures))
+	for suffix, a := range b.ResolvedSignatures {
+		raw, err := fetchCompanion(a)
+		if err != nil {
+			return nil, fmt.Errorf("fetching signature %s: %w", a.Name, err)
+		}
+		material[suffix] = raw
+	}
+	results, err := b.Verify.Verify(data, material)
+	if err != nil {
+		return nil, fmt.Errorf("%s: signature verification failed: %w", signed.Name, err)
+	}
+	for i := range results {
+		results[i].Signed = signed.Name
+	}
+	return results, nil
+}
+
+// verifyImage checks the cosign signatures attached to an oci:// image and
+// returns the signed manifest digest to install from.
+func (b *Binary) verifyImage(o *provider.OCI) (string, error) {
+	b.Signatures = nil
+	digest, sigs, err := o.CosignSignatures(b.ProviderRef, b.Version, b.Platform)
+	if err != nil {
+		return "", err
+	}
+	if len(sigs) == 0 {
+		return "", fmt.Errorf("%s: image has no cosign signatures (verify: requires %s)",
+			b.ProviderRef, strings.Join(b.Verify.Verifiers(), ", "))
+	}
+	var errs []error
+	for _, s := range sigs {
+		results, err := b.Verify.VerifyImage(digest, s.Payload, s.Signature, s.Certificate, s.Bundle)
+		if err == nil {
+			b.Signatures = results
+			return digest, nil
+		}
+		errs = append(errs, err)
+	}
+	return "", fmt.Errorf("%s: signature verification failed: %w", b.ProviderRef, errors.Join(errs...))
+}
+
+// fetchCompanion downloads a small release file (checksums, signatures).
+func fetchCompanion(a *provider.Asset) ([]byte, error) {
+	resp, err := httpclient.Get(a.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d downloading %s", resp.StatusCode, a.Name)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,305 @@
+package binary
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// testSigner signs blobs the way `cosign sign-blob --key` does.
+type testSigner struct {
+	priv   *ecdsa.PrivateKey
+	pubPEM string
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{priv: priv, pubPEM: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}
+}
+
+func (s *testSigner) sign(t *testing.T, data []byte) string {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.priv, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func (s *testSigner) policy() *signature.Config {
+	return &signature.Config{Cosign: &signature.Cosign{Key: s.pubPEM}}
+}
+
+// serveFiles serves a fixed set of release files by path.
+func serveFiles(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func releaseAssets(srv *httptest.Server, names ...string) []provider.Asset {
+	assets := make([]provider.Asset, len(names))
+	for i, n := range names {
+		assets[i] = provider.Asset{Name: n, URL: srv.URL + "/" + n}
+	}
+	return assets
+}
+
+func TestResolveCompanions(t *testing.T) {
+	s := newTestSigner(t)
+	srv := serveFiles(t, nil)
+
+	// Signature over the asset itself wins.
+	assets := releaseAssets(srv, "foo.bin", "foo.bin.sig", "checksums.txt", "checksums.txt.sig")
+	b := &Binary{Verify: s.policy()}
+	b.ResolveCompanions(assets, &assets[0])
+	if b.SignedAsset == nil || b.SignedAsset.Name != "foo.bin" || b.ResolvedSignatures[".sig"].Name != "foo.bin.sig" {
+		t.Errorf("asset signature: signed=%v sigs=%v", b.SignedAsset, b.ResolvedSignatures)
+	}
+
+	// Unsigned per-asset checksum: switch to the signed manifest.
+	assets = releaseAssets(srv, "foo.bin", "foo.bin.sha256", "checksums.txt", "checksums.txt.sig")
+	b = &Binary{Verify: s.policy()}
+	b.ResolveCompanions(assets, &assets[0])
+	if b.ResolvedChecksum.Name != "checksums.txt" || b.SignedAsset.Name != "checksums.txt" {
+		t.Errorf("manifest signature: checksum=%v signed=%v", b.ResolvedChecksum, b.SignedAsset)
+	}
+
+	// Without a policy nothing but the checksum is resolved.
+	b = &Binary{}
+	b.ResolveCompanions(assets, &assets[0])
+	if b.ResolvedChecksum.Name != "foo.bin.sha256" || b.SignedAsset != nil || b.ResolvedSignatures != nil {
+		t.Errorf("no policy: checksum=%v signed=%v", b.ResolvedChecksum, b.SignedAsset)
+	}
+}
+
+func TestDownloadAsset_SignedAsset(t *testing.T) {
+	s := newTestSigner(t)
+	srv := serveFiles(t, map[string]string{
+		"/foo.bin":     "raw-bin",
+		"/foo.bin.sig": s.sign(t, []byte("raw-bin")),
+	})
+	assets := releaseAssets(srv, "foo.bin", "foo.bin.sig")
+	b := &Binary{Name: "foo", File: filepath.Join(t.TempDir(), "foo"), Verify: s.policy()}
+	b.ResolveCompanions(assets, &assets[0])
+	if err := b.downloadAsset(&assets[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Signatures) != 1 || b.Signatures[0].Verifier != "cosign" || b.Signatures[0].Signed != "foo.bin" {
+		t.Errorf("Signatures = %+v", b.Signatures)
+	}
+	if want := fmt.Sprintf("%x", sha256.Sum256([]byte("raw-bin"))); b.AssetSHA256 != want {
+		t.Errorf("AssetSHA256 = %q, want %q", b.AssetSHA256, want)
+	}
+	if data, _ := os.ReadFile(b.File); string(data) != "raw-bin" {
+		t.Errorf("got %q", data)
+	}
+}
+
+func TestDownloadAsset_SignedChecksumManifest(t *testing.T) {
+	s := newTestSigner(t)
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte("raw-bin")))
+	manifest := sum + "  foo.bin\n"
+	srv := serveFiles(t, map[string]string{
+		"/foo.bin":           "raw-bin",
+		"/checksums.txt":     manifest,
+		"/checksums.txt.sig": s.sign(t, []byte(manifest)),
+	})
+	assets := releaseAssets(srv, "foo.bin", "checksums.txt", "checksums.txt.sig")
+	b := &Binary{Name: "foo", File: filepath.Join(t.TempDir(), "foo"), Verify: s.policy()}
+	b.ResolveCompanions(assets, &assets[0])
+	if err := b.downloadAsset(&assets[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Signatures) != 1 || b.Signatures[0].Signed != "checksums.txt" {
+		t.Errorf("Signatures = %+v", b.Signatures)
+	}
+	if b.AssetSHA256 != sum {
+		t.Errorf("AssetSHA256 = %q, want %q", b.AssetSHA256, sum)
+	}
+}
+
+func TestDownloadAsset_SignatureRefusesInstall(t *testing.T) {
+	s := newTestSigner(t)
+	other := fmt.Sprintf("%x", sha256.Sum256([]byte("other")))
+	manifest := other + "  bar.bin\n"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		assets  []string
+		wantErr string
+	}{
+		{
+			name:    "bad signature",
+			files:   map[string]string{"/foo.bin": "tampered", "/foo.bin.sig": s.sign(t, []byte("raw-bin"))},
+			assets:  []string{"foo.bin", "foo.bin.sig"},
+			wantErr: "signature verification failed",
+		},
+		{
+			name:    "unsigned release",
+			files:   map[string]string{"/foo.bin": "raw-bin"},
+			assets:  []string{"foo.bin"},
+			wantErr: "no signature published",
+		},
+		{
+			name: "asset missing from signed manifest",
+			files: map[string]string{
+				"/foo.bin": "raw-bin", "/checksums.txt": manifest,
+				"/checksums.txt.sig": s.sign(t, []byte(manifest)),
+			},
+			assets:  []string{"foo.bin", "checksums.txt", "checksums.txt.sig"},
+			wantErr: "not listed in signed checksums",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serveFiles(t, tt.files)
+			assets := releaseAssets(srv, tt.assets...)
+			b := &Binary{Name: "foo", File: filepath.Join(t.TempDir(), "foo"), Verify: s.policy()}
+			b.ResolveCompanions(assets, &assets[0])
+			err := b.downloadAsset(&assets[0])
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+			if _, statErr := os.Stat(b.File); !os.IsNotExist(statErr) {
+				t.Error("binary must not be written when verification fails")
+			}
+			if b.Signatures != nil || b.AssetSHA256 != "" {
+				t.Errorf("nothing may be recorded on failure: %+v %q", b.Signatures, b.AssetSHA256)
+			}
+		})
+	}
+}
+
+func TestDownloadBinary_VerifyUnsupported(t *testing.T) {
+	policy := newTestSigner(t).policy()
+	for _, b := range []*Binary{
+		{Name: "jq", Verify: policy},
+		{Name: "tool", AutoDetect: true, ProviderRef: "go://github.com/org/tool", Verify: policy},
+	} {
+		b.File = filepath.Join(t.TempDir(), b.Name)
+		if err := b.downloadBinary(); err == nil || !strings.Contains(err.Error(), "signature verification is") {
+			t.Errorf("%s: expected unsupported verification error, got %v", b.Name, err)
+		}
+	}
+}
+
+func TestDownloadViaProvider_OCISigned(t *testing.T) {
+	s := newTestSigner(t)
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	repo := strings.TrimPrefix(srv.URL, "http://") + "/tools/tool"
+
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	_ = tw.WriteHeader(&tar.Header{Name: "usr/local/bin/tool", Typeflag: tar.TypeReg, Mode: 0755, Size: 4})
+	_, _ = tw.Write([]byte("tool"))
+	_ = tw.Close()
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(tarBuf.Bytes())), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(mustRef(t, repo+":v1"), img); err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := img.Digest()
+
+	install := func() (*Binary, error) {
+		b := &Binary{
+			Name:        "tool",
+			File:        filepath.Join(t.TempDir(), "tool"),
+			AutoDetect:  true,
+			ProviderRef: "oci://" + repo,
+			Version:     "v1",
+			Verify:      s.policy(),
+		}
+		return b, b.downloadViaProvider()
+	}
+
+	if _, err := install(); err == nil || !strings.Contains(err.Error(), "no cosign signatures") {
+		t.Fatalf("unsigned image: expected refusal, got %v", err)
+	}
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"image":{"docker-manifest-digest":%q}}}`, digest))
+	sigImg, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+		Annotations: map[string]string{"dev.cosignproject.cosign/signature": s.sign(t, payload)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(mustRef(t, fmt.Sprintf("%s:sha256-%s.sig", repo, digest.Hex)), sigImg); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := install()
+	if err != nil {
+		t.Fatalf("signed image: %v", err)
+	}
+	if data, _ := os.ReadFile(b.File); string(data) != "tool" {
+		t.Errorf("content = %q", data)
+	}
+	if len(b.Signatures) != 1 || b.Signatures[0].Signed != digest.String() {
+		t.Errorf("Signatures = %+v", b.Signatures)
+	}
+	if b.Version != "v1" {
+		t.Errorf("Version = %q; the tag must be kept for the lock", b.Version)
+	}
+}
+
+func mustRef(t *testing.T, s string) name.Reference {
+	t.Helper()
+	ref, err := name.ParseReference(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
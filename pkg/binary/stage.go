@@ -0,0 +1,165 @@
+package binary
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/goodies/progress"
+)
+
+// maxResumes bounds how often fetch picks an interrupted transfer up again.
+var maxResumes = 5
+
+// fetch downloads url into a temp file in b.File's directory and returns
+// it rewound to the start. A transfer cut short is resumed with a Range
+// request from the last byte received rather than started over. When size
+// is known (a release's asset size) the download must match it exactly.
+// statusErr turns an unexpected first response into the error to return.
+//
+// Nothing is written to b.File here; the caller verifies and extracts the
+// staged file and then calls discard on it.
+func (b *Binary) fetch(url, name string, size int64, statusErr func(*http.Response) error) (*os.File, error) {
+	dest := b.BinaryPath()
+	f, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.part")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp file: %w", err)
+	}
+	if err := b.fetchInto(f, url, name, size, statusErr); err != nil {
+		discard(f)
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		discard(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+func (b *Binary) fetchInto(f *os.File, url, name string, size int64, statusErr func(*http.Response) error) error {
+	var (
+		written   int64
+		validator string
+	)
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if written > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", written))
+			if validator != "" {
+				req.Header.Set("If-Range", validator)
+			}
+		}
+		resp, err := httpclient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case written > 0 && resp.StatusCode == http.StatusPartialContent:
+			if start, ok := rangeStart(resp.Header.Get("Content-Range")); !ok || start != written {
+				resp.Body.Close()
+				return fmt.Errorf("resuming %s: server sent range %q, want bytes %d-", name, resp.Header.Get("Content-Range"), written)
+			}
+		case resp.StatusCode == http.StatusOK:
+			// A fresh start, or a server that ignored the Range (or whose
+			// content changed since): begin again from the top.
+			if written > 0 {
+				if err := f.Truncate(0); err != nil {
+					resp.Body.Close()
+					return err
+				}
+				if _, err := f.Seek(0, io.SeekStart); err != nil {
+					resp.Body.Close()
+					return err
+				}
+				written = 0
+			}
+			validator = rangeValidator(resp.Header)
+			if b.Tracker != nil {
+				b.Tracker.UpdateMessage(fmt.Sprintf("Downloading %s", name))
+				b.Tracker.UpdateTotal(resp.ContentLength)
+				b.Tracker.SetValue(0)
+			}
+		case written > 0:
+			resp.Body.Close()
+			return fmt.Errorf("resuming %s: HTTP %d", name, resp.StatusCode)
+		default:
+			err := statusErr(resp)
+			resp.Body.Close()
+			return err
+		}
+
+		var body io.Reader = resp.Body
+		if b.Tracker != nil {
+			body = progress.NewReader(resp.Body, b.Tracker)
+		}
+		n, err := io.Copy(f, body)
+		resp.Body.Close()
+		written += n
+
+		// A body that ended cleanly is complete, unless it had no length
+		// and came up short of the listed size.
+		var pathErr *fs.PathError
+		switch {
+		case err == nil && (size <= 0 || written >= size || resp.ContentLength >= 0):
+			return checkSize(name, written, size)
+		case errors.As(err, &pathErr):
+			// Writing the temp file failed; the network is not to blame.
+			return err
+		case attempt >= maxResumes:
+			if err == nil {
+				return checkSize(name, written, size)
+			}
+			return fmt.Errorf("downloading %s: %w", name, err)
+		}
+	}
+}
+
+// checkSize compares the bytes received with the size the release lists.
+func checkSize(name string, got, want int64) error {
+	if want > 0 && got != want {
+		return fmt.Errorf("%s: downloaded %d bytes, release lists %d", name, got, want)
+	}
+	return nil
+}
+
+// rangeValidator returns the header value for If-Range, so a resumed
+// request only gets a partial response when the content is unchanged. Weak
+// ETags are not allowed there; Last-Modified is the fallback.
+func rangeValidator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+// rangeStart parses the first byte position of a Content-Range header such
+// as "bytes 100-199/200".
+func rangeStart(v string) (int64, bool) {
+	v, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(v, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+// discard closes and removes a staged download.
+func discard(f *os.File) {
+	f.Close()
+	_ = os.Remove(f.Name())
+}
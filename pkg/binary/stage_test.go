@@ -0,0 +1,217 @@
+package binary
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+// flakyServer serves content but cuts the connection after cut bytes for
+// the first drops responses. Range requests are honoured unless ignoreRange.
+type flakyServer struct {
+	content     string
+	cut         int
+	drops       int
+	ignoreRange bool
+
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.ranges = append(s.ranges, r.Header.Get("Range"))
+	n := len(s.ranges)
+	s.mu.Unlock()
+
+	w.Header().Set("ETag", `"v1"`)
+	body, status := s.content, http.StatusOK
+	if rng := r.Header.Get("Range"); rng != "" && !s.ignoreRange {
+		if r.Header.Get("If-Range") != `"v1"` {
+			http.Error(w, "If-Range missing", http.StatusBadRequest)
+			return
+		}
+		start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(s.content)-1, len(s.content)))
+		body, status = s.content[start:], http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if n <= s.drops {
+		_, _ = w.Write([]byte(body[:min(s.cut, len(body))]))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	_, _ = w.Write([]byte(body))
+}
+
+// leftovers lists the files in dir other than keep.
+func leftovers(t *testing.T, dir string, keep ...string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, e := range entries {
+		if !slices.Contains(keep, e.Name()) {
+			out = append(out, e.Name())
+		}
+	}
+	return out
+}
+
+func TestDownloadAsset_Staged(t *testing.T) {
+	const content = "0123456789abcdefghij"
+	tests := []struct {
+		name       string
+		srv        *flakyServer
+		size       int64
+		wantErr    string
+		wantRanges []string
+	}{
+		{
+			name:       "complete",
+			srv:        &flakyServer{content: content},
+			wantRanges: []string{""},
+		},
+		{
+			name:       "resumes after a cut",
+			srv:        &flakyServer{content: content, cut: 8, drops: 1},
+			size:       int64(len(content)),
+			wantRanges: []string{"", "bytes=8-"},
+		},
+		{
+			name:       "resumes repeatedly",
+			srv:        &flakyServer{content: content, cut: 5, drops: 3},
+			wantRanges: []string{"", "bytes=5-", "bytes=10-", "bytes=15-"},
+		},
+		{
+			name:       "restarts when the range is ignored",
+			srv:        &flakyServer{content: content, cut: 8, drops: 1, ignoreRange: true},
+			wantRanges: []string{"", "bytes=8-"},
+		},
+		{
+			name:    "gives up after maxResumes",
+			srv:     &flakyServer{content: content, cut: 1, drops: 10},
+			wantErr: "downloading file.bin",
+		},
+		{
+			name:    "size mismatch",
+			srv:     &flakyServer{content: content},
+			size:    99,
+			wantErr: "downloaded 20 bytes, release lists 99",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := maxResumes
+			maxResumes = 3
+			t.Cleanup(func() { maxResumes = old })
+			srv := httptest.NewServer(tt.srv)
+			defer srv.Close()
+
+			dir := t.TempDir()
+			b := &Binary{Name: "x", File: filepath.Join(dir, "x")}
+			if err := os.WriteFile(b.File, []byte("previous"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/file.bin", Name: "file.bin", Size: tt.size})
+
+			got, _ := os.ReadFile(b.File)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if string(got) != "previous" {
+					t.Errorf("binary = %q, a failed download must leave it untouched", got)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != content {
+					t.Errorf("binary = %q, want %q", got, content)
+				}
+				if fmt.Sprint(tt.srv.ranges) != fmt.Sprint(tt.wantRanges) {
+					t.Errorf("ranges = %q, want %q", tt.srv.ranges, tt.wantRanges)
+				}
+			}
+			if left := leftovers(t, dir, "x"); len(left) > 0 {
+				t.Errorf("temp files left behind: %v", left)
+			}
+		})
+	}
+}
+
+func TestDownloadAsset_ExtractFailureKeepsBinary(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not a gzip stream"))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	b := &Binary{Name: "x", File: filepath.Join(dir, "x")}
+	if err := os.WriteFile(b.File, []byte("previous"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/x.tar.gz", Name: "x.tar.gz"}); err == nil {
+		t.Fatal("expected error for a corrupt archive")
+	}
+	if got, _ := os.ReadFile(b.File); string(got) != "previous" {
+		t.Errorf("binary = %q, want it untouched", got)
+	}
+	if left := leftovers(t, dir, "x"); len(left) > 0 {
+		t.Errorf("temp files left behind: %v", left)
+	}
+}
+
+func TestRangeStart(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"bytes 100-199/200", 100, true},
+		{"bytes 0-0/*", 0, true},
+		{"bytes */200", 0, false},
+		{"items 1-2/3", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := rangeStart(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("rangeStart(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRangeValidator(t *testing.T) {
+	tests := []struct {
+		etag, modified, want string
+	}{
+		{`"abc"`, "Mon, 02 Jan 2006 15:04:05 GMT", `"abc"`},
+		{`W/"abc"`, "Mon, 02 Jan 2006 15:04:05 GMT", "Mon, 02 Jan 2006 15:04:05 GMT"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.etag != "" {
+			h.Set("ETag", tt.etag)
+		}
+		if tt.modified != "" {
+			h.Set("Last-Modified", tt.modified)
+		}
+		if got := rangeValidator(h); got != tt.want {
+			t.Errorf("rangeValidator(%q, %q) = %q, want %q", tt.etag, tt.modified, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package binary
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+// Install layouts a b.yaml binary can declare.
+const (
+	// LayoutFile installs the single executable from the download (default).
+	LayoutFile = "file"
+	// LayoutTree installs the whole extracted archive under .opt in the
+	// binary directory, and a shim running its entrypoint — for tools
+	// that need their lib/ or share/ next to them (JDK- or node-based
+	// CLIs, packer with bundled plugins).
+	LayoutTree = "tree"
+)
+
+// ValidateLayout checks the layout, entrypoint and extract settings of lb
+// against each other.
+func (lb *LocalBinary) ValidateLayout() error {
+	switch lb.Layout {
+	case "", LayoutFile:
+		if lb.Entrypoint != "" {
+			return fmt.Errorf("entrypoint: needs layout: %s", LayoutTree)
+		}
+	case LayoutTree:
+		if lb.Entrypoint == "" {
+			return fmt.Errorf("layout: %s needs an entrypoint", LayoutTree)
+		}
+		if !filepath.IsLocal(filepath.FromSlash(cleanMember(lb.Entrypoint))) {
+			return fmt.Errorf("entrypoint %q is not a path inside the archive", lb.Entrypoint)
+		}
+		if len(lb.Extract) > 0 {
+			return fmt.Errorf("extract: doesn't apply to layout: %s, the whole archive is installed", LayoutTree)
+		}
+	default:
+		return fmt.Errorf("unknown layout %q (want %s or %s)", lb.Layout, LayoutFile, LayoutTree)
+	}
+	return nil
+}
+
+// TreeDir is where a tree layout install of name at version lives, below
+// the binary directory binDir.
+func TreeDir(binDir, name, version string) string {
+	return filepath.Join(binDir, ".opt", name, strings.ReplaceAll(version, "/", "_"))
+}
+
+// TreeDir is where b's archive is extracted with layout: tree.
+func (b *Binary) TreeDir() string {
+	return TreeDir(filepath.Dir(b.BinaryPath()), b.Name, b.Version)
+}
+
+// TreeExists reports whether the tree b's shim runs is installed; true
+// for other layouts. Without a version, any installed version counts.
+func (b *Binary) TreeExists() bool {
+	if b.Layout != LayoutTree {
+		return true
+	}
+	entrypoint := filepath.FromSlash(cleanMember(b.Entrypoint))
+	if b.Version != "" {
+		_, err := os.Stat(filepath.Join(b.TreeDir(), entrypoint))
+		return err == nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(b.TreeDir()), "*", entrypoint))
+	return len(matches) > 0
+}
+
+// presetArchiveType is the archive type of a preset download, in the
+// terms of provider.DetectArchiveType.
+func (b *Binary) presetArchiveType() string {
+	switch {
+	case b.IsTarGz:
+		return "tar.gz"
+	case b.IsTarXz:
+		return "tar.xz"
+	case b.IsTarBz2:
+		return "tar.bz2"
+	case b.IsTarZst:
+		return "tar.zst"
+	case b.IsZip:
+		return "zip"
+	}
+	return b.Compression
+}
+
+// cleanMember normalizes an archive path like isArchiveMember does.
+func cleanMember(p string) string {
+	return path.Clean(strings.TrimPrefix(strings.TrimPrefix(p, "./"), "/"))
+}
+
+// extractTree extracts the whole archive into b.TreeDir and points a shim
+// at b.Entrypoint in it. archiveType is a tar type or "zip", as returned
+// by provider.DetectArchiveType. The tree is staged next to its final
+// place and swapped in once complete; the trees of other versions are
+// removed afterwards.
+func (b *Binary) extractTree(stream io.Reader, archiveType string) error {
+	if b.Version == "" {
+		return fmt.Errorf("%s: layout: %s needs a version", b.Name, LayoutTree)
+	}
+	if b.windows() {
+		return fmt.Errorf("%s: layout: %s installs a shell shim, which Windows can't run", b.Name, LayoutTree)
+	}
+	dest := b.TreeDir()
+	parent := filepath.Dir(dest)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return err
+	}
+	stage, err := os.MkdirTemp(parent, "."+filepath.Base(dest)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stage)
+
+	root, err := os.OpenRoot(stage)
+	if err != nil {
+		return err
+	}
+	switch archiveType {
+	case "tar.gz", "tar.xz", "tar.bz2", "tar.zst":
+		err = untar(root, stream, strings.TrimPrefix(archiveType, "tar."))
+	case "zip":
+		err = unzip(root, stream)
+	default:
+		err = fmt.Errorf("layout: %s needs a tar or zip archive", LayoutTree)
+	}
+	if err == nil {
+		err = checkEntrypoint(root, b.Entrypoint)
+	}
+	root.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", b.Name, err)
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if err := os.Rename(stage, dest); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(filepath.Dir(b.BinaryPath()), filepath.Join(dest, filepath.FromSlash(cleanMember(b.Entrypoint))))
+	if err != nil {
+		return err
+	}
+	if err := provider.WriteExecutable(b.BinaryPath(), strings.NewReader(shim(b, rel))); err != nil {
+		return err
+	}
+	pruneTrees(parent, filepath.Base(dest))
+	return nil
+}
+
+// shim is the script installed as b's binary, running the entrypoint at
+// rel, relative to the shim's own directory.
+func shim(b *Binary, rel string) string {
+	return fmt.Sprintf("#!/bin/sh\n# %s %s (layout: %s), installed by b\nexec \"$(dirname \"$0\")\"/%s \"$@\"\n",
+		b.Name, b.Version, LayoutTree, shellQuote(filepath.ToSlash(rel)))
+}
+
+// shellQuote quotes s for POSIX sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// pruneTrees removes the trees in dir other than keep, and what earlier
+// interrupted installs staged there.
+func pruneTrees(dir, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.Name() != keep {
+			_ = os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// checkEntrypoint makes sure the entrypoint is an executable file in root.
+func checkEntrypoint(root *os.Root, entrypoint string) error {
+	name := filepath.FromSlash(cleanMember(entrypoint))
+	info, err := root.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("entrypoint %s not found in archive", entrypoint)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("entrypoint %s is not a file", entrypoint)
+	}
+	if info.Mode()&0111 == 0 {
+		return root.Chmod(name, info.Mode()|0755)
+	}
+	return nil
+}
+
+// treePath validates an archive entry name and returns it as a path
+// relative to the tree root.
+func treePath(name string) (string, error) {
+	p := filepath.FromSlash(cleanMember(name))
+	if p == "." {
+		return "", nil
+	}
+	if !filepath.IsLocal(p) {
+		return "", fmt.Errorf("archive entry %q escapes the install directory", name)
+	}
+	return p, nil
+}
+
+// linkTarget validates the target of a symlink at name: it must stay in
+// the tree.
+func linkTarget(name, target string) error {
+	if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), filepath.FromSlash(target))) {
+		return fmt.Errorf("archive link %q points outside the install directory (%s)", name, target)
+	}
+	return nil
+}
+
+// untar extracts the tar in stream into root: directories, files with
+// their permission bits, symlinks and hard links.
+func untar(root *os.Root, stream io.Reader, compression string) error {
+	r, err := decompress(stream, compression)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name, err := treePath(header.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if dir := filepath.Dir(name); dir != "." {
+			if err := root.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = root.MkdirAll(name, 0755)
+		case tar.TypeReg:
+			err = writeTreeFile(root, name, tr, fs.FileMode(header.Mode))
+		case tar.TypeSymlink:
+			if err = linkTarget(name, header.Linkname); err == nil {
+				err = root.Symlink(header.Linkname, name)
+			}
+		case tar.TypeLink:
+			var target string
+			if target, err = treePath(header.Linkname); err == nil {
+				err = root.Link(target, name)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// unzip extracts the zip in stream into root. Zip archives made on Unix
+// carry permission bits and symlinks; others get 0644 files.
+func unzip(root *os.Root, stream io.Reader) error {
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zr.File {
+		name, err := treePath(f.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			continue
+		}
+		if dir := filepath.Dir(name); dir != "." {
+			if err := root.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+		}
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			err = root.MkdirAll(name, 0755)
+		case mode&fs.ModeSymlink != 0:
+			err = unzipLink(root, name, f)
+		default:
+			if mode.Perm() == 0 {
+				mode = 0644
+			}
+			var rc io.ReadCloser
+			if rc, err = f.Open(); err == nil {
+				err = writeTreeFile(root, name, rc, mode)
+				rc.Close()
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipLink(root *os.Root, name string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	target, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	if err := linkTarget(name, string(target)); err != nil {
+		return err
+	}
+	return root.Symlink(string(target), name)
+}
+
+// writeTreeFile writes r to name in root with the permission bits of mode,
+// always readable and writable by the owner.
+func writeTreeFile(root *os.Root, name string, r io.Reader, mode fs.FileMode) error {
+	f, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	// OpenFile's mode is subject to the umask; executables must stay so.
+	return root.Chmod(name, mode.Perm()|0600)
+}
@@ -0,0 +1,253 @@
+package binary
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/provider"
+)
+
+func TestValidateLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		lb      LocalBinary
+		wantErr string
+	}{
+		{name: "default", lb: LocalBinary{}},
+		{name: "file", lb: LocalBinary{Layout: LayoutFile}},
+		{name: "tree", lb: LocalBinary{Layout: LayoutTree, Entrypoint: "bin/packer"}},
+		{name: "tree without entrypoint", lb: LocalBinary{Layout: LayoutTree}, wantErr: "needs an entrypoint"},
+		{name: "entrypoint outside", lb: LocalBinary{Layout: LayoutTree, Entrypoint: "../packer"}, wantErr: "not a path inside the archive"},
+		{name: "entrypoint without tree", lb: LocalBinary{Entrypoint: "bin/packer"}, wantErr: "needs layout: tree"},
+		{name: "tree with extract", lb: LocalBinary{Layout: LayoutTree, Entrypoint: "packer", Extract: []Extract{{Member: "x"}}}, wantErr: "doesn't apply"},
+		{name: "unknown", lb: LocalBinary{Layout: "dir"}, wantErr: `unknown layout "dir"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.lb.ValidateLayout()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateLayout() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateLayout() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTreeDir(t *testing.T) {
+	if got, want := TreeDir("/p/.bin", "packer", "v1.11.0"), filepath.Join("/p/.bin", ".opt", "packer", "v1.11.0"); got != want {
+		t.Errorf("TreeDir() = %q, want %q", got, want)
+	}
+	if got := TreeDir("/p/.bin", "tool", "release/1.0"); filepath.Base(got) != "release_1.0" {
+		t.Errorf("TreeDir() = %q, want the version's slash replaced", got)
+	}
+}
+
+// makeUnixZip builds a zip whose entries carry Unix modes, symlinks
+// included.
+func makeUnixZip(t *testing.T, entries ...tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+		if e.link != "" {
+			hdr.SetMode(os.ModeSymlink | 0777)
+			content = []byte(e.link)
+		} else {
+			hdr.SetMode(os.FileMode(e.mode))
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatalf("zip CreateHeader %q: %v", e.name, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatalf("zip Write %q: %v", e.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadAsset_Tree(t *testing.T) {
+	script := []byte("#!/bin/sh\necho \"$(cat \"$(dirname \"$0\")/../share/greeting\") $1\"\n")
+	entries := []tarEntry{
+		{name: "tool-1.0/bin/tool", mode: 0755, content: script},
+		{name: "tool-1.0/share/greeting", mode: 0644, content: []byte("hello")},
+		{name: "tool-1.0/lib/libtool.so.1", mode: 0644, content: []byte("lib")},
+		{name: "tool-1.0/lib/libtool.so", link: "libtool.so.1"},
+	}
+	tests := []struct {
+		name  string
+		asset string
+		body  []byte
+	}{
+		{name: "tar", asset: "tool.tar.gz", body: makeTarGz(t, entries...).Bytes()},
+		{name: "zip", asset: "tool.zip", body: makeUnixZip(t, entries...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+			dir := t.TempDir()
+			b := &Binary{
+				Name:       "tool",
+				Version:    "v1.0",
+				File:       filepath.Join(dir, "tool"),
+				Layout:     LayoutTree,
+				Entrypoint: "tool-1.0/bin/tool",
+			}
+			if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/" + tt.asset, Name: tt.asset}); err != nil {
+				t.Fatal(err)
+			}
+			tree := filepath.Join(dir, ".opt", "tool", "v1.0", "tool-1.0")
+			if data, err := os.ReadFile(filepath.Join(tree, "share", "greeting")); err != nil || string(data) != "hello" {
+				t.Errorf("share/greeting = %q err=%v", data, err)
+			}
+			if target, err := os.Readlink(filepath.Join(tree, "lib", "libtool.so")); err != nil || target != "libtool.so.1" {
+				t.Errorf("lib/libtool.so -> %q err=%v", target, err)
+			}
+			if !b.Installed() {
+				t.Error("Installed() = false after the install")
+			}
+
+			// The shim runs the entrypoint, which finds its share/ dir.
+			out, err := exec.Command(b.File, "world").Output()
+			if err != nil {
+				t.Fatalf("running the shim: %v", err)
+			}
+			if got := strings.TrimSpace(string(out)); got != "hello world" {
+				t.Errorf("shim output = %q, want %q", got, "hello world")
+			}
+
+			// A new version replaces the tree of the old one.
+			b.Version = "v1.1"
+			if err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/" + tt.asset, Name: tt.asset}); err != nil {
+				t.Fatal(err)
+			}
+			versions, _ := os.ReadDir(filepath.Join(dir, ".opt", "tool"))
+			if len(versions) != 1 || versions[0].Name() != "v1.1" {
+				t.Errorf(".opt/tool = %v, want only v1.1", versions)
+			}
+			if _, err := exec.Command(b.File).Output(); err != nil {
+				t.Errorf("running the shim after the update: %v", err)
+			}
+
+			// Without its tree the binary counts as not installed.
+			if err := os.RemoveAll(filepath.Join(dir, ".opt")); err != nil {
+				t.Fatal(err)
+			}
+			if b.Installed() {
+				t.Error("Installed() = true without the tree")
+			}
+		})
+	}
+}
+
+func TestDownloadAsset_TreeRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		asset   string
+		body    []byte
+		wantErr string
+	}{
+		{
+			name:    "entry outside",
+			asset:   "tool.tar.gz",
+			body:    makeTarGz(t, tarEntry{name: "../evil", mode: 0755, content: []byte("x")}).Bytes(),
+			wantErr: "escapes the install directory",
+		},
+		{
+			name:    "absolute symlink",
+			asset:   "tool.tar.gz",
+			body:    makeTarGz(t, tarEntry{name: "bin/tool", mode: 0755, content: []byte("x")}, tarEntry{name: "etc", link: "/etc"}).Bytes(),
+			wantErr: "points outside the install directory",
+		},
+		{
+			name:    "relative symlink outside",
+			asset:   "tool.zip",
+			body:    makeUnixZip(t, tarEntry{name: "bin/tool", mode: 0755, content: []byte("x")}, tarEntry{name: "bin/up", link: "../../up"}),
+			wantErr: "points outside the install directory",
+		},
+		{
+			name:    "no entrypoint",
+			asset:   "tool.tar.gz",
+			body:    makeTarGz(t, tarEntry{name: "tool", mode: 0755, content: []byte("x")}).Bytes(),
+			wantErr: "entrypoint bin/tool not found in archive",
+		},
+		{
+			name:    "single file",
+			asset:   "tool-linux-amd64",
+			body:    []byte("x"),
+			wantErr: "needs a tar or zip archive",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write(tt.body)
+			}))
+			defer srv.Close()
+			dir := t.TempDir()
+			b := &Binary{Name: "tool", Version: "v1.0", File: filepath.Join(dir, "tool"), Layout: LayoutTree, Entrypoint: "bin/tool"}
+			err := b.downloadAsset(&provider.Asset{URL: srv.URL + "/" + tt.asset, Name: tt.asset})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("downloadAsset() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(b.File); err == nil {
+				t.Error("shim installed despite the failure")
+			}
+			if entries, _ := os.ReadDir(filepath.Join(dir, ".opt", "tool")); len(entries) != 0 {
+				t.Errorf(".opt/tool = %v, want the staged tree removed", entries)
+			}
+		})
+	}
+}
+
+func TestDownloadPreset_Tree(t *testing.T) {
+	tgz := makeTarGz(t, tarEntry{name: "bin/tool", mode: 0755, content: []byte("#!/bin/sh\necho preset\n")})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(tgz.Bytes())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	b := &Binary{
+		Name:       "tool",
+		File:       filepath.Join(dir, "tool"),
+		URL:        srv.URL + "/tool.tar.gz",
+		Version:    "v2",
+		IsTarGz:    true,
+		Layout:     LayoutTree,
+		Entrypoint: "./bin/tool",
+	}
+	if err := b.downloadPreset(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(b.File).Output()
+	if err != nil || strings.TrimSpace(string(out)) != "preset" {
+		t.Errorf("shim output = %q err=%v", out, err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("shellQuote() = %s", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 	pwrap "github.com/fentas/goodies/progress"
 	pretty "github.com/jedib0t/go-pretty/v6/progress"
 )
@@ -35,7 +36,10 @@ type Binary struct {
 	File          string          `json:"-"`
 	IsTarGz       bool            `json:"-"`
 	IsTarXz       bool            `json:"-"`
+	IsTarBz2      bool            `json:"-"`
+	IsTarZst      bool            `json:"-"`
 	IsZip         bool            `json:"-"`
+	Compression   string          `json:"-"` // single compressed file: "gz", "xz", "bz2" or "zst"
 	IsDynamic     bool            `json:"-"`
 	TarFile       string          `json:"-"`
 	TarFileF      Callback        `json:"-"`
@@ -52,6 +56,96 @@ type Binary struct {
 	SelectAsset   SelectAssetFunc `json:"-"` // interactive asset selector for ambiguous matches
 	ResolvedAsset *provider.Asset `json:"-"` // pre-resolved asset (skips matching during download)
 	OnPost        string          `json:"-"` // shell command to run after successful install/update
+
+	// ResolvedChecksum is the upstream checksum file (per-asset ".sha256"
+	// or an aggregate "checksums.txt") for ResolvedAsset. Nil when the
+	// release ships no checksums.
+	ResolvedChecksum *provider.Asset `json:"-"`
+	// AssetSHA256 is the sha256 of the downloaded release asset, set only
+	// after it was verified against the upstream checksum file or signature.
+	AssetSHA256 string `json:"-"`
+
+	// Verify is the signature policy from the b.yaml `verify:` block; nil
+	// skips signature verification.
+	Verify *signature.Config `json:"-"`
+	// SignedAsset is the release file the publisher signed — the asset
+	// itself or the checksum manifest listing it — and ResolvedSignatures
+	// its signature companions, keyed by suffix (".sig", ".bundle", ...).
+	SignedAsset        *provider.Asset            `json:"-"`
+	ResolvedSignatures map[string]*provider.Asset `json:"-"`
+	// Signatures are the verifications that passed for the last download.
+	Signatures []signature.Result `json:"-"`
+
+	// HTTP configures a templated http(s):// ref: OS/arch aliases, the
+	// latest-version endpoint, and the archive member.
+	HTTP *provider.HTTPConfig `json:"-"`
+	// ArchiveMember is the exact path of the binary inside the downloaded
+	// archive; when set it replaces heuristic detection.
+	ArchiveMember string `json:"-"`
+
+	// Build configures how a go:// binary is compiled (the b.yaml
+	// `build:` block); nil is a plain `go install`.
+	Build *provider.GoBuild `json:"-"`
+	// Rebuild marks a binary b.lock recorded as built another way than
+	// Build asks for; it counts as not installed.
+	Rebuild bool `json:"-"`
+
+	// Runtime is the container CLI docker:// images are installed with;
+	// empty picks one. Paths are the files and directories copied out of
+	// a docker:// or oci:// image; see provider.SplitPaths.
+	Runtime string   `json:"-"`
+	Paths   []string `json:"-"`
+
+	// Package is the registry package the binary is defined by (e.g.
+	// "aqua:cli/cli"), its b.yaml key in place of ProviderRef.
+	Package string `json:"-"`
+	// AssetF names the release asset for the version and platform, in
+	// place of auto-detection; ChecksumF names the checksum file listing
+	// it. Registry packages know both.
+	AssetF    Callback `json:"-"`
+	ChecksumF Callback `json:"-"`
+
+	// Constraint is a version range ("~1.7", ">=1.28 <1.31") resolved to
+	// the highest matching release into Version; see SetVersion.
+	Constraint string `json:"-"`
+	// Query is the branch or commit a go:// binary asked for, resolved to
+	// a module version into Version; see ResolveQuery.
+	Query string `json:"-"`
+	// Channel picks what "latest" means for release providers; empty is
+	// the stable channel.
+	Channel provider.Channel `json:"-"`
+
+	// Asset is the release asset (or preset URL) the last download came
+	// from; b.lock records it so --offline installs can find it again.
+	Asset *provider.Asset `json:"-"`
+	// LockedAssetSHA256 is the asset digest b.lock recorded. Offline, where
+	// no checksum file can be fetched, the cached asset is verified against
+	// it instead.
+	LockedAssetSHA256 string `json:"-"`
+	// LockedSHA256 is the digest of the installed binary b.lock recorded.
+	// Offline, a blob with that digest (see `b bundle import`) is
+	// installed as is, whatever the provider.
+	LockedSHA256 string `json:"-"`
+	// Locked makes b.lock authoritative online too (`b install
+	// --locked`): the asset is verified against LockedAssetSHA256.
+	Locked bool `json:"-"`
+
+	// Extract lists further files installed from the release archive next
+	// to the binary; see Companions.
+	Extract []Extract `json:"-"`
+	// LockedExtract maps the companions' file names to the digests b.lock
+	// recorded for them, installed from the download cache like
+	// LockedSHA256.
+	LockedExtract map[string]string `json:"-"`
+
+	// Platform is the OS/arch the binary is installed for; the zero value
+	// is the host. Another platform's binary is downloaded, never run.
+	Platform provider.Platform `json:"-"`
+
+	// Layout is LayoutTree to install the whole archive with a shim
+	// running Entrypoint, a path inside the archive; see extractTree.
+	Layout     string `json:"-"`
+	Entrypoint string `json:"-"`
 }
 
 type LocalBinary struct {
@@ -71,6 +165,33 @@ type LocalBinary struct {
 	// changed — skipped on no-op installs, digest-match skips, and
 	// --dry-run. Non-zero exit is surfaced as a warning, not a fatal error.
 	OnPost string `json:"onPost,omitempty" yaml:"onPost,omitempty"`
+	// Verify requires a valid publisher signature (cosign, minisign, or
+	// GPG) before the binary is written. See signature.Config.
+	Verify *signature.Config `json:"verify,omitempty" yaml:"verify,omitempty"`
+	// HTTP configures templated http(s):// refs (aliases, latest-version
+	// endpoint, archive member). See provider.HTTPConfig.
+	HTTP *provider.HTTPConfig `json:"http,omitempty" yaml:"http,omitempty"`
+	// Channel is the release channel followed when no version is pinned:
+	// stable (default), prerelease or nightly. See provider.Channel.
+	Channel string `json:"channel,omitempty" yaml:"channel,omitempty"`
+	// Extract names further files to install from the release archive,
+	// e.g. kubectl-convert next to kubectl. See Extract.
+	Extract []Extract `json:"extract,omitempty" yaml:"extract,omitempty"`
+	// Layout is "file" (default) or "tree": install the whole archive
+	// under .bin/.opt/<name>/<version> and a shim in .bin running
+	// Entrypoint, the executable's path inside the archive.
+	Layout     string `json:"layout,omitempty" yaml:"layout,omitempty"`
+	Entrypoint string `json:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+	// Build configures how go:// refs are compiled (tags, ldflags, env,
+	// toolchain). See provider.GoBuild.
+	Build *provider.GoBuild `json:"build,omitempty" yaml:"build,omitempty"`
+	// Runtime is the container CLI for docker:// refs: docker, podman or
+	// nerdctl. Empty uses B_CONTAINER_RUNTIME or the first one on PATH.
+	Runtime string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	// Paths are files or directories to copy out of a docker:// or oci://
+	// image: the one named like the binary is the binary, the others are
+	// installed next to it, e.g. a plugin directory.
+	Paths []string `json:"paths,omitempty" yaml:"paths,omitempty"`
 	// IsProviderRef is true when Name is a provider ref (e.g. github.com/derailed/k9s)
 	IsProviderRef bool `json:"-" yaml:"-"`
 }
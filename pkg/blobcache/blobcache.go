@@ -0,0 +1,345 @@
+// Package blobcache is a content-addressable store for downloaded release
+// assets and image layers, shared by every project on the machine. Blobs
+// are stored by sha256 under sha256/<hex>; an index maps each download URL
+// to the digest it last produced:
+//
+//	~/.cache/b/blobs/
+//	  sha256/<hex>        read-only blob
+//	  urls/<sha256(url)>  "<hex> <url>"
+//
+// A blob's modification time records when it was last used, which is what
+// Prune goes by. The cache is off until SetRoot is called; every function
+// is then a no-op or a miss, so library users and tests never touch it.
+package blobcache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu   sync.RWMutex
+	root string
+)
+
+// DefaultRoot returns ~/.cache/b/blobs.
+func DefaultRoot() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "b", "blobs")
+}
+
+// SetRoot enables the cache in dir; "" disables it.
+func SetRoot(dir string) {
+	mu.Lock()
+	root = dir
+	mu.Unlock()
+}
+
+// Root returns the cache directory, or "" when the cache is off.
+func Root() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return root
+}
+
+var hexDigest = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func blobPath(dir, digest string) string {
+	return filepath.Join(dir, "sha256", digest)
+}
+
+func indexPath(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, "urls", hex.EncodeToString(sum[:]))
+}
+
+// Lookup returns the cached blob for url. With digest set (a sha256 in
+// hex, e.g. from a checksum file) any blob of that digest qualifies, no
+// matter which URL it came from; otherwise the URL must have been cached
+// before. A hit counts as a use for Prune.
+func Lookup(url, digest string) (string, bool) {
+	dir := Root()
+	if dir == "" {
+		return "", false
+	}
+	if digest == "" {
+		digest = indexed(dir, url)
+	}
+	digest = strings.ToLower(digest)
+	if !hexDigest.MatchString(digest) {
+		return "", false
+	}
+	p := blobPath(dir, digest)
+	if _, err := os.Stat(p); err != nil {
+		return "", false
+	}
+	now := time.Now()
+	_ = os.Chtimes(p, now, now)
+	return p, true
+}
+
+// indexed returns the digest url last produced, or "".
+func indexed(dir, url string) string {
+	data, err := os.ReadFile(indexPath(dir, url))
+	if err != nil {
+		return ""
+	}
+	digest, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	return digest
+}
+
+// Put stores the complete file at src as the content of url and returns
+// the blob's path. src is hard-linked into the cache when it is on the
+// same filesystem and copied otherwise; it is left in place either way.
+func Put(url, src string) (string, error) {
+	dir := Root()
+	if dir == "" {
+		return "", errors.New("blob cache is disabled")
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	dest := blobPath(dir, digest)
+	if _, err := os.Stat(dest); err != nil {
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		if err := linkOrCopy(src, dest); err != nil {
+			return "", err
+		}
+	}
+	return dest, record(dir, url, digest)
+}
+
+// PutReader stores r as the content of url and returns the blob's path.
+// want, when set, is the digest r must have; a mismatch stores nothing.
+func PutReader(url string, r io.Reader, want string) (string, error) {
+	dir := Root()
+	if dir == "" {
+		return "", errors.New("blob cache is disabled")
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sha256"), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Join(dir, "sha256"), ".put-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	digest := hex.EncodeToString(h.Sum(nil))
+	if want != "" && !strings.EqualFold(want, digest) {
+		return "", fmt.Errorf("content of %s has sha256 %s, want %s", url, digest, want)
+	}
+	dest := blobPath(dir, digest)
+	if _, err := os.Stat(dest); err != nil {
+		if err := os.Chmod(tmp.Name(), 0555); err != nil {
+			return "", err
+		}
+		if err := os.Rename(tmp.Name(), dest); err != nil {
+			return "", err
+		}
+	}
+	return dest, record(dir, url, digest)
+}
+
+// record points url's index entry at digest.
+func record(dir, url, digest string) error {
+	p := indexPath(dir, url)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".url-*")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(tmp, "%s %s\n", digest, url)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// linkOrCopy places a read-only copy of src at dest, atomically.
+func linkOrCopy(src, dest string) error {
+	tmp := fmt.Sprintf("%s.%d.tmp", dest, os.Getpid())
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	// Blobs are shared by hard links; keep tools from writing to them.
+	if err := os.Chmod(tmp, 0555); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Materialize installs blob at dest: a hard link when both are on the same
+// filesystem, a copy otherwise. dest is replaced atomically.
+func Materialize(blob, dest string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	_ = os.Remove(tmp.Name())
+	if err := os.Link(blob, tmp.Name()); err != nil {
+		if err := copyFile(blob, tmp.Name()); err != nil {
+			_ = os.Remove(tmp.Name())
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Entry describes one cached blob.
+type Entry struct {
+	Digest   string    `json:"digest"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"lastUsed"`
+	URLs     []string  `json:"urls,omitempty"`
+}
+
+// List returns the blobs in dir, most recently used first.
+func List(dir string) ([]Entry, error) {
+	blobs, err := os.ReadDir(filepath.Join(dir, "sha256"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	urls := urlsByDigest(dir)
+	var entries []Entry
+	for _, b := range blobs {
+		if !hexDigest.MatchString(b.Name()) {
+			continue
+		}
+		info, err := b.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{
+			Digest:   b.Name(),
+			Size:     info.Size(),
+			LastUsed: info.ModTime(),
+			URLs:     urls[b.Name()],
+		})
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return b.LastUsed.Compare(a.LastUsed) })
+	return entries, nil
+}
+
+// urlsByDigest reads the URL index.
+func urlsByDigest(dir string) map[string][]string {
+	out := make(map[string][]string)
+	files, _ := os.ReadDir(filepath.Join(dir, "urls"))
+	for _, f := range files {
+		fh, err := os.Open(filepath.Join(dir, "urls", f.Name()))
+		if err != nil {
+			continue
+		}
+		s := bufio.NewScanner(fh)
+		if s.Scan() {
+			if digest, url, ok := strings.Cut(s.Text(), " "); ok {
+				out[digest] = append(out[digest], url)
+			}
+		}
+		fh.Close()
+	}
+	for _, urls := range out {
+		slices.Sort(urls)
+	}
+	return out
+}
+
+// Prune removes the blobs in dir not used since before and the URL index
+// entries left pointing nowhere. It returns the removed blobs.
+func Prune(dir string, before time.Time) ([]Entry, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []Entry
+	for _, e := range entries {
+		if !e.LastUsed.Before(before) {
+			continue
+		}
+		if err := os.Remove(blobPath(dir, e.Digest)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed = append(removed, e)
+	}
+	files, _ := os.ReadDir(filepath.Join(dir, "urls"))
+	for _, f := range files {
+		p := filepath.Join(dir, "urls", f.Name())
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		digest, _, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+		if _, err := os.Stat(blobPath(dir, digest)); errors.Is(err, os.ErrNotExist) {
+			_ = os.Remove(p)
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,210 @@
+package blobcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useRoot enables the cache in a temp dir for the test.
+func useRoot(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	SetRoot(dir)
+	t.Cleanup(func() { SetRoot("") })
+	return dir
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "download")
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func sum(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func TestDisabled(t *testing.T) {
+	SetRoot("")
+	if _, ok := Lookup("https://example.com/a", sum("a")); ok {
+		t.Error("Lookup hit with the cache off")
+	}
+	if _, err := Put("https://example.com/a", writeFile(t, "a")); err == nil {
+		t.Error("Put succeeded with the cache off")
+	}
+	if _, err := PutReader("https://example.com/a", strings.NewReader("a"), ""); err == nil {
+		t.Error("PutReader succeeded with the cache off")
+	}
+}
+
+func TestPutLookup(t *testing.T) {
+	dir := useRoot(t)
+	const url = "https://example.com/v1/tool.tar.gz"
+
+	blob, err := Put(url, writeFile(t, "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "sha256", sum("tool")); blob != want {
+		t.Errorf("Put() = %s, want %s", blob, want)
+	}
+	info, err := os.Stat(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0222 != 0 {
+		t.Errorf("blob mode = %v, want read-only", info.Mode())
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		digest string
+		want   bool
+	}{
+		{"by url", url, "", true},
+		{"by digest", "https://mirror.example.com/tool.tar.gz", sum("tool"), true},
+		{"by digest, upper case", "", strings.ToUpper(sum("tool")), true},
+		{"unknown url", "https://example.com/v2/tool.tar.gz", "", false},
+		{"unknown digest", url, sum("other"), false},
+		{"malformed digest", url, "../../etc/passwd", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Lookup(tt.url, tt.digest)
+			if ok != tt.want {
+				t.Fatalf("Lookup(%q, %q) ok = %v, want %v", tt.url, tt.digest, ok, tt.want)
+			}
+			if ok && got != blob {
+				t.Errorf("Lookup() = %s, want %s", got, blob)
+			}
+		})
+	}
+}
+
+func TestPut_SameContentTwice(t *testing.T) {
+	dir := useRoot(t)
+	if _, err := Put("https://a.example.com/tool", writeFile(t, "tool")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Put("https://b.example.com/tool", writeFile(t, "tool")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("List() = %d entries, want 1", len(entries))
+	}
+	if got := entries[0].URLs; len(got) != 2 || got[0] != "https://a.example.com/tool" || got[1] != "https://b.example.com/tool" {
+		t.Errorf("URLs = %v, want both sources", got)
+	}
+}
+
+func TestPutReader(t *testing.T) {
+	dir := useRoot(t)
+	if _, err := PutReader("oci://ghcr.io/org/img@sha256:x", strings.NewReader("layer"), sum("other")); err == nil {
+		t.Fatal("PutReader accepted content that doesn't match the digest")
+	}
+	if entries, _ := List(dir); len(entries) != 0 {
+		t.Errorf("mismatched content was stored: %v", entries)
+	}
+
+	blob, err := PutReader("oci://ghcr.io/org/img@sha256:x", strings.NewReader("layer"), sum("layer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := Lookup("", sum("layer")); !ok || got != blob {
+		t.Errorf("Lookup() = %s, %v, want %s", got, ok, blob)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "sha256", ".put-*")); len(left) > 0 {
+		t.Errorf("temp files left behind: %v", left)
+	}
+}
+
+func TestMaterialize(t *testing.T) {
+	useRoot(t)
+	blob, err := Put("https://example.com/v1/tool", writeFile(t, "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(dest, []byte("previous"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Materialize(blob, dest); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "tool" {
+		t.Errorf("dest = %q, want %q", got, "tool")
+	}
+	info, _ := os.Stat(dest)
+	if info.Mode().Perm()&0111 == 0 {
+		t.Errorf("dest mode = %v, want executable", info.Mode())
+	}
+	entries, _ := os.ReadDir(filepath.Dir(dest))
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
+
+func TestListPrune(t *testing.T) {
+	dir := useRoot(t)
+	old, err := Put("https://example.com/v1/old", writeFile(t, "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Put("https://example.com/v1/new", writeFile(t, "new")); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(old, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Digest != sum("new") || entries[1].Size != 3 {
+		t.Fatalf("List() = %+v, want new then old", entries)
+	}
+
+	removed, err := Prune(dir, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0].Digest != sum("old") {
+		t.Errorf("Prune() removed %+v, want the old blob", removed)
+	}
+	if _, ok := Lookup("https://example.com/v1/old", ""); ok {
+		t.Error("pruned blob still found")
+	}
+	if _, err := os.Stat(indexPath(dir, "https://example.com/v1/old")); !os.IsNotExist(err) {
+		t.Errorf("index entry of the pruned blob kept: %v", err)
+	}
+	if _, ok := Lookup("https://example.com/v1/new", ""); !ok {
+		t.Error("recent blob pruned")
+	}
+}
+
+func TestList_Missing(t *testing.T) {
+	entries, err := List(filepath.Join(t.TempDir(), "nope"))
+	if err != nil || entries != nil {
+		t.Errorf("List() = %v, %v, want nothing", entries, err)
+	}
+}
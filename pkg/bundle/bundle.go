@@ -0,0 +1,362 @@
+// Package bundle packs everything a b.lock pins into one tar archive, for
+// machines without network access, and seeds the local caches from it.
+// Installed binaries go into the download cache (pkg/blobcache) under
+// their sha256, the locked commits of env repositories into the git cache
+// (pkg/gitcache). With the caches seeded, `b install --offline`
+// reproduces the lock.
+//
+// The archive starts with manifest.json, followed by the binaries
+// (binaries/<name>) and one shallow repository per env repo
+// (repos/<n>/objects/..., repos/<n>/shallow).
+package bundle
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/gitcache"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// host is the platform the bundled binaries are installed and locked for.
+var host = provider.HostPlatform().String()
+
+// ManifestName is the first member of every bundle.
+const ManifestName = "manifest.json"
+
+// FormatVersion is the manifest version this package writes and reads.
+const FormatVersion = 1
+
+// Manifest describes a bundle's content.
+type Manifest struct {
+	Version  int       `json:"version"`
+	Created  time.Time `json:"created"`
+	Tool     string    `json:"b,omitempty"` // b version that wrote it
+	Binaries []Binary  `json:"binaries,omitempty"`
+	Repos    []Repo    `json:"repos,omitempty"`
+}
+
+// Binary is an installed binary in the bundle.
+type Binary struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Source  string `json:"source,omitempty"`
+	SHA256  string `json:"sha256"`
+	Path    string `json:"path"` // archive member
+}
+
+// Repo holds the locked commits of one env repository.
+type Repo struct {
+	Ref     string   `json:"ref"`
+	URL     string   `json:"url"` // origin of the git cache clone
+	Commits []string `json:"commits"`
+	Path    string   `json:"path"` // archive directory
+}
+
+// Source tells Export where to find what b.lock pins.
+type Source struct {
+	BinDir    string // installed binaries
+	GitRoot   string // git cache root
+	ConfigDir string // resolves relative env refs, like b.yaml's directory
+	Tool      string // b version, recorded in the manifest
+	// Paths are the installed files of the binaries not at BinDir/<name>
+	// (alias:, file:, .exe), by lock entry name.
+	Paths map[string]string
+}
+
+// path is where the binary locked as name is installed.
+func (s Source) path(name string) string {
+	if p, ok := s.Paths[name]; ok {
+		return p
+	}
+	return filepath.Join(s.BinDir, name)
+}
+
+// Export writes a bundle of everything lk pins to w. Every binary must be
+// installed (see Source.Paths) with the sha256 b.lock recorded; env commits
+// missing from the git cache are fetched first. Envs synced from local
+// repositories are read in place and not bundled, nor are binaries
+// installed with layout: tree, whose shim is useless without the tree.
+func Export(w io.Writer, lk *lock.Lock, src Source) (*Manifest, error) {
+	m := &Manifest{Version: FormatVersion, Created: time.Now().UTC(), Tool: src.Tool}
+
+	var problems []string
+	for _, e := range lk.Binaries {
+		e := e.For(host)
+		if e.TreeSHA256 != "" {
+			continue
+		}
+		sum, err := lock.SHA256File(src.path(e.Name))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			problems = append(problems, e.Name+": not installed")
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", e.Name, err))
+		case sum != e.SHA256:
+			problems = append(problems, e.Name+": installed binary differs from b.lock")
+		default:
+			m.Binaries = append(m.Binaries, Binary{
+				Name:    e.Name,
+				Version: e.Version,
+				Source:  e.Source,
+				SHA256:  e.SHA256,
+				Path:    "binaries/" + e.Name,
+			})
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("binaries don't match b.lock (run `b install` first):\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	// One repository per ref, however many labels share it.
+	for _, e := range lk.Envs {
+		if e.Commit == "" || gitcache.ResolveGitURL(e.Ref, src.ConfigDir).IsLocal {
+			continue
+		}
+		i := slices.IndexFunc(m.Repos, func(r Repo) bool { return r.Ref == e.Ref })
+		if i < 0 {
+			m.Repos = append(m.Repos, Repo{Ref: e.Ref, Path: fmt.Sprintf("repos/%d", len(m.Repos))})
+			i = len(m.Repos) - 1
+		}
+		if !slices.Contains(m.Repos[i].Commits, e.Commit) {
+			m.Repos[i].Commits = append(m.Repos[i].Commits, e.Commit)
+		}
+	}
+
+	stage, err := os.MkdirTemp("", "b-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stage)
+	for i := range m.Repos {
+		r := &m.Repos[i]
+		resolved := gitcache.ResolveGitURL(r.Ref, src.ConfigDir)
+		r.URL = resolved.URL
+		if err := gitcache.EnsureCloneAuth(src.GitRoot, r.Ref, resolved.URL, resolved.AuthHeader); err != nil {
+			return nil, fmt.Errorf("cloning %s: %w", r.Ref, err)
+		}
+		for _, c := range r.Commits {
+			if gitcache.HasCommit(src.GitRoot, r.Ref, c) {
+				continue
+			}
+			if err := gitcache.FetchAuth(src.GitRoot, r.Ref, c, resolved.AuthHeader); err != nil {
+				return nil, fmt.Errorf("fetching %s@%s: %w", r.Ref, c, err)
+			}
+		}
+		if err := gitcache.ExportCommits(src.GitRoot, r.Ref, filepath.Join(stage, r.Path), r.Commits); err != nil {
+			return nil, fmt.Errorf("exporting %s: %w", r.Ref, err)
+		}
+	}
+
+	tw := tar.NewWriter(w)
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: ManifestName, Mode: 0644, Size: int64(len(data)), ModTime: m.Created}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	for _, b := range m.Binaries {
+		if err := addFile(tw, b.Path, src.path(b.Name), 0755); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range m.Repos {
+		if err := addRepo(tw, r.Path, filepath.Join(stage, r.Path)); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Import reads a bundle from r and seeds the caches with it: binaries go
+// into the download cache, env commits into the clones under gitRoot.
+// The manifest is checked against lk before anything is written, and each
+// binary's content against its sha256 while it is stored.
+func Import(r io.Reader, lk *lock.Lock, gitRoot string) (*Manifest, error) {
+	if blobcache.Root() == "" {
+		return nil, errors.New("the download cache is disabled")
+	}
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("reading bundle: %w", err)
+	}
+	if hdr.Name != ManifestName {
+		return nil, fmt.Errorf("not a b bundle: starts with %s, not %s", hdr.Name, ManifestName)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ManifestName, err)
+	}
+	if m.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d (this b reads %d)", m.Version, FormatVersion)
+	}
+	if err := m.check(lk); err != nil {
+		return nil, err
+	}
+
+	stage, err := os.MkdirTemp("", "b-bundle-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stage)
+
+	imported := make(map[string]bool)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading bundle: %w", err)
+		}
+		if i := slices.IndexFunc(m.Binaries, func(b Binary) bool { return b.Path == hdr.Name }); i >= 0 {
+			b := m.Binaries[i]
+			if _, err := blobcache.PutReader(cacheKey(b), tr, b.SHA256); err != nil {
+				return nil, fmt.Errorf("%s: %w (from b.lock)", b.Name, err)
+			}
+			imported[b.Path] = true
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		i := slices.IndexFunc(m.Repos, func(r Repo) bool { return strings.HasPrefix(hdr.Name, r.Path+"/") })
+		if i < 0 {
+			return nil, fmt.Errorf("unexpected %s in bundle", hdr.Name)
+		}
+		rel := strings.TrimPrefix(hdr.Name, m.Repos[i].Path+"/")
+		if !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("unsafe path %s in bundle", hdr.Name)
+		}
+		if err := writeFile(filepath.Join(stage, m.Repos[i].Path, rel), tr); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range m.Binaries {
+		if !imported[b.Path] {
+			return nil, fmt.Errorf("bundle is missing %s", b.Path)
+		}
+	}
+	for _, r := range m.Repos {
+		if err := gitcache.ImportCommits(gitRoot, r.Ref, r.URL, filepath.Join(stage, r.Path), r.Commits); err != nil {
+			return nil, fmt.Errorf("importing %s: %w", r.Ref, err)
+		}
+	}
+	return &m, nil
+}
+
+// check reports bundle content that b.lock doesn't pin.
+func (m *Manifest) check(lk *lock.Lock) error {
+	var problems []string
+	for _, b := range m.Binaries {
+		found := lk.FindBinary(b.Name)
+		var e lock.BinEntry
+		if found != nil {
+			e = found.For(host)
+		}
+		switch {
+		case found == nil:
+			problems = append(problems, b.Name+": not in b.lock")
+		case e.SHA256 != b.SHA256 && e.Version != b.Version:
+			problems = append(problems, fmt.Sprintf("%s: bundle has %s, b.lock pins %s", b.Name, b.Version, e.Version))
+		case e.SHA256 != b.SHA256:
+			problems = append(problems, b.Name+": sha256 differs from b.lock")
+		case b.Path != "binaries/"+b.Name:
+			problems = append(problems, fmt.Sprintf("%s: unexpected path %s", b.Name, b.Path))
+		}
+	}
+	for _, r := range m.Repos {
+		if !strings.HasPrefix(r.Path, "repos/") || !filepath.IsLocal(r.Path) {
+			problems = append(problems, fmt.Sprintf("%s: unexpected path %s", r.Ref, r.Path))
+		}
+		for _, c := range r.Commits {
+			if !slices.ContainsFunc(lk.Envs, func(e lock.EnvEntry) bool { return e.Ref == r.Ref && e.Commit == c }) {
+				problems = append(problems, fmt.Sprintf("%s@%s: not in b.lock", r.Ref, c))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("bundle doesn't match b.lock:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// cacheKey is the name an imported binary is listed under in the
+// download cache; offline installs find it by its sha256.
+func cacheKey(b Binary) string {
+	return "bundle://" + b.Name + "@" + b.Version
+}
+
+// addFile adds the file at src to tw as name.
+func addFile(tw *tar.Writer, name, src string, mode int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: mode, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// addRepo adds the objects and shallow list of the repository at dir to
+// tw under name; everything else git init recreates on import.
+func addRepo(tw *tar.Writer, name, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, "shallow")); err == nil {
+		if err := addFile(tw, path.Join(name, "shallow"), filepath.Join(dir, "shallow"), 0644); err != nil {
+			return err
+		}
+	}
+	return filepath.WalkDir(filepath.Join(dir, "objects"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		return addFile(tw, path.Join(name, filepath.ToSlash(rel)), p, 0444)
+	})
+}
+
+// writeFile writes r to p, creating its directory.
+func writeFile(p string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
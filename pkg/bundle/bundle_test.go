@@ -0,0 +1,251 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/gitcache"
+	"github.com/fentas/b/pkg/lock"
+)
+
+const envRef = "github.com/org/infra"
+
+func git(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// fixture is a project with one installed binary and one env repo, cached
+// as if b install had run.
+type fixture struct {
+	lk      *lock.Lock
+	src     Source
+	commits []string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	work := filepath.Join(t.TempDir(), "work")
+	git(t, "init", "-q", work)
+	git(t, "-C", work, "config", "user.email", "t@t.com")
+	git(t, "-C", work, "config", "user.name", "T")
+	var commits []string
+	for _, v := range []string{"one", "two"} {
+		if err := os.WriteFile(filepath.Join(work, "a.yaml"), []byte(v), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git(t, "-C", work, "add", "-A")
+		git(t, "-C", work, "commit", "-q", "--no-gpg-sign", "-m", v)
+		commits = append(commits, git(t, "-C", work, "rev-parse", "HEAD"))
+	}
+	gitRoot := t.TempDir()
+	git(t, "clone", "-q", "--bare", work, gitcache.CacheDir(gitRoot, envRef))
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "tool"), []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &fixture{
+		lk: &lock.Lock{
+			Binaries: []lock.BinEntry{{Name: "tool", Version: "v1.0.0", Source: "github.com/org/tool", SHA256: sum("binary")}},
+			Envs: []lock.EnvEntry{
+				{Ref: envRef, Label: "base", Commit: commits[0]},
+				{Ref: envRef, Label: "prod", Commit: commits[1]},
+				{Ref: envRef, Label: "dev", Commit: commits[1]},
+			},
+		},
+		src:     Source{BinDir: binDir, GitRoot: gitRoot, ConfigDir: t.TempDir(), Tool: "v1.2.3"},
+		commits: commits,
+	}
+}
+
+func sum(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func TestExportImport(t *testing.T) {
+	f := newFixture(t)
+	var buf bytes.Buffer
+	m, err := Export(&buf, f.lk, f.src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Binaries) != 1 || len(m.Repos) != 1 || len(m.Repos[0].Commits) != 2 {
+		t.Fatalf("manifest = %+v, want one binary and one repo with two commits", m)
+	}
+	if m.Repos[0].URL != "https://github.com/org/infra.git" {
+		t.Errorf("URL = %q", m.Repos[0].URL)
+	}
+
+	blobcache.SetRoot(t.TempDir())
+	t.Cleanup(func() { blobcache.SetRoot("") })
+	gitRoot := t.TempDir()
+	for range 2 { // the second import finds the clone in place
+		if _, err := Import(bytes.NewReader(buf.Bytes()), f.lk, gitRoot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := blobcache.Lookup("", sum("binary")); !ok {
+		t.Error("binary not in the download cache")
+	}
+	for _, c := range f.commits {
+		if !gitcache.HasCommit(gitRoot, envRef, c) {
+			t.Errorf("commit %s not in the git cache", c)
+		}
+	}
+	dir := gitcache.CacheDir(gitRoot, envRef)
+	if got := git(t, "-C", dir, "config", "remote.origin.url"); got != m.Repos[0].URL {
+		t.Errorf("origin = %q, want %q", got, m.Repos[0].URL)
+	}
+	if got := git(t, "-C", dir, "show", f.commits[0]+":a.yaml"); got != "one" {
+		t.Errorf("a.yaml = %q, want %q", got, "one")
+	}
+	if left, _ := filepath.Glob(filepath.Join(gitRoot, ".import-*")); len(left) > 0 {
+		t.Errorf("left behind: %v", left)
+	}
+}
+
+func TestExport_Mismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *fixture)
+		want  string
+	}{
+		{"not installed", func(f *fixture) { os.Remove(filepath.Join(f.src.BinDir, "tool")) }, "tool: not installed"},
+		{"modified", func(f *fixture) { os.WriteFile(filepath.Join(f.src.BinDir, "tool"), []byte("other"), 0755) }, "tool: installed binary differs"},
+		{"commit gone", func(f *fixture) { f.lk.Envs[0].Commit = strings.Repeat("0", 40) }, "fetching " + envRef},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			tt.setup(f)
+			// The clone's origin is unreachable, so fetches fail.
+			git(t, "-C", gitcache.CacheDir(f.src.GitRoot, envRef), "config", "remote.origin.url", filepath.Join(t.TempDir(), "gone"))
+			_, err := Export(&bytes.Buffer{}, f.lk, f.src)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Export() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExport_Paths(t *testing.T) {
+	f := newFixture(t)
+	// Installed with alias: tl, under another name than it is locked as.
+	if err := os.Rename(filepath.Join(f.src.BinDir, "tool"), filepath.Join(f.src.BinDir, "tl")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(&bytes.Buffer{}, f.lk, f.src); err == nil || !strings.Contains(err.Error(), "tool: not installed") {
+		t.Fatalf("Export() without Paths: %v", err)
+	}
+	f.src.Paths = map[string]string{"tool": filepath.Join(f.src.BinDir, "tl")}
+	var buf bytes.Buffer
+	m, err := Export(&buf, f.lk, f.src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Binaries) != 1 || m.Binaries[0].Path != "binaries/tool" {
+		t.Errorf("bundled binaries = %+v", m.Binaries)
+	}
+}
+
+func TestExport_SkipsTrees(t *testing.T) {
+	f := newFixture(t)
+	// Installed with layout: tree; its shim alone is no use offline.
+	f.lk.Binaries = append(f.lk.Binaries, lock.BinEntry{Name: "packer", Version: "v1.11.0", SHA256: sum("shim"), TreeSHA256: sum("tree")})
+	m, err := Export(&bytes.Buffer{}, f.lk, f.src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Binaries) != 1 || m.Binaries[0].Name != "tool" {
+		t.Errorf("bundled binaries = %+v, want only tool", m.Binaries)
+	}
+}
+
+func TestImport_Mismatch(t *testing.T) {
+	f := newFixture(t)
+	var buf bytes.Buffer
+	if _, err := Export(&buf, f.lk, f.src); err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ := json.Marshal(Manifest{
+		Version:  FormatVersion,
+		Binaries: []Binary{{Name: "tool", Version: "v1.0.0", SHA256: sum("binary"), Path: "binaries/tool"}},
+	})
+
+	tests := []struct {
+		name   string
+		bundle []byte
+		lock   func(lk *lock.Lock)
+		want   string
+	}{
+		{"other version", buf.Bytes(), func(lk *lock.Lock) { lk.Binaries[0].Version, lk.Binaries[0].SHA256 = "v2.0.0", sum("v2") }, "tool: bundle has v1.0.0, b.lock pins v2.0.0"},
+		{"binary not locked", buf.Bytes(), func(lk *lock.Lock) { lk.Binaries = nil }, "tool: not in b.lock"},
+		{"commit not locked", buf.Bytes(), func(lk *lock.Lock) { lk.Envs = lk.Envs[:1] }, envRef + "@" + f.commits[1] + ": not in b.lock"},
+		{"tampered binary", tarOf(t, ManifestName, string(manifest), "binaries/tool", "evil"), nil, "tool: content of"},
+		{"truncated", tarOf(t, ManifestName, string(manifest)), nil, "bundle is missing binaries/tool"},
+		{"unsafe path", tarOf(t, ManifestName, string(manifest), "binaries/tool", "binary", "../../etc/passwd", "x"), nil, "unexpected ../../etc/passwd"},
+		{"not a bundle", tarOf(t, "README", "hello"), nil, "not a b bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blobcache.SetRoot(t.TempDir())
+			t.Cleanup(func() { blobcache.SetRoot("") })
+			lk := &lock.Lock{
+				Binaries: append([]lock.BinEntry(nil), f.lk.Binaries...),
+				Envs:     append([]lock.EnvEntry(nil), f.lk.Envs...),
+			}
+			if tt.lock != nil {
+				tt.lock(lk)
+			}
+			gitRoot := t.TempDir()
+			_, err := Import(bytes.NewReader(tt.bundle), lk, gitRoot)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Import() error = %v, want %q", err, tt.want)
+			}
+			if _, ok := blobcache.Lookup("", sum("evil")); ok {
+				t.Error("tampered binary was cached")
+			}
+			if _, err := os.Stat(gitcache.CacheDir(gitRoot, envRef)); err == nil {
+				t.Error("git cache written for a rejected bundle")
+			}
+		})
+	}
+}
+
+func TestImport_CacheDisabled(t *testing.T) {
+	blobcache.SetRoot("")
+	if _, err := Import(bytes.NewReader(nil), &lock.Lock{}, t.TempDir()); err == nil {
+		t.Error("Import() succeeded with the download cache off")
+	}
+}
+
+// tarOf builds a tar archive from name, content pairs.
+func tarOf(t *testing.T, pairs ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for i := 0; i < len(pairs); i += 2 {
+		if err := tw.WriteHeader(&tar.Header{Name: pairs[i], Mode: 0644, Size: int64(len(pairs[i+1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(pairs[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fentas/goodies/templates"
+	"github.com/spf13/cobra"
+
+	"github.com/fentas/b/pkg/bundle"
+	"github.com/fentas/b/pkg/gitcache"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/path"
+)
+
+// BundleOptions holds options for the bundle command
+type BundleOptions struct {
+	*SharedOptions
+}
+
+// NewBundleCmd creates the bundle subcommand with subcommands
+func NewBundleCmd(shared *SharedOptions) *cobra.Command {
+	o := &BundleOptions{
+		SharedOptions: shared,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Move everything b.lock pins to a machine without network access",
+		Long: templates.LongDesc(`
+			Pack every binary and env commit b.lock pins into one archive, and
+			import it on a machine without network access. Importing seeds the
+			download and git caches; b install --offline then installs from them.
+		`),
+		Example: templates.Examples(`
+			# On a connected machine, after b install
+			b bundle export infra.tar
+
+			# On the disconnected one, in a checkout with the same b.lock
+			b bundle import infra.tar
+			b install --offline
+		`),
+	}
+
+	cmd.AddCommand(newBundleExportCmd(o))
+	cmd.AddCommand(newBundleImportCmd(o))
+
+	return cmd
+}
+
+// newBundleExportCmd creates the bundle export subcommand
+func newBundleExportCmd(o *BundleOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export <file>",
+		Short: "Pack the binaries and env commits b.lock pins into a tar archive",
+		Long:  "Pack the installed binaries (which must match b.lock) and the git objects of every locked env commit into a tar archive. Use - to write to stdout.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runExport(args[0])
+		},
+	}
+}
+
+// newBundleImportCmd creates the bundle import subcommand
+func newBundleImportCmd(o *BundleOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "import <file>",
+		Short: "Seed the local caches from a bundle",
+		Long:  "Check a bundle against b.lock and add its binaries to the download cache and its env commits to the git cache. Use - to read from stdin.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runImport(args[0])
+		},
+	}
+}
+
+// installedPaths maps the binaries of b.yaml, and the files extracted next
+// to them, to where install put them.
+func (o *BundleOptions) installedPaths() map[string]string {
+	paths := make(map[string]string)
+	for _, b := range o.GetBinariesFromConfig() {
+		paths[b.Name] = b.BinaryPath()
+		for _, e := range b.Companions() {
+			paths[e.Name()] = b.ExtractPath(e)
+		}
+	}
+	return paths
+}
+
+// runExport writes the bundle to file, moving it into place only once it
+// is complete.
+func (o *BundleOptions) runExport(file string) error {
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		return fmt.Errorf("reading b.lock: %w", err)
+	}
+	if len(lk.Binaries) == 0 && len(lk.Envs) == 0 {
+		return fmt.Errorf("no entries in b.lock — nothing to bundle")
+	}
+	binPath := path.GetBinaryPath()
+	if binPath == "" {
+		return fmt.Errorf("no binary path found")
+	}
+	src := bundle.Source{
+		BinDir:    binPath,
+		GitRoot:   gitcache.DefaultCacheRoot(),
+		ConfigDir: o.LockDir(),
+		Tool:      o.bVersion,
+		Paths:     o.installedPaths(),
+	}
+
+	if file == "-" {
+		_, err := bundle.Export(o.IO.Out, lk, src)
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	m, err := bundle.Export(tmp, lk, src)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+	fmt.Fprintf(o.IO.Out, "Bundled %d binaries and %d env commits into %s (%s)\n",
+		len(m.Binaries), commitCount(m), file, formatSize(info.Size()))
+	if missing := notBundled(m, lk); len(missing) > 0 {
+		fmt.Fprintf(o.IO.Out, "Not in the bundle: %s\n", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// runImport seeds the caches from the bundle in file.
+func (o *BundleOptions) runImport(file string) error {
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		return fmt.Errorf("reading b.lock: %w", err)
+	}
+	if len(lk.Binaries) == 0 && len(lk.Envs) == 0 {
+		return fmt.Errorf("no entries in b.lock — import needs the b.lock the bundle was made from")
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	m, err := bundle.Import(r, lk, gitcache.DefaultCacheRoot())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(o.IO.Out, "Imported %d binaries and %d env commits\n", len(m.Binaries), commitCount(m))
+	if missing := notBundled(m, lk); len(missing) > 0 {
+		fmt.Fprintf(o.IO.Out, "Not in the bundle: %s\n", strings.Join(missing, ", "))
+	}
+	fmt.Fprintln(o.IO.Out, "Run `b install --offline` to install them")
+	return nil
+}
+
+// commitCount returns the number of env commits in m.
+func commitCount(m *bundle.Manifest) int {
+	n := 0
+	for _, r := range m.Repos {
+		n += len(r.Commits)
+	}
+	return n
+}
+
+// notBundled lists b.lock entries the bundle doesn't carry; envs from
+// local repositories are among them, as those aren't bundled.
+func notBundled(m *bundle.Manifest, lk *lock.Lock) []string {
+	var missing []string
+	for _, e := range lk.Binaries {
+		if !slices.ContainsFunc(m.Binaries, func(b bundle.Binary) bool { return b.Name == e.Name }) {
+			missing = append(missing, e.Name)
+		}
+	}
+	for _, e := range lk.Envs {
+		if !slices.ContainsFunc(m.Repos, func(r bundle.Repo) bool { return r.Ref == e.Ref && slices.Contains(r.Commits, e.Commit) }) {
+			missing = append(missing, e.Ref)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fentas/goodies/streams"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/lock"
+)
+
+func makeBundleOpts(t *testing.T, lk *lock.Lock) (*BundleOptions, *bytes.Buffer) {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("binaries: {}\n"))
+	if err := lock.WriteLock(dir, lk, "test"); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	return &BundleOptions{
+		SharedOptions: &SharedOptions{
+			IO:               &streams.IO{Out: out, ErrOut: &bytes.Buffer{}},
+			ConfigPath:       configPath,
+			loadedConfigPath: configPath,
+		},
+	}, out
+}
+
+func TestBundle_ExportImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	binDir := t.TempDir()
+	t.Setenv("PATH_BIN", binDir)
+	mustWrite(t, filepath.Join(binDir, "tool"), []byte("binary"))
+	sum, err := lock.SHA256File(filepath.Join(binDir, "tool"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lk := &lock.Lock{
+		Binaries: []lock.BinEntry{{Name: "tool", Version: "v1.0.0", SHA256: sum}},
+		Envs:     []lock.EnvEntry{{Ref: "/srv/local/infra", Commit: "abc"}},
+	}
+
+	o, out := makeBundleOpts(t, lk)
+	file := filepath.Join(t.TempDir(), "out.tar")
+	if err := o.runExport(file); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Bundled 1 binaries and 0 env commits into "+file) {
+		t.Errorf("export output = %q", out.String())
+	}
+	if left, _ := filepath.Glob(filepath.Join(filepath.Dir(file), ".out.tar.*")); len(left) > 0 {
+		t.Errorf("left behind: %v", left)
+	}
+
+	// Another machine: empty caches, same b.lock.
+	t.Setenv("HOME", t.TempDir())
+	blobcache.SetRoot(t.TempDir())
+	t.Cleanup(func() { blobcache.SetRoot("") })
+	o, out = makeBundleOpts(t, lk)
+	if err := o.runImport(file); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Imported 1 binaries and 0 env commits",
+		"Not in the bundle: /srv/local/infra",
+		"b install --offline",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("import output = %q, missing %q", out.String(), want)
+		}
+	}
+	if _, ok := blobcache.Lookup("", sum); !ok {
+		t.Error("binary not in the download cache")
+	}
+}
+
+func TestBundle_EmptyLock(t *testing.T) {
+	o, _ := makeBundleOpts(t, &lock.Lock{})
+	if err := o.runExport(filepath.Join(t.TempDir(), "out.tar")); err == nil {
+		t.Error("runExport() with an empty b.lock succeeded")
+	}
+	if err := o.runImport(filepath.Join(t.TempDir(), "out.tar")); err == nil {
+		t.Error("runImport() with an empty b.lock succeeded")
+	}
+}
+
+func TestBundle_ExportFailureKeepsFile(t *testing.T) {
+	t.Setenv("PATH_BIN", t.TempDir())
+	o, _ := makeBundleOpts(t, &lock.Lock{Binaries: []lock.BinEntry{{Name: "tool", SHA256: "abc"}}})
+	file := filepath.Join(t.TempDir(), "out.tar")
+	mustWrite(t, file, []byte("previous"))
+	if err := o.runExport(file); err == nil {
+		t.Fatal("runExport() with an uninstalled binary succeeded")
+	}
+	if data, _ := os.ReadFile(file); string(data) != "previous" {
+		t.Errorf("out.tar = %q, want the previous bundle kept", data)
+	}
+}
+
+func TestBundle_ExportAlias(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, "bin")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH_BIN", binDir)
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("binaries:\n  github.com/org/tool:\n    alias: tl\n"))
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	b := shared.GetBinariesFromConfig()[0]
+	mustWrite(t, b.BinaryPath(), []byte("binary"))
+	sum, err := lock.SHA256File(b.BinaryPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lock.WriteLock(dir, &lock.Lock{Binaries: []lock.BinEntry{{Name: b.Name, Version: "v1.0.0", SHA256: sum}}}, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &BundleOptions{SharedOptions: shared}
+	file := filepath.Join(t.TempDir(), "out.tar")
+	if err := o.runExport(file); err != nil {
+		t.Fatalf("runExport() with an aliased binary: %v", err)
+	}
+	if out := shared.IO.Out.(*bytes.Buffer).String(); !strings.Contains(out, "Bundled 1 binaries") {
+		t.Errorf("export output = %q", out)
+	}
+}
@@ -4,16 +4,22 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/fentas/goodies/templates"
 	"github.com/spf13/cobra"
 
+	"github.com/fentas/b/pkg/blobcache"
 	"github.com/fentas/b/pkg/gitcache"
 )
 
 // CacheOptions holds options for the cache command
 type CacheOptions struct {
 	*SharedOptions
+	OlderThan string
 }
 
 // NewCacheCmd creates the cache subcommand with subcommands
@@ -24,24 +30,74 @@ func NewCacheCmd(shared *SharedOptions) *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "cache",
-		Short: "Manage the local git cache",
-		Long:  "Manage the local git cache used for env file syncing.",
+		Short: "Manage the local download and git cache",
+		Long: templates.LongDesc(`
+			Manage the caches b shares between all projects on this machine:
+			git repositories cloned for env file syncing (~/.cache/b/repos) and
+			downloaded release assets and image layers (~/.cache/b/blobs).
+
+			Without a subcommand, prints how much space each takes.
+		`),
+		Example: templates.Examples(`
+			# Show cache size
+			b cache
+
+			# Drop everything not used in the last two weeks
+			b cache prune --older-than 2w
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runReport()
+		},
 	}
 
+	cmd.AddCommand(newCacheListCmd(o))
+	cmd.AddCommand(newCachePruneCmd(o))
 	cmd.AddCommand(newCacheCleanCmd(o))
 	cmd.AddCommand(newCachePathCmd(o))
 
 	return cmd
 }
 
+// newCacheListCmd creates the cache list subcommand
+func newCacheListCmd(o *CacheOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List cached repositories and downloads",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runList()
+		},
+	}
+}
+
+// newCachePruneCmd creates the cache prune subcommand
+func newCachePruneCmd(o *CacheOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cache entries not used recently",
+		Long:  "Remove cached downloads not used, and git repositories not fetched, within --older-than.",
+		Example: templates.Examples(`
+			# Remove entries unused for 30 days
+			b cache prune
+
+			# Remove entries unused for a week
+			b cache prune --older-than 7d
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.runPrune()
+		},
+	}
+	cmd.Flags().StringVar(&o.OlderThan, "older-than", "30d", "Age of entries to remove (e.g. 36h, 7d, 2w)")
+	return cmd
+}
+
 // newCacheCleanCmd creates the cache clean subcommand
 func newCacheCleanCmd(o *CacheOptions) *cobra.Command {
 	return &cobra.Command{
 		Use:   "clean",
-		Short: "Remove cached git repositories",
-		Long:  "Remove all cached git repositories used for env file syncing.",
+		Short: "Remove the whole cache",
+		Long:  "Remove all cached git repositories and downloads.",
 		Example: templates.Examples(`
-			# Remove all cached repos
+			# Remove all cached repos and downloads
 			b cache clean
 		`),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -54,7 +110,7 @@ func newCacheCleanCmd(o *CacheOptions) *cobra.Command {
 func newCachePathCmd(o *CacheOptions) *cobra.Command {
 	return &cobra.Command{
 		Use:   "path",
-		Short: "Print the cache directory path",
+		Short: "Print the git cache directory path",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			fmt.Fprintln(o.IO.Out, gitcache.DefaultCacheRoot())
 			return nil
@@ -62,28 +118,251 @@ func newCachePathCmd(o *CacheOptions) *cobra.Command {
 	}
 }
 
-// runClean removes the git cache directory and reports freed space.
-func (o *CacheOptions) runClean() error {
-	cacheRoot := gitcache.DefaultCacheRoot()
+// cachedRepo is one bare clone in the git cache.
+type cachedRepo struct {
+	dir      string
+	url      string
+	size     int64
+	lastUsed time.Time
+}
 
-	// Compute size before removal
-	size, err := dirSize(cacheRoot)
+// listRepos returns the clones under root, most recently fetched first.
+func listRepos(root string) ([]cachedRepo, error) {
+	dirs, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Fprintln(o.IO.Out, "Cache is already clean (nothing to remove)")
-			return nil
+		return nil, err
+	}
+	var repos []cachedRepo
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, d.Name())
+		size, err := dirSize(dir)
+		if err != nil {
+			continue
+		}
+		repos = append(repos, cachedRepo{
+			dir:      dir,
+			url:      repoRemote(dir),
+			size:     size,
+			lastUsed: repoFetched(dir),
+		})
+	}
+	slices.SortFunc(repos, func(a, b cachedRepo) int { return b.lastUsed.Compare(a.lastUsed) })
+	return repos, nil
+}
+
+// repoRemote reads the clone URL from a bare repository's config.
+func repoRemote(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "config"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), "="); ok && strings.TrimSpace(k) == "url" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// repoFetched returns when a clone was last fetched (or created).
+func repoFetched(dir string) time.Time {
+	for _, name := range []string{"FETCH_HEAD", "."} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return info.ModTime()
+		}
+	}
+	return time.Time{}
+}
+
+// runReport prints how much space the git and blob caches take.
+func (o *CacheOptions) runReport() error {
+	repos, err := listRepos(gitcache.DefaultCacheRoot())
+	if err != nil {
+		return fmt.Errorf("reading git cache: %w", err)
+	}
+	blobs, err := blobcache.List(blobcache.DefaultRoot())
+	if err != nil {
+		return fmt.Errorf("reading download cache: %w", err)
+	}
+	var repoSize, blobSize int64
+	for _, r := range repos {
+		repoSize += r.size
+	}
+	for _, b := range blobs {
+		blobSize += b.Size
+	}
+	fmt.Fprintf(o.IO.Out, "%-14s %6d  %10s  %s\n", "repositories", len(repos), formatSize(repoSize), gitcache.DefaultCacheRoot())
+	fmt.Fprintf(o.IO.Out, "%-14s %6d  %10s  %s\n", "downloads", len(blobs), formatSize(blobSize), blobcache.DefaultRoot())
+	fmt.Fprintf(o.IO.Out, "%-14s %6d  %10s\n", "total", len(repos)+len(blobs), formatSize(repoSize+blobSize))
+	return nil
+}
+
+// runList prints every cached repository and download.
+func (o *CacheOptions) runList() error {
+	repos, err := listRepos(gitcache.DefaultCacheRoot())
+	if err != nil {
+		return fmt.Errorf("reading git cache: %w", err)
+	}
+	blobs, err := blobcache.List(blobcache.DefaultRoot())
+	if err != nil {
+		return fmt.Errorf("reading download cache: %w", err)
+	}
+	if len(repos) == 0 && len(blobs) == 0 {
+		fmt.Fprintln(o.IO.Out, "Cache is empty")
+		return nil
+	}
+	now := time.Now()
+	if len(repos) > 0 {
+		fmt.Fprintln(o.IO.Out, "Repositories:")
+		for _, r := range repos {
+			id := filepath.Base(r.dir)
+			if len(id) > 12 {
+				id = id[:12]
+			}
+			url := r.url
+			if url == "" {
+				url = "-"
+			}
+			fmt.Fprintf(o.IO.Out, "  %-12s %10s  %-10s %s\n", id, formatSize(r.size), formatAge(now.Sub(r.lastUsed)), url)
+		}
+	}
+	if len(blobs) > 0 {
+		fmt.Fprintln(o.IO.Out, "Downloads:")
+		for _, b := range blobs {
+			source := "-"
+			if len(b.URLs) > 0 {
+				source = b.URLs[0]
+				if len(b.URLs) > 1 {
+					source += fmt.Sprintf(" (+%d)", len(b.URLs)-1)
+				}
+			}
+			fmt.Fprintf(o.IO.Out, "  %-12s %10s  %-10s %s\n", b.Digest[:12], formatSize(b.Size), formatAge(now.Sub(b.LastUsed)), source)
 		}
-		return fmt.Errorf("reading cache: %w", err)
+	}
+	return nil
+}
+
+// runPrune removes the repositories and downloads not used within
+// --older-than and reports freed space.
+func (o *CacheOptions) runPrune() error {
+	age, err := parseAge(o.OlderThan)
+	if err != nil {
+		return err
+	}
+	before := time.Now().Add(-age)
+
+	repos, err := listRepos(gitcache.DefaultCacheRoot())
+	if err != nil {
+		return fmt.Errorf("reading git cache: %w", err)
+	}
+	var (
+		count int
+		freed int64
+	)
+	for _, r := range repos {
+		if !r.lastUsed.Before(before) {
+			continue
+		}
+		if err := os.RemoveAll(r.dir); err != nil {
+			return fmt.Errorf("removing %s: %w", r.dir, err)
+		}
+		count++
+		freed += r.size
+	}
+	removed, err := blobcache.Prune(blobcache.DefaultRoot(), before)
+	for _, b := range removed {
+		count++
+		freed += b.Size
+	}
+	if err != nil {
+		return fmt.Errorf("pruning download cache: %w", err)
 	}
 
-	if err := os.RemoveAll(cacheRoot); err != nil {
-		return fmt.Errorf("removing cache: %w", err)
+	if count == 0 {
+		fmt.Fprintf(o.IO.Out, "Nothing unused for %s\n", o.OlderThan)
+		return nil
 	}
+	fmt.Fprintf(o.IO.Out, "Removed %d entries (%s freed)\n", count, formatSize(freed))
+	return nil
+}
 
-	fmt.Fprintf(o.IO.Out, "Removed %s (%s freed)\n", cacheRoot, formatSize(size))
+// runClean removes the git and blob cache directories and reports freed
+// space.
+func (o *CacheOptions) runClean() error {
+	var (
+		removed []string
+		freed   int64
+	)
+	for _, cacheRoot := range []string{gitcache.DefaultCacheRoot(), blobcache.DefaultRoot()} {
+		// Compute size before removal
+		size, err := dirSize(cacheRoot)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("reading cache: %w", err)
+		}
+		if err := os.RemoveAll(cacheRoot); err != nil {
+			return fmt.Errorf("removing cache: %w", err)
+		}
+		removed = append(removed, cacheRoot)
+		freed += size
+	}
+
+	if len(removed) == 0 {
+		fmt.Fprintln(o.IO.Out, "Cache is already clean (nothing to remove)")
+		return nil
+	}
+	fmt.Fprintf(o.IO.Out, "Removed %s (%s freed)\n", strings.Join(removed, ", "), formatSize(freed))
 	return nil
 }
 
+// parseAge parses a --older-than value: a Go duration, or a whole number
+// of days ("7d") or weeks ("2w").
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	var (
+		d   time.Duration
+		err error
+	)
+	if unit > 0 {
+		var n int
+		n, err = strconv.Atoi(s[:len(s)-1])
+		d = time.Duration(n) * unit
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --older-than %q (use e.g. 36h, 7d or 2w)", s)
+	}
+	return d, nil
+}
+
+// formatAge formats how long ago something was used.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "just now"
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 // dirSize returns the total size in bytes of all files in a directory tree.
 func dirSize(path string) (int64, error) {
 	var total int64
@@ -1,9 +1,15 @@
 package cli
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/gitcache"
 )
 
 func TestFormatSize(t *testing.T) {
@@ -59,3 +65,144 @@ func TestDirSize_NotExist(t *testing.T) {
 		t.Error("dirSize() on nonexistent path should return error")
 	}
 }
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "36h", want: 36 * time.Hour},
+		{in: "7d", want: 7 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "0d", want: 0},
+		{in: "d", wantErr: true},
+		{in: "-1d", wantErr: true},
+		{in: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseAge(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeCache fills $HOME/.cache/b with one git repository and one blob, both
+// last used age ago.
+func fakeCache(t *testing.T, age time.Duration) (repo, blob string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	repo = filepath.Join(gitcache.DefaultCacheRoot(), "0123456789abcdef0123")
+	mustMkdir(t, repo)
+	mustWrite(t, filepath.Join(repo, "config"), []byte("[remote \"origin\"]\n\turl = https://github.com/org/envs\n"))
+	mustWrite(t, filepath.Join(repo, "FETCH_HEAD"), []byte("abc\n"))
+
+	blobcache.SetRoot(blobcache.DefaultRoot())
+	t.Cleanup(func() { blobcache.SetRoot("") })
+	src := filepath.Join(t.TempDir(), "dl")
+	mustWrite(t, src, []byte("kubectl"))
+	blob, err := blobcache.Put("https://dl.k8s.io/v1.31.0/kubectl", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	then := time.Now().Add(-age)
+	for _, p := range []string{filepath.Join(repo, "FETCH_HEAD"), blob} {
+		if err := os.Chtimes(p, then, then); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo, blob
+}
+
+func TestCacheOptions_RunReport(t *testing.T) {
+	fakeCache(t, time.Hour)
+	o := &CacheOptions{SharedOptions: NewSharedOptions(mkIO(), nil)}
+	if err := o.runReport(); err != nil {
+		t.Fatal(err)
+	}
+	out := o.IO.Out.(*bytes.Buffer).String()
+	for _, want := range []string{"repositories", "downloads", "7 B", "total", gitcache.DefaultCacheRoot(), blobcache.DefaultRoot()} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCacheOptions_RunList(t *testing.T) {
+	fakeCache(t, 3*24*time.Hour)
+	o := &CacheOptions{SharedOptions: NewSharedOptions(mkIO(), nil)}
+	if err := o.runList(); err != nil {
+		t.Fatal(err)
+	}
+	out := o.IO.Out.(*bytes.Buffer).String()
+	for _, want := range []string{"https://github.com/org/envs", "https://dl.k8s.io/v1.31.0/kubectl", "3d ago", "0123456789ab"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestCacheOptions_RunList_Empty(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	o := &CacheOptions{SharedOptions: NewSharedOptions(mkIO(), nil)}
+	if err := o.runList(); err != nil {
+		t.Fatal(err)
+	}
+	if out := o.IO.Out.(*bytes.Buffer).String(); !strings.Contains(out, "Cache is empty") {
+		t.Errorf("out = %q", out)
+	}
+}
+
+func TestCacheOptions_RunPrune(t *testing.T) {
+	tests := []struct {
+		name      string
+		age       time.Duration
+		olderThan string
+		removed   bool
+		wantErr   bool
+	}{
+		{name: "stale entries", age: 10 * 24 * time.Hour, olderThan: "7d", removed: true},
+		{name: "recent entries", age: time.Hour, olderThan: "7d"},
+		{name: "invalid age", olderThan: "a while", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, blob := fakeCache(t, tt.age)
+			o := &CacheOptions{SharedOptions: NewSharedOptions(mkIO(), nil), OlderThan: tt.olderThan}
+			err := o.runPrune()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, p := range []string{repo, blob} {
+				_, statErr := os.Stat(p)
+				if gone := os.IsNotExist(statErr); gone != tt.removed {
+					t.Errorf("%s removed = %v, want %v", p, gone, tt.removed)
+				}
+			}
+			if tt.removed && !strings.Contains(o.IO.Out.(*bytes.Buffer).String(), "Removed 2 entries") {
+				t.Errorf("out = %q", o.IO.Out.(*bytes.Buffer).String())
+			}
+		})
+	}
+}
+
+func TestCacheOptions_RunClean_Blobs(t *testing.T) {
+	repo, blob := fakeCache(t, time.Hour)
+	o := &CacheOptions{SharedOptions: NewSharedOptions(mkIO(), nil)}
+	if err := o.runClean(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{repo, blob} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s survived clean", p)
+		}
+	}
+}
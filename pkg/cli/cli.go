@@ -155,7 +155,7 @@ func (o *CmdBinaryOptions) Complete(cmd *cobra.Command, args []string) error {
 		for _, lb := range o.config.Binaries {
 			for b, do := range o.ensure {
 				if lb.Name == b.Name {
-					b.Version = lb.Version
+					b.SetVersion(lb.Version)
 
 					if o.all {
 						*do = true
@@ -1,17 +1,25 @@
 package cli
 
 import (
+	"archive/zip"
 	"bytes"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/envmatch"
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
 	"github.com/fentas/b/pkg/lock"
 	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 	"github.com/fentas/b/pkg/state"
 	"github.com/fentas/goodies/streams"
 )
@@ -1082,6 +1090,44 @@ func TestGetBinary_ProviderRefWithAssetFromConfig(t *testing.T) {
 	}
 }
 
+func TestGetBinary_HTTPTemplateFromConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("v2.0.1\n"))
+	}))
+	defer srv.Close()
+
+	ref := "https://dl.example.com/tool/{{.Version}}/tool_{{.OS}}_{{.Arch}}.tar.gz"
+	io := &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	shared := NewSharedOptions(io, nil)
+	shared.Config = &state.State{
+		Binaries: state.BinaryList{
+			&binary.LocalBinary{
+				Name:          ref,
+				IsProviderRef: true,
+				HTTP: &provider.HTTPConfig{
+					Latest: &provider.HTTPLatest{URL: srv.URL},
+				},
+			},
+		},
+	}
+
+	bins := shared.GetBinariesFromConfig()
+	if len(bins) != 1 {
+		t.Fatalf("got %d binaries, want 1", len(bins))
+	}
+	b := bins[0]
+	if b.Name != "tool" || b.ProviderType != "http" || b.HTTP == nil {
+		t.Fatalf("unexpected binary: name=%q provider=%q http=%v", b.Name, b.ProviderType, b.HTTP)
+	}
+	latest, err := b.VersionF(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != "v2.0.1" {
+		t.Errorf("latest = %q, want v2.0.1", latest)
+	}
+}
+
 func TestGetBinariesFromConfig_WithConfigOverrides(t *testing.T) {
 	binaries := []*binary.Binary{
 		{Name: "jq", Version: "1.6"},
@@ -1474,6 +1520,17 @@ func TestAddEnvToConfig_WithLabel(t *testing.T) {
 
 // --- updateLock tests ---
 
+// lockedHere returns what lk locks the named binary to on this platform,
+// or nil.
+func lockedHere(lk *lock.Lock, name string) *lock.BinEntry {
+	e := lk.FindBinary(name)
+	if e == nil {
+		return nil
+	}
+	host := e.For(provider.HostPlatform().String())
+	return &host
+}
+
 func TestUpdateLock_NewBinary(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "b.yaml")
@@ -1503,7 +1560,7 @@ func TestUpdateLock_NewBinary(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ReadLock error = %v", err)
 	}
-	entry := lk.FindBinary("mybin")
+	entry := lockedHere(lk, "mybin")
 	if entry == nil {
 		t.Fatal("expected binary in lock")
 	}
@@ -1547,7 +1604,7 @@ func TestUpdateLock_WithProviderRef(t *testing.T) {
 	}
 
 	lk, _ := lock.ReadLock(tmpDir)
-	entry := lk.FindBinary("k9s")
+	entry := lockedHere(lk, "k9s")
 	if entry == nil {
 		t.Fatal("expected binary in lock")
 	}
@@ -1562,6 +1619,445 @@ func TestUpdateLock_WithProviderRef(t *testing.T) {
 	}
 }
 
+func TestUpdateLock_Companions(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	os.WriteFile(configPath, []byte("binaries: {}\n"), 0644)
+	binDir := t.TempDir()
+	t.Setenv("PATH_BIN", binDir)
+	os.WriteFile(filepath.Join(binDir, "cilium"), []byte("cilium binary"), 0755)
+	os.WriteFile(filepath.Join(binDir, "hb"), []byte("hubble binary"), 0755)
+
+	var out bytes.Buffer
+	shared := &SharedOptions{
+		IO:               &streams.IO{Out: &out, ErrOut: &bytes.Buffer{}},
+		ConfigPath:       configPath,
+		loadedConfigPath: configPath,
+	}
+	o := &InstallOptions{SharedOptions: shared}
+	b := &binary.Binary{
+		Name:         "cilium",
+		Version:      "v0.16.0",
+		File:         filepath.Join(binDir, "cilium"),
+		AutoDetect:   true,
+		ProviderRef:  "github.com/cilium/cilium-cli",
+		ProviderType: "github",
+		Extract:      []binary.Extract{{Member: "cilium"}, {Member: "hubble", As: "hb"}},
+	}
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ := lock.ReadLock(tmpDir)
+	if len(lk.Binaries) != 2 {
+		t.Fatalf("lock binaries = %v, want cilium and hb", lk.Binaries)
+	}
+	hb := lockedHere(lk, "hb")
+	if hb == nil || hb.From != "cilium" || hb.Version != "v0.16.0" || hb.Source != "github.com/cilium/cilium-cli" {
+		t.Fatalf("hb entry = %+v", hb)
+	}
+	if want, _ := lock.SHA256File(filepath.Join(binDir, "hb")); hb.SHA256 != want {
+		t.Errorf("hb sha256 = %q, want %q", hb.SHA256, want)
+	}
+
+	// b verify checks the companion on its own.
+	v := &VerifyOptions{SharedOptions: shared}
+	if err := v.Run(); err != nil {
+		t.Fatalf("verify: %v\n%s", err, out.String())
+	}
+	os.WriteFile(filepath.Join(binDir, "hb"), []byte("tampered"), 0755)
+	if err := v.Run(); err == nil || !strings.Contains(out.String(), "hb") {
+		t.Errorf("verify with a changed companion = %v\n%s", err, out.String())
+	}
+
+	// Dropped from extract: its entry goes too.
+	b.Extract = nil
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ = lock.ReadLock(tmpDir)
+	if lk.FindBinary("hb") != nil || lk.FindBinary("cilium") == nil {
+		t.Errorf("lock binaries = %v, want only cilium", lk.Binaries)
+	}
+}
+
+func TestUpdateLock_CompanionCollision(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	os.WriteFile(configPath, []byte("binaries: {}\n"), 0644)
+	binDir := t.TempDir()
+	t.Setenv("PATH_BIN", binDir)
+	for _, name := range []string{"cilium", "hubble"} {
+		os.WriteFile(filepath.Join(binDir, name), []byte(name+" binary"), 0755)
+	}
+	shared := &SharedOptions{
+		IO:               &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+		ConfigPath:       configPath,
+		loadedConfigPath: configPath,
+	}
+	o := &InstallOptions{SharedOptions: shared}
+	newBinary := func(name string, extract ...binary.Extract) *binary.Binary {
+		return &binary.Binary{
+			Name:         name,
+			Version:      "v1.0.0",
+			File:         filepath.Join(binDir, name),
+			AutoDetect:   true,
+			ProviderRef:  "github.com/cilium/" + name,
+			ProviderType: "github",
+			Extract:      extract,
+		}
+	}
+
+	// hubble from b.yaml first, then extracted next to cilium.
+	if err := o.updateLock([]*binary.Binary{newBinary("hubble")}); err != nil {
+		t.Fatal(err)
+	}
+	cilium := newBinary("cilium", binary.Extract{Member: "hubble"})
+	if err := o.updateLock([]*binary.Binary{cilium}); err == nil || !strings.Contains(err.Error(), "extracted file hubble is already in b.lock as a binary") {
+		t.Errorf("updateLock() error = %v, want the collision reported", err)
+	}
+
+	// The other way around: the extracted file is locked first.
+	os.Remove(filepath.Join(tmpDir, "b.lock"))
+	if err := o.updateLock([]*binary.Binary{cilium}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.updateLock([]*binary.Binary{newBinary("hubble")}); err == nil || !strings.Contains(err.Error(), "already in b.lock as a file extracted for cilium") {
+		t.Errorf("updateLock() error = %v, want the collision reported", err)
+	}
+	lk, _ := lock.ReadLock(tmpDir)
+	if hb := lk.FindBinary("hubble"); hb == nil || hb.From != "cilium" {
+		t.Errorf("hubble entry = %+v, want the extracted file kept", hb)
+	}
+}
+
+func TestCheckExtractNames(t *testing.T) {
+	binDir := t.TempDir()
+	t.Setenv("PATH_BIN", binDir)
+	newBinary := func(name string, extract ...string) *binary.Binary {
+		b := &binary.Binary{Name: name, File: filepath.Join(binDir, name)}
+		for _, m := range extract {
+			b.Extract = append(b.Extract, binary.Extract{Member: m})
+		}
+		return b
+	}
+	locked := func(entries ...lock.BinEntry) *lock.Lock {
+		lk := &lock.Lock{}
+		for _, e := range entries {
+			lk.SetBinary(provider.HostPlatform().String(), e)
+		}
+		return lk
+	}
+
+	tests := []struct {
+		name     string
+		binaries []*binary.Binary
+		lk       *lock.Lock
+		wantErr  string
+	}{
+		{
+			name:     "no collision",
+			binaries: []*binary.Binary{newBinary("cilium", "hubble"), newBinary("kubectl")},
+		},
+		{
+			name:     "another binary",
+			binaries: []*binary.Binary{newBinary("hubble"), newBinary("cilium", "hubble")},
+			wantErr:  "cilium: extracted file hubble is also the binary hubble",
+		},
+		{
+			name:     "another binary's extracted file",
+			binaries: []*binary.Binary{newBinary("cilium", "hubble"), newBinary("hubble-cli", "hubble")},
+			wantErr:  "hubble-cli: extracted file hubble is also the file extracted for cilium",
+		},
+		{
+			name:     "binary in b.lock",
+			binaries: []*binary.Binary{newBinary("cilium", "hubble")},
+			lk:       locked(lock.BinEntry{Name: "hubble", SHA256: "a"}),
+			wantErr:  "cilium: extracted file hubble is also the binary hubble in b.lock",
+		},
+		{
+			name:     "extracted for another binary in b.lock",
+			binaries: []*binary.Binary{newBinary("cilium", "hubble")},
+			lk:       locked(lock.BinEntry{Name: "hubble", SHA256: "a", From: "hubble-cli"}),
+			wantErr:  "also the file extracted for hubble-cli in b.lock",
+		},
+		{
+			name:     "extracted for the same binary in b.lock",
+			binaries: []*binary.Binary{newBinary("cilium", "hubble")},
+			lk:       locked(lock.BinEntry{Name: "hubble", SHA256: "a", From: "cilium"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExtractNames(tt.binaries, tt.lk)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkExtractNames() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkExtractNames() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallOptions_Run_ExtractCollision(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("binaries:\n  \""+srv.URL+"/tool_{{.OS}}_{{.Arch}}@v1\":\n    extract: [hubble]\n"))
+	lk := &lock.Lock{}
+	lk.SetBinary(provider.HostPlatform().String(), lock.BinEntry{Name: "hubble", Version: "v1.0.0", SHA256: "a"})
+	if err := lock.WriteLock(dir, lk, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &InstallOptions{SharedOptions: shared}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "extracted file hubble is also the binary hubble in b.lock") {
+		t.Errorf("Run() error = %v, want the collision reported", err)
+	}
+	if requests != 0 {
+		t.Errorf("Run() made %d requests, want none before the collision is reported", requests)
+	}
+}
+
+func TestUpdateLock_Tree(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	os.WriteFile(configPath, []byte("binaries: {}\n"), 0644)
+	binDir := t.TempDir()
+	t.Setenv("PATH_BIN", binDir)
+	tree := binary.TreeDir(binDir, "packer", "v1.11.0")
+	os.MkdirAll(filepath.Join(tree, "plugins"), 0755)
+	os.WriteFile(filepath.Join(tree, "packer"), []byte("packer binary"), 0755)
+	os.WriteFile(filepath.Join(tree, "plugins", "docker"), []byte("plugin"), 0755)
+	os.WriteFile(filepath.Join(binDir, "packer"), []byte("#!/bin/sh\n"), 0755)
+
+	var out bytes.Buffer
+	shared := &SharedOptions{
+		IO:               &streams.IO{Out: &out, ErrOut: &bytes.Buffer{}},
+		ConfigPath:       configPath,
+		loadedConfigPath: configPath,
+	}
+	o := &InstallOptions{SharedOptions: shared}
+	b := &binary.Binary{
+		Name:       "packer",
+		Version:    "v1.11.0",
+		File:       filepath.Join(binDir, "packer"),
+		Layout:     binary.LayoutTree,
+		Entrypoint: "packer",
+	}
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ := lock.ReadLock(tmpDir)
+	entry := lockedHere(lk, "packer")
+	if want, _ := lock.SHA256Tree(tree); entry == nil || entry.TreeSHA256 != want {
+		t.Fatalf("packer entry = %+v, want tree sha256 %s", entry, want)
+	}
+
+	v := &VerifyOptions{SharedOptions: shared}
+	if err := v.Run(); err != nil {
+		t.Fatalf("verify: %v\n%s", err, out.String())
+	}
+	os.WriteFile(filepath.Join(tree, "plugins", "docker"), []byte("tampered"), 0755)
+	out.Reset()
+	if err := v.Run(); err == nil || !strings.Contains(out.String(), "tree sha256 mismatch") {
+		t.Errorf("verify with a changed tree = %v\n%s", err, out.String())
+	}
+	os.RemoveAll(tree)
+	out.Reset()
+	if err := v.Run(); err == nil || !strings.Contains(out.String(), "tree missing") {
+		t.Errorf("verify without the tree = %v\n%s", err, out.String())
+	}
+}
+
+func TestUpdateLock_AssetSHA256(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	os.WriteFile(configPath, []byte("binaries: {}\n"), 0644)
+
+	binPath := filepath.Join(tmpDir, "k9s")
+	os.WriteFile(binPath, []byte("k9s binary"), 0644)
+
+	o := &InstallOptions{
+		SharedOptions: &SharedOptions{
+			IO:               &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+			ConfigPath:       configPath,
+			loadedConfigPath: configPath,
+		},
+	}
+	b := &binary.Binary{
+		Name:         "k9s",
+		Version:      "v0.32.0",
+		File:         binPath,
+		AutoDetect:   true,
+		ProviderRef:  "github.com/derailed/k9s",
+		ProviderType: "github",
+		AssetSHA256:  "abc123",
+	}
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ := lock.ReadLock(tmpDir)
+	if got := lockedHere(lk, "k9s").AssetSHA256; got != "abc123" {
+		t.Errorf("AssetSHA256 = %q, want abc123", got)
+	}
+
+	// A later no-op install (nothing downloaded) keeps the verified digest
+	// for the same source+version.
+	b.AssetSHA256 = ""
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ = lock.ReadLock(tmpDir)
+	if got := lockedHere(lk, "k9s").AssetSHA256; got != "abc123" {
+		t.Errorf("AssetSHA256 after no-op = %q, want abc123", got)
+	}
+
+	// A version change without a fresh verification drops it.
+	b.Version = "v0.33.0"
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ = lock.ReadLock(tmpDir)
+	if got := lockedHere(lk, "k9s").AssetSHA256; got != "" {
+		t.Errorf("AssetSHA256 after version change = %q, want empty", got)
+	}
+}
+
+func TestUpdateLock_Signatures(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	os.WriteFile(configPath, []byte("binaries: {}\n"), 0644)
+
+	binPath := filepath.Join(tmpDir, "minisign")
+	os.WriteFile(binPath, []byte("minisign binary"), 0644)
+
+	o := &InstallOptions{
+		SharedOptions: &SharedOptions{
+			IO:               &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+			ConfigPath:       configPath,
+			loadedConfigPath: configPath,
+		},
+	}
+	sigs := []signature.Result{{Verifier: "minisign", Signer: "E7620F1842B4E81F", Signed: "minisign.tar.gz"}}
+	b := &binary.Binary{
+		Name:         "minisign",
+		Version:      "0.12",
+		File:         binPath,
+		AutoDetect:   true,
+		ProviderRef:  "github.com/jedisct1/minisign",
+		ProviderType: "github",
+		Signatures:   sigs,
+	}
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ := lock.ReadLock(tmpDir)
+	if got := lockedHere(lk, "minisign").Signatures; len(got) != 1 || got[0] != sigs[0] {
+		t.Errorf("Signatures = %+v, want %+v", got, sigs)
+	}
+
+	// A no-op install keeps the verification for the same source+version.
+	b.Signatures = nil
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ = lock.ReadLock(tmpDir)
+	if got := lockedHere(lk, "minisign").Signatures; len(got) != 1 {
+		t.Errorf("Signatures after no-op = %+v, want kept", got)
+	}
+
+	b.Version = "0.13"
+	if err := o.updateLock([]*binary.Binary{b}); err != nil {
+		t.Fatalf("updateLock() error = %v", err)
+	}
+	lk, _ = lock.ReadLock(tmpDir)
+	if got := lockedHere(lk, "minisign").Signatures; got != nil {
+		t.Errorf("Signatures after version change = %+v, want none", got)
+	}
+}
+
+func TestVerifyRun_SignaturePolicy(t *testing.T) {
+	tmpDir := t.TempDir()
+	binDir := filepath.Join(tmpDir, ".bin")
+	t.Setenv("PATH_BIN", binDir)
+	os.MkdirAll(binDir, 0755)
+	os.WriteFile(filepath.Join(binDir, "minisign"), []byte("minisign binary"), 0755)
+	hash, _ := lock.SHA256File(filepath.Join(binDir, "minisign"))
+
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	os.WriteFile(configPath, []byte(`binaries:
+  github.com/jedisct1/minisign:
+    verify:
+      minisign:
+        key: RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3
+`), 0644)
+	config, err := state.LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sigs    []signature.Result
+		wantErr bool
+		want    string
+	}{
+		{"recorded", []signature.Result{{Verifier: "minisign", Signer: "E7620F1842B4E81F"}}, false, "signed (minisign)"},
+		{"unsigned", nil, true, "no minisign signature recorded"},
+		{"other key", []signature.Result{{Verifier: "minisign", Signer: "0000000000000001"}}, true, "signed by key 0000000000000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lk := &lock.Lock{Version: 1, Binaries: []lock.BinEntry{
+				{Name: "minisign", Version: "0.12", SHA256: hash, Source: "github.com/jedisct1/minisign", Signatures: tt.sigs},
+			}}
+			lock.WriteLock(tmpDir, lk, "test")
+
+			var buf bytes.Buffer
+			o := &VerifyOptions{
+				SharedOptions: &SharedOptions{
+					IO:               &streams.IO{Out: &buf, ErrOut: &bytes.Buffer{}},
+					ConfigPath:       configPath,
+					loadedConfigPath: configPath,
+					Config:           config,
+				},
+			}
+			err := o.Run()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v\n%s", err, tt.wantErr, buf.String())
+			}
+			if !bytes.Contains(buf.Bytes(), []byte(tt.want)) {
+				t.Errorf("expected %q in output, got: %s", tt.want, buf.String())
+			}
+		})
+	}
+}
+
 func TestUpdateLock_PresetBinary(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "b.yaml")
@@ -1608,6 +2104,93 @@ func TestInstallValidate(t *testing.T) {
 	}
 }
 
+func TestInstallValidate_Target(t *testing.T) {
+	other := provider.Platform{OS: "windows", Arch: "arm64"}
+	if runtime.GOOS == "windows" {
+		other.OS = "linux"
+	}
+	tests := []struct {
+		name    string
+		o       *InstallOptions
+		wantErr string
+	}{
+		{name: "host into dest", o: &InstallOptions{Dest: "dist"}},
+		{name: "other platform", o: &InstallOptions{OS: other.OS, Arch: other.Arch, Dest: "dist"}},
+		{name: "other platform without dest", o: &InstallOptions{OS: other.OS}, wantErr: "need a --dest directory"},
+		{name: "unknown os", o: &InstallOptions{OS: "macos", Dest: "dist"}, wantErr: `unknown os "macos"`},
+		{name: "add", o: &InstallOptions{Dest: "dist", Add: true}, wantErr: "--add can't be combined"},
+		{name: "envs", o: &InstallOptions{Dest: "dist", configEnvRefs: []string{"github.com/org/infra"}}, wantErr: "only applies to binaries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.o.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				if !filepath.IsAbs(tt.o.Dest) {
+					t.Errorf("Dest = %q, want it made absolute", tt.o.Dest)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallOptions_Run_Dest(t *testing.T) {
+	var requested []string
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for name, content := range map[string]string{"tool.exe": "windows tool", "README.md": "docs, larger than the tool"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		_, _ = w.Write(zipped.Bytes())
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("binaries:\n  \""+srv.URL+"/tool_{{.OS}}_{{.Arch}}.zip\":\n    http:\n      arch: {arm64: aarch64}\n"))
+	binDir := filepath.Join(dir, ".bin")
+	t.Setenv("PATH_BIN", binDir)
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(dir, "dist")
+	o := &InstallOptions{SharedOptions: shared, OS: "windows", Arch: "arm64", Dest: dest}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 1 || requested[0] != "/tool_windows_aarch64.zip" {
+		t.Errorf("requested %v, want the windows/arm64 asset", requested)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "tool.exe")); err != nil || string(data) != "windows tool" {
+		t.Errorf("dist/tool.exe = %q err=%v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.lock")); err == nil {
+		t.Error("b.lock written for a --dest install")
+	}
+	if _, err := os.Stat(binDir); err == nil {
+		t.Error("binary path touched by a --dest install")
+	}
+}
+
 // --- LoadConfig ---
 
 func TestLoadConfig_WithExplicitPath(t *testing.T) {
@@ -1734,3 +2317,312 @@ func TestLoadConfig_MissingPath(t *testing.T) {
 		t.Error("expected error for missing config")
 	}
 }
+
+func TestLoadConfig_UserPresets(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	write := func(file, content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(configPath, "presets:\n  - tools.yaml\nbinaries:\n  mytool: {}\n")
+	write(filepath.Join(dir, "tools.yaml"), "name: mytool\nrepo: org/mytool\nfile: mytool-{{.OS}}\n---\nname: jq\nrepo: fork/jq\nfile: jq\n")
+	envFile := filepath.Join(dir, "env.yaml")
+	write(envFile, "name: mytool\nrepo: env/mytool\nfile: mytool\n")
+	t.Setenv("B_PRESETS", "")
+
+	io := &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	shared := NewSharedOptions(io, []*binary.Binary{{Name: "jq", GitHubRepo: "jqlang/jq"}})
+	shared.ConfigPath = configPath
+	// Loading twice must not duplicate presets.
+	for range 2 {
+		if err := shared.LoadConfig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(shared.Binaries) != 2 {
+		t.Fatalf("got %d binaries, want 2", len(shared.Binaries))
+	}
+	if b, ok := shared.lookup["jq"]; !ok || b.GitHubRepo != "fork/jq" {
+		t.Errorf("jq not replaced by user preset: %+v", b)
+	}
+	b, ok := shared.GetBinary("mytool")
+	if !ok || b.GitHubRepo != "org/mytool" {
+		t.Fatalf("mytool = %+v, %v", b, ok)
+	}
+
+	t.Setenv("B_PRESETS", envFile)
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if b := shared.lookup["mytool"]; b.GitHubRepo != "env/mytool" {
+		t.Errorf("B_PRESETS should win over b.yaml, got %s", b.GitHubRepo)
+	}
+
+	t.Setenv("B_PRESETS", filepath.Join(dir, "missing.yaml"))
+	if err := shared.LoadConfig(); err == nil || !strings.Contains(err.Error(), "B_PRESETS") {
+		t.Errorf("missing preset file: err = %v", err)
+	}
+}
+
+func TestGetBinary_Aqua(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("aqua:\n  - registry.yaml\nbinaries:\n  aqua:cli/cli:\n    version: v2.40.1\n"))
+	mustWrite(t, filepath.Join(dir, "registry.yaml"), []byte(`packages:
+  - type: github_release
+    repo_owner: cli
+    repo_name: cli
+    description: GitHub's official command line tool
+    asset: gh_{{trimV .Version}}_{{.OS}}_{{.Arch}}.tar.gz
+    files:
+      - name: gh
+        src: gh_{{trimV .Version}}_{{.OS}}_{{.Arch}}/bin/gh
+  - type: github_release
+    repo_owner: jqlang
+    repo_name: jq
+    asset: jq-{{.OS}}-{{.Arch}}
+`))
+	t.Setenv("B_AQUA_REGISTRY", "")
+
+	errOut := &bytes.Buffer{}
+	shared := NewSharedOptions(&streams.IO{Out: &bytes.Buffer{}, ErrOut: errOut}, nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	configured := shared.GetBinariesFromConfig()
+	if len(configured) != 1 {
+		t.Fatalf("got %d binaries from b.yaml, want 1", len(configured))
+	}
+	gh := configured[0]
+	if gh.Name != "gh" || gh.Version != "v2.40.1" || gh.Package != "aqua:cli/cli" || gh.ProviderRef != "github.com/cli/cli" {
+		t.Errorf("aqua:cli/cli = %+v", gh)
+	}
+	gh.Platform = provider.Platform{OS: "linux", Arch: "amd64"}
+	if asset, err := gh.AssetF(gh); err != nil || asset != "gh_2.40.1_linux_amd64.tar.gz" {
+		t.Errorf("asset = %q, %v", asset, err)
+	}
+
+	if _, ok := shared.GetBinary("aqua:no/such"); ok || !strings.Contains(errOut.String(), "aqua package no/such not found") {
+		t.Errorf("unknown package: ok=%v, stderr %q", ok, errOut.String())
+	}
+
+	// b install --add writes the aqua: ref back
+	o := &InstallOptions{SharedOptions: shared}
+	if err := o.addToConfig([]*binary.Binary{gh}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := state.LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Binaries) != 1 || cfg.Binaries[0].Name != "aqua:cli/cli" || len(cfg.Aqua) != 1 {
+		t.Errorf("b.yaml after --add: binaries %+v, aqua %v", cfg.Binaries, cfg.Aqua)
+	}
+
+	// b search lists the registry's packages
+	out := &bytes.Buffer{}
+	shared.IO = &streams.IO{Out: out, ErrOut: errOut}
+	s := &SearchOptions{SharedOptions: shared, Query: "command line"}
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "aqua:cli/cli") || strings.Contains(out.String(), "aqua:jqlang/jq") {
+		t.Errorf("search output:\n%s", out)
+	}
+}
+
+func TestLoadConfig_Hosts(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	config := "hosts:\n  github.example.corp:\n    type: github\nbinaries:\n  github.example.corp/org/tool: {}\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = hosts.Set(nil) })
+
+	io := &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	shared := NewSharedOptions(io, nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if h, ok := hosts.Lookup("github.example.corp"); !ok || h.Type != hosts.GitHub {
+		t.Fatalf("host not configured: %+v", h)
+	}
+	bins := shared.GetBinariesFromConfig()
+	if len(bins) != 1 || bins[0].Name != "tool" || bins[0].ProviderType != "github" {
+		t.Errorf("enterprise ref not resolved: %+v", bins)
+	}
+
+	if err := os.WriteFile(configPath, []byte("hosts:\n  x.corp:\n    type: svn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := shared.LoadConfig(); err == nil || !strings.Contains(err.Error(), "x.corp") {
+		t.Errorf("invalid host: err = %v", err)
+	}
+}
+
+func TestLoadConfig_Network(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	var agent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+	}))
+	defer srv.Close()
+	if err := os.WriteFile(configPath, []byte("network:\n  userAgent: corp-ci/1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = httpclient.Set(nil, "") })
+
+	io := &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	shared := NewSharedOptions(io, nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := httpclient.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if agent != "corp-ci/1.0" {
+		t.Errorf("User-Agent = %q", agent)
+	}
+
+	// CA bundles resolve next to b.yaml.
+	if err := os.WriteFile(configPath, []byte("network:\n  caFiles: [corp.pem]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = shared.LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "corp.pem")) {
+		t.Errorf("missing CA bundle: err = %v", err)
+	}
+}
+
+func TestGetBinariesFromConfig_VersionConstraint(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	config := "binaries:\n  github.com/derailed/k9s:\n    version: \"~0.32\"\n  github.com/org/pinned:\n    version: v1.2.3\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	io := &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	shared := NewSharedOptions(io, nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	byName := map[string]*binary.Binary{}
+	for _, b := range shared.GetBinariesFromConfig() {
+		byName[b.Name] = b
+	}
+	if b := byName["k9s"]; b == nil || b.Constraint != "~0.32" || b.Version != "" {
+		t.Errorf("range: %+v", b)
+	}
+	if b := byName["pinned"]; b == nil || b.Constraint != "" || b.Version != "v1.2.3" {
+		t.Errorf("exact: %+v", b)
+	}
+
+	// An explicit @version on the command line replaces the range.
+	b, ok := shared.GetBinary("github.com/derailed/k9s@v0.31.0")
+	if !ok || b.Version != "v0.31.0" || b.Constraint != "" {
+		t.Errorf("GetBinary@version = %+v", b)
+	}
+}
+
+func TestAddToConfig_VersionConstraint(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".bin", "b.yaml")
+
+	o := &InstallOptions{
+		SharedOptions: &SharedOptions{
+			IO:               &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+			ConfigPath:       configPath,
+			loadedConfigPath: configPath,
+			Config:           &state.State{},
+		},
+	}
+
+	// Resolved to a tag by the install, but b.yaml keeps the range.
+	binaries := []*binary.Binary{
+		{Name: "k9s", Version: "v0.32.5", Constraint: "^0.32", AutoDetect: true, ProviderRef: "github.com/derailed/k9s"},
+	}
+	if err := o.addToConfig(binaries); err != nil {
+		t.Fatalf("addToConfig() error = %v", err)
+	}
+	cfg, err := state.LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Binaries) != 1 || cfg.Binaries[0].Version != "^0.32" {
+		t.Errorf("config = %+v", cfg.Binaries[0])
+	}
+}
+
+func TestAddToConfig_Channel(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, ".bin", "b.yaml")
+
+	o := &InstallOptions{
+		SharedOptions: &SharedOptions{
+			IO:               &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+			ConfigPath:       configPath,
+			loadedConfigPath: configPath,
+			Config: &state.State{Binaries: state.BinaryList{
+				{Name: "github.com/org/existing", IsProviderRef: true},
+			}},
+		},
+		Pre: true,
+	}
+
+	binaries := []*binary.Binary{
+		{Name: "k9s", AutoDetect: true, ProviderRef: "github.com/derailed/k9s"},
+		{Name: "existing", AutoDetect: true, ProviderRef: "github.com/org/existing"},
+	}
+	followPrereleases(binaries)
+	if err := o.addToConfig(binaries); err != nil {
+		t.Fatalf("addToConfig() error = %v", err)
+	}
+	cfg, err := state.LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"github.com/derailed/k9s", "github.com/org/existing"} {
+		if lb := cfg.Binaries.Get(name); lb == nil || lb.Channel != "prerelease" {
+			t.Errorf("%s = %+v, want channel prerelease", name, lb)
+		}
+	}
+}
+
+func TestGetBinariesFromConfig_Channel(t *testing.T) {
+	o := &SharedOptions{
+		IO: &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+		Config: &state.State{Binaries: state.BinaryList{
+			{Name: "github.com/derailed/k9s", IsProviderRef: true, Channel: "nightly"},
+		}},
+	}
+	bins := o.GetBinariesFromConfig()
+	if len(bins) != 1 || bins[0].Channel != provider.ChannelNightly {
+		t.Fatalf("binaries = %+v", bins)
+	}
+}
+
+func TestGetBinariesFromConfig_Extract(t *testing.T) {
+	extract := []binary.Extract{{Member: "hubble"}}
+	o := &SharedOptions{
+		IO: &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}},
+		Config: &state.State{Binaries: state.BinaryList{
+			{Name: "github.com/cilium/cilium-cli", IsProviderRef: true, Extract: extract},
+		}},
+	}
+	bins := o.GetBinariesFromConfig()
+	if len(bins) != 1 || len(bins[0].Extract) != 1 || bins[0].Extract[0].Member != "hubble" {
+		t.Fatalf("binaries = %+v", bins)
+	}
+}
@@ -59,7 +59,7 @@ func TestNewRootCmd_AllSubcommands(t *testing.T) {
 		t.Fatal("nil root")
 	}
 	// Check expected subcommands exist
-	want := []string{"install", "update", "list", "search", "init", "version", "request", "verify", "cache", "env"}
+	want := []string{"install", "update", "list", "search", "init", "version", "versions", "request", "verify", "cache", "env"}
 	for _, w := range want {
 		found := false
 		for _, c := range root.Commands() {
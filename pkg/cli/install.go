@@ -1,22 +1,27 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/fatih/color"
 	"github.com/fentas/goodies/progress"
 	"github.com/fentas/goodies/templates"
 	"github.com/spf13/cobra"
 
+	"github.com/fentas/b/pkg/aqua"
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/env"
 	"github.com/fentas/b/pkg/envmatch"
 	"github.com/fentas/b/pkg/gitcache"
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
 	"github.com/fentas/b/pkg/path"
 	"github.com/fentas/b/pkg/provider"
 	"github.com/fentas/b/pkg/state"
@@ -36,14 +41,23 @@ type envInstall struct {
 // InstallOptions holds options for the install command
 type InstallOptions struct {
 	*SharedOptions
-	Add               bool             // Add to b.yaml during install
-	Fix               bool             // Pin version in b.yaml
-	Alias             string           // Alias for the binary
-	Asset             string           // Asset filter glob pattern
-	OnPost            string           // Shell command to run after install/update
-	specifiedBinaries []*binary.Binary // Binaries specified on command line
-	envInstalls       []envInstall     // SCP-style env installs
-	configEnvRefs     []string         // env refs to sync from config
+	Add               bool              // Add to b.yaml during install
+	Fix               bool              // Pin version in b.yaml
+	Alias             string            // Alias for the binary
+	Asset             string            // Asset filter glob pattern
+	OnPost            string            // Shell command to run after install/update
+	Pre               bool              // Follow the prerelease channel
+	Jobs              int               // Parallel downloads; 0 means B_JOBS or DefaultJobs
+	jobsPerHost       int               // B_JOBS_PER_HOST, resolved by Validate
+	OS                string            // Target OS (GOOS); empty is the host's
+	Arch              string            // Target architecture (GOARCH); empty is the host's
+	Dest              string            // Install binaries here instead of the binary path
+	Locked            bool              // Install exactly what b.lock pins
+	target            provider.Platform // OS and Arch, validated
+	specifiedBinaries []*binary.Binary  // Binaries specified on command line
+	envInstalls       []envInstall      // SCP-style env installs
+	configEnvRefs     []string          // env refs to sync from config
+	missing           missingArtifacts  // what --offline couldn't find locally
 }
 
 // NewInstallCmd creates the install subcommand
@@ -67,6 +81,12 @@ func NewInstallCmd(shared *SharedOptions) *cobra.Command {
 			# Install from GitHub release
 			b install github.com/derailed/k9s
 
+			# Install the latest release candidate
+			b install --pre --add github.com/derailed/k9s
+
+			# Install a package of the aqua registry
+			b install aqua:cli/cli
+
 			# Install a specific release asset by glob pattern
 			b install --asset "argsh-so-*" arg-sh/argsh
 
@@ -78,6 +98,12 @@ func NewInstallCmd(shared *SharedOptions) *cobra.Command {
 
 			# Install + save to b.yaml
 			b install --add github.com/org/infra@v2.0:/manifests/hetzner/** /hetzner
+
+			# Fetch the binaries of b.yaml for macOS on Apple silicon into dist/
+			b install --os darwin --arch arm64 --dest dist/darwin-arm64
+
+			# Install exactly what b.lock pins for this platform (CI)
+			b install --locked
 		`),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := o.Complete(args); err != nil {
@@ -95,6 +121,12 @@ func NewInstallCmd(shared *SharedOptions) *cobra.Command {
 	cmd.Flags().StringVar(&o.Alias, "alias", "", "Alias for the binary")
 	cmd.Flags().StringVar(&o.Asset, "asset", "", "Glob pattern to filter release assets (e.g. \"argsh-so-*\")")
 	cmd.Flags().StringVar(&o.OnPost, "on-post", "", "Shell command to run after install/update (saved to b.yaml with --add)")
+	cmd.Flags().BoolVar(&o.Pre, "pre", false, "Install the latest prerelease when no version is given (saved to b.yaml with --add)")
+	cmd.Flags().IntVarP(&o.Jobs, "jobs", "j", 0, jobsHelp)
+	cmd.Flags().StringVar(&o.OS, "os", "", "Install binaries for this OS (GOOS, e.g. darwin, windows); needs --dest")
+	cmd.Flags().StringVar(&o.Arch, "arch", "", "Install binaries for this architecture (GOARCH, e.g. arm64); needs --dest")
+	cmd.Flags().StringVar(&o.Dest, "dest", "", "Install binaries into this directory; b.lock and b.yaml are left alone")
+	cmd.Flags().BoolVar(&o.Locked, "locked", false, "Install exactly what b.lock pins for the platform and fail on anything else; b.lock is left alone")
 	return cmd
 }
 
@@ -135,7 +167,7 @@ func (o *InstallOptions) Complete(args []string) error {
 		b, ok := o.GetBinary(name)
 		if !ok {
 			// If it looks like a provider ref, check for upstream b.yaml
-			if provider.IsProviderRef(name) {
+			if provider.IsProviderRef(name) && !aqua.IsRef(name) {
 				if hint := o.discoverUpstreamConfig(name); hint != "" {
 					return fmt.Errorf("no releases found for %s, but the repo has a b.yaml:\n%s\n  Hint: use SCP syntax to sync files, e.g.:\n    b install %s:/<glob> <dest>", name, hint, name)
 				}
@@ -144,7 +176,7 @@ func (o *InstallOptions) Complete(args []string) error {
 		}
 
 		if version != "" {
-			b.Version = version
+			b.SetVersion(version)
 		}
 
 		b.Alias = o.Alias
@@ -162,9 +194,48 @@ func (o *InstallOptions) Complete(args []string) error {
 
 // Validate checks if the install operation is valid
 func (o *InstallOptions) Validate() error {
+	var err error
+	if o.Jobs, err = resolveJobs(o.Jobs); err != nil {
+		return err
+	}
+	if o.jobsPerHost, err = resolveJobsPerHost(); err != nil {
+		return err
+	}
+	o.target = provider.Platform{OS: o.OS, Arch: o.Arch}
+	if err := o.target.Validate(); err != nil {
+		return err
+	}
+	if o.Locked && (o.Add || o.Fix) {
+		return fmt.Errorf("--locked installs what b.lock pins; it can't be combined with --add or --fix")
+	}
+	if o.Dest == "" {
+		if !o.target.IsHost() {
+			return fmt.Errorf("--os/--arch install %s binaries, which need a --dest directory", o.target)
+		}
+		return nil
+	}
+	switch {
+	case o.Add:
+		return fmt.Errorf("--add can't be combined with --dest")
+	case len(o.envInstalls) > 0 || len(o.configEnvRefs) > 0:
+		return fmt.Errorf("--dest only applies to binaries, not env files")
+	case offline.Enabled() && !o.target.IsHost():
+		return fmt.Errorf("--offline installs what b.lock pins for this platform, not for %s", o.target)
+	}
+	if o.Dest, err = filepath.Abs(o.Dest); err != nil {
+		return err
+	}
 	return nil
 }
 
+// retarget points binaries at o.Dest, as builds for o.target.
+func (o *InstallOptions) retarget(binaries []*binary.Binary) {
+	for _, b := range binaries {
+		b.Platform = o.target
+		b.File = filepath.Join(o.Dest, o.target.Exe(filepath.Base(b.BinaryPath())))
+	}
+}
+
 // Run executes the install operation
 func (o *InstallOptions) Run() error {
 	// Handle env installs (SCP-style or config-based)
@@ -184,7 +255,7 @@ func (o *InstallOptions) Run() error {
 		binariesToInstall = o.GetBinariesFromConfig()
 
 		// Also sync all configured envs
-		if o.Config != nil && len(o.Config.Envs) > 0 {
+		if o.Config != nil && len(o.Config.Envs) > 0 && o.Dest == "" {
 			if err := o.syncConfigEnvs(nil); err != nil {
 				return err
 			}
@@ -197,20 +268,65 @@ func (o *InstallOptions) Run() error {
 	}
 
 	if len(binariesToInstall) > 0 {
+		if o.Pre {
+			followPrereleases(binariesToInstall)
+		}
+		if o.Dest != "" {
+			o.retarget(binariesToInstall)
+		}
+		if o.Dest == "" {
+			if lk, err := lock.ReadLock(o.LockDir()); err == nil {
+				markRebuilds(binariesToInstall, lk)
+			}
+		}
+		if offline.Enabled() || o.Locked {
+			lk, err := lock.ReadLock(o.LockDir())
+			if err != nil {
+				return err
+			}
+			pinFromLock(binariesToInstall, lk)
+			if o.Locked {
+				if err := requireLocked(binariesToInstall, lk); err != nil {
+					return err
+				}
+			}
+		}
+		var lk *lock.Lock
+		if o.Dest == "" {
+			// Without a readable b.lock there's nothing of it to collide with.
+			lk, _ = lock.ReadLock(o.LockDir())
+		}
+		if err := checkExtractNames(binariesToInstall, lk); err != nil {
+			return err
+		}
 		if err := o.installBinaries(binariesToInstall); err != nil {
 			return err
 		}
+		if o.Locked {
+			// b.lock is the input here; it is not rewritten.
+			if err := checkLocked(binariesToInstall); err != nil {
+				return err
+			}
+			return o.missing.err()
+		}
+		if o.Dest != "" {
+			// Binaries for another directory, maybe another platform:
+			// nothing about them belongs in this project's b.lock.
+			return o.missing.err()
+		}
 
 		if err := o.updateLock(binariesToInstall); err != nil {
-			fmt.Fprintf(o.IO.ErrOut, "Warning: failed to update b.lock: %v\n", err)
+			return fmt.Errorf("updating b.lock: %w", err)
 		}
 
 		if o.Add {
-			return o.addToConfig(binariesToInstall)
+			if err := o.addToConfig(binariesToInstall); err != nil {
+				return err
+			}
 		}
 	}
 
-	return nil
+	return o.missing.err()
 }
 
 // installBinaries installs the specified binaries with progress tracking
@@ -223,7 +339,7 @@ func (o *InstallOptions) installBinaries(binaries []*binary.Binary) error {
 	} else {
 		var missing []*binary.Binary
 		for _, b := range binaries {
-			if !b.BinaryExists() {
+			if !b.Installed() {
 				missing = append(missing, b)
 			}
 		}
@@ -243,23 +359,26 @@ func (o *InstallOptions) installBinaries(binaries []*binary.Binary) error {
 	wg := sync.WaitGroup{}
 	pw := progress.NewWriter(progress.StyleDownload, o.IO.Out)
 	pw.Style().Visibility.Percentage = true
-	go pw.Render()
-	defer pw.Stop()
+	// Trackers are added up front so queued binaries show as such.
+	for _, b := range binaries {
+		b.Tracker = pw.AddTracker(fmt.Sprintf("Installing %s (queued)", b.Name), 0)
+		b.Writer = pw
+	}
+	rendered := renderProgress(pw)
 
+	pool := newJobPool(o.Jobs, o.jobsPerHost)
 	for _, b := range binaries {
 		wg.Add(1)
-
 		go func(b *binary.Binary) {
 			defer wg.Done()
-
-			tracker := pw.AddTracker(fmt.Sprintf("Installing %s", b.Name), 0)
-			b.Tracker = tracker
-			b.Writer = pw
+			release := pool.acquire(binaryHost(b))
+			defer release()
+			b.Tracker.UpdateMessage(fmt.Sprintf("Installing %s", b.Name))
 
 			// Track whether a download actually happened so we only run
 			// the onPost hook when the binary changed — not on a no-op
 			// "already exists" skip from EnsureBinary(false).
-			wasMissing := !b.BinaryExists()
+			wasMissing := !b.Installed()
 			var err error
 			if o.Force {
 				err = b.DownloadBinary()
@@ -267,9 +386,11 @@ func (o *InstallOptions) installBinaries(binaries []*binary.Binary) error {
 				err = b.EnsureBinary(false) // Don't update, just ensure
 			}
 			downloaded := err == nil && (o.Force || wasMissing)
+			o.missing.add(err)
 
-			// Run onPost hook only when a download actually happened.
-			if downloaded && b.OnPost != "" {
+			// Run onPost hook only when a download actually happened, and
+			// not for binaries installed into a --dest directory.
+			if downloaded && b.OnPost != "" && o.Dest == "" {
 				if hookErr := binary.RunHook(b.OnPost, o.ProjectRoot(), "install", b.Name, b.Version, b.BinaryPath(), o.IO.ErrOut, o.IO.ErrOut); hookErr != nil {
 					fmt.Fprintf(o.IO.ErrOut, "Warning: onPost hook for %s failed: %v\n", b.Name, hookErr)
 				}
@@ -288,11 +409,24 @@ func (o *InstallOptions) installBinaries(binaries []*binary.Binary) error {
 	}
 
 	wg.Wait()
-	// Let the progress bar render
-	time.Sleep(200 * time.Millisecond)
+	<-rendered
 	return nil
 }
 
+// renderProgress renders pw until all its trackers, which must have been
+// added, are done, and returns a channel closed when it has finished. The
+// renderer is waited for rather than stopped: Stop doesn't wait for it, and
+// one outliving its command races whatever is written to the output next.
+func renderProgress(pw *progress.Writer) <-chan struct{} {
+	pw.SetAutoStop(true)
+	rendered := make(chan struct{})
+	go func() {
+		defer close(rendered)
+		pw.Render()
+	}()
+	return rendered
+}
+
 // addToConfig adds binaries to the configuration file
 func (o *InstallOptions) addToConfig(binaries []*binary.Binary) error {
 	configPath, err := o.getConfigPath()
@@ -310,21 +444,37 @@ func (o *InstallOptions) addToConfig(binaries []*binary.Binary) error {
 	for _, b := range binaries {
 		// Use provider ref as the config key if auto-detected
 		configName := b.Name
-		if b.AutoDetect && b.ProviderRef != "" {
+		switch {
+		case b.Package != "":
+			configName = b.Package
+		case b.AutoDetect && b.ProviderRef != "":
 			configName = b.ProviderRef
 		}
 
+		// A range, branch or commit is written as given; the lock records
+		// what it resolved to.
+		version := b.Version
+		switch {
+		case b.Constraint != "":
+			version = b.Constraint
+		case b.Query != "":
+			version = b.Query
+		}
+
 		// Check if already exists
 		found := false
 		for i, existing := range config.Binaries {
 			if existing.Name == configName {
 				// Update version only if we have a specific version
-				if b.Version != "" && b.Version != "latest" {
-					config.Binaries[i].Version = b.Version
+				if version != "" && version != "latest" {
+					config.Binaries[i].Version = version
 					if o.Fix {
-						config.Binaries[i].Enforced = b.Version
+						config.Binaries[i].Enforced = version
 					}
 				}
+				if o.Pre {
+					config.Binaries[i].Channel = string(b.Channel)
+				}
 				found = true
 				break
 			}
@@ -335,10 +485,10 @@ func (o *InstallOptions) addToConfig(binaries []*binary.Binary) error {
 				Name: configName,
 			}
 			// Only set version if it's not "latest" or empty
-			if b.Version != "" && b.Version != "latest" {
-				entry.Version = b.Version
+			if version != "" && version != "latest" {
+				entry.Version = version
 				if o.Fix {
-					entry.Enforced = b.Version
+					entry.Enforced = version
 				}
 			}
 			if b.Alias != "" {
@@ -351,6 +501,9 @@ func (o *InstallOptions) addToConfig(binaries []*binary.Binary) error {
 			if b.OnPost != "" {
 				entry.OnPost = b.OnPost
 			}
+			if b.Channel != "" && b.Channel != provider.ChannelStable {
+				entry.Channel = string(b.Channel)
+			}
 			config.Binaries = append(config.Binaries, entry)
 		}
 	}
@@ -358,6 +511,20 @@ func (o *InstallOptions) addToConfig(binaries []*binary.Binary) error {
 	return state.SaveConfig(config, configPath)
 }
 
+// markRebuilds marks the go:// binaries b.lock recorded as built with
+// other build options than b.yaml now asks for: their build inputs
+// changed, so they are built again like a missing binary.
+func markRebuilds(binaries []*binary.Binary, lk *lock.Lock) {
+	for _, b := range binaries {
+		if !b.AutoDetect || b.ProviderType != (&provider.GoInstall{}).Name() {
+			continue
+		}
+		if entry := lk.FindBinary(b.Name); entry != nil && entry.Source == b.ProviderRef && !entry.Build.Equal(b.Build) {
+			b.Rebuild = true
+		}
+	}
+}
+
 // updateLock updates b.lock with installed binary checksums
 func (o *InstallOptions) updateLock(binaries []*binary.Binary) error {
 	lockDir := o.LockDir()
@@ -366,6 +533,7 @@ func (o *InstallOptions) updateLock(binaries []*binary.Binary) error {
 		return err
 	}
 
+	stale := make(map[string][]string)
 	for _, b := range binaries {
 		if b.File == "" {
 			continue
@@ -374,6 +542,7 @@ func (o *InstallOptions) updateLock(binaries []*binary.Binary) error {
 		if err != nil {
 			continue
 		}
+		platform := b.Platform.String()
 		entry := lock.BinEntry{
 			Name:    b.Name,
 			Version: b.Version,
@@ -382,6 +551,31 @@ func (o *InstallOptions) updateLock(binaries []*binary.Binary) error {
 		if b.AutoDetect {
 			entry.Source = b.ProviderRef
 			entry.Provider = b.ProviderType
+			if !b.Build.IsZero() {
+				entry.Build = b.Build
+			}
+			entry.Query = b.Query
+			// AssetSHA256 is only known when this run actually downloaded
+			// and verified the asset. On a no-op install keep the digest
+			// recorded for the same source+version.
+			// The same holds for the verified signatures.
+			entry.AssetSHA256 = b.AssetSHA256
+			entry.Signatures = b.Signatures
+			if b.Asset != nil {
+				entry.Asset, entry.AssetURL = b.Asset.Name, b.Asset.URL
+			}
+			if prev := lk.FindBinary(b.Name); prev != nil && prev.Source == entry.Source && prev.Version == entry.Version && prev.Build.Equal(entry.Build) {
+				prev := prev.For(platform)
+				if entry.AssetSHA256 == "" {
+					entry.AssetSHA256 = prev.AssetSHA256
+				}
+				if len(entry.Signatures) == 0 {
+					entry.Signatures = prev.Signatures
+				}
+				if entry.AssetURL == "" {
+					entry.Asset, entry.AssetURL = prev.Asset, prev.AssetURL
+				}
+			}
 			// For providers that expose a stable content digest (docker://,
 			// oci://) record it so `b update` can skip re-pulls when the
 			// tag's manifest hasn't moved upstream. ResolveDigest has a
@@ -426,10 +620,146 @@ func (o *InstallOptions) updateLock(binaries []*binary.Binary) error {
 				entry.Source = "github.com/" + b.GitHubRepo
 			}
 		}
-		lk.UpsertBinary(entry)
+		if b.Layout == binary.LayoutTree {
+			if sum, err := lock.SHA256Tree(b.TreeDir()); err == nil {
+				entry.TreeSHA256 = sum
+			}
+		}
+		if prev := lk.FindBinary(b.Name); prev != nil && prev.From != "" {
+			return fmt.Errorf("%s: already in b.lock as a file extracted for %s; rename that one with as:", b.Name, prev.From)
+		}
+		for _, p := range lk.SetBinary(platform, entry) {
+			stale[p] = append(stale[p], b.Name)
+		}
+		if err := lockCompanions(lk, b, platform, entry); err != nil {
+			return err
+		}
 	}
 
-	return lock.WriteLock(lockDir, lk, o.bVersion)
+	// relock reads b.lock from disk, so it is written first, but b.lock
+	// goes back to how it was if relocking fails: without the platforms
+	// that didn't lock again it would cover less than before.
+	var prev *lock.Lock
+	if len(stale) > 0 {
+		if prev, err = lock.ReadLock(lockDir); err != nil {
+			return err
+		}
+	}
+	if err := lock.WriteLock(lockDir, lk, o.bVersion); err != nil {
+		return err
+	}
+	if err := o.relock(stale); err != nil {
+		if werr := lock.WriteLock(lockDir, prev, o.bVersion); werr != nil {
+			return errors.Join(err, werr)
+		}
+		return fmt.Errorf("%w; b.lock is left as it was", err)
+	}
+	return nil
+}
+
+// relock resolves binaries whose source, version or build changed again
+// for the other platforms b.lock had them locked for (stale, by platform),
+// as `b lock` does. A binary it can't lock for one of them is an error:
+// b.lock would silently stop covering that platform.
+func (o *InstallOptions) relock(stale map[string][]string) error {
+	for _, s := range slices.Sorted(maps.Keys(stale)) {
+		p, err := provider.ParsePlatform(s)
+		if err != nil {
+			return err
+		}
+		names := stale[s]
+		fmt.Fprintf(o.IO.Out, "Locking %s for %s\n", strings.Join(names, ", "), s)
+		lo := &LockOptions{SharedOptions: o.SharedOptions, Jobs: o.Jobs, jobsPerHost: o.jobsPerHost, names: names}
+		if _, err := lo.lockPlatform(p); err != nil {
+			return fmt.Errorf("locking for %s: %w", s, err)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		return err
+	}
+	var failed []string
+	for s, names := range stale {
+		for _, name := range names {
+			if entry := lk.FindBinary(name); entry == nil || entry.For(s).SHA256 == "" {
+				failed = append(failed, fmt.Sprintf("%s for %s", name, s))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("can't lock %s at the new version", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// checkExtractNames refuses files listed under extract: that would land
+// on another file of binaries, or of an entry of lk (nil for no b.lock),
+// before anything is downloaded: installing one would overwrite the other.
+func checkExtractNames(binaries []*binary.Binary, lk *lock.Lock) error {
+	owners := make(map[string]string)
+	for _, b := range binaries {
+		owners[filepath.Base(b.BinaryPath())] = "binary " + b.Name
+	}
+	for _, b := range binaries {
+		for _, e := range b.Companions() {
+			owner, ok := owners[e.Name()]
+			if !ok && lk != nil {
+				if prev := lk.FindBinary(e.Name()); prev != nil && prev.From != b.Name {
+					ok, owner = true, "binary "+prev.Name+" in b.lock"
+					if prev.From != "" {
+						owner = "file extracted for " + prev.From + " in b.lock"
+					}
+				}
+			}
+			if ok {
+				return fmt.Errorf("%s: extracted file %s is also the %s; rename it with as:", b.Name, e.Name(), owner)
+			}
+			owners[e.Name()] = "file extracted for " + b.Name
+		}
+	}
+	return nil
+}
+
+// lockCompanions records the files extracted next to b (see
+// binary.Extract) as entries of their own, linked to b's entry, so `b
+// verify` checks them and `b bundle` carries them. Entries for files no
+// longer extracted are dropped. A file named like another entry of b.lock
+// is an error: one entry would silently replace the other.
+func lockCompanions(lk *lock.Lock, b *binary.Binary, platform string, entry lock.BinEntry) error {
+	keep := make(map[string]bool)
+	for _, e := range b.Companions() {
+		hash, err := lock.SHA256File(b.ExtractPath(e))
+		if err != nil {
+			continue
+		}
+		if prev := lk.FindBinary(e.Name()); prev != nil && prev.From != entry.Name {
+			owner := "binary"
+			if prev.From != "" {
+				owner = "file extracted for " + prev.From
+			}
+			return fmt.Errorf("%s: extracted file %s is already in b.lock as a %s; rename it with as:", entry.Name, e.Name(), owner)
+		}
+		keep[e.Name()] = true
+		lk.SetBinary(platform, lock.BinEntry{
+			Name:     e.Name(),
+			Version:  entry.Version,
+			SHA256:   hash,
+			Source:   entry.Source,
+			Preset:   entry.Preset,
+			Provider: entry.Provider,
+			From:     entry.Name,
+		})
+	}
+	for _, prev := range append([]lock.BinEntry(nil), lk.Binaries...) {
+		if prev.From == entry.Name && !keep[prev.Name] {
+			lk.RemoveBinary(prev.Name)
+		}
+	}
+	return nil
 }
 
 // parseBinaryArg parses binary argument in format "name" or "name@version".
@@ -615,6 +945,7 @@ func (o *InstallOptions) syncConfigEnvs(refs []string) error {
 		result, err := env.SyncEnv(cfg, projectRoot, "", lockEntry)
 		if err != nil {
 			fmt.Fprintf(o.IO.ErrOut, "  %-40s ✗ %s\n", entry.Key, firstLine(err.Error()))
+			o.missing.add(err)
 			continue
 		}
 
@@ -0,0 +1,156 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// DefaultJobs is how many binaries install and update work on at once
+// when neither --jobs nor B_JOBS is set.
+const DefaultJobs = 8
+
+// DefaultJobsPerHost caps the binaries fetched from one host at a time
+// when B_JOBS_PER_HOST is not set, so a b.yaml full of github.com refs
+// doesn't trip its secondary rate limits.
+const DefaultJobsPerHost = 4
+
+const jobsHelp = "Binaries to download in parallel (default $B_JOBS or 8, at most $B_JOBS_PER_HOST or 4 per host)"
+
+// resolveJobs returns the --jobs value, falling back to B_JOBS and then
+// DefaultJobs when the flag is unset (0).
+func resolveJobs(flag int) (int, error) {
+	if flag < 0 {
+		return 0, fmt.Errorf("--jobs must be at least 1")
+	}
+	if flag > 0 {
+		return flag, nil
+	}
+	v := strings.TrimSpace(os.Getenv("B_JOBS"))
+	if v == "" {
+		return DefaultJobs, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("B_JOBS=%q is not a positive number", v)
+	}
+	return n, nil
+}
+
+// resolveJobsPerHost returns B_JOBS_PER_HOST, or DefaultJobsPerHost when
+// it is unset. Hosts with higher limits, a mirror or an internal registry,
+// can raise it.
+func resolveJobsPerHost() (int, error) {
+	v := strings.TrimSpace(os.Getenv("B_JOBS_PER_HOST"))
+	if v == "" {
+		return DefaultJobsPerHost, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("B_JOBS_PER_HOST=%q is not a positive number", v)
+	}
+	return n, nil
+}
+
+// jobPool bounds concurrent work overall and per host.
+type jobPool struct {
+	slots   chan struct{}
+	perHost int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newJobPool(jobs, perHost int) *jobPool {
+	if jobs < 1 {
+		jobs = DefaultJobs
+	}
+	if perHost < 1 {
+		perHost = DefaultJobsPerHost
+	}
+	return &jobPool{
+		slots:   make(chan struct{}, jobs),
+		perHost: min(jobs, perHost),
+		hosts:   make(map[string]chan struct{}),
+	}
+}
+
+// acquire blocks until a slot for host is free and returns its release.
+// An empty host is only bound by the overall limit. The host slot is taken
+// first, so work queued behind a busy host never holds an overall slot.
+func (p *jobPool) acquire(host string) (release func()) {
+	var hostSlots chan struct{}
+	if host != "" {
+		p.mu.Lock()
+		hostSlots = p.hosts[host]
+		if hostSlots == nil {
+			hostSlots = make(chan struct{}, p.perHost)
+			p.hosts[host] = hostSlots
+		}
+		p.mu.Unlock()
+		hostSlots <- struct{}{}
+	}
+	p.slots <- struct{}{}
+	return func() {
+		<-p.slots
+		if hostSlots != nil {
+			<-hostSlots
+		}
+	}
+}
+
+// binaryHost returns the host b is downloaded from, or "" when it can't
+// be told without resolving the download first.
+func binaryHost(b *binary.Binary) string {
+	if b.AutoDetect && b.ProviderRef != "" {
+		if host := refHost(b.ProviderRef); host != "" {
+			return host
+		}
+		// A bare owner/repo is GitHub's short form.
+		if p, err := provider.Detect(b.ProviderRef); err == nil && p.Name() == (&provider.GitHub{}).Name() {
+			return "github.com"
+		}
+		return ""
+	}
+	switch {
+	case b.URL != "":
+		return refHost(b.URL)
+	case b.GitHubRepo != "":
+		return "github.com"
+	}
+	return ""
+}
+
+// refHost returns the host part of a provider ref or URL: "github.com"
+// for "github.com/org/repo", the registry for "oci://ghcr.io/org/img".
+// Local refs (git:///path) and docker:// images, which the container
+// runtime pulls, have none.
+func refHost(ref string) string {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+		if u, err := url.Parse(ref); err == nil {
+			return u.Host
+		}
+		return ""
+	}
+	switch {
+	case strings.HasPrefix(ref, "docker://"):
+		// Pulled by the local container runtime.
+		return ""
+	case strings.HasPrefix(ref, "go://"):
+		return "go"
+	}
+	if i := strings.Index(ref, "://"); i >= 0 {
+		ref = ref[i+3:]
+	}
+	host, _, _ := strings.Cut(ref, "/")
+	if !strings.Contains(host, ".") && !strings.Contains(host, ":") {
+		return ""
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/env"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/state"
+)
+
+func TestResolveJobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    int
+		env     string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", want: DefaultJobs},
+		{name: "env", env: "3", want: 3},
+		{name: "flag wins over env", flag: 2, env: "3", want: 2},
+		{name: "env not a number", env: "many", wantErr: true},
+		{name: "env zero", env: "0", wantErr: true},
+		{name: "negative flag", flag: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("B_JOBS", tt.env)
+			got, err := resolveJobs(tt.flag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveJobs(%d) = %d, want %d", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveJobsPerHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    int
+		wantErr bool
+	}{
+		{name: "default", want: DefaultJobsPerHost},
+		{name: "env", env: " 16 ", want: 16},
+		{name: "env not a number", env: "many", wantErr: true},
+		{name: "env zero", env: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("B_JOBS_PER_HOST", tt.env)
+			got, err := resolveJobsPerHost()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveJobsPerHost() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryHost(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *binary.Binary
+		want string
+	}{
+		{"github ref", &binary.Binary{AutoDetect: true, ProviderRef: "github.com/derailed/k9s"}, "github.com"},
+		{"bare github ref", &binary.Binary{AutoDetect: true, ProviderRef: "derailed/k9s@v0.32.0"}, "github.com"},
+		{"gitlab ref", &binary.Binary{AutoDetect: true, ProviderRef: "GitLab.com/org/tool"}, "gitlab.com"},
+		{"oci", &binary.Binary{AutoDetect: true, ProviderRef: "oci://ghcr.io/org/img:v1"}, "ghcr.io"},
+		{"oci with port", &binary.Binary{AutoDetect: true, ProviderRef: "oci://localhost:5000/img"}, "localhost:5000"},
+		{"https", &binary.Binary{AutoDetect: true, ProviderRef: "https://dl.example.com/tool-{version}"}, "dl.example.com"},
+		{"git remote", &binary.Binary{AutoDetect: true, ProviderRef: "git://github.com/org/repo:bin/tool"}, "github.com"},
+		{"git local", &binary.Binary{AutoDetect: true, ProviderRef: "git:///srv/repo:bin/tool"}, ""},
+		{"docker", &binary.Binary{AutoDetect: true, ProviderRef: "docker://alpine"}, ""},
+		{"go", &binary.Binary{AutoDetect: true, ProviderRef: "go://golang.org/x/tools/cmd/stringer"}, "go"},
+		{"preset url", &binary.Binary{URL: "https://get.example.com/x"}, "get.example.com"},
+		{"preset github", &binary.Binary{GitHubRepo: "jqlang/jq"}, "github.com"},
+		{"preset dynamic url", &binary.Binary{Name: "x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := binaryHost(tt.b); got != tt.want {
+				t.Errorf("binaryHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobPool_Limits(t *testing.T) {
+	pool := newJobPool(3, 2)
+	var (
+		mu      sync.Mutex
+		active  int
+		peak    int
+		perHost = map[string]int{}
+		hostMax = map[string]int{}
+		wg      sync.WaitGroup
+	)
+	for i := range 12 {
+		host := []string{"a", "b", ""}[i%3]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := pool.acquire(host)
+			mu.Lock()
+			active++
+			perHost[host]++
+			peak = max(peak, active)
+			hostMax[host] = max(hostMax[host], perHost[host])
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			active--
+			perHost[host]--
+			mu.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if peak > 3 {
+		t.Errorf("%d jobs ran at once, limit is 3", peak)
+	}
+	for _, host := range []string{"a", "b"} {
+		if hostMax[host] > 2 {
+			t.Errorf("%d jobs ran against %s at once, limit is 2", hostMax[host], host)
+		}
+	}
+}
+
+// TestUpdateEnvs_ParallelEnvs: envs of different repositories sync
+// concurrently, envs of one repository one after the other, and output and
+// failures are reported in config order.
+func TestUpdateEnvs_ParallelEnvs(t *testing.T) {
+	saveHooks(t)
+	isTTYFunc = func() bool { return false }
+
+	var (
+		mu      sync.Mutex
+		running = map[string]int{}
+		overlap []string
+		active  int
+		peak    int
+	)
+	syncEnvFunc = func(cfg env.EnvConfig, projectRoot, cacheRoot string, lockEntry *lock.EnvEntry) (*env.SyncResult, error) {
+		mu.Lock()
+		running[cfg.Ref]++
+		if running[cfg.Ref] > 1 {
+			overlap = append(overlap, cfg.Ref)
+		}
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running[cfg.Ref]--
+		active--
+		mu.Unlock()
+
+		if cfg.Ref == "github.com/org/broken" {
+			return nil, errors.New("clone failed")
+		}
+		return &env.SyncResult{
+			Ref:    cfg.Ref,
+			Label:  cfg.Label,
+			Commit: "abc1234",
+			Files:  []lock.LockFile{{Path: cfg.Label + ".yaml", Dest: cfg.Ref + "/" + cfg.Label + ".yaml", Status: "replaced"}},
+		}, nil
+	}
+
+	o, out, errBuf := makeUpdateOpts(t, state.EnvEntry{Key: "github.com/org/a#one", Safety: state.SafetyAuto}, func(o *UpdateOptions) {
+		o.ParallelEnvs = true
+		o.Jobs = 4
+	})
+	o.Config.Envs = append(o.Config.Envs,
+		&state.EnvEntry{Key: "github.com/org/a#two", Safety: state.SafetyAuto},
+		&state.EnvEntry{Key: "github.com/org/broken", Safety: state.SafetyAuto},
+		&state.EnvEntry{Key: "github.com/org/b", Safety: state.SafetyAuto},
+	)
+
+	err := o.updateEnvs(nil)
+	if err == nil || !strings.Contains(err.Error(), "github.com/org/broken") {
+		t.Fatalf("err = %v, want the broken env reported", err)
+	}
+	if len(overlap) > 0 {
+		t.Errorf("envs of one repository ran concurrently: %v", overlap)
+	}
+	if peak < 2 {
+		t.Errorf("envs of different repositories did not run concurrently")
+	}
+
+	text := out.String()
+	first, second, last := strings.Index(text, "github.com/org/a#one"), strings.Index(text, "github.com/org/a#two"), strings.Index(text, "github.com/org/b ")
+	if first < 0 || second < first || last < second {
+		t.Errorf("output not in config order:\n%s", text)
+	}
+	if !strings.Contains(errBuf.String(), "clone failed") {
+		t.Errorf("stderr = %q, want the sync error", errBuf.String())
+	}
+
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lk.Envs) != 3 {
+		t.Errorf("lock has %d envs, want 3", len(lk.Envs))
+	}
+}
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fentas/goodies/templates"
+	"github.com/spf13/cobra"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// LockOptions holds options for the lock command
+type LockOptions struct {
+	*SharedOptions
+	Platforms   []string // os/arch to lock; empty is the host and those b.lock has
+	Jobs        int      // Parallel downloads; 0 means B_JOBS or DefaultJobs
+	jobsPerHost int      // B_JOBS_PER_HOST, resolved by Validate
+	names       []string // binaries to lock; empty is all of b.yaml
+	platforms   []provider.Platform
+}
+
+// NewLockCmd creates the lock subcommand
+func NewLockCmd(shared *SharedOptions) *cobra.Command {
+	o := &LockOptions{
+		SharedOptions: shared,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "lock [binary...]",
+		Short: "Lock the binaries of b.yaml for several platforms",
+		Long: templates.LongDesc(`
+			Resolve the binaries of b.yaml for each platform and record what they
+			download, sha256 included, in b.lock, so b install --locked and b verify
+			check against it on every one of them. Nothing is installed: the
+			downloads go to a temporary directory.
+
+			Without --platform, b.lock is refreshed for this platform and the ones
+			it already locks.
+		`),
+		Example: templates.Examples(`
+			# Lock for Linux CI and Apple silicon Macs
+			b lock --platform linux/amd64,darwin/arm64
+
+			# Refresh the platforms b.lock already has, for jq only
+			b lock jq
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&o.Platforms, "platform", nil, "Platforms to lock as os/arch, comma-separated (default: this one and those b.lock has)")
+	cmd.Flags().IntVarP(&o.Jobs, "jobs", "j", 0, jobsHelp)
+	return cmd
+}
+
+// Complete sets up the lock operation
+func (o *LockOptions) Complete(args []string) error {
+	if o.Config == nil {
+		return fmt.Errorf("no b.yaml configuration found")
+	}
+	o.names = args
+	return nil
+}
+
+// Validate checks if the lock operation is valid
+func (o *LockOptions) Validate() error {
+	var err error
+	if o.Jobs, err = resolveJobs(o.Jobs); err != nil {
+		return err
+	}
+	if o.jobsPerHost, err = resolveJobsPerHost(); err != nil {
+		return err
+	}
+	if offline.Enabled() {
+		return fmt.Errorf("b lock needs the network; use `b install --offline` to install what b.lock pins")
+	}
+	o.platforms = nil
+	for _, s := range o.Platforms {
+		p, err := provider.ParsePlatform(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		o.addPlatform(p)
+	}
+	configured := o.GetBinariesFromConfig()
+	for _, name := range o.names {
+		if !slices.ContainsFunc(configured, func(b *binary.Binary) bool { return b.Name == name }) {
+			return fmt.Errorf("%s is not in b.yaml", name)
+		}
+	}
+	return nil
+}
+
+// Run executes the lock operation
+func (o *LockOptions) Run() error {
+	if len(o.platforms) == 0 {
+		lk, err := lock.ReadLock(o.LockDir())
+		if err != nil {
+			return err
+		}
+		o.addPlatform(provider.HostPlatform())
+		for _, s := range lk.LockedPlatforms() {
+			if p, err := provider.ParsePlatform(s); err == nil {
+				o.addPlatform(p)
+			}
+		}
+	}
+
+	var failed []string
+	for _, p := range o.platforms {
+		fmt.Fprintf(o.IO.Out, "Locking for %s\n", p)
+		missing, err := o.lockPlatform(p)
+		if err != nil {
+			return err
+		}
+		failed = append(failed, missing...)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d binaries could not be locked:\n  %s", len(failed), strings.Join(failed, "\n  "))
+	}
+	return nil
+}
+
+// lockPlatform downloads the binaries for p into a temporary directory and
+// records them in b.lock, at the versions b.lock already pins where b.yaml
+// allows. It returns the binaries that didn't download.
+func (o *LockOptions) lockPlatform(p provider.Platform) ([]string, error) {
+	dir, err := os.MkdirTemp("", "b-lock-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	var binaries []*binary.Binary
+	for _, b := range o.GetBinariesFromConfig() {
+		if len(o.names) == 0 || slices.Contains(o.names, b.Name) {
+			binaries = append(binaries, b)
+		}
+	}
+	inst := &InstallOptions{SharedOptions: o.SharedOptions, Jobs: o.Jobs, jobsPerHost: o.jobsPerHost, Dest: dir, target: p}
+	inst.retarget(binaries)
+
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		return nil, err
+	}
+	pinFromLock(binaries, lk)
+	if err := checkExtractNames(binaries, lk); err != nil {
+		return nil, err
+	}
+	if err := inst.installBinaries(binaries); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, b := range binaries {
+		if !b.BinaryExists() {
+			missing = append(missing, fmt.Sprintf("%s for %s", b.Name, p))
+		}
+	}
+	return missing, inst.updateLock(binaries)
+}
+
+// addPlatform adds p, with the host's values filled in, unless it's there.
+func (o *LockOptions) addPlatform(p provider.Platform) {
+	p = p.Resolve()
+	if !slices.Contains(o.platforms, p) {
+		o.platforms = append(o.platforms, p)
+	}
+}
@@ -0,0 +1,507 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/provider"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// lockFixture serves a raw "tool" binary per platform, named after it, and
+// configures it in a b.yaml.
+func lockFixture(t *testing.T) (*SharedOptions, string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tool for " + r.URL.Path))
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("binaries:\n  \""+srv.URL+"/tool_{{.OS}}_{{.Arch}}@v1\": {}\n"))
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return shared, dir
+}
+
+func sumOf(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+func TestLockOptions_Run(t *testing.T) {
+	shared, dir := lockFixture(t)
+	host := provider.HostPlatform()
+	other := provider.Platform{OS: "plan9", Arch: "386"}
+
+	o := &LockOptions{SharedOptions: shared, Platforms: []string{other.String()}}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".bin")); err == nil {
+		t.Error("b lock installed into the binary path")
+	}
+
+	// Without --platform: this one, plus those already locked.
+	o = &LockOptions{SharedOptions: shared}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	lk, err := lock.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lk.FindBinary("tool")
+	if entry == nil || entry.Version != "v1" {
+		t.Fatalf("tool entry = %+v", entry)
+	}
+	for _, p := range []provider.Platform{host, other} {
+		path := "/tool_" + p.OS + "_" + p.Arch
+		got := entry.For(p.String())
+		if got.SHA256 != sumOf("tool for "+path) || !strings.HasSuffix(got.AssetURL, path) {
+			t.Errorf("%s: locked %+v, want %s", p, got, path)
+		}
+	}
+}
+
+func TestInstallOptions_RelocksPlatforms(t *testing.T) {
+	shared, dir := lockFixture(t)
+	host := provider.HostPlatform()
+	other := provider.Platform{OS: "plan9", Arch: "386"}
+	lo := &LockOptions{SharedOptions: shared, Platforms: []string{host.String(), other.String()}}
+	if err := lo.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lo.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving to v2 re-resolves every locked platform, not just this one.
+	data, err := os.ReadFile(shared.ConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, shared.ConfigPath, []byte(strings.Replace(string(data), "@v1", "@v2", 1)))
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	o := &InstallOptions{SharedOptions: shared}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	lk, err := lock.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lk.FindBinary("tool")
+	if entry == nil || entry.Version != "v2" {
+		t.Fatalf("tool entry = %+v", entry)
+	}
+	for _, p := range []provider.Platform{host, other} {
+		if got := entry.For(p.String()); got.SHA256 == "" || !strings.Contains(got.AssetURL, "/tool_"+p.OS+"_"+p.Arch) {
+			t.Errorf("%s: locked %+v", p, got)
+		}
+	}
+}
+
+func TestInstallOptions_RelockFailureKeepsLock(t *testing.T) {
+	var gone atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gone.Load() && strings.Contains(r.URL.Path, "plan9") {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("tool for " + r.URL.Path))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	mustWrite(t, configPath, []byte("binaries:\n  \""+srv.URL+"/tool_{{.OS}}_{{.Arch}}@v1\": {}\n"))
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	host := provider.HostPlatform()
+	other := provider.Platform{OS: "plan9", Arch: "386"}
+	lo := &LockOptions{SharedOptions: shared, Platforms: []string{host.String(), other.String()}}
+	if err := lo.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lo.Run(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, "b.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// v2 can't be downloaded for plan9: b.lock keeps locking v1 for both.
+	gone.Store(true)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustWrite(t, configPath, []byte(strings.Replace(string(data), "@v1", "@v2", 1)))
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	o := &InstallOptions{SharedOptions: shared}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "b.lock is left as it was") {
+		t.Fatalf("Run() error = %v, want the relock failure", err)
+	}
+	lk, err := lock.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lk.FindBinary("tool")
+	if entry == nil || entry.Version != "v1" {
+		t.Fatalf("tool entry = %+v, want v1 kept", entry)
+	}
+	for _, p := range []provider.Platform{host, other} {
+		if got := entry.For(p.String()); got.SHA256 == "" {
+			t.Errorf("%s: no longer locked, b.lock was\n%s", p, before)
+		}
+	}
+}
+
+func TestLockOptions_Validate(t *testing.T) {
+	shared, _ := lockFixture(t)
+	tests := []struct {
+		name    string
+		o       *LockOptions
+		wantErr string
+	}{
+		{name: "bad platform", o: &LockOptions{Platforms: []string{"macos/arm64"}}, wantErr: `unknown os "macos"`},
+		{name: "unknown binary", o: &LockOptions{names: []string{"jq"}}, wantErr: "jq is not in b.yaml"},
+		{name: "platforms", o: &LockOptions{Platforms: []string{"linux/amd64", " darwin/arm64", "linux/amd64"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.o.SharedOptions = shared
+			err := tt.o.Validate()
+			if tt.wantErr == "" {
+				if err != nil || len(tt.o.platforms) != 2 {
+					t.Errorf("Validate() = %v, platforms %v", err, tt.o.platforms)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallOptions_Locked(t *testing.T) {
+	shared, dir := lockFixture(t)
+	install := func() error {
+		o := &InstallOptions{SharedOptions: shared, Locked: true}
+		if err := o.Complete(nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		return o.Run()
+	}
+
+	if err := install(); err == nil || !strings.Contains(err.Error(), "tool: not in b.lock") {
+		t.Fatalf("install --locked without b.lock: %v", err)
+	}
+
+	lo := &LockOptions{SharedOptions: shared}
+	if err := lo.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := lo.Run(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, "b.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := install(); err != nil {
+		t.Fatalf("install --locked: %v", err)
+	}
+	host := provider.HostPlatform()
+	bin := filepath.Join(dir, ".bin", host.Exe("tool"))
+	if data, err := os.ReadFile(bin); err != nil || string(data) != "tool for /tool_"+host.OS+"_"+host.Arch {
+		t.Errorf("installed %q, err=%v", data, err)
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, "b.lock")); string(after) != string(before) {
+		t.Error("install --locked rewrote b.lock")
+	}
+
+	mustWrite(t, bin, []byte("tampered"))
+	if err := install(); err == nil || !strings.Contains(err.Error(), "tool: installed binary differs from b.lock") {
+		t.Errorf("install --locked over a changed binary: %v", err)
+	}
+}
+
+func TestInstallValidate_Locked(t *testing.T) {
+	o := &InstallOptions{Locked: true, Add: true}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "--locked") {
+		t.Errorf("Validate() error = %v, want --locked refused with --add", err)
+	}
+}
+
+func TestInstallOptions_GoBuild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go is a shell script")
+	}
+	// A fake go "builds" the binary from its arguments.
+	fakeGo := t.TempDir()
+	mustWrite(t, filepath.Join(fakeGo, "go"), []byte("#!/bin/sh\necho \"$@ CGO_ENABLED=$CGO_ENABLED\" > \"$GOBIN/tool\"\n"))
+	if err := os.Chmod(filepath.Join(fakeGo, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", fakeGo+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	install := func(config string) *lock.BinEntry {
+		t.Helper()
+		mustWrite(t, configPath, []byte("binaries:\n  go://example.com/org/tool@v1.2.3:\n"+config))
+		shared := NewSharedOptions(mkIO(), nil)
+		shared.ConfigPath = configPath
+		if err := shared.LoadConfig(); err != nil {
+			t.Fatal(err)
+		}
+		o := &InstallOptions{SharedOptions: shared}
+		if err := o.Complete(nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatal(err)
+		}
+		lk, err := lock.ReadLock(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lk.FindBinary("tool")
+	}
+	built := func() string {
+		data, _ := os.ReadFile(filepath.Join(dir, ".bin", "tool"))
+		return strings.TrimSpace(string(data))
+	}
+
+	entry := install("    build:\n      tags: [netgo]\n      env: { CGO_ENABLED: \"0\" }\n")
+	if got := built(); got != "install -tags netgo example.com/org/tool@v1.2.3 CGO_ENABLED=0" {
+		t.Errorf("built %q", got)
+	}
+	if entry == nil || entry.Build == nil || entry.Build.Tags[0] != "netgo" || entry.Build.Env["CGO_ENABLED"] != "0" {
+		t.Fatalf("b.lock entry = %+v", entry)
+	}
+
+	// Unchanged build options leave the binary alone; changed ones build
+	// it again.
+	mustWrite(t, filepath.Join(dir, ".bin", "tool"), []byte("kept"))
+	install("    build:\n      env: { CGO_ENABLED: \"0\" }\n      tags: [netgo]\n")
+	if got := built(); got != "kept" {
+		t.Errorf("same build options rebuilt: %q", got)
+	}
+	entry = install("    build:\n      tags: [netgo]\n      env: { CGO_ENABLED: \"1\" }\n")
+	if got := built(); got != "install -tags netgo example.com/org/tool@v1.2.3 CGO_ENABLED=1" {
+		t.Errorf("changed build options: built %q", got)
+	}
+	if entry.Build.Env["CGO_ENABLED"] != "1" {
+		t.Errorf("b.lock build = %+v", entry.Build)
+	}
+	if entry = install("    {}\n"); built() != "install example.com/org/tool@v1.2.3 CGO_ENABLED=" || entry.Build != nil {
+		t.Errorf("dropped build options: built %q, locked %+v", built(), entry.Build)
+	}
+}
+
+func TestInstallOptions_GoModuleVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go is a shell script")
+	}
+	fakeGo := t.TempDir()
+	mustWrite(t, filepath.Join(fakeGo, "go"), []byte("#!/bin/sh\necho \"$2\" > \"$GOBIN/tool\"\n"))
+	if err := os.Chmod(filepath.Join(fakeGo, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", fakeGo+string(os.PathListSeparator)+os.Getenv("PATH"))
+	proxy := t.TempDir()
+	mustMkdir(t, filepath.Join(proxy, "example.com", "tool", "@v"))
+	setVersions := func(list string) {
+		mustWrite(t, filepath.Join(proxy, "example.com", "tool", "@v", "list"), []byte(list))
+	}
+	setVersions("v1.0.0\nv1.1.0\n")
+	mustWrite(t, filepath.Join(proxy, "example.com", "tool", "@v", "main.info"), []byte(`{"Version":"v1.1.1-0.20240102150405-abcdefabcdef"}`))
+	t.Setenv("GOPROXY", "file://"+proxy)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	shared := func(key string) *SharedOptions {
+		mustWrite(t, configPath, []byte("binaries:\n  "+key+": {}\n"))
+		shared := NewSharedOptions(mkIO(), nil)
+		shared.ConfigPath = configPath
+		if err := shared.LoadConfig(); err != nil {
+			t.Fatal(err)
+		}
+		return shared
+	}
+	locked := func() lock.BinEntry {
+		lk, err := lock.ReadLock(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := lockedHere(lk, "tool")
+		if entry == nil {
+			t.Fatal("tool not in b.lock")
+		}
+		return *entry
+	}
+	built := func() string {
+		data, _ := os.ReadFile(filepath.Join(dir, ".bin", "tool"))
+		return strings.TrimSpace(string(data))
+	}
+	update := func() {
+		t.Helper()
+		o := &UpdateOptions{SharedOptions: shared("go://example.com/tool")}
+		if err := o.Complete(nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := o.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// latest is built, and locked, as the module version it stands for.
+	o := &InstallOptions{SharedOptions: shared("go://example.com/tool")}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, entry := built(), locked(); got != "example.com/tool@v1.1.0" || entry.Version != "v1.1.0" || entry.Query != "" {
+		t.Fatalf("built %q, locked %+v", got, entry)
+	}
+
+	// b update compares the locked version with the latest one.
+	update()
+	if got := built(); got != "example.com/tool@v1.1.0" {
+		t.Errorf("update without a newer version built %q", got)
+	}
+	setVersions("v1.0.0\nv1.1.0\nv1.2.0\n")
+	update()
+	if got, entry := built(), locked(); got != "example.com/tool@v1.2.0" || entry.Version != "v1.2.0" {
+		t.Errorf("update built %q, locked %+v", got, entry)
+	}
+
+	// A branch is locked as the pseudo-version it resolved to.
+	o = &InstallOptions{SharedOptions: shared("go://example.com/tool@main")}
+	o.Force = true
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if got, entry := built(), locked(); got != "example.com/tool@v1.1.1-0.20240102150405-abcdefabcdef" || entry.Version != "v1.1.1-0.20240102150405-abcdefabcdef" || entry.Query != "main" {
+		t.Errorf("built %q, locked %+v", got, entry)
+	}
+}
+
+func TestInstallOptions_OCIArtifact(t *testing.T) {
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	repo := strings.TrimPrefix(srv.URL, "http://") + "/org/tool"
+	host := provider.HostPlatform()
+	img := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), "application/vnd.example.tool.v1")
+	img, err := mutate.Append(img, mutate.Addendum{
+		Layer:       static.NewLayer([]byte("tool-binary"), "application/octet-stream"),
+		Annotations: map[string]string{"org.opencontainers.image.title": "tool-" + host.OS + "-" + host.Arch},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(repo + ":v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("pushing artifact: %v", err)
+	}
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	mustWrite(t, configPath, []byte("binaries:\n  oci://"+repo+"@v1:\n"))
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	o := &InstallOptions{SharedOptions: shared}
+	if err := o.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".bin", "tool")); string(data) != "tool-binary" {
+		t.Errorf("installed %q", data)
+	}
+	lk, err := lock.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := lk.FindBinary("tool")
+	want := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("tool-binary")))
+	if entry == nil || entry.Digest != want || "sha256:"+entry.For(host.String()).SHA256 != want {
+		t.Errorf("b.lock entry = %+v, want digest %s", entry, want)
+	}
+}
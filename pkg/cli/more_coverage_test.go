@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -8,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/provider"
 )
 
 // --- request.go ---
@@ -241,6 +243,40 @@ func TestVerifyRun_WithEntries(t *testing.T) {
 	}
 }
 
+// TestVerifyRun_OtherPlatform checks that verify compares against the
+// entry for this platform and flags binaries only locked for others.
+func TestVerifyRun_OtherPlatform(t *testing.T) {
+	dir := t.TempDir()
+	binDir := filepath.Join(dir, ".bin")
+	t.Setenv("PATH_BIN", binDir)
+	mustMkdir(t, binDir)
+	mustWrite(t, filepath.Join(binDir, "jq"), []byte("jq for this platform"))
+	hash, err := lock.SHA256File(filepath.Join(binDir, "jq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lk := &lock.Lock{}
+	lk.SetBinary("plan9/386", lock.BinEntry{Name: "jq", Version: "v1", SHA256: "plan9"})
+	lk.SetBinary(provider.HostPlatform().String(), lock.BinEntry{Name: "jq", Version: "v1", SHA256: hash})
+	lk.SetBinary("plan9/386", lock.BinEntry{Name: "yq", Version: "v1", SHA256: "plan9"})
+	if err := lock.WriteLock(dir, lk, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = filepath.Join(dir, "b.yaml")
+	out := &bytes.Buffer{}
+	shared.IO.Out = out
+	o := &VerifyOptions{SharedOptions: shared}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "1 artifact(s)") {
+		t.Errorf("Run() error = %v, want yq reported", err)
+	}
+	if !strings.Contains(out.String(), "yq") || !strings.Contains(out.String(), "not locked for "+provider.HostPlatform().String()) {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
 // TestVerifyRun_DefaultBinLayout_FindsEnvFiles is the regression for the default
 // `.bin/b.yaml` layout: lockDir is <root>/.bin but env files live at the project
 // root (<root>/<dest>). verify must resolve dests against the project root — not
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// pinFromLock points binaries at what b.lock recorded, so an offline
+// install resolves nothing remotely: the locked version, the sha256 of the
+// locked binary and of the files extracted with it (a bundle imports
+// binaries by it), and for release and http(s) refs the locked asset,
+// which the download cache holds under its URL and sha256 — all as locked
+// for the binary's platform; a go:// branch or commit pins the module
+// version it was resolved to. A binary whose b.yaml version or build
+// options no longer match the lock is left alone and reported missing by
+// its download.
+func pinFromLock(binaries []*binary.Binary, lk *lock.Lock) {
+	for _, b := range binaries {
+		entry := lk.FindBinary(b.Name)
+		if entry == nil || entry.Version == "" {
+			continue
+		}
+		if b.AutoDetect && entry.Source != b.ProviderRef || !entry.Build.Equal(b.Build) {
+			continue
+		}
+		switch {
+		case b.Version == entry.Version:
+		case b.Version == "" && (b.Constraint == "" || binary.Satisfies(b.Constraint, entry.Version)),
+			b.Version != "" && b.Version == entry.Query:
+			b.Version, b.Query = entry.Version, entry.Query
+		default:
+			continue
+		}
+		platform := b.Platform.String()
+		locked := entry.For(platform)
+		if b.AutoDetect && locked.AssetURL != "" && b.ResolvedAsset == nil {
+			b.ResolvedAsset = &provider.Asset{Name: locked.Asset, URL: locked.AssetURL}
+		}
+		b.LockedAssetSHA256 = locked.AssetSHA256
+		b.LockedSHA256 = locked.SHA256
+		for _, e := range lk.Binaries {
+			if sum := e.For(platform).SHA256; e.From == b.Name && sum != "" {
+				if b.LockedExtract == nil {
+					b.LockedExtract = make(map[string]string)
+				}
+				b.LockedExtract[e.Name] = sum
+			}
+		}
+	}
+}
+
+// requireLocked reports the binaries pinFromLock couldn't pin, for
+// `b install --locked`: they aren't in lk, b.yaml asks for another
+// version or build, or nothing is locked for their platform. It marks the
+// others Locked.
+func requireLocked(binaries []*binary.Binary, lk *lock.Lock) error {
+	var problems []string
+	for _, b := range binaries {
+		entry := lk.FindBinary(b.Name)
+		switch {
+		case entry == nil:
+			problems = append(problems, b.Name+": not in b.lock")
+		case b.AutoDetect && entry.Source != b.ProviderRef:
+			problems = append(problems, fmt.Sprintf("%s: b.lock pins it from %s", b.Name, entry.Source))
+		case !entry.Build.Equal(b.Build):
+			problems = append(problems, b.Name+": b.yaml builds it with other build options than b.lock pins")
+		case b.Version != entry.Version:
+			want := b.Version
+			if want == "" {
+				want = b.Constraint
+			}
+			problems = append(problems, fmt.Sprintf("%s: b.yaml asks for %s, b.lock pins %s", b.Name, want, entry.Version))
+		case b.LockedSHA256 == "":
+			problems = append(problems, fmt.Sprintf("%s: not locked for %s (run `b lock --platform %s`)", b.Name, b.Platform, b.Platform))
+		default:
+			b.Locked = true
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("b.lock doesn't pin everything to install:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// checkLocked reports installed binaries, and files extracted with them,
+// whose sha256 isn't the one b.lock pins. It catches what the locked asset
+// digest can't: builds, images, and binaries installed before.
+func checkLocked(binaries []*binary.Binary) error {
+	var problems []string
+	check := func(name, file, want string) {
+		sum, err := lock.SHA256File(file)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		case sum != want:
+			problems = append(problems, name+": installed binary differs from b.lock")
+		}
+	}
+	for _, b := range binaries {
+		check(b.Name, b.BinaryPath(), b.LockedSHA256)
+		for _, e := range b.Companions() {
+			if want, ok := b.LockedExtract[e.Name()]; ok {
+				check(e.Name(), b.ExtractPath(e), want)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("--locked: %d artifact(s) differ from b.lock (reinstall with --force):\n  %s", len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// missingArtifacts collects what an offline run couldn't find locally.
+type missingArtifacts struct {
+	mu   sync.Mutex
+	errs []*offline.Error
+}
+
+// add records err if it reports a missing artifact and says whether it did.
+func (m *missingArtifacts) add(err error) bool {
+	var oe *offline.Error
+	if !errors.As(err, &oe) {
+		return false
+	}
+	m.mu.Lock()
+	m.errs = append(m.errs, oe)
+	m.mu.Unlock()
+	return true
+}
+
+// err lists the missing artifacts, or returns nil when nothing was.
+func (m *missingArtifacts) err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.errs) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "offline: %d artifact(s) not available locally:", len(m.errs))
+	for _, e := range m.errs {
+		fmt.Fprintf(&sb, "\n  %-40s %s", e.Artifact, e.Reason)
+	}
+	sb.WriteString("\n  Run `b install` once with network access to fill the caches")
+	return errors.New(sb.String())
+}
@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+)
+
+func TestPinFromLock(t *testing.T) {
+	lk := &lock.Lock{Binaries: []lock.BinEntry{
+		{Name: "tool", Version: "v1.2.0", Source: "github.com/org/tool", Asset: "tool-linux-amd64", AssetURL: "https://github.com/org/tool/releases/download/v1.2.0/tool-linux-amd64", AssetSHA256: "abc", SHA256: "bin-abc"},
+		{Name: "jq", Version: "jq-1.7.1", Source: "jq", Preset: true, AssetSHA256: "def", SHA256: "bin-def"},
+		{Name: "gotool", Version: "v0.0.0-20240102150405-abcdefabcdef", Query: "main", Source: "go://example.com/gotool"},
+	}}
+	tests := []struct {
+		name        string
+		bin         *binary.Binary
+		wantVersion string
+		wantAsset   string
+		wantSHA256  string
+	}{
+		{
+			name:        "unpinned takes the lock",
+			bin:         &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/org/tool"},
+			wantVersion: "v1.2.0",
+			wantAsset:   "tool-linux-amd64",
+			wantSHA256:  "abc",
+		},
+		{
+			name:        "same version",
+			bin:         &binary.Binary{Name: "tool", Version: "v1.2.0", AutoDetect: true, ProviderRef: "github.com/org/tool"},
+			wantVersion: "v1.2.0",
+			wantAsset:   "tool-linux-amd64",
+			wantSHA256:  "abc",
+		},
+		{
+			name:        "constraint satisfied",
+			bin:         &binary.Binary{Name: "tool", Constraint: "^1.0", AutoDetect: true, ProviderRef: "github.com/org/tool"},
+			wantVersion: "v1.2.0",
+			wantAsset:   "tool-linux-amd64",
+			wantSHA256:  "abc",
+		},
+		{
+			name: "constraint not satisfied",
+			bin:  &binary.Binary{Name: "tool", Constraint: "^2.0", AutoDetect: true, ProviderRef: "github.com/org/tool"},
+		},
+		{
+			name:        "other version",
+			bin:         &binary.Binary{Name: "tool", Version: "v1.3.0", AutoDetect: true, ProviderRef: "github.com/org/tool"},
+			wantVersion: "v1.3.0",
+		},
+		{
+			name: "other source",
+			bin:  &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/fork/tool"},
+		},
+		{
+			name:        "preset",
+			bin:         &binary.Binary{Name: "jq"},
+			wantVersion: "jq-1.7.1",
+			wantSHA256:  "def",
+		},
+		{
+			name:        "go branch",
+			bin:         &binary.Binary{Name: "gotool", Version: "main", AutoDetect: true, ProviderRef: "go://example.com/gotool"},
+			wantVersion: "v0.0.0-20240102150405-abcdefabcdef",
+		},
+		{
+			name: "not locked",
+			bin:  &binary.Binary{Name: "kubectl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinFromLock([]*binary.Binary{tt.bin}, lk)
+			if tt.bin.Version != tt.wantVersion {
+				t.Errorf("Version = %q, want %q", tt.bin.Version, tt.wantVersion)
+			}
+			var asset string
+			if tt.bin.ResolvedAsset != nil {
+				asset = tt.bin.ResolvedAsset.Name
+			}
+			if asset != tt.wantAsset {
+				t.Errorf("ResolvedAsset = %q, want %q", asset, tt.wantAsset)
+			}
+			if tt.bin.LockedAssetSHA256 != tt.wantSHA256 {
+				t.Errorf("LockedAssetSHA256 = %q, want %q", tt.bin.LockedAssetSHA256, tt.wantSHA256)
+			}
+			// Pinned binaries carry the lock's binary digest too.
+			wantLocked := ""
+			if tt.wantSHA256 != "" {
+				wantLocked = "bin-" + tt.wantSHA256
+			}
+			if tt.bin.LockedSHA256 != wantLocked {
+				t.Errorf("LockedSHA256 = %q, want %q", tt.bin.LockedSHA256, wantLocked)
+			}
+		})
+	}
+}
+
+func TestPinFromLock_Companions(t *testing.T) {
+	lk := &lock.Lock{Binaries: []lock.BinEntry{
+		{Name: "cilium", Version: "v0.16.0", SHA256: "bin-cilium"},
+		{Name: "hubble", Version: "v0.16.0", SHA256: "bin-hubble", From: "cilium"},
+		{Name: "kubectl", Version: "v1.31.0", SHA256: "bin-kubectl"},
+	}}
+	cilium := &binary.Binary{Name: "cilium", Extract: []binary.Extract{{Member: "hubble"}}}
+	kubectl := &binary.Binary{Name: "kubectl"}
+	pinFromLock([]*binary.Binary{cilium, kubectl}, lk)
+	if got := cilium.LockedExtract["hubble"]; got != "bin-hubble" {
+		t.Errorf("LockedExtract[hubble] = %q, want bin-hubble", got)
+	}
+	if kubectl.LockedExtract != nil {
+		t.Errorf("kubectl LockedExtract = %v, want none", kubectl.LockedExtract)
+	}
+}
+
+func TestPinFromLock_Platform(t *testing.T) {
+	lk := &lock.Lock{}
+	lk.SetBinary("darwin/arm64", lock.BinEntry{Name: "tool", Version: "v1", Source: "github.com/org/tool", Asset: "tool-darwin-arm64", AssetURL: "https://x/tool-darwin-arm64", SHA256: "bin-darwin"})
+	lk.SetBinary("linux/amd64", lock.BinEntry{Name: "tool", Version: "v1", Source: "github.com/org/tool", Asset: "tool-linux-amd64", AssetURL: "https://x/tool-linux-amd64", SHA256: "bin-linux"})
+
+	mac := &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/org/tool", Platform: provider.Platform{OS: "darwin", Arch: "arm64"}}
+	win := &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/org/tool", Platform: provider.Platform{OS: "windows", Arch: "amd64"}}
+	pinFromLock([]*binary.Binary{mac, win}, lk)
+	if mac.ResolvedAsset == nil || mac.ResolvedAsset.Name != "tool-darwin-arm64" || mac.LockedSHA256 != "bin-darwin" {
+		t.Errorf("darwin/arm64 pinned to %+v, %q", mac.ResolvedAsset, mac.LockedSHA256)
+	}
+	if win.Version != "v1" || win.ResolvedAsset != nil || win.LockedSHA256 != "" {
+		t.Errorf("windows/amd64 pinned to %q %+v %q, want the version only", win.Version, win.ResolvedAsset, win.LockedSHA256)
+	}
+}
+
+func TestRequireLocked(t *testing.T) {
+	host := provider.HostPlatform().String()
+	lk := &lock.Lock{}
+	lk.SetBinary(host, lock.BinEntry{Name: "tool", Version: "v1", Source: "github.com/org/tool", SHA256: "bin"})
+	tests := []struct {
+		name    string
+		bin     *binary.Binary
+		wantErr string
+	}{
+		{name: "locked", bin: &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/org/tool"}},
+		{name: "not in lock", bin: &binary.Binary{Name: "jq"}, wantErr: "jq: not in b.lock"},
+		{name: "other source", bin: &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/fork/tool"}, wantErr: "pins it from github.com/org/tool"},
+		{name: "other version", bin: &binary.Binary{Name: "tool", Constraint: "^2", AutoDetect: true, ProviderRef: "github.com/org/tool"}, wantErr: "b.yaml asks for ^2, b.lock pins v1"},
+		{name: "other build", bin: &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/org/tool", Build: &provider.GoBuild{Tags: []string{"netgo"}}}, wantErr: "tool: b.yaml builds it with other build options"},
+		{name: "other platform", bin: &binary.Binary{Name: "tool", AutoDetect: true, ProviderRef: "github.com/org/tool", Platform: provider.Platform{OS: "plan9", Arch: "386"}}, wantErr: "not locked for plan9/386"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinFromLock([]*binary.Binary{tt.bin}, lk)
+			err := requireLocked([]*binary.Binary{tt.bin}, lk)
+			if tt.wantErr == "" {
+				if err != nil || !tt.bin.Locked {
+					t.Errorf("requireLocked() = %v, Locked = %v", err, tt.bin.Locked)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("requireLocked() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingArtifacts(t *testing.T) {
+	var m missingArtifacts
+	if m.err() != nil {
+		t.Fatal("err() with nothing missing")
+	}
+	if m.add(fmt.Errorf("plain failure")) {
+		t.Error("add() took an error that isn't an offline one")
+	}
+	m.add(fmt.Errorf("installing: %w", offline.Missing("kubectl@v1.31.0", "kubectl is not in the download cache")))
+	m.add(offline.Missing("github.com/org/infra@v2", "not in b.lock or the git cache"))
+
+	err := m.err()
+	if err == nil {
+		t.Fatal("err() = nil, want the missing artifacts")
+	}
+	for _, want := range []string{
+		"offline: 2 artifact(s) not available locally:",
+		"kubectl@v1.31.0",
+		"github.com/org/infra@v2",
+		"not in b.lock or the git cache",
+		"with network access",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("err() = %q, missing %q", err, want)
+		}
+	}
+}
+
+func TestUpdateOptions_Validate_Offline(t *testing.T) {
+	offline.Set(true)
+	t.Cleanup(func() { offline.Set(false) })
+	o := &UpdateOptions{}
+	if err := o.Validate(); err == nil || !strings.Contains(err.Error(), "b install --offline") {
+		t.Errorf("Validate() = %v, want a refusal pointing at b install --offline", err)
+	}
+}
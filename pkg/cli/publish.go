@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fentas/goodies/templates"
+	"github.com/spf13/cobra"
+
+	"github.com/fentas/b/pkg/offline"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// PublishOptions holds options for the publish command
+type PublishOptions struct {
+	*SharedOptions
+	// Files and Platforms pair up in order: Files[i] is the binary for
+	// Platforms[i].
+	Files     []string
+	Platforms []string
+}
+
+// NewPublishCmd creates the publish subcommand
+func NewPublishCmd(shared *SharedOptions) *cobra.Command {
+	o := &PublishOptions{
+		SharedOptions: shared,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "publish oci://<registry>/<repo>@<tag>",
+		Short: "Push binaries to an OCI registry for b install",
+		Long: templates.LongDesc(`
+			Push a binary per platform to an OCI registry as an artifact: an
+			index with one manifest per platform, each holding its binary as
+			the only layer. b install oci://... installs the one for its
+			platform. Credentials come from ~/.docker/config.json, as for
+			docker login.
+		`),
+		Example: templates.Examples(`
+			# Publish a release for two platforms
+			b publish oci://ghcr.io/org/tool@v1.2.0 \
+			  --file dist/tool-linux-amd64 --platform linux/amd64 \
+			  --file dist/tool-darwin-arm64 --platform darwin/arm64
+
+			# Install it
+			b install oci://ghcr.io/org/tool@v1.2.0
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Validate(args[0]); err != nil {
+				return err
+			}
+			return o.Run(args[0])
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&o.Files, "file", nil, "Binary to publish, once per platform")
+	cmd.Flags().StringArrayVar(&o.Platforms, "platform", nil, "Platform of the --file at the same position, as os/arch")
+
+	return cmd
+}
+
+// Validate checks the ref and that every file has a platform.
+func (o *PublishOptions) Validate(ref string) error {
+	if offline.Enabled() {
+		return fmt.Errorf("b publish needs the network")
+	}
+	if !strings.HasPrefix(ref, "oci://") {
+		return fmt.Errorf("%s: b publish pushes to oci:// refs", ref)
+	}
+	if len(o.Files) == 0 {
+		return fmt.Errorf("nothing to publish; pass --file and --platform")
+	}
+	if len(o.Files) != len(o.Platforms) {
+		return fmt.Errorf("got %d --file and %d --platform, want one --platform per --file", len(o.Files), len(o.Platforms))
+	}
+	return nil
+}
+
+// Run pushes the files to ref.
+func (o *PublishOptions) Run(ref string) error {
+	files := make([]provider.ArtifactFile, len(o.Files))
+	platforms := make([]string, len(o.Files))
+	for i, file := range o.Files {
+		p, err := provider.ParsePlatform(o.Platforms[i])
+		if err != nil {
+			return fmt.Errorf("--platform %s: %w", o.Platforms[i], err)
+		}
+		files[i] = provider.ArtifactFile{Platform: p, Path: file}
+		platforms[i] = p.String()
+	}
+	digest, err := (&provider.OCI{}).Publish(ref, "", files)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(o.IO.Out, "Published %s (%s) for %s\n", ref, digest, strings.Join(platforms, ", "))
+	return nil
+}
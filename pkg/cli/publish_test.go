@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/provider"
+)
+
+func TestPublishOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		ref       string
+		files     []string
+		platforms []string
+		wantErr   string
+	}{
+		{name: "ok", ref: "oci://ghcr.io/org/tool@v1", files: []string{"a", "b"}, platforms: []string{"linux/amd64", "darwin/arm64"}},
+		{name: "not oci", ref: "docker://org/tool@v1", files: []string{"a"}, platforms: []string{"linux/amd64"}, wantErr: "pushes to oci:// refs"},
+		{name: "no files", ref: "oci://ghcr.io/org/tool@v1", wantErr: "nothing to publish"},
+		{name: "platform missing", ref: "oci://ghcr.io/org/tool@v1", files: []string{"a", "b"}, platforms: []string{"linux/amd64"}, wantErr: "got 2 --file and 1 --platform"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &PublishOptions{SharedOptions: NewSharedOptions(mkIO(), nil), Files: tt.files, Platforms: tt.platforms}
+			err := o.Validate(tt.ref)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublishOptions_Run(t *testing.T) {
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	ref := "oci://" + strings.TrimPrefix(srv.URL, "http://") + "/org/tool@v1.2.0"
+
+	host := provider.HostPlatform()
+	other := provider.Platform{OS: "plan9", Arch: "386"}
+	dist := t.TempDir()
+	mustWrite(t, filepath.Join(dist, "tool-host"), []byte("host build"))
+	mustWrite(t, filepath.Join(dist, "tool-plan9-386"), []byte("plan9 build"))
+
+	io := mkIO()
+	o := &PublishOptions{
+		SharedOptions: NewSharedOptions(io, nil),
+		Files:         []string{filepath.Join(dist, "tool-host"), filepath.Join(dist, "tool-plan9-386")},
+		Platforms:     []string{host.String(), other.String()},
+	}
+	if err := o.Run(ref); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out := io.Out.(*bytes.Buffer).String(); !strings.Contains(out, "Published "+ref+" (sha256:") {
+		t.Errorf("output = %q", out)
+	}
+
+	// What was published installs with b install, for each platform.
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "b.yaml")
+	t.Setenv("PATH_BIN", filepath.Join(dir, ".bin"))
+	mustWrite(t, configPath, []byte("binaries:\n  "+ref+":\n"))
+	shared := NewSharedOptions(mkIO(), nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	install := &InstallOptions{SharedOptions: shared}
+	if err := install.Complete(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := install.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".bin", "tool")); string(data) != "host build" {
+		t.Errorf("installed %q", data)
+	}
+	lk, err := lock.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := lk.FindBinary("tool"); entry == nil || entry.Digest != "sha256:"+entry.For(host.String()).SHA256 {
+		t.Errorf("b.lock entry = %+v, want the layer digest", entry)
+	}
+
+	dest := t.TempDir()
+	path, err := (&provider.OCI{}).Install(ref, "", dest, other, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "plan9 build" {
+		t.Errorf("plan9/386 installed %q", data)
+	}
+}
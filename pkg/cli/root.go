@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/fentas/goodies/output"
 	"github.com/fentas/goodies/streams"
 	"github.com/spf13/cobra"
 
 	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/httpclient"
 )
 
 // wantsVersionBanner reports whether THIS invocation asked for b's own version
@@ -35,6 +38,7 @@ func wantsVersionBanner(cmd *cobra.Command) bool {
 func NewRootCmd(binaries []*binary.Binary, io *streams.IO, version, versionPreRelease string) *cobra.Command {
 	shared := NewSharedOptions(io, binaries)
 	shared.bVersion = version
+	httpclient.DefaultUserAgent = "b/" + strings.TrimPrefix(version, "v")
 
 	cmd := &cobra.Command{
 		Use:   "b",
@@ -74,6 +78,7 @@ func NewRootCmd(binaries []*binary.Binary, io *streams.IO, version, versionPreRe
 	cmd.PersistentFlags().StringVarP(&shared.ConfigPath, "config", "c", "", configHelp)
 	cmd.PersistentFlags().BoolVar(&shared.Force, "force", false, "Force operations, overwriting existing binaries")
 	cmd.PersistentFlags().BoolVarP(&shared.Quiet, "quiet", "q", false, "Quiet mode")
+	cmd.PersistentFlags().BoolVar(&shared.Offline, "offline", false, "Install only from b.lock and the local caches, never touching the network (or set B_OFFLINE=1)")
 	cmd.PersistentFlags().BoolP("version", "v", false, "Print version information and quit")
 
 	// Add output format flag
@@ -86,9 +91,13 @@ func NewRootCmd(binaries []*binary.Binary, io *streams.IO, version, versionPreRe
 	cmd.AddCommand(NewSearchCmd(shared))
 	cmd.AddCommand(NewInitCmd(shared))
 	cmd.AddCommand(NewVersionCmd(shared))
+	cmd.AddCommand(NewVersionsCmd(shared))
 	cmd.AddCommand(NewRequestCmd(shared))
 	cmd.AddCommand(NewVerifyCmd(shared))
+	cmd.AddCommand(NewLockCmd(shared))
 	cmd.AddCommand(NewCacheCmd(shared))
+	cmd.AddCommand(NewBundleCmd(shared))
+	cmd.AddCommand(NewPublishCmd(shared))
 	cmd.AddCommand(NewEnvCmd(shared))
 
 	// Set custom usage template to show aliases in command list
@@ -125,5 +134,7 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 // Execute runs the root command
 func Execute(binaries []*binary.Binary, io *streams.IO, version, versionPreRelease string) error {
 	root := NewRootCmd(binaries, io, version, versionPreRelease)
+	// Share downloads between projects; left off for library use and tests.
+	blobcache.SetRoot(blobcache.DefaultRoot())
 	return root.Execute()
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/fentas/goodies/templates"
 	"github.com/spf13/cobra"
 
+	"github.com/fentas/b/pkg/aqua"
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/provider"
 )
@@ -33,6 +34,10 @@ In addition to pre-packaged binaries, you can install any GitHub/GitLab release:
   b install github.com/org/repo
   b install gitlab.com/org/repo
 
+Packages of the aqua registries listed under aqua: in b.yaml (or in
+$B_AQUA_REGISTRY) are listed as aqua:<package> refs:
+  b install aqua:cli/cli
+
 Or sync environment files from any git repository:
   b install github.com/org/repo:/path/** dest`,
 		Example: templates.Examples(`
@@ -72,6 +77,21 @@ func (o *SearchOptions) Validate() error {
 	return nil
 }
 
+// matchesPackage reports whether the query is in one of p's names, its
+// command or its description.
+func (o *SearchOptions) matchesPackage(p *aqua.Package) bool {
+	if o.Query == "" {
+		return true
+	}
+	query := strings.ToLower(o.Query)
+	for _, s := range append(p.Names(), p.Command(), p.Description) {
+		if strings.Contains(strings.ToLower(s), query) {
+			return true
+		}
+	}
+	return false
+}
+
 // Run executes the search operation
 func (o *SearchOptions) Run() error {
 	var results []*binary.Binary
@@ -83,6 +103,19 @@ func (o *SearchOptions) Run() error {
 		}
 	}
 
+	// Packages of the configured aqua registries, as aqua: refs
+	if len(o.aquaRegistries()) > 0 {
+		pkgs, err := o.aquaIndex().Packages()
+		if err != nil {
+			return err
+		}
+		for _, p := range pkgs {
+			if o.matchesPackage(p) {
+				results = append(results, &binary.Binary{Name: aqua.Prefix + p.FullName(), GitHubRepo: p.Repo()})
+			}
+		}
+	}
+
 	if err := o.IO.Print(results); err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -9,7 +10,13 @@ import (
 	"sync"
 
 	"github.com/fatih/color"
+	"github.com/fentas/b/pkg/aqua"
+	"github.com/fentas/b/pkg/binaries"
 	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
 	"github.com/fentas/b/pkg/path"
 	"github.com/fentas/b/pkg/provider"
 	"github.com/fentas/b/pkg/state"
@@ -26,12 +33,14 @@ type SharedOptions struct {
 	ConfigPath string
 	Force      bool
 	Quiet      bool
+	Offline    bool
 	Output     string
 
 	// Internal
 	bVersion         string // version of b itself, for lock metadata
 	lookup           map[string]*binary.Binary
-	loadedConfigPath string // path where config was actually loaded from
+	loadedConfigPath string      // path where config was actually loaded from
+	aqua             *aqua.Index // see aquaIndex
 }
 
 // NewSharedOptions creates a new SharedOptions with default values
@@ -72,8 +81,101 @@ func (o *SharedOptions) LoadConfig() error {
 		}
 		o.Config, err = state.LoadConfig()
 	}
+	if err != nil {
+		return err
+	}
+
+	var configured map[string]*hosts.Host
+	var network *httpclient.Config
+	if o.Config != nil {
+		configured = o.Config.Hosts
+		network = o.Config.Network
+	}
+	if err := hosts.Set(configured); err != nil {
+		return err
+	}
+	if err := httpclient.Set(network, o.LockDir()); err != nil {
+		return err
+	}
+	fromEnv, err := offline.FromEnv()
+	if err != nil {
+		return err
+	}
+	offline.Set(o.Offline || fromEnv)
+
+	o.aqua = nil // opened again for the registries of this b.yaml
+	return o.loadPresets()
+}
+
+// loadPresets adds the user presets listed under `presets:` in b.yaml and
+// in $B_PRESETS to the known binaries, so they show up in `b search` and
+// resolve by name. $B_PRESETS wins over b.yaml, and both win over a
+// built-in preset of the same name.
+func (o *SharedOptions) loadPresets() error {
+	var presets []*binaries.Preset
+	if o.Config != nil && len(o.Config.Presets) > 0 {
+		var err error
+		if presets, err = binaries.LoadPaths(o.LockDir(), o.Config.Presets); err != nil {
+			return err
+		}
+	}
+	fromEnv, err := binaries.LoadPaths("", binaries.EnvPaths())
+	if err != nil {
+		return fmt.Errorf("%s: %w", binaries.PresetsEnv, err)
+	}
+	presets = binaries.Merge(presets, fromEnv)
+
+	options := &binaries.BinaryOptions{Context: context.Background()}
+	for _, b := range binaries.Binaries(options, presets) {
+		o.addBinary(b)
+	}
+	return nil
+}
+
+// aquaRegistries returns the aqua registries listed under `aqua:` in
+// b.yaml and in $B_AQUA_REGISTRY, the latter winning.
+func (o *SharedOptions) aquaRegistries() []string {
+	var refs []string
+	if o.Config != nil {
+		refs = append(refs, o.Config.Aqua...)
+	}
+	for _, ref := range aqua.EnvPaths() {
+		// Relative to the working directory, not b.yaml
+		if strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") {
+			if abs, err := filepath.Abs(ref); err == nil {
+				ref = abs
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
 
-	return err
+// aquaIndex returns the index aqua: refs resolve in: the configured
+// registries, or aqua.DefaultRegistry.
+func (o *SharedOptions) aquaIndex() *aqua.Index {
+	if o.aqua == nil {
+		refs := o.aquaRegistries()
+		if len(refs) == 0 {
+			refs = []string{aqua.DefaultRegistry}
+		}
+		o.aqua = aqua.NewIndex(o.LockDir(), refs)
+	}
+	return o.aqua
+}
+
+// addBinary registers b, replacing a known binary of the same name.
+func (o *SharedOptions) addBinary(b *binary.Binary) {
+	if _, ok := o.lookup[b.Name]; ok {
+		for i, existing := range o.Binaries {
+			if existing.Name == b.Name {
+				o.Binaries[i] = b
+			}
+		}
+	} else {
+		o.Binaries = append(o.Binaries, b)
+	}
+	o.lookup[b.Name] = b
 }
 
 // resolveBinary resolves a binary from config, handling references
@@ -97,10 +199,10 @@ func (o *SharedOptions) resolveBinary(lb *binary.LocalBinary) (*binary.Binary, b
 	if ok {
 		// Apply config overrides
 		if lb.Version != "" {
-			b.Version = lb.Version
+			b.SetVersion(lb.Version)
 		}
 		if lb.Enforced != "" {
-			b.Version = lb.Enforced
+			b.SetVersion(lb.Enforced)
 		}
 		if lb.File != "" {
 			b.File = lb.File
@@ -108,11 +210,51 @@ func (o *SharedOptions) resolveBinary(lb *binary.LocalBinary) (*binary.Binary, b
 		if lb.OnPost != "" {
 			b.OnPost = lb.OnPost
 		}
+		if lb.Verify != nil {
+			b.Verify = lb.Verify.Resolve(o.LockDir())
+		}
+		if lb.Channel != "" {
+			b.Channel = provider.Channel(lb.Channel)
+		}
+		if len(lb.Extract) > 0 {
+			b.Extract = lb.Extract
+		}
+		if lb.Layout != "" {
+			b.Layout = lb.Layout
+			b.Entrypoint = lb.Entrypoint
+		}
+		if lb.Build != nil {
+			b.Build = lb.Build
+		}
+		if lb.Runtime != "" {
+			b.Runtime = lb.Runtime
+		}
+		if len(lb.Paths) > 0 {
+			b.Paths = lb.Paths
+		}
 	}
 
 	return b, ok
 }
 
+// lockedVersion returns the version b.lock records for b while the
+// installed binary is the one it locked.
+func (o *SharedOptions) lockedVersion(b *binary.Binary) (string, error) {
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		return "", err
+	}
+	entry := lk.FindBinary(b.Name)
+	if entry == nil || entry.Source != b.ProviderRef {
+		return "", fmt.Errorf("%s: not in b.lock", b.Name)
+	}
+	locked := entry.For(b.Platform.String())
+	if sum, err := lock.SHA256File(b.BinaryPath()); err != nil || sum != locked.SHA256 {
+		return "", fmt.Errorf("%s: installed binary differs from b.lock", b.Name)
+	}
+	return locked.Version, nil
+}
+
 // GetBinary returns a binary by name or provider ref.
 func (o *SharedOptions) GetBinary(name string) (*binary.Binary, bool) {
 	// First try direct lookup (preset)
@@ -137,27 +279,46 @@ func (o *SharedOptions) GetBinary(name string) (*binary.Binary, bool) {
 	// Check if this is a provider ref (e.g. github.com/derailed/k9s)
 	if provider.IsProviderRef(name) {
 		ref, version := provider.ParseRef(name)
-		p, err := provider.Detect(ref)
-		if err != nil {
-			return nil, false
-		}
-		b := &binary.Binary{
-			Name:         provider.BinaryName(ref),
-			Version:      version,
-			AutoDetect:   true,
-			ProviderRef:  ref,
-			ProviderType: p.Name(),
-			VersionF: func(b *binary.Binary) (string, error) {
-				return p.LatestVersion(ref)
-			},
+		var b *binary.Binary
+		if aqua.IsRef(ref) {
+			pkg, err := o.aquaIndex().Find(strings.TrimPrefix(ref, aqua.Prefix))
+			if err != nil {
+				fmt.Fprintf(o.IO.ErrOut, "Warning: %v\n", err)
+				return nil, false
+			}
+			b = pkg.Binary()
+		} else {
+			p, err := provider.Detect(ref)
+			if err != nil {
+				return nil, false
+			}
+			b = &binary.Binary{
+				Name:         provider.BinaryName(ref),
+				AutoDetect:   true,
+				ProviderRef:  ref,
+				ProviderType: p.Name(),
+				VersionF: func(b *binary.Binary) (string, error) {
+					// Templated URLs resolve latest from their b.yaml config
+					if hp, ok := p.(*provider.HTTP); ok {
+						return hp.Latest(ref, b.HTTP)
+					}
+					return provider.LatestInChannel(p, ref, b.Channel)
+				},
+			}
+			if _, ok := p.(*provider.GoInstall); ok {
+				// A build has no version command; b.lock knows what was
+				// built.
+				b.VersionLocalF = o.lockedVersion
+			}
 		}
+		b.SetVersion(version)
 		// Apply config overrides if this ref came from config
 		if configEntry != nil {
 			if configEntry.Version != "" && version == "" {
-				b.Version = configEntry.Version
+				b.SetVersion(configEntry.Version)
 			}
 			if configEntry.Enforced != "" {
-				b.Version = configEntry.Enforced
+				b.SetVersion(configEntry.Enforced)
 			}
 			if configEntry.File != "" {
 				b.File = configEntry.File
@@ -168,6 +329,31 @@ func (o *SharedOptions) GetBinary(name string) (*binary.Binary, bool) {
 			if configEntry.OnPost != "" {
 				b.OnPost = configEntry.OnPost
 			}
+			if configEntry.Verify != nil {
+				b.Verify = configEntry.Verify.Resolve(o.LockDir())
+			}
+			if configEntry.HTTP != nil {
+				b.HTTP = configEntry.HTTP
+			}
+			if configEntry.Channel != "" {
+				b.Channel = provider.Channel(configEntry.Channel)
+			}
+			if len(configEntry.Extract) > 0 {
+				b.Extract = configEntry.Extract
+			}
+			if configEntry.Layout != "" {
+				b.Layout = configEntry.Layout
+				b.Entrypoint = configEntry.Entrypoint
+			}
+			if configEntry.Build != nil {
+				b.Build = configEntry.Build
+			}
+			if configEntry.Runtime != "" {
+				b.Runtime = configEntry.Runtime
+			}
+			if len(configEntry.Paths) > 0 {
+				b.Paths = configEntry.Paths
+			}
 		}
 		return b, true
 	}
@@ -192,10 +378,10 @@ func (o *SharedOptions) GetBinariesFromConfig() []*binary.Binary {
 			}
 			// Apply config overrides
 			if lb.Version != "" {
-				b.Version = lb.Version
+				b.SetVersion(lb.Version)
 			}
 			if lb.Enforced != "" {
-				b.Version = lb.Enforced
+				b.SetVersion(lb.Enforced)
 			}
 			if lb.File != "" {
 				b.File = lb.File
@@ -209,6 +395,31 @@ func (o *SharedOptions) GetBinariesFromConfig() []*binary.Binary {
 			if lb.OnPost != "" {
 				b.OnPost = lb.OnPost
 			}
+			if lb.Verify != nil {
+				b.Verify = lb.Verify.Resolve(o.LockDir())
+			}
+			if lb.HTTP != nil {
+				b.HTTP = lb.HTTP
+			}
+			if lb.Channel != "" {
+				b.Channel = provider.Channel(lb.Channel)
+			}
+			if len(lb.Extract) > 0 {
+				b.Extract = lb.Extract
+			}
+			if lb.Layout != "" {
+				b.Layout = lb.Layout
+				b.Entrypoint = lb.Entrypoint
+			}
+			if lb.Build != nil {
+				b.Build = lb.Build
+			}
+			if lb.Runtime != "" {
+				b.Runtime = lb.Runtime
+			}
+			if len(lb.Paths) > 0 {
+				b.Paths = lb.Paths
+			}
 			result = append(result, b)
 		} else if b, ok := o.resolveBinary(lb); ok {
 			result = append(result, b)
@@ -220,6 +431,16 @@ func (o *SharedOptions) GetBinariesFromConfig() []*binary.Binary {
 	return result
 }
 
+// followPrereleases moves binaries on the stable channel to the prerelease
+// channel for `--pre`. Nightly binaries already include prereleases.
+func followPrereleases(binaries []*binary.Binary) {
+	for _, b := range binaries {
+		if b.Channel == "" || b.Channel == provider.ChannelStable {
+			b.Channel = provider.ChannelPrerelease
+		}
+	}
+}
+
 // LockDir returns the directory where b.lock lives — next to b.yaml.
 func (o *SharedOptions) LockDir() string {
 	if o.ConfigPath != "" {
@@ -361,9 +582,12 @@ func resolveAmbiguousAssets(binaries []*binary.Binary, quiet bool, io *streams.I
 			continue
 		}
 
+		if err := b.ResolveConstraint(); err != nil {
+			continue
+		}
 		version := b.Version
 		if version == "" {
-			version, err = p.LatestVersion(b.ProviderRef)
+			version, err = provider.LatestInChannel(p, b.ProviderRef, b.Channel)
 			if err != nil {
 				continue
 			}
@@ -375,16 +599,15 @@ func resolveAmbiguousAssets(binaries []*binary.Binary, quiet bool, io *streams.I
 			continue
 		}
 
-		repoName := provider.BinaryName(b.ProviderRef)
-		candidates := provider.MatchAssets(release.Assets, repoName, b.AssetFilter)
-
-		if len(candidates) == 0 {
+		candidates, err := b.MatchAssets(release.Assets)
+		if err != nil || len(candidates) == 0 {
 			continue
 		}
 
 		// No ambiguity — cache the winner to avoid re-fetching during download
 		if len(candidates) < 2 || candidates[0].Score != candidates[1].Score {
 			b.ResolvedAsset = candidates[0].Asset
+			b.ResolveCompanions(release.Assets, b.ResolvedAsset)
 			continue
 		}
 
@@ -397,6 +620,7 @@ func resolveAmbiguousAssets(binaries []*binary.Binary, quiet bool, io *streams.I
 		if !quiet && isTTYFunc() {
 			asset, confirmed := promptAssetPick(b, candidates, io)
 			b.ResolvedAsset = asset
+			b.ResolveCompanions(release.Assets, asset)
 			if confirmed && b.AssetFilter == "" {
 				b.AssetFilter = escapeAssetGlob(asset.Name)
 			}
@@ -409,6 +633,7 @@ func resolveAmbiguousAssets(binaries []*binary.Binary, quiet bool, io *streams.I
 			continue
 		}
 		b.ResolvedAsset = asset
+		b.ResolveCompanions(release.Assets, asset)
 	}
 }
 
@@ -2,13 +2,13 @@ package cli
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/fatih/color"
 	"github.com/fentas/goodies/progress"
@@ -20,6 +20,7 @@ import (
 	"github.com/fentas/b/pkg/env"
 	"github.com/fentas/b/pkg/gitcache"
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
 	"github.com/fentas/b/pkg/provider"
 	"github.com/fentas/b/pkg/state"
 )
@@ -50,6 +51,10 @@ type UpdateOptions struct {
 	Group             string                       // only update envs in this group
 	EnvsOnly          bool                         // update envs only, skip binaries
 	BinariesOnly      bool                         // update binaries only, skip envs
+	Pre               bool                         // follow the prerelease channel
+	Jobs              int                          // parallel downloads; 0 means B_JOBS or DefaultJobs
+	jobsPerHost       int                          // B_JOBS_PER_HOST, resolved by Validate
+	ParallelEnvs      bool                         // sync independent envs concurrently
 	stdinReader       io.Reader                    // overridden by tests; nil means os.Stdin
 	updateBinariesF   func([]*binary.Binary) error // overridden by tests; nil means o.updateBinaries
 }
@@ -116,6 +121,9 @@ func NewUpdateCmd(shared *SharedOptions) *cobra.Command {
 	cmd.Flags().StringVar(&o.Group, "group", "", "Only update envs in this group (implies --envs-only)")
 	cmd.Flags().BoolVar(&o.EnvsOnly, "envs-only", false, "Only update envs, skip binaries")
 	cmd.Flags().BoolVar(&o.BinariesOnly, "binaries-only", false, "Only update binaries, skip envs")
+	cmd.Flags().BoolVar(&o.Pre, "pre", false, "Update to the latest prerelease when no version is pinned")
+	cmd.Flags().IntVarP(&o.Jobs, "jobs", "j", 0, jobsHelp)
+	cmd.Flags().BoolVar(&o.ParallelEnvs, "parallel-envs", false, "Sync envs from different repositories in parallel (up to --jobs at once)")
 
 	return cmd
 }
@@ -205,7 +213,7 @@ func (o *UpdateOptions) resolveUpdateArg(arg string) (envKey string, b *binary.B
 		return "", nil, o.unknownArgError(arg)
 	}
 	if version != "" {
-		bin.Version = version
+		bin.SetVersion(version)
 	}
 	// If this resolved to an ad-hoc provider binary (not in config) while a
 	// configured env targets the same repo, the user probably meant the env
@@ -426,6 +434,11 @@ func isLocalRefPath(s string) bool {
 
 // Validate checks if the update operation is valid
 func (o *UpdateOptions) Validate() error {
+	// Updating means asking upstream what's new; offline there is no one
+	// to ask.
+	if offline.Enabled() {
+		return fmt.Errorf("b update needs the network; use `b install --offline` to install what b.lock pins")
+	}
 	// Scope flags select which namespace the "update all" form touches; they
 	// are contradictory together and meaningless once specific args narrow the
 	// scope already.
@@ -466,7 +479,12 @@ func (o *UpdateOptions) Validate() error {
 				o.Safety, state.SafetyStrict, state.SafetyPrompt, state.SafetyAuto)
 		}
 	}
-	return nil
+	var err error
+	if o.Jobs, err = resolveJobs(o.Jobs); err != nil {
+		return err
+	}
+	o.jobsPerHost, err = resolveJobsPerHost()
+	return err
 }
 
 // Run executes the update operation
@@ -566,6 +584,9 @@ func (o *UpdateOptions) runSpecified() error {
 
 // callUpdateBinaries delegates to the test hook or the real implementation.
 func (o *UpdateOptions) callUpdateBinaries(binaries []*binary.Binary) error {
+	if o.Pre {
+		followPrereleases(binaries)
+	}
 	if o.updateBinariesF != nil {
 		return o.updateBinariesF(binaries)
 	}
@@ -582,7 +603,6 @@ func (o *UpdateOptions) updateEnvs(refs []string) error {
 	o.checkEnvConflicts(refs, o.Group)
 
 	lockDir := o.LockDir()
-	projectRoot := o.ProjectRoot()
 	lk, err := lock.ReadLock(lockDir)
 	if err != nil {
 		return err
@@ -607,6 +627,7 @@ func (o *UpdateOptions) updateEnvs(refs []string) error {
 	// emit a single JSON array at the end.
 	var planJSONOut []*env.Plan
 
+	var entries []*state.EnvEntry
 	for _, entry := range o.Config.Envs {
 		if refs != nil {
 			found := false
@@ -625,185 +646,27 @@ func (o *UpdateOptions) updateEnvs(refs []string) error {
 		if o.Group != "" && entry.Group != o.Group {
 			continue
 		}
+		entries = append(entries, entry)
+	}
 
-		label := gitcache.RefLabel(entry.Key)
-		ref := gitcache.RefBase(entry.Key)
-
-		// Determine strategy: CLI flag > config > default
-		strategy := entry.Strategy
-		if o.Strategy != "" {
-			strategy = o.Strategy
-		}
-
-		// Determine safety: CLI flag > config > default (prompt). Defaulted
-		// via state.NormalizeSafety so unknown values fall back safely.
-		safety := state.NormalizeSafety(entry.Safety)
-		if o.Safety != "" {
-			safety = state.NormalizeSafety(o.Safety)
-		}
-		// `--plan-json` implies `--dry-run` — the helper hides this
-		// dependency from the rest of the loop so future dry-run-like
-		// flags only need to be added in one place.
-		isDryRun := o.effectiveDryRun()
-
-		// We only need a dry-run "plan" pass when the safety mode might
-		// reject the apply (strict, or prompt where the user has to be
-		// asked). For SafetyAuto and for explicit --yes we can go straight
-		// to the real apply and render the plan from its result — no
-		// double work, no second clone-cache hit.
-		needsPlanFirst := isDryRun ||
-			safety == state.SafetyStrict ||
-			(safety == state.SafetyPrompt && !o.Yes)
-
-		cfg := env.EnvConfig{
-			Ref:        ref,
-			Label:      label,
-			Version:    entry.Version,
-			ConfigDir:  lockDir,
-			Ignore:     entry.Ignore,
-			Strategy:   strategy,
-			Files:      entry.Files,
-			DryRun:     needsPlanFirst,
-			OnPreSync:  entry.OnPreSync,
-			OnPostSync: entry.OnPostSync,
-			Stdout:     o.IO.Out,
-			Stderr:     o.IO.ErrOut,
-		}
-		// Attach the interactive conflict resolver only when this very
-		// pass will actually apply to disk (auto / --yes path). The
-		// resolver is interactive — running it during a dry-run plan
-		// pass would prompt the user before they've even approved the
-		// plan. The plan-first path sets it on the second pass instead.
-		if !needsPlanFirst && (strategy == "" || strategy == env.StrategyReplace) && isTTYFunc() {
-			cfg.ResolveConflict = o.interactiveConflictResolver(ref, lk)
-		}
-
-		lockEntry := lk.FindEnv(ref, label)
-
-		// Handle rollback: use previous commit from lock
-		if o.Rollback {
-			if lockEntry == nil || lockEntry.PreviousCommit == "" {
-				fmt.Fprintf(o.IO.ErrOut, "  %-40s ✗ no previous commit to rollback to\n", entry.Key)
-				failedEnvs = append(failedEnvs, entry.Key)
-				continue
-			}
-			cfg.ForceCommit = lockEntry.PreviousCommit
-		}
-
-		// First pass — dry-run when we need a plan to gate on, real
-		// apply when safety is auto / --yes.
-		firstResult, err := syncEnvFunc(cfg, projectRoot, "", lockEntry)
-		if err != nil {
-			fmt.Fprintf(o.IO.ErrOut, "  %-40s ✗ %s\n", entry.Key, firstLine(err.Error()))
-			failedEnvs = append(failedEnvs, entry.Key)
-			continue
-		}
-
-		if firstResult.Skipped {
-			// Plan-json mode: emit an explicit empty plan for the
-			// skipped env so consumers can distinguish "all envs are
-			// up to date" from "no envs configured" — both used to
-			// produce [].
-			// Plain dry-run / plan-text mode prints just the cheap
-			// "(up to date)" line; no plan table or summary is
-			// rendered for skipped envs in text mode.
-			if o.PlanJSON {
-				planJSONOut = append(planJSONOut, &env.Plan{
-					Ref:     ref,
-					Label:   label,
-					Version: entry.Version,
-					Commit:  firstResult.Commit,
-				})
-			} else {
-				fmt.Fprintf(o.IO.Out, "  %-40s %s\n", entry.Key, firstResult.Message)
-			}
-			continue // don't overwrite lock entry when up-to-date
-		}
-
-		plan := env.PlanFromResult(firstResult, lockEntry)
-
-		// --plan-json: collect the plan for batched JSON output below.
-		// We never apply in plan-json mode (it implies dry-run).
-		if o.PlanJSON {
-			planJSONOut = append(planJSONOut, plan)
-			continue
-		}
-
-		// Header line + plan table.
-		fmt.Fprintf(o.IO.Out, "  %-40s %s → %s\n", entry.Key,
-			shortCommit(firstResult.PreviousCommit), shortCommit(firstResult.Commit))
-		env.RenderPlanText(o.IO.Out, plan)
-
-		// If the first pass was already a real apply (auto / --yes path),
-		// just write the lock and move on. No gate, no second SyncEnv.
-		if !needsPlanFirst {
-			if firstResult.Conflicts > 0 {
-				printConflictHint(o.IO.ErrOut, firstResult, projectRoot)
-			}
-			lk.UpsertEnv(lock.EnvEntry{
-				Ref:            firstResult.Ref,
-				Label:          firstResult.Label,
-				Version:        firstResult.Version,
-				Commit:         firstResult.Commit,
-				PreviousCommit: firstResult.PreviousCommit,
-				Files:          firstResult.Files,
-			})
-			continue
-		}
-
-		// Plan-first path: gate on safety, then apply if approved.
-		apply, gateErr := o.gateApply(safety, plan, isDryRun)
-		if gateErr != nil {
-			fmt.Fprintf(o.IO.ErrOut, "  %-40s ✗ %s\n", entry.Key, gateErr)
-			refusedEnvs = append(refusedEnvs, entry.Key)
-			continue
-		}
-		if !apply {
-			// Dry-run or user declined the prompt — not an error,
-			// just nothing to do for this env.
-			continue
-		}
-
-		// Second pass: real apply. The gitcache is hot from the first
-		// pass, so only the actual file writes hit disk newly.
-		//
-		// Notably we do NOT attach the per-file
-		// interactiveConflictResolver here, even on TTY+replace.
-		// In the plan-first flow the user has already approved (or
-		// rejected) the entire plan via the safety gate. Attaching
-		// the legacy per-file resolver would (a) show a second
-		// round of interactive prompts after they already accepted
-		// the plan, and (b) create a plan-vs-reality skew because
-		// the dry-run pass that produced the plan ran without the
-		// resolver, so its destructiveness verdict (and the strict
-		// gate's decision) was based on "unconditional overwrite"
-		// while the apply pass would actually call the resolver
-		// and might pick keep/merge/diff per file.
-		//
-		// Auto / --yes mode is the only path where the legacy
-		// resolver is still attached (handled at the top of the
-		// loop where !needsPlanFirst).
-		applyCfg := cfg
-		applyCfg.DryRun = false
-		realResult, err := syncEnvFunc(applyCfg, projectRoot, "", lockEntry)
-		if err != nil {
-			fmt.Fprintf(o.IO.ErrOut, "  %-40s ✗ %s\n", entry.Key, firstLine(err.Error()))
-			failedEnvs = append(failedEnvs, entry.Key)
-			continue
+	results := make([]envResult, len(entries))
+	if o.ParallelEnvs {
+		o.syncEnvsParallel(entries, lk, results)
+	} else {
+		var lkMu sync.Mutex
+		for i, entry := range entries {
+			results[i] = o.updateEnv(entry, lk, &lkMu, o.IO.Out, o.IO.ErrOut)
 		}
-
-		if realResult.Conflicts > 0 {
-			printConflictHint(o.IO.ErrOut, realResult, projectRoot)
+	}
+	for i, res := range results {
+		switch {
+		case res.failed:
+			failedEnvs = append(failedEnvs, entries[i].Key)
+		case res.refused:
+			refusedEnvs = append(refusedEnvs, entries[i].Key)
+		case res.plan != nil:
+			planJSONOut = append(planJSONOut, res.plan)
 		}
-
-		lk.UpsertEnv(lock.EnvEntry{
-			Ref:            realResult.Ref,
-			Label:          realResult.Label,
-			Version:        realResult.Version,
-			Commit:         realResult.Commit,
-			PreviousCommit: realResult.PreviousCommit,
-			Files:          realResult.Files,
-		})
 	}
 
 	if o.PlanJSON {
@@ -840,6 +703,284 @@ func (o *UpdateOptions) updateEnvs(refs []string) error {
 	return aggregateEnvErrors(refusedEnvs, failedEnvs)
 }
 
+// envResult is the outcome of updating one env.
+type envResult struct {
+	plan    *env.Plan // collected for --plan-json
+	refused bool      // a safety gate refused the apply
+	failed  bool      // the sync itself failed
+}
+
+// envPolicy returns the strategy and safety for entry.
+func (o *UpdateOptions) envPolicy(entry *state.EnvEntry) (strategy, safety string) {
+	// Determine strategy: CLI flag > config > default
+	strategy = entry.Strategy
+	if o.Strategy != "" {
+		strategy = o.Strategy
+	}
+
+	// Determine safety: CLI flag > config > default (prompt). Defaulted
+	// via state.NormalizeSafety so unknown values fall back safely.
+	safety = state.NormalizeSafety(entry.Safety)
+	if o.Safety != "" {
+		safety = state.NormalizeSafety(o.Safety)
+	}
+	return strategy, safety
+}
+
+// updateEnv syncs one env and records it in lk, which lkMu guards. Output
+// goes to out and errOut; prompts still use the terminal.
+func (o *UpdateOptions) updateEnv(entry *state.EnvEntry, lk *lock.Lock, lkMu *sync.Mutex, out, errOut io.Writer) envResult {
+
+	projectRoot := o.ProjectRoot()
+	label := gitcache.RefLabel(entry.Key)
+	ref := gitcache.RefBase(entry.Key)
+
+	strategy, safety := o.envPolicy(entry)
+	// `--plan-json` implies `--dry-run` — the helper hides this
+	// dependency from the rest of the loop so future dry-run-like
+	// flags only need to be added in one place.
+	isDryRun := o.effectiveDryRun()
+
+	// We only need a dry-run "plan" pass when the safety mode might
+	// reject the apply (strict, or prompt where the user has to be
+	// asked). For SafetyAuto and for explicit --yes we can go straight
+	// to the real apply and render the plan from its result — no
+	// double work, no second clone-cache hit.
+	needsPlanFirst := isDryRun ||
+		safety == state.SafetyStrict ||
+		(safety == state.SafetyPrompt && !o.Yes)
+
+	cfg := env.EnvConfig{
+		Ref:        ref,
+		Label:      label,
+		Version:    entry.Version,
+		ConfigDir:  o.LockDir(),
+		Ignore:     entry.Ignore,
+		Strategy:   strategy,
+		Files:      entry.Files,
+		DryRun:     needsPlanFirst,
+		OnPreSync:  entry.OnPreSync,
+		OnPostSync: entry.OnPostSync,
+		Stdout:     out,
+		Stderr:     errOut,
+	}
+	// Attach the interactive conflict resolver only when this very
+	// pass will actually apply to disk (auto / --yes path). The
+	// resolver is interactive — running it during a dry-run plan
+	// pass would prompt the user before they've even approved the
+	// plan. The plan-first path sets it on the second pass instead.
+	if !needsPlanFirst && (strategy == "" || strategy == env.StrategyReplace) && isTTYFunc() {
+		cfg.ResolveConflict = o.interactiveConflictResolver(ref, lk)
+	}
+
+	lkMu.Lock()
+	lockEntry := lk.FindEnv(ref, label)
+	lkMu.Unlock()
+
+	// Handle rollback: use previous commit from lock
+	if o.Rollback {
+		if lockEntry == nil || lockEntry.PreviousCommit == "" {
+			fmt.Fprintf(errOut, "  %-40s ✗ no previous commit to rollback to\n", entry.Key)
+			return envResult{failed: true}
+		}
+		cfg.ForceCommit = lockEntry.PreviousCommit
+	}
+
+	// First pass — dry-run when we need a plan to gate on, real
+	// apply when safety is auto / --yes.
+	firstResult, err := syncEnvFunc(cfg, projectRoot, "", lockEntry)
+	if err != nil {
+		fmt.Fprintf(errOut, "  %-40s ✗ %s\n", entry.Key, firstLine(err.Error()))
+		return envResult{failed: true}
+	}
+
+	if firstResult.Skipped {
+		// Plan-json mode: emit an explicit empty plan for the
+		// skipped env so consumers can distinguish "all envs are
+		// up to date" from "no envs configured" — both used to
+		// produce [].
+		// Plain dry-run / plan-text mode prints just the cheap
+		// "(up to date)" line; no plan table or summary is
+		// rendered for skipped envs in text mode.
+		if o.PlanJSON {
+			return envResult{plan: &env.Plan{
+				Ref:     ref,
+				Label:   label,
+				Version: entry.Version,
+				Commit:  firstResult.Commit,
+			}}
+		}
+		fmt.Fprintf(out, "  %-40s %s\n", entry.Key, firstResult.Message)
+		return envResult{} // don't overwrite lock entry when up-to-date
+	}
+
+	plan := env.PlanFromResult(firstResult, lockEntry)
+
+	// --plan-json: collect the plan for batched JSON output below.
+	// We never apply in plan-json mode (it implies dry-run).
+	if o.PlanJSON {
+		return envResult{plan: plan}
+	}
+
+	// Header line + plan table.
+	fmt.Fprintf(out, "  %-40s %s → %s\n", entry.Key,
+		shortCommit(firstResult.PreviousCommit), shortCommit(firstResult.Commit))
+	env.RenderPlanText(out, plan)
+
+	// If the first pass was already a real apply (auto / --yes path),
+	// just write the lock and move on. No gate, no second SyncEnv.
+	if !needsPlanFirst {
+		if firstResult.Conflicts > 0 {
+			printConflictHint(errOut, firstResult, projectRoot)
+		}
+		lkMu.Lock()
+		defer lkMu.Unlock()
+		lk.UpsertEnv(lock.EnvEntry{
+			Ref:            firstResult.Ref,
+			Label:          firstResult.Label,
+			Version:        firstResult.Version,
+			Commit:         firstResult.Commit,
+			PreviousCommit: firstResult.PreviousCommit,
+			Files:          firstResult.Files,
+		})
+		return envResult{}
+	}
+
+	// Plan-first path: gate on safety, then apply if approved.
+	apply, gateErr := o.gateApply(safety, plan, isDryRun)
+	if gateErr != nil {
+		fmt.Fprintf(errOut, "  %-40s ✗ %s\n", entry.Key, gateErr)
+		return envResult{refused: true}
+	}
+	if !apply {
+		// Dry-run or user declined the prompt — not an error,
+		// just nothing to do for this env.
+		return envResult{}
+	}
+
+	// Second pass: real apply. The gitcache is hot from the first
+	// pass, so only the actual file writes hit disk newly.
+	//
+	// Notably we do NOT attach the per-file
+	// interactiveConflictResolver here, even on TTY+replace.
+	// In the plan-first flow the user has already approved (or
+	// rejected) the entire plan via the safety gate. Attaching
+	// the legacy per-file resolver would (a) show a second
+	// round of interactive prompts after they already accepted
+	// the plan, and (b) create a plan-vs-reality skew because
+	// the dry-run pass that produced the plan ran without the
+	// resolver, so its destructiveness verdict (and the strict
+	// gate's decision) was based on "unconditional overwrite"
+	// while the apply pass would actually call the resolver
+	// and might pick keep/merge/diff per file.
+	//
+	// Auto / --yes mode is the only path where the legacy
+	// resolver is still attached (handled at the top of the
+	// loop where !needsPlanFirst).
+	applyCfg := cfg
+	applyCfg.DryRun = false
+	realResult, err := syncEnvFunc(applyCfg, projectRoot, "", lockEntry)
+	if err != nil {
+		fmt.Fprintf(errOut, "  %-40s ✗ %s\n", entry.Key, firstLine(err.Error()))
+		return envResult{failed: true}
+	}
+
+	if realResult.Conflicts > 0 {
+		printConflictHint(errOut, realResult, projectRoot)
+	}
+
+	lkMu.Lock()
+	defer lkMu.Unlock()
+	lk.UpsertEnv(lock.EnvEntry{
+		Ref:            realResult.Ref,
+		Label:          realResult.Label,
+		Version:        realResult.Version,
+		Commit:         realResult.Commit,
+		PreviousCommit: realResult.PreviousCommit,
+		Files:          realResult.Files,
+	})
+	return envResult{}
+}
+
+// envMayPrompt reports whether updating entry may ask on the terminal:
+// through the prompt safety gate, or through the per-file conflict resolver
+// of a replace sync.
+func (o *UpdateOptions) envMayPrompt(entry *state.EnvEntry) bool {
+	if o.effectiveDryRun() || !isTTYFunc() {
+		return false
+	}
+	strategy, safety := o.envPolicy(entry)
+	if safety == state.SafetyPrompt && !o.Yes {
+		return true
+	}
+	return safety != state.SafetyStrict && (strategy == "" || strategy == env.StrategyReplace)
+}
+
+// syncEnvsParallel runs updateEnv for entries, up to o.Jobs at once, and
+// stores the outcomes in results. Envs of one repository share its git
+// cache clone and run one after the other. Each env's output is buffered
+// and printed in config order; envs that may prompt run last, directly on
+// the terminal.
+func (o *UpdateOptions) syncEnvsParallel(entries []*state.EnvEntry, lk *lock.Lock, results []envResult) {
+	var lkMu sync.Mutex
+	type buffered struct{ out, errOut bytes.Buffer }
+	bufs := make([]buffered, len(entries))
+	prompts := make([]bool, len(entries))
+	repos := make(map[string][]int)
+	var order []string
+	for i, entry := range entries {
+		if o.envMayPrompt(entry) {
+			prompts[i] = true
+			continue
+		}
+		ref := gitcache.RefBase(entry.Key)
+		if _, ok := repos[ref]; !ok {
+			order = append(order, ref)
+		}
+		repos[ref] = append(repos[ref], i)
+	}
+
+	// flush prints the finished envs at the head of the config order.
+	var printMu sync.Mutex
+	done := make([]bool, len(entries))
+	next := 0
+	flush := func() {
+		for next < len(entries) && done[next] {
+			_, _ = o.IO.Out.Write(bufs[next].out.Bytes())
+			_, _ = o.IO.ErrOut.Write(bufs[next].errOut.Bytes())
+			next++
+		}
+	}
+
+	var wg sync.WaitGroup
+	pool := newJobPool(o.Jobs, o.jobsPerHost)
+	for _, ref := range order {
+		wg.Add(1)
+		go func(idx []int) {
+			defer wg.Done()
+			release := pool.acquire("")
+			defer release()
+			for _, i := range idx {
+				results[i] = o.updateEnv(entries[i], lk, &lkMu, &bufs[i].out, &bufs[i].errOut)
+				printMu.Lock()
+				done[i] = true
+				flush()
+				printMu.Unlock()
+			}
+		}(repos[ref])
+	}
+	wg.Wait()
+
+	for i := next; i < len(entries); i++ {
+		if prompts[i] {
+			results[i] = o.updateEnv(entries[i], lk, &lkMu, o.IO.Out, o.IO.ErrOut)
+			continue
+		}
+		_, _ = o.IO.Out.Write(bufs[i].out.Bytes())
+		_, _ = o.IO.ErrOut.Write(bufs[i].errOut.Bytes())
+	}
+}
+
 // pruneOrphanedEnvs normalizes the lock's env entries against the config,
 // returning the number removed. It drops entries whose (ref,label) is not in
 // b.yaml (orphans, e.g. a renamed label) AND collapses duplicates of the same
@@ -1179,6 +1320,13 @@ func (o *UpdateOptions) updateBinaries(binaries []*binary.Binary) error {
 		lk = readLk
 	}
 
+	// Resolve version ranges ("~1.7") to concrete tags first so digests are
+	// looked up for, and the lock records, the tag that will be installed.
+	// A failure is reported by the binary's own download below.
+	for _, b := range binaries {
+		_ = b.ResolveConstraint()
+	}
+
 	// Resolve digests once per digest-capable binary up-front. We reuse these
 	// values both for the skip decision and the post-update lock refresh, so
 	// each registry HEAD only happens once per run.
@@ -1237,23 +1385,30 @@ func (o *UpdateOptions) updateBinaries(binaries []*binary.Binary) error {
 	wg := sync.WaitGroup{}
 	pw := progress.NewWriter(progress.StyleDownload, o.IO.Out)
 	pw.Style().Visibility.Percentage = true
-	go pw.Render()
-	defer pw.Stop()
+	// Trackers are added up front so queued binaries show as such.
+	for _, b := range binaries {
+		name := b.Name
+		if b.Alias != "" {
+			name = b.Alias
+		}
+		b.Tracker = pw.AddTracker(fmt.Sprintf("Updating %s (queued)", name), 0)
+		b.Writer = pw
+	}
+	rendered := renderProgress(pw)
 
+	pool := newJobPool(o.Jobs, o.jobsPerHost)
 	for _, b := range binaries {
 		wg.Add(1)
 
+		name := b.Name
+		if b.Alias != "" {
+			name = b.Alias
+		}
 		go func(b *binary.Binary) {
 			defer wg.Done()
-
-			name := b.Name
-			if b.Alias != "" {
-				name = b.Alias
-			}
-
-			tracker := pw.AddTracker(fmt.Sprintf("Updating %s", name), 0)
-			b.Tracker = tracker
-			b.Writer = pw
+			release := pool.acquire(binaryHost(b))
+			defer release()
+			b.Tracker.UpdateMessage(fmt.Sprintf("Updating %s", name))
 
 			var err error
 			attempted := false
@@ -1337,17 +1492,46 @@ func (o *UpdateOptions) updateBinaries(binaries []*binary.Binary) error {
 	}
 
 	wg.Wait()
-	time.Sleep(200 * time.Millisecond)
+	<-rendered
 
 	// Record freshly-resolved digests in the lockfile so subsequent `b update`
 	// runs can skip when the tag hasn't moved. Only touches digest-resolver
 	// providers; non-digest entries and the rest of the lock are left alone.
 	if !o.effectiveDryRun() {
 		o.refreshLockDigests(binaries, freshDigests, preSHA, downloadFailed)
+		o.lockModuleVersions(binaries, downloadFailed)
 	}
 	return nil
 }
 
+// lockModuleVersions records the go:// binaries this run built at another
+// module version than b.lock pins, so the lock keeps naming the code that
+// was built and the next `b update` compares against it.
+func (o *UpdateOptions) lockModuleVersions(binaries []*binary.Binary, downloadFailed map[string]bool) {
+	lk, err := lock.ReadLock(o.LockDir())
+	if err != nil {
+		fmt.Fprintf(o.IO.ErrOut, "Warning: can't read b.lock to record module versions: %v\n", err)
+		return
+	}
+	var built []*binary.Binary
+	for _, b := range binaries {
+		if b.ProviderType != (&provider.GoInstall{}).Name() || downloadFailed[b.Name] || !provider.IsModuleVersion(b.Version) || !b.BinaryExists() {
+			continue
+		}
+		if entry := lk.FindBinary(b.Name); entry != nil && entry.Source == b.ProviderRef && entry.Version == b.Version {
+			continue
+		}
+		built = append(built, b)
+	}
+	if len(built) == 0 {
+		return
+	}
+	inst := &InstallOptions{SharedOptions: o.SharedOptions}
+	if err := inst.updateLock(built); err != nil {
+		fmt.Fprintf(o.IO.ErrOut, "Warning: failed to update b.lock: %v\n", err)
+	}
+}
+
 // refreshLockDigests re-reads b.lock and updates the Digest + SHA256 for
 // every digest-resolver binary that actually changed on disk during this
 // update run. Failed downloads are identified via downloadFailed and
@@ -1378,10 +1562,12 @@ func (o *UpdateOptions) refreshLockDigests(binaries []*binary.Binary, freshDiges
 		if downloadFailed[b.Name] {
 			continue
 		}
-		entry := lk.FindBinary(b.Name)
-		if entry == nil {
+		found := lk.FindBinary(b.Name)
+		if found == nil {
 			continue
 		}
+		platform := b.Platform.String()
+		entry := found.For(platform)
 		// If the lock entry's Source drifted from the configured
 		// ProviderRef (e.g. the user edited b.yaml in place and changed
 		// the ref but kept the derived binary name), don't rewrite the
@@ -1410,9 +1596,16 @@ func (o *UpdateOptions) refreshLockDigests(binaries []*binary.Binary, freshDiges
 		// SHA256: refresh whenever the on-disk bytes actually moved.
 		// downloadFailed is already filtered out above, so a changed
 		// hash here proves a successful download.
+		entryChanged := false
 		if hashChanged && entry.SHA256 != hash {
 			entry.SHA256 = hash
-			changed = true
+			entryChanged = true
+		}
+		// Signatures: a verified re-pull records what it was verified
+		// against, alongside the new SHA256.
+		if hashChanged && len(b.Signatures) > 0 {
+			entry.Signatures = b.Signatures
+			entryChanged = true
 		}
 		// Digest: refresh whenever we have a fresh value to store.
 		// Empty means ResolveDigest didn't know — keep the previous
@@ -1421,6 +1614,10 @@ func (o *UpdateOptions) refreshLockDigests(binaries []*binary.Binary, freshDiges
 		// the next `b update` can short-circuit when it matches.
 		if digest != "" && entry.Digest != digest {
 			entry.Digest = digest
+			entryChanged = true
+		}
+		if entryChanged {
+			lk.SetBinary(platform, entry)
 			changed = true
 		}
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/env"
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/provider"
 	"github.com/fentas/b/pkg/state"
 	"github.com/fentas/goodies/streams"
 )
@@ -861,6 +862,29 @@ func TestCallUpdateBinaries_UsesHook(t *testing.T) {
 	}
 }
 
+func TestCallUpdateBinaries_Pre(t *testing.T) {
+	var got []*binary.Binary
+	o := &UpdateOptions{
+		SharedOptions:   &SharedOptions{IO: &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}},
+		Pre:             true,
+		updateBinariesF: func(bins []*binary.Binary) error { got = bins; return nil },
+	}
+	bins := []*binary.Binary{
+		{Name: "k9s"},
+		{Name: "kubectl", Channel: provider.ChannelStable},
+		{Name: "nvim", Channel: provider.ChannelNightly},
+	}
+	if err := o.callUpdateBinaries(bins); err != nil {
+		t.Fatal(err)
+	}
+	want := []provider.Channel{provider.ChannelPrerelease, provider.ChannelPrerelease, provider.ChannelNightly}
+	for i, b := range got {
+		if b.Channel != want[i] {
+			t.Errorf("%s channel = %q, want %q", b.Name, b.Channel, want[i])
+		}
+	}
+}
+
 func TestCallUpdateBinaries_DefaultPath(t *testing.T) {
 	if raceEnabled {
 		t.Skip("skipping: go-pretty progress.Render/Stop race (library bug)")
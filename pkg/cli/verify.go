@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/env"
 	"github.com/fentas/b/pkg/lock"
 	"github.com/fentas/b/pkg/path"
+	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 	"github.com/fentas/goodies/templates"
 	"github.com/spf13/cobra"
 )
@@ -26,7 +30,7 @@ func NewVerifyCmd(shared *SharedOptions) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "verify",
 		Short: "Verify installed binaries and env files against b.lock",
-		Long:  "Check every managed artifact against b.lock checksums. Exit 0 if clean, 1 if mismatch.",
+		Long:  "Check every managed artifact against the b.lock checksums for this platform, and binaries with a `verify:` policy against the signatures recorded in b.lock. Exit 0 if clean, 1 if mismatch.",
 		Example: templates.Examples(`
 			# Verify all managed artifacts
 			b verify
@@ -55,14 +59,30 @@ func (o *VerifyOptions) Run() error {
 
 	failures := 0
 
-	// Verify binaries
+	// Signature policies from b.yaml `verify:` — the lock must record a
+	// verification that still satisfies each one.
+	policies := make(map[string]*signature.Config)
+	for _, b := range o.GetBinariesFromConfig() {
+		if !b.Verify.IsZero() {
+			policies[b.Name] = b.Verify
+		}
+	}
+
+	// Verify binaries, against what b.lock pins for this platform
+	host := provider.HostPlatform().String()
 	for _, entry := range lk.Binaries {
+		entry := entry.For(host)
 		binPath := path.GetBinaryPath()
 		if binPath == "" {
 			fmt.Fprintf(o.IO.Out, "  %-40s ? (no binary path)\n", entry.Name)
 			failures++
 			continue
 		}
+		if entry.SHA256 == "" {
+			fmt.Fprintf(o.IO.Out, "  %-40s ✗ not locked for %s (run `b lock`)\n", entry.Name, host)
+			failures++
+			continue
+		}
 		filePath := filepath.Join(binPath, entry.Name)
 		if _, err := os.Stat(filePath); os.IsNotExist(err) {
 			fmt.Fprintf(o.IO.Out, "  %-40s ✗ missing\n", entry.Name)
@@ -78,8 +98,36 @@ func (o *VerifyOptions) Run() error {
 		if hash != entry.SHA256 {
 			fmt.Fprintf(o.IO.Out, "  %-40s ✗ sha256 mismatch\n", entry.Name)
 			failures++
-		} else {
+			continue
+		}
+		if entry.TreeSHA256 != "" {
+			// layout: tree — the archive tree the shim runs
+			tree, err := lock.SHA256Tree(binary.TreeDir(binPath, entry.Name, entry.Version))
+			switch {
+			case os.IsNotExist(err):
+				fmt.Fprintf(o.IO.Out, "  %-40s ✗ tree missing\n", entry.Name)
+				failures++
+				continue
+			case err != nil:
+				fmt.Fprintf(o.IO.Out, "  %-40s ✗ tree: %v\n", entry.Name, err)
+				failures++
+				continue
+			case tree != entry.TreeSHA256:
+				fmt.Fprintf(o.IO.Out, "  %-40s ✗ tree sha256 mismatch\n", entry.Name)
+				failures++
+				continue
+			}
+		}
+		policy := policies[entry.Name]
+		if policy == nil {
 			fmt.Fprintf(o.IO.Out, "  %-40s ✓\n", entry.Name)
+			continue
+		}
+		if err := policy.Check(entry.Signatures); err != nil {
+			fmt.Fprintf(o.IO.Out, "  %-40s ✗ signature: %v\n", entry.Name, err)
+			failures++
+		} else {
+			fmt.Fprintf(o.IO.Out, "  %-40s ✓ signed (%s)\n", entry.Name, strings.Join(policy.Verifiers(), ", "))
 		}
 	}
 
@@ -11,6 +11,7 @@ import (
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/gitcache"
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/semver"
 )
 
 // VersionOptions holds options for the version command
@@ -122,6 +123,12 @@ func (o *VersionOptions) Run() error {
 		notUpToDate := make([]*binary.LocalBinary, 0)
 		for _, l := range locals {
 			// Skip if version is pinned (enforced)
+			if semver.IsConstraint(l.Enforced) {
+				if !binary.Satisfies(l.Enforced, l.Version) {
+					notUpToDate = append(notUpToDate, l)
+				}
+				continue
+			}
 			if l.Enforced != "" && l.Enforced != "latest" {
 				if l.Enforced != l.Version {
 					notUpToDate = append(notUpToDate, l)
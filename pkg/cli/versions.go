@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/fentas/goodies/templates"
+	"github.com/spf13/cobra"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/provider"
+)
+
+// VersionsOptions holds options for the versions command
+type VersionsOptions struct {
+	*SharedOptions
+	Page    int // Page of the release list, starting at 1
+	PerPage int // Releases per page
+	name    string
+	binary  *binary.Binary
+}
+
+// ReleaseVersion is one row of `b versions`.
+type ReleaseVersion struct {
+	Version    string `json:"version"`
+	Prerelease bool   `json:"prerelease,omitempty"`
+	Draft      bool   `json:"draft,omitempty"`
+	Published  string `json:"published,omitempty"`
+	// Installed marks the version of the binary on disk.
+	Installed bool `json:"installed,omitempty"`
+	// Locked marks the version recorded in b.lock.
+	Locked bool `json:"locked,omitempty"`
+}
+
+// NewVersionsCmd creates the versions subcommand
+func NewVersionsCmd(shared *SharedOptions) *cobra.Command {
+	o := &VersionsOptions{
+		SharedOptions: shared,
+	}
+
+	cmd := &cobra.Command{
+		Use:   "versions <binary|ref>",
+		Short: "List the versions published for a binary",
+		Long: `Lists the versions a binary can be pinned to, newest first: releases on
+GitHub, GitLab and Gitea, tags of docker:// and oci:// images and git:// repos,
+and module versions of go:// refs from the module proxy ($GOPROXY).
+
+The installed version and the version recorded in b.lock are marked.`,
+		Example: templates.Examples(`
+			# List the releases of a preset
+			b versions kubectl
+
+			# List the releases of any ref
+			b versions github.com/derailed/k9s
+
+			# Next page, as JSON
+			b versions oci://ghcr.io/org/tool --page 2 --output json
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().IntVar(&o.Page, "page", 1, "Page of the release list")
+	cmd.Flags().IntVar(&o.PerPage, "per-page", provider.DefaultPerPage, "Releases per page")
+
+	return cmd
+}
+
+// Complete sets up the versions operation
+func (o *VersionsOptions) Complete(args []string) error {
+	b, ok := o.GetBinary(args[0])
+	if !ok {
+		return fmt.Errorf("unknown binary: %s", args[0])
+	}
+	o.name = args[0]
+	o.binary = b
+	return nil
+}
+
+// Validate checks if the versions operation is valid
+func (o *VersionsOptions) Validate() error {
+	if o.Page < 1 {
+		return fmt.Errorf("--page must be at least 1")
+	}
+	if o.PerPage < 1 {
+		return fmt.Errorf("--per-page must be at least 1")
+	}
+	return nil
+}
+
+// Run executes the versions operation
+func (o *VersionsOptions) Run() error {
+	lister, ref, err := o.binary.ReleaseLister()
+	if err != nil {
+		return fmt.Errorf("%s: %w", o.binary.Name, err)
+	}
+	page, err := lister.ListReleases(ref, provider.ListOptions{Page: o.Page, PerPage: o.PerPage})
+	if err != nil {
+		return err
+	}
+
+	installed, locked := o.installedVersions()
+	out := make([]*ReleaseVersion, 0, len(page.Releases))
+	for _, r := range page.Releases {
+		out = append(out, &ReleaseVersion{
+			Version:    r.Version,
+			Prerelease: r.Prerelease,
+			Draft:      r.Draft,
+			Published:  r.Published,
+			Installed:  installed != "" && r.Version == installed,
+			Locked:     locked != "" && r.Version == locked,
+		})
+	}
+	if err := o.IO.Print(out); err != nil {
+		return err
+	}
+	if page.Next != 0 && !o.Quiet {
+		fmt.Fprintf(o.IO.ErrOut, "More versions: b versions %s --page %d\n", o.name, page.Next)
+	}
+	return nil
+}
+
+// installedVersions returns the version of the binary on disk and the one
+// recorded in b.lock. Binaries without a version command count as installed
+// at the locked version while the file still matches the lock checksum.
+func (o *VersionsOptions) installedVersions() (installed, locked string) {
+	b := o.binary
+	if lk, err := lock.ReadLock(o.LockDir()); err == nil {
+		if entry := lk.FindBinary(b.Name); entry != nil {
+			entry := entry.For(b.Platform.String())
+			locked = entry.Version
+			if b.VersionLocalF == nil && b.BinaryExists() {
+				if sum, err := lock.SHA256File(b.BinaryPath()); err == nil && sum == entry.SHA256 {
+					installed = entry.Version
+				}
+			}
+		}
+	}
+	if b.VersionLocalF != nil && b.BinaryExists() {
+		installed, _ = b.VersionLocalF(b)
+	}
+	return installed, locked
+}
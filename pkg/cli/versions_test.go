@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fentas/goodies/output"
+	"github.com/fentas/goodies/streams"
+
+	"github.com/fentas/b/pkg/binary"
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/lock"
+)
+
+func TestVersionsOptions_Run(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/org/tool/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("limit") != "2" {
+			t.Errorf("limit = %q", r.URL.Query().Get("limit"))
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`[{"tag_name":"v2.0.0-rc.1","prerelease":true},{"tag_name":"v1.1.0"}]`))
+		default:
+			_, _ = w.Write([]byte(`[{"tag_name":"v1.0.0"}]`))
+		}
+	}))
+	defer srv.Close()
+	t.Cleanup(func() { _ = hosts.Set(nil) })
+
+	dir := t.TempDir()
+	t.Setenv("PATH_BIN", dir)
+	configPath := filepath.Join(dir, "b.yaml")
+	config := fmt.Sprintf("hosts:\n  gitea.test:\n    type: gitea\n    api: %s/api/v1\nbinaries:\n  gitea.test/org/tool: {}\n", srv.URL)
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Installed and locked at v1.1.0.
+	if err := os.WriteFile(filepath.Join(dir, "tool"), []byte("tool"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := lock.SHA256File(filepath.Join(dir, "tool"))
+	lk := &lock.Lock{Binaries: []lock.BinEntry{{Name: "tool", Version: "v1.1.0", SHA256: sum}}}
+	if err := lock.WriteLock(dir, lk, "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	io := &streams.IO{Out: out, ErrOut: errOut, OutFlags: output.Opts{"json": {""}}}
+	shared := NewSharedOptions(io, nil)
+	shared.ConfigPath = configPath
+	if err := shared.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &VersionsOptions{SharedOptions: shared, Page: 1, PerPage: 2}
+	if err := o.Complete([]string{"gitea.test/org/tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var got []ReleaseVersion
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("output %q: %v", out, err)
+	}
+	want := []ReleaseVersion{
+		{Version: "v2.0.0-rc.1", Prerelease: true},
+		{Version: "v1.1.0", Installed: true, Locked: true},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if !strings.Contains(errOut.String(), "b versions gitea.test/org/tool --page 2") {
+		t.Errorf("missing next-page hint: %q", errOut)
+	}
+
+	// Page 2 is the last one.
+	out.Reset()
+	errOut.Reset()
+	o.Page = 2
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != (ReleaseVersion{Version: "v1.0.0"}) || errOut.Len() != 0 {
+		t.Errorf("page 2 = %+v, %q", got, errOut)
+	}
+
+	// A binary changed on disk is no longer the locked version.
+	if err := os.WriteFile(filepath.Join(dir, "tool"), []byte("other"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	o.Page = 1
+	if err := o.Run(); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1] != (ReleaseVersion{Version: "v1.1.0", Locked: true}) {
+		t.Errorf("modified binary = %+v", got)
+	}
+}
+
+func TestVersionsOptions_Errors(t *testing.T) {
+	io := &streams.IO{Out: &bytes.Buffer{}, ErrOut: &bytes.Buffer{}}
+	shared := NewSharedOptions(io, []*binary.Binary{{Name: "plain", URL: "https://example.com/plain"}})
+
+	o := &VersionsOptions{SharedOptions: shared, Page: 1, PerPage: 10}
+	if err := o.Complete([]string{"nonexistent"}); err == nil || !strings.Contains(err.Error(), "unknown binary") {
+		t.Errorf("unknown: err = %v", err)
+	}
+	if err := o.Complete([]string{"plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(); err == nil || !strings.Contains(err.Error(), "needs a GitHub release preset") {
+		t.Errorf("unlistable: err = %v", err)
+	}
+	o.Page = 0
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for --page 0")
+	}
+}
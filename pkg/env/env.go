@@ -14,6 +14,7 @@ import (
 	"github.com/fentas/b/pkg/envmatch"
 	"github.com/fentas/b/pkg/gitcache"
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
 )
 
 // Strategy constants for env update behavior.
@@ -98,6 +99,11 @@ func SyncEnv(cfg EnvConfig, projectRoot, cacheRoot string, lockEntry *lock.EnvEn
 		if err != nil {
 			return nil, fmt.Errorf("resolving %s@%s: %w", cfg.Ref, cfg.Version, err)
 		}
+	} else if offline.Enabled() {
+		commit, err = offlineCommit(cfg, cacheRoot, baseRef, lockEntry)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		commit, err = gitcache.ResolveRefAuth(resolved.URL, cfg.Version, resolved.AuthHeader)
 		if err != nil {
@@ -853,6 +859,26 @@ func ValidatePathUnderRoot(root, destPath string) error {
 	return nil
 }
 
+// offlineCommit resolves cfg's version without the network: the commit
+// b.lock recorded for the same version, else what the cached clone knows
+// the version as.
+func offlineCommit(cfg EnvConfig, cacheRoot, baseRef string, lockEntry *lock.EnvEntry) (string, error) {
+	if lockEntry != nil && lockEntry.Commit != "" && lockEntry.Version == cfg.Version {
+		return lockEntry.Commit, nil
+	}
+	version := cfg.Version
+	if version == "" {
+		version = "HEAD"
+	}
+	dir := gitcache.CacheDir(cacheRoot, baseRef)
+	if _, err := os.Stat(dir); err == nil {
+		if commit, err := gitcache.ResolveLocalRef(dir, version); err == nil {
+			return commit, nil
+		}
+	}
+	return "", offline.Missing(cfg.Ref+"@"+version, "not in b.lock or the git cache")
+}
+
 // lockedStateInSync reports whether the on-disk state still matches the lock
 // AND, where verifiable, the source content at the pinned commit — the
 // precondition for SyncEnv's "(up to date)" fast path.
@@ -2,6 +2,7 @@ package env
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,7 +11,9 @@ import (
 	"testing"
 
 	"github.com/fentas/b/pkg/envmatch"
+	"github.com/fentas/b/pkg/gitcache"
 	"github.com/fentas/b/pkg/lock"
+	"github.com/fentas/b/pkg/offline"
 )
 
 // setupLocalBareRepo creates a work repo with files, commits, then clones to a bare repo.
@@ -570,3 +573,56 @@ func TestSyncEnv_NoMatch(t *testing.T) {
 		t.Error("expected no-match error")
 	}
 }
+
+// TestSyncEnv_Offline: offline, a remote env syncs from the clone already
+// in the git cache, pinned by b.lock when it has the version, and fails
+// with an offline error when the cache doesn't have the repo.
+func TestSyncEnv_Offline(t *testing.T) {
+	bare, commit := setupLocalBareRepo(t)
+	const ref = "github.com/org/infra"
+	cacheRoot := t.TempDir()
+	if out, err := exec.Command("git", "clone", "--bare", "-q", bare, gitcache.CacheDir(cacheRoot, ref)).CombinedOutput(); err != nil {
+		t.Fatalf("clone: %v\n%s", err, out)
+	}
+	offline.Set(true)
+	t.Cleanup(func() { offline.Set(false) })
+
+	tests := []struct {
+		name      string
+		version   string
+		cacheRoot string
+		lock      *lock.EnvEntry
+		missing   bool
+	}{
+		{name: "head of the cached clone", cacheRoot: cacheRoot},
+		{name: "locked version", version: "v1.0.0", cacheRoot: cacheRoot, lock: &lock.EnvEntry{Ref: ref, Version: "v1.0.0", Commit: commit}},
+		{name: "version not locked nor cached", version: "v2.0.0", cacheRoot: cacheRoot, lock: &lock.EnvEntry{Ref: ref, Version: "v1.0.0", Commit: commit}, missing: true},
+		{name: "repo not cached", cacheRoot: t.TempDir(), missing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			cfg := EnvConfig{
+				Ref:     ref,
+				Version: tt.version,
+				Files:   map[string]envmatch.GlobConfig{"cfg/*.yaml": {Dest: "configs"}},
+			}
+			res, err := SyncEnv(cfg, project, tt.cacheRoot, tt.lock)
+			if tt.missing {
+				if !errors.Is(err, offline.ErrOffline) {
+					t.Fatalf("SyncEnv() error = %v, want an offline error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Commit != commit {
+				t.Errorf("commit = %s, want %s", res.Commit, commit)
+			}
+			if _, err := os.Stat(filepath.Join(project, "configs", "a.yaml")); err != nil {
+				t.Errorf("file not synced: %v", err)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package gitcache
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/fentas/b/pkg/offline"
 )
 
 func setupBareRepo(t *testing.T) (bareDir, commit string) {
@@ -74,3 +77,57 @@ func TestGitcache_ResolveRef_NotFound(t *testing.T) {
 		t.Error("expected error")
 	}
 }
+
+func TestGitcache_ListTags(t *testing.T) {
+	bare, _ := setupBareRepo(t)
+	for _, tag := range []string{"v1.0.0", "v1.1.0"} {
+		if out, err := exec.Command("git", "-C", bare, "tag", tag).CombinedOutput(); err != nil {
+			t.Fatalf("%v\n%s", err, out)
+		}
+	}
+	tags, err := ListTagsAuth(bare, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tags, ",") != "v1.0.0,v1.1.0" {
+		t.Errorf("tags = %v", tags)
+	}
+	if _, err := ListTagsAuth(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Error("expected error for missing repo")
+	}
+}
+
+func TestGitcache_Offline(t *testing.T) {
+	bare, commit := setupBareRepo(t)
+	root := t.TempDir()
+	if err := EnsureClone(root, bare, bare); err != nil {
+		t.Fatal(err)
+	}
+	offline.Set(true)
+	t.Cleanup(func() { offline.Set(false) })
+
+	tests := []struct {
+		name    string
+		run     func() error
+		missing bool
+	}{
+		{"cached clone", func() error { return EnsureClone(root, bare, bare) }, false},
+		{"uncached clone", func() error { return EnsureClone(t.TempDir(), bare, bare) }, true},
+		{"cached commit", func() error { return Fetch(root, bare, commit) }, false},
+		{"uncached commit", func() error { return Fetch(root, bare, strings.Repeat("0", 40)) }, true},
+		{"local ls-remote", func() error { _, err := ResolveRef(bare, ""); return err }, false},
+		{"remote ls-remote", func() error { _, err := ResolveRef("https://github.com/org/infra", "v1"); return err }, true},
+		{"remote tags", func() error { _, err := ListTagsAuth("https://github.com/org/infra", ""); return err }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if tt.missing != errors.Is(err, offline.ErrOffline) {
+				t.Errorf("error = %v, want offline error %v", err, tt.missing)
+			}
+			if !tt.missing && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
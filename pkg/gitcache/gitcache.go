@@ -12,6 +12,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/fentas/b/pkg/offline"
 )
 
 // DefaultCacheRoot returns ~/.cache/b/repos.
@@ -39,6 +41,9 @@ func EnsureCloneAuth(root, ref, url, authHeader string) error {
 	if _, err := os.Stat(dir); err == nil {
 		return nil // already cached
 	}
+	if offline.Enabled() {
+		return offline.Missing(url, "not in the git cache (%s)", root)
+	}
 	if err := os.MkdirAll(root, 0755); err != nil {
 		return fmt.Errorf("creating cache root %s: %w", root, err)
 	}
@@ -56,6 +61,13 @@ func Fetch(root, ref, commitOrTag string) error {
 
 // FetchAuth fetches with optional auth token.
 func FetchAuth(root, ref, commitOrTag, authHeader string) error {
+	if offline.Enabled() {
+		// Nothing to fetch when the cache has it already.
+		if HasCommit(root, ref, commitOrTag) {
+			return nil
+		}
+		return offline.Missing(ref+"@"+commitOrTag, "not in the git cache (%s)", root)
+	}
 	dir := CacheDir(root, ref)
 	ac := authCmd(authHeader, "-C", dir, "fetch", "--depth", "1", "origin", commitOrTag)
 	if err := runAuth(ac); err != nil {
@@ -73,6 +85,75 @@ func HasCommit(root, ref, commit string) bool {
 	return cmd.Run() == nil
 }
 
+// ExportCommits creates a shallow bare repository at dest holding only the
+// given commits of ref's cache clone, their trees and blobs — what
+// ImportCommits needs on another machine. Only dest's objects/ and shallow
+// carry content.
+func ExportCommits(root, ref, dest string, commits []string) error {
+	if err := runAuth(AuthCmd{Args: []string{"git", "init", "-q", "--bare", dest}}); err != nil {
+		return err
+	}
+	src := CacheDir(root, ref)
+	for _, c := range commits {
+		if err := fetchCommit(dest, src, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportCommits fetches commits from src, a repository written by
+// ExportCommits (objects/ and shallow suffice), into ref's cache clone.
+// A missing clone is created with url as its origin, so later online
+// fetches work as if it had been cloned. git checks every object it
+// receives, so a damaged src fails here rather than during a sync.
+func ImportCommits(root, ref, url, src string, commits []string) error {
+	if err := runAuth(AuthCmd{Args: []string{"git", "init", "-q", "--bare", src}}); err != nil {
+		return err
+	}
+	dir := CacheDir(root, ref)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		// Build the clone next to its final place and move it there once
+		// it holds the commits; a half-built dir would pass as cached.
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return fmt.Errorf("creating cache root %s: %w", root, err)
+		}
+		tmp, err := os.MkdirTemp(root, ".import-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		if err := runAuth(AuthCmd{Args: []string{"git", "init", "-q", "--bare", tmp}}); err != nil {
+			return err
+		}
+		if err := runAuth(AuthCmd{Args: []string{"git", "-C", tmp, "config", "remote.origin.url", url}}); err != nil {
+			return err
+		}
+		for _, c := range commits {
+			if err := fetchCommit(tmp, src, c); err != nil {
+				return err
+			}
+		}
+		return os.Rename(tmp, dir)
+	}
+	for _, c := range commits {
+		if HasCommit(root, ref, c) {
+			continue
+		}
+		if err := fetchCommit(dir, src, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchCommit fetches a single commit by id from the local repository src
+// into dir, cut off at depth 1 like the cache's own clones.
+func fetchCommit(dir, src, commit string) error {
+	return runAuth(AuthCmd{Args: []string{"git", "-c", "uploadpack.allowAnySHA1InWant=true",
+		"-C", dir, "fetch", "-q", "--depth", "1", src, commit}})
+}
+
 // redactWrap wraps an error with a redacted message while preserving the error chain.
 func redactWrap(err error, authHeader string) error {
 	if authHeader == "" {
@@ -109,6 +190,9 @@ func ResolveRefAuth(url, version, authHeader string) (string, error) {
 	if version == "" {
 		version = "HEAD"
 	}
+	if offline.Enabled() && !isLocalPath(url) {
+		return "", offline.Missing(url+"@"+version, "resolving it needs git ls-remote")
+	}
 	ac := authCmd(authHeader, "ls-remote", url, version)
 	out, err := outputAuth(ac)
 	if err != nil {
@@ -137,6 +221,27 @@ func ResolveRefAuth(url, version, authHeader string) (string, error) {
 	return "", fmt.Errorf("could not resolve %q for %s", version, url)
 }
 
+// ListTagsAuth lists the tag names of a repository via ls-remote. url may
+// also be a local repository path.
+func ListTagsAuth(url, authHeader string) ([]string, error) {
+	if offline.Enabled() && !isLocalPath(url) {
+		return nil, offline.Missing(url, "listing tags needs git ls-remote")
+	}
+	ac := authCmd(authHeader, "ls-remote", "--tags", "--refs", url)
+	out, err := outputAuth(ac)
+	if err != nil {
+		return nil, fmt.Errorf("git ls-remote --tags %s: %w", url, redactWrap(err, authHeader))
+	}
+	var tags []string
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) >= 2 {
+			tags = append(tags, strings.TrimPrefix(parts[1], "refs/tags/"))
+		}
+	}
+	return tags, nil
+}
+
 // TreeEntry represents a single entry from git ls-tree with its file mode.
 type TreeEntry struct {
 	Path string
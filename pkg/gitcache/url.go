@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/fentas/b/pkg/hosts"
 )
 
 // ResolvedRef holds a parsed and resolved git reference.
@@ -156,13 +158,20 @@ type authInfo struct {
 }
 
 // detectAuth returns the auth token and formatted header for a given ref.
+// Hosts configured in b.yaml `hosts:` use their token env and header format.
 func detectAuth(ref string) authInfo {
 	host := ref
 	if i := strings.Index(host, "/"); i > 0 {
 		host = host[:i]
 	}
+	if h, ok := hosts.Lookup(host); ok {
+		return hostAuth(h)
+	}
 	if i := strings.Index(host, ":"); i > 0 {
 		host = host[:i]
+		if h, ok := hosts.Lookup(host); ok {
+			return hostAuth(h)
+		}
 	}
 
 	var token string
@@ -186,6 +195,16 @@ func detectAuth(ref string) authInfo {
 	return authInfo{}
 }
 
+// hostAuth returns the auth for a configured host.
+func hostAuth(h *hosts.Host) authInfo {
+	token := h.Token()
+	if token == "" {
+		return authInfo{}
+	}
+	key, value := h.AuthHeader(token)
+	return authInfo{token: token, header: key + ": " + value}
+}
+
 // redactAuth removes auth secrets from strings (for safe error messages).
 // Accepts either a raw token or a full auth header — extracts and redacts
 // the secret part in both cases.
@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/fentas/b/pkg/hosts"
 )
 
 // --- Comprehensive resolution table ---
@@ -429,6 +431,41 @@ func TestDetectAuth_UnknownHost(t *testing.T) {
 	}
 }
 
+func TestDetectAuth_ConfiguredHosts(t *testing.T) {
+	if err := hosts.Set(map[string]*hosts.Host{
+		"gitlab.example.corp":      {Type: hosts.GitLab, TokenEnv: "CORP_GITLAB_TOKEN"},
+		"github.example.corp:8443": {Type: hosts.GitHub},
+		"gitea.example.corp":       {Type: hosts.Gitea},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = hosts.Set(nil) })
+	t.Setenv("CORP_GITLAB_TOKEN", "corp-gl")
+	t.Setenv("GITLAB_TOKEN", "public-gl")
+	t.Setenv("GITHUB_TOKEN", "ghe")
+	t.Setenv("GITEA_TOKEN", "")
+
+	tests := []struct {
+		ref, token, header string
+	}{
+		{"gitlab.example.corp/group/repo", "corp-gl", "PRIVATE-TOKEN: corp-gl"},
+		{"github.example.corp:8443/org/repo", "ghe", "Authorization: Bearer ghe"},
+		{"gitea.example.corp/org/repo", "", ""},
+		{"gitlab.com/org/repo", "public-gl", "PRIVATE-TOKEN: public-gl"},
+	}
+	for _, tt := range tests {
+		auth := detectAuth(tt.ref)
+		if auth.token != tt.token || auth.header != tt.header {
+			t.Errorf("detectAuth(%q) = %+v, want token %q header %q", tt.ref, auth, tt.token, tt.header)
+		}
+	}
+
+	r := ResolveGitURL("gitlab.example.corp/group/repo@v1", "")
+	if r.URL != "https://gitlab.example.corp/group/repo.git" || r.AuthHeader != "PRIVATE-TOKEN: corp-gl" {
+		t.Errorf("ResolveGitURL = %+v", r)
+	}
+}
+
 func TestDetectAuth_SpoofedHost(t *testing.T) {
 	t.Setenv("GITHUB_TOKEN", "ghp_secret")
 	auth := detectAuth("github.evil.example/org/repo")
@@ -0,0 +1,160 @@
+// Package hosts holds the self-hosted forge configuration from b.yaml
+// `hosts:`. It maps a hostname to the forge software it runs, its API base
+// URL and the environment variable holding its token, so refs on GitHub
+// Enterprise or self-hosted GitLab/Gitea resolve like public ones.
+//
+//	hosts:
+//	  github.example.corp:
+//	    type: github
+//	  gitlab.example.corp:
+//	    type: gitlab
+//	    api: https://gitlab.example.corp/api/v4
+//	    tokenEnv: CORP_GITLAB_TOKEN
+package hosts
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Forge types.
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+	Gitea  = "gitea"
+)
+
+// Host configures one forge host.
+type Host struct {
+	// Type is the forge software: github, gitlab or gitea.
+	Type string `json:"type" yaml:"type"`
+	// API is the API base URL. Defaults to https://<host>/api/v3 for
+	// GitHub Enterprise, /api/v4 for GitLab and /api/v1 for Gitea.
+	API string `json:"api,omitempty" yaml:"api,omitempty"`
+	// TokenEnv names the environment variable holding the access token.
+	// Defaults to GITHUB_TOKEN, GITLAB_TOKEN or GITEA_TOKEN.
+	TokenEnv string `json:"tokenEnv,omitempty" yaml:"tokenEnv,omitempty"`
+}
+
+var (
+	mu         sync.RWMutex
+	configured = map[string]*Host{}
+)
+
+// Set replaces the configured hosts. Names are matched case-insensitively
+// against the first segment of a ref, including any port.
+func Set(hosts map[string]*Host) error {
+	next := make(map[string]*Host, len(hosts))
+	for name, h := range hosts {
+		if err := h.Validate(); err != nil {
+			return fmt.Errorf("hosts: %s: %w", name, err)
+		}
+		next[strings.ToLower(name)] = h
+	}
+	mu.Lock()
+	configured = next
+	mu.Unlock()
+	return nil
+}
+
+// Lookup returns the configured host called name.
+func Lookup(name string) (*Host, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	h, ok := configured[strings.ToLower(name)]
+	return h, ok
+}
+
+// Split splits a ref like "github.example.corp/org/tool" into its host and
+// the path below it. A scheme prefix (https://) is dropped.
+func Split(ref string) (host, rest string) {
+	if i := strings.Index(ref, "://"); i >= 0 {
+		ref = ref[i+3:]
+	}
+	host, rest, _ = strings.Cut(ref, "/")
+	return host, rest
+}
+
+// ForRef returns the configured host ref lives on, with the host name and
+// the path below it.
+func ForRef(ref string) (h *Host, name, rest string, ok bool) {
+	name, rest = Split(ref)
+	h, ok = Lookup(name)
+	return h, name, rest, ok
+}
+
+// Validate reports an unknown type or a malformed API URL.
+func (h *Host) Validate() error {
+	if h == nil {
+		return fmt.Errorf("missing type")
+	}
+	switch h.Type {
+	case GitHub, GitLab, Gitea:
+	case "":
+		return fmt.Errorf("missing type (github, gitlab or gitea)")
+	default:
+		return fmt.Errorf("unknown type %q (github, gitlab or gitea)", h.Type)
+	}
+	if h.API != "" {
+		u, err := url.Parse(h.API)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("api %q is not an http(s) URL", h.API)
+		}
+	}
+	return nil
+}
+
+// APIBase returns the API base URL for the host called name, without a
+// trailing slash.
+func (h *Host) APIBase(name string) string {
+	if h.API != "" {
+		return strings.TrimRight(h.API, "/")
+	}
+	switch h.Type {
+	case GitHub:
+		if strings.EqualFold(name, "github.com") {
+			return "https://api.github.com"
+		}
+		return "https://" + name + "/api/v3"
+	case GitLab:
+		return "https://" + name + "/api/v4"
+	default:
+		return "https://" + name + "/api/v1"
+	}
+}
+
+// Token returns the host's access token from the environment.
+func (h *Host) Token() string {
+	return os.Getenv(h.TokenVar())
+}
+
+// TokenVar returns the environment variable the token is read from.
+func (h *Host) TokenVar() string {
+	if h.TokenEnv != "" {
+		return h.TokenEnv
+	}
+	switch h.Type {
+	case GitLab:
+		return "GITLAB_TOKEN"
+	case Gitea:
+		return "GITEA_TOKEN"
+	default:
+		return "GITHUB_TOKEN"
+	}
+}
+
+// AuthHeader returns the HTTP header carrying token in the format the
+// forge's API expects.
+func (h *Host) AuthHeader(token string) (key, value string) {
+	switch h.Type {
+	case GitLab:
+		return "PRIVATE-TOKEN", token
+	case Gitea:
+		return "Authorization", "token " + token
+	default:
+		return "Authorization", "Bearer " + token
+	}
+}
@@ -0,0 +1,136 @@
+package hosts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetLookup(t *testing.T) {
+	t.Cleanup(func() { _ = Set(nil) })
+
+	err := Set(map[string]*Host{
+		"GitHub.Example.Corp": {Type: GitHub},
+		"git.corp:8443":       {Type: GitLab, API: "https://git.corp:8443/api/v4/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, ok := Lookup("github.example.corp"); !ok || h.Type != GitHub {
+		t.Errorf("Lookup(github.example.corp) = %+v, %v", h, ok)
+	}
+	if _, ok := Lookup("git.corp"); ok {
+		t.Error("port is part of the host name")
+	}
+	h, name, rest, ok := ForRef("git.corp:8443/group/sub/tool")
+	if !ok || h.Type != GitLab || name != "git.corp:8443" || rest != "group/sub/tool" {
+		t.Errorf("ForRef = %+v %q %q %v", h, name, rest, ok)
+	}
+
+	// Set replaces, it doesn't merge.
+	if err := Set(map[string]*Host{"gitea.corp": {Type: Gitea}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := Lookup("github.example.corp"); ok {
+		t.Error("previous hosts kept after Set")
+	}
+
+	// An invalid entry leaves the configuration untouched.
+	if err := Set(map[string]*Host{"x": {Type: "bitbucket"}}); err == nil || !strings.Contains(err.Error(), "hosts: x") {
+		t.Errorf("err = %v", err)
+	}
+	if _, ok := Lookup("gitea.corp"); !ok {
+		t.Error("invalid Set cleared the configuration")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		ref, host, rest string
+	}{
+		{"github.example.corp/org/tool", "github.example.corp", "org/tool"},
+		{"https://gitlab.corp/group/tool", "gitlab.corp", "group/tool"},
+		{"gitlab.corp", "gitlab.corp", ""},
+	}
+	for _, tt := range tests {
+		host, rest := Split(tt.ref)
+		if host != tt.host || rest != tt.rest {
+			t.Errorf("Split(%q) = %q, %q; want %q, %q", tt.ref, host, rest, tt.host, tt.rest)
+		}
+	}
+}
+
+func TestHost_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    *Host
+		wantErr string
+	}{
+		{"github", &Host{Type: GitHub}, ""},
+		{"with api", &Host{Type: GitLab, API: "http://127.0.0.1:8080/api/v4"}, ""},
+		{"nil", nil, "missing type"},
+		{"no type", &Host{}, "missing type"},
+		{"unknown type", &Host{Type: "svn"}, "unknown type"},
+		{"relative api", &Host{Type: Gitea, API: "/api/v1"}, "not an http(s) URL"},
+		{"ftp api", &Host{Type: Gitea, API: "ftp://x/api"}, "not an http(s) URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.host.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHost_APIBase(t *testing.T) {
+	tests := []struct {
+		host *Host
+		name string
+		want string
+	}{
+		{&Host{Type: GitHub}, "github.com", "https://api.github.com"},
+		{&Host{Type: GitHub}, "github.example.corp", "https://github.example.corp/api/v3"},
+		{&Host{Type: GitLab}, "gitlab.corp", "https://gitlab.corp/api/v4"},
+		{&Host{Type: Gitea}, "codeberg.org", "https://codeberg.org/api/v1"},
+		{&Host{Type: GitHub, API: "https://ghe.corp/api/v3/"}, "github.example.corp", "https://ghe.corp/api/v3"},
+	}
+	for _, tt := range tests {
+		if got := tt.host.APIBase(tt.name); got != tt.want {
+			t.Errorf("%s APIBase(%q) = %q, want %q", tt.host.Type, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHost_Auth(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "gh")
+	t.Setenv("GITLAB_TOKEN", "gl")
+	t.Setenv("GITEA_TOKEN", "gt")
+	t.Setenv("CORP_TOKEN", "corp")
+
+	tests := []struct {
+		host              *Host
+		token, key, value string
+	}{
+		{&Host{Type: GitHub}, "gh", "Authorization", "Bearer gh"},
+		{&Host{Type: GitLab}, "gl", "PRIVATE-TOKEN", "gl"},
+		{&Host{Type: Gitea}, "gt", "Authorization", "token gt"},
+		{&Host{Type: GitLab, TokenEnv: "CORP_TOKEN"}, "corp", "PRIVATE-TOKEN", "corp"},
+	}
+	for _, tt := range tests {
+		token := tt.host.Token()
+		if token != tt.token {
+			t.Errorf("%+v: token = %q, want %q", tt.host, token, tt.token)
+		}
+		key, value := tt.host.AuthHeader(token)
+		if key != tt.key || value != tt.value {
+			t.Errorf("%+v: header = %s: %s, want %s: %s", tt.host, key, value, tt.key, tt.value)
+		}
+	}
+}
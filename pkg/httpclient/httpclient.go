@@ -0,0 +1,251 @@
+// Package httpclient is the HTTP client b uses for provider APIs, release
+// downloads and registries. It bounds how long a silent server may stall,
+// retries transient failures, and applies the b.yaml `network:` settings:
+//
+//	network:
+//	  timeout: 30s                       # stall timeout, "0" disables
+//	  retries: 3                         # on 5xx, 429 and timeouts
+//	  proxy: http://proxy.example.corp:3128
+//	  noProxy: [localhost, .example.corp]
+//	  caFiles: [certs/corp-root.pem]     # relative to b.yaml
+//	  userAgent: my-ci/1.0
+//
+// Without a proxy setting, HTTPS_PROXY, HTTP_PROXY and NO_PROXY apply as
+// usual; SSL_CERT_FILE and SSL_CERT_DIR replace the system roots.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for an unset Config field.
+const (
+	DefaultTimeout = 30 * time.Second
+	DefaultRetries = 3
+)
+
+// DefaultUserAgent is sent when b.yaml sets no userAgent. The CLI sets it
+// to "b/<version>".
+var DefaultUserAgent = "b"
+
+// Config is the b.yaml `network:` block.
+type Config struct {
+	// Timeout is how long a server may go silent — before answering or in
+	// the middle of a download — before the request fails. It bounds
+	// stalls, not transfers, so large downloads are unaffected. Go
+	// duration syntax; "0" disables it. Defaults to 30s.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Retries is how often a GET or HEAD is retried after a 5xx, a 429 or
+	// a timeout, with exponential backoff that honours Retry-After.
+	// Defaults to 3; 0 disables retries.
+	Retries *int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Proxy is the proxy URL for all requests, replacing the
+	// HTTP(S)_PROXY environment.
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+	// NoProxy lists hosts, domains (".example.corp") and CIDRs that
+	// bypass Proxy.
+	NoProxy []string `json:"noProxy,omitempty" yaml:"noProxy,omitempty"`
+	// CAFiles are PEM bundles trusted in addition to the system roots,
+	// relative to b.yaml.
+	CAFiles []string `json:"caFiles,omitempty" yaml:"caFiles,omitempty"`
+	// UserAgent replaces the User-Agent header b sends.
+	UserAgent string `json:"userAgent,omitempty" yaml:"userAgent,omitempty"`
+}
+
+// settings is a validated Config.
+type settings struct {
+	timeout   time.Duration
+	retries   int
+	userAgent string
+	// base carries the proxy and CA settings; nil uses
+	// http.DefaultTransport, looked up per request.
+	base http.RoundTripper
+}
+
+var (
+	mu      sync.RWMutex
+	current = &settings{timeout: DefaultTimeout, retries: DefaultRetries}
+)
+
+// Set applies cfg to every request from now on; nil restores the defaults.
+// Relative CAFiles are read from dir.
+func Set(cfg *Config, dir string) error {
+	s, err := cfg.settings(dir)
+	if err != nil {
+		return fmt.Errorf("network: %w", err)
+	}
+	mu.Lock()
+	current = s
+	mu.Unlock()
+	return nil
+}
+
+func load() *settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+func (c *Config) settings(dir string) (*settings, error) {
+	s := &settings{timeout: DefaultTimeout, retries: DefaultRetries}
+	if c == nil {
+		return s, nil
+	}
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("timeout %q is not a duration such as 30s or 2m", c.Timeout)
+		}
+		s.timeout = d
+	}
+	if c.Retries != nil {
+		if *c.Retries < 0 {
+			return nil, fmt.Errorf("retries must not be negative")
+		}
+		s.retries = *c.Retries
+	}
+	s.userAgent = c.UserAgent
+
+	if c.Proxy == "" && len(c.NoProxy) == 0 && len(c.CAFiles) == 0 {
+		return s, nil
+	}
+	t := &http.Transport{}
+	if dt, ok := http.DefaultTransport.(*http.Transport); ok {
+		t = dt.Clone()
+	}
+	if c.Proxy != "" || len(c.NoProxy) > 0 {
+		proxy, err := c.proxyFunc()
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = proxy
+	}
+	if len(c.CAFiles) > 0 {
+		pool, err := certPool(c.CAFiles, dir)
+		if err != nil {
+			return nil, err
+		}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.RootCAs = pool
+	}
+	s.base = t
+	return s, nil
+}
+
+// proxyFunc resolves the proxy per request URL. An unset Proxy keeps the
+// environment's proxies and only adds the NoProxy exclusions. Loopback
+// hosts are never proxied, as with the environment.
+func (c *Config) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	var fixed *url.URL
+	if c.Proxy != "" {
+		u, err := url.Parse(c.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("proxy %q is not a URL", c.Proxy)
+		}
+		fixed = u
+	}
+	noProxy := slices.Clone(c.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL, noProxy) {
+			return nil, nil
+		}
+		if fixed != nil {
+			return fixed, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}, nil
+}
+
+// bypassProxy reports whether u goes direct: a loopback host, or one
+// matched by a noProxy entry — "*", a host ("example.corp" also covers its
+// subdomains), a ".domain", a "host:port" or a CIDR.
+func bypassProxy(u *url.URL, noProxy []string) bool {
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host)
+	if host == "localhost" || ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case strings.Contains(entry, "/"):
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if h, port, err := net.SplitHostPort(entry); err == nil {
+			if port != u.Port() {
+				continue
+			}
+			entry = h
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// certPool returns the system roots plus the PEM bundles in files.
+func certPool(files []string, dir string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, f := range files {
+		if !filepath.IsAbs(f) && dir != "" {
+			f = filepath.Join(dir, f)
+		}
+		pem, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("caFiles: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("caFiles: %s holds no PEM certificates", f)
+		}
+	}
+	return pool, nil
+}
+
+// Client returns the shared client. It has no overall timeout; stalls are
+// bounded by the transport instead.
+func Client() *http.Client {
+	return client
+}
+
+// Transport returns the shared transport, for libraries that take an
+// http.RoundTripper such as the container registry client.
+func Transport() http.RoundTripper {
+	return transport{}
+}
+
+var client = &http.Client{Transport: transport{}}
+
+// Get issues a GET with the shared client.
+func Get(url string) (*http.Response, error) {
+	return client.Get(url)
+}
+
+// Do sends req with the shared client.
+func Do(req *http.Request) (*http.Response, error) {
+	return client.Do(req)
+}
@@ -0,0 +1,167 @@
+package httpclient
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// configure applies cfg for the test and restores the defaults after it,
+// with a short backoff so retries don't slow the suite down.
+func configure(t *testing.T, cfg *Config) {
+	t.Helper()
+	if err := Set(cfg, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	backoffWas := initialBackoff
+	initialBackoff = time.Millisecond
+	t.Cleanup(func() {
+		initialBackoff = backoffWas
+		_ = Set(nil, "")
+	})
+}
+
+func retries(n int) *int { return &n }
+
+func TestSet_Errors(t *testing.T) {
+	t.Cleanup(func() { _ = Set(nil, "") })
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("not a cert"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr string
+	}{
+		{"timeout", &Config{Timeout: "soon"}, `timeout "soon"`},
+		{"negative timeout", &Config{Timeout: "-1s"}, `timeout "-1s"`},
+		{"retries", &Config{Retries: retries(-1)}, "retries must not be negative"},
+		{"proxy", &Config{Proxy: "::"}, `proxy "::"`},
+		{"missing ca", &Config{CAFiles: []string{"missing.pem"}}, "caFiles:"},
+		{"empty ca", &Config{CAFiles: []string{"empty.pem"}}, "holds no PEM certificates"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Set(tt.cfg, dir)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasPrefix(err.Error(), "network: ") {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get("User-Agent"))
+	}))
+	defer srv.Close()
+
+	configure(t, nil)
+	was := DefaultUserAgent
+	DefaultUserAgent = "b/1.2.3"
+	t.Cleanup(func() { DefaultUserAgent = was })
+	resp, err := Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Load() != "b/1.2.3" {
+		t.Errorf("User-Agent = %v", got.Load())
+	}
+
+	configure(t, &Config{UserAgent: "corp-ci/1.0"})
+	resp, err = Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got.Load() != "corp-ci/1.0" {
+		t.Errorf("User-Agent = %v", got.Load())
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	configure(t, &Config{Proxy: proxy.URL, NoProxy: []string{".internal.corp"}})
+	resp, err := Get("http://releases.example.corp/tool.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "via proxy" || proxied.Load() != "http://releases.example.corp/tool.tar.gz" {
+		t.Errorf("body = %q, proxied = %v", body, proxied.Load())
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := []string{"example.corp", ".internal", "mirror.corp:8443", "10.0.0.0/8", " "}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"http://localhost:8080/x", true},
+		{"http://127.0.0.1/x", true},
+		{"https://example.corp/x", true},
+		{"https://dl.example.corp/x", true},
+		{"https://notexample.corp/x", false},
+		{"https://git.internal/x", true},
+		{"https://mirror.corp:8443/x", true},
+		{"https://mirror.corp/x", false},
+		{"http://10.1.2.3/x", true},
+		{"http://192.168.1.1/x", false},
+		{"https://github.com/x", false},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		if got := bypassProxy(u, noProxy); got != tt.want {
+			t.Errorf("bypassProxy(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+	u, _ := url.Parse("https://github.com/x")
+	if !bypassProxy(u, []string{"*"}) {
+		t.Error("* bypasses every host")
+	}
+}
+
+func TestCAFiles(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	configure(t, nil)
+	if _, err := Get(srv.URL); err == nil {
+		t.Fatal("self-signed server trusted without a CA bundle")
+	}
+
+	dir := t.TempDir()
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(filepath.Join(dir, "corp.pem"), bundle, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(&Config{CAFiles: []string{"corp.pem"}}, dir); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := Get(srv.URL)
+	if err != nil {
+		t.Fatalf("with CA bundle: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,233 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fentas/b/pkg/offline"
+)
+
+// Backoff between retries: initialBackoff doubles per attempt up to
+// maxBackoff. A Retry-After longer than maxRetryAfter is not waited for;
+// the response is returned as is.
+var (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+	maxRetryAfter  = 60 * time.Second
+)
+
+// errStalled is the cause of a request the stall timer cancelled.
+var errStalled = errors.New("server stalled")
+
+// transport applies the current settings to each request.
+type transport struct{}
+
+func (transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if offline.Enabled() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, offline.Missing(req.URL.Redacted(), "%s needs the network", req.Method)
+	}
+	s := load()
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		ua := s.userAgent
+		if ua == "" {
+			ua = DefaultUserAgent
+		}
+		req.Header.Set("User-Agent", ua)
+	}
+	retryable := (req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		(req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+
+	for attempt := 0; ; attempt++ {
+		resp, err := s.roundTrip(req)
+		if !retryable || attempt >= s.retries || !shouldRetry(resp, err) {
+			return resp, err
+		}
+		wait, ok := backoff(attempt, resp)
+		if !ok {
+			// The server asked for a longer pause than is worth waiting.
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// roundTrip sends one attempt. The request is cancelled when the server
+// stays silent for the timeout: before the response headers, or between
+// two reads of the body.
+func (s *settings) roundTrip(req *http.Request) (*http.Response, error) {
+	base := s.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if s.timeout == 0 {
+		return base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancelCause(req.Context())
+	timer := time.AfterFunc(s.timeout, func() { cancel(errStalled) })
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		stalled := context.Cause(ctx) == errStalled
+		cancel(nil)
+		if stalled {
+			return nil, &stallError{url: req.URL.Redacted(), timeout: s.timeout, headers: true}
+		}
+		return nil, err
+	}
+	resp.Body = &stallReader{
+		ReadCloser: resp.Body,
+		ctx:        ctx,
+		timer:      timer,
+		timeout:    s.timeout,
+		cancel:     cancel,
+		url:        req.URL.Redacted(),
+	}
+	return resp, nil
+}
+
+// shouldRetry reports whether a failed attempt is worth repeating: a server
+// error other than 501, a 429 or 408, a timeout, or a connection cut short.
+// Refused connections and DNS failures are not, they rarely heal in seconds.
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		var stall *stallError
+		var nerr net.Error
+		switch {
+		case errors.As(err, &stall):
+			return true
+		case errors.As(err, &nerr) && nerr.Timeout():
+			return true
+		case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+			return true
+		}
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout:
+		return true
+	case http.StatusNotImplemented:
+		return false
+	}
+	return resp.StatusCode >= 500
+}
+
+// backoff returns the pause before the next attempt: the response's
+// Retry-After when it sets one, otherwise exponential backoff with jitter.
+// ok is false when Retry-After exceeds maxRetryAfter.
+func backoff(attempt int, resp *http.Response) (wait time.Duration, ok bool) {
+	if resp != nil {
+		if d, set := retryAfter(resp.Header.Get("Retry-After")); set {
+			return d, d <= maxRetryAfter
+		}
+	}
+	wait = initialBackoff << attempt
+	if wait <= 0 || wait > maxBackoff {
+		wait = maxBackoff
+	}
+	// Up to 20% jitter, so parallel downloads don't retry in lockstep.
+	wait += time.Duration(rand.Int64N(int64(wait)/5 + 1))
+	return wait, true
+}
+
+// retryAfter parses a Retry-After header: delay seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// stallError reports a server that went silent for the timeout.
+type stallError struct {
+	url     string
+	timeout time.Duration
+	headers bool
+}
+
+func (e *stallError) Error() string {
+	if e.headers {
+		return fmt.Sprintf("%s: no response within %s", e.url, e.timeout)
+	}
+	return fmt.Sprintf("%s: download stalled for %s", e.url, e.timeout)
+}
+
+// Timeout reports true, as for network timeouts.
+func (e *stallError) Timeout() bool { return true }
+
+// stallReader re-arms the stall timer on every read and releases it on
+// Close.
+type stallReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelCauseFunc
+	url     string
+	once    sync.Once
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	switch {
+	case err == io.EOF:
+		r.timer.Stop()
+	case n > 0:
+		r.timer.Reset(r.timeout)
+	}
+	if err != nil && err != io.EOF && context.Cause(r.ctx) == errStalled {
+		err = &stallError{url: r.url, timeout: r.timeout}
+	}
+	return n, err
+}
+
+func (r *stallReader) Close() error {
+	r.once.Do(func() {
+		r.timer.Stop()
+		r.cancel(nil)
+	})
+	return r.ReadCloser.Close()
+}
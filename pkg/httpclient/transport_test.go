@@ -0,0 +1,201 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fentas/b/pkg/offline"
+)
+
+// flaky answers the first fails requests with status, then 200 "ok".
+func flaky(fails int, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(calls.Add(1)) <= fails {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	return srv, &calls
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		fails      int
+		status     int
+		header     http.Header
+		retries    *int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"recovers from 503", 2, http.StatusServiceUnavailable, nil, nil, http.StatusOK, 3},
+		{"recovers from 429", 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}}, nil, http.StatusOK, 2},
+		{"gives up after retries", 10, http.StatusBadGateway, nil, retries(2), http.StatusBadGateway, 3},
+		{"retries disabled", 10, http.StatusBadGateway, nil, retries(0), http.StatusBadGateway, 1},
+		{"no retry on 404", 10, http.StatusNotFound, nil, nil, http.StatusNotFound, 1},
+		{"no retry on 501", 10, http.StatusNotImplemented, nil, nil, http.StatusNotImplemented, 1},
+		{"Retry-After too long", 10, http.StatusTooManyRequests, http.Header{"Retry-After": {"3600"}}, nil, http.StatusTooManyRequests, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := flaky(tt.fails, tt.status, tt.header)
+			defer srv.Close()
+			configure(t, &Config{Retries: tt.retries})
+
+			resp, err := Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || calls.Load() != tt.wantCalls {
+				t.Errorf("status %d after %d calls, want %d after %d", resp.StatusCode, calls.Load(), tt.wantStatus, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetry_OnlyIdempotent(t *testing.T) {
+	srv, calls := flaky(10, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+	configure(t, nil)
+
+	resp, err := Client().Post(srv.URL, "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("POST sent %d times", calls.Load())
+	}
+}
+
+func TestOffline(t *testing.T) {
+	srv, calls := flaky(0, http.StatusOK, nil)
+	defer srv.Close()
+	configure(t, nil)
+	offline.Set(true)
+	t.Cleanup(func() { offline.Set(false) })
+
+	_, err := Get(srv.URL + "/v1/tool.tar.gz")
+	if !errors.Is(err, offline.ErrOffline) {
+		t.Fatalf("Get() error = %v, want offline.ErrOffline", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("server saw %d requests while offline", calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d, ok := retryAfter("7"); !ok || d != 7*time.Second {
+		t.Errorf("seconds: %v %v", d, ok)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(date); !ok || d < 59*time.Minute {
+		t.Errorf("date: %v %v", d, ok)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("garbage parsed")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range []time.Duration{initialBackoff, 2 * initialBackoff, 4 * initialBackoff} {
+		got, ok := backoff(attempt, nil)
+		if !ok || got < want || got > want+want/5 {
+			t.Errorf("attempt %d: %v, want %v plus jitter", attempt, got, want)
+		}
+	}
+	if got, _ := backoff(40, nil); got > maxBackoff+maxBackoff/5 {
+		t.Errorf("backoff not capped: %v", got)
+	}
+}
+
+func TestStall_BeforeHeaders(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-release
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	defer close(release)
+	configure(t, &Config{Timeout: "50ms"})
+
+	// The first attempt hangs; the retry succeeds.
+	resp, err := Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" || calls.Load() != 2 {
+		t.Errorf("body %q after %d calls", body, calls.Load())
+	}
+
+	// Without retries the hang surfaces as an error.
+	configure(t, &Config{Timeout: "50ms", Retries: retries(0)})
+	calls.Store(0)
+	_, err = Get(srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "no response within 50ms") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStall_DuringBody(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		_, _ = io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	configure(t, &Config{Timeout: "50ms"})
+
+	resp, err := Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	_, err = io.ReadAll(resp.Body)
+	if err == nil || !strings.Contains(err.Error(), "download stalled for 50ms") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStall_SlowButSteady(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			_, _ = io.WriteString(w, "chunk")
+			w.(http.Flusher).Flush()
+			time.Sleep(30 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+	configure(t, &Config{Timeout: "50ms"})
+
+	// 150ms in total, but never silent for 50ms.
+	resp, err := Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || len(body) != 25 {
+		t.Errorf("body %q, err %v", body, err)
+	}
+}
@@ -1,6 +1,8 @@
 package lock
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -90,3 +92,82 @@ func TestRemoveEnv_PersistsAfterWrite(t *testing.T) {
 		t.Errorf("remaining ref = %q, want github.com/org/b", lk2.Envs[0].Ref)
 	}
 }
+
+// --- RemoveBinary ---
+
+func TestRemoveBinary(t *testing.T) {
+	lk := &Lock{
+		Binaries: []BinEntry{
+			{Name: "cilium"},
+			{Name: "hubble", From: "cilium"},
+		},
+	}
+
+	if !lk.RemoveBinary("hubble") {
+		t.Error("RemoveBinary should return true when found")
+	}
+	if len(lk.Binaries) != 1 || lk.FindBinary("hubble") != nil {
+		t.Errorf("binaries after remove = %v", lk.Binaries)
+	}
+	if lk.RemoveBinary("hubble") {
+		t.Error("RemoveBinary should return false when not found")
+	}
+}
+
+func TestSHA256Tree(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, mode os.FileMode) {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("bin/tool", 0755)
+	write("lib/libtool.so.1", 0644)
+	if err := os.Symlink("libtool.so.1", filepath.Join(dir, "lib", "libtool.so")); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := SHA256Tree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := SHA256Tree(dir); again != sum {
+		t.Errorf("SHA256Tree() not stable: %s != %s", again, sum)
+	}
+
+	changes := []struct {
+		name   string
+		change func() error
+	}{
+		{"exec bit", func() error { return os.Chmod(filepath.Join(dir, "bin", "tool"), 0644) }},
+		{"content", func() error { return os.WriteFile(filepath.Join(dir, "lib", "libtool.so.1"), []byte("patched"), 0644) }},
+		{"link target", func() error {
+			p := filepath.Join(dir, "lib", "libtool.so")
+			if err := os.Remove(p); err != nil {
+				return err
+			}
+			return os.Symlink("../bin/tool", p)
+		}},
+		{"new dir", func() error { return os.Mkdir(filepath.Join(dir, "share"), 0755) }},
+	}
+	for _, c := range changes {
+		if err := c.change(); err != nil {
+			t.Fatal(err)
+		}
+		next, err := SHA256Tree(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if next == sum {
+			t.Errorf("SHA256Tree() unchanged after changing the %s", c.name)
+		}
+		sum = next
+	}
+
+	if _, err := SHA256Tree(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("SHA256Tree(missing) error = %v, want not exist", err)
+	}
+}
@@ -7,9 +7,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"time"
+
+	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 )
 
 // Lock is the top-level b.lock structure.
@@ -26,22 +32,77 @@ type ToolInfo struct {
 	B string `json:"b"`
 }
 
+// FormatVersion is the b.lock format this b writes. Version 2 moved the
+// download of each binary under a per-platform key; see BinEntry.
+const FormatVersion = 2
+
+// hostPlatform is the "os/arch" key of the platform b runs on.
+var hostPlatform = runtime.GOOS + "/" + runtime.GOARCH
+
 // BinEntry is a single binary in the lockfile.
+//
+// What is downloaded differs per platform: SHA256, Asset, AssetURL,
+// AssetSHA256, Signatures and TreeSHA256. SetBinary files them under
+// Platforms, keyed "os/arch", and For reads them back for one platform.
+// Written at the top level, by b.lock format version 1, they stand for
+// the host's.
 type BinEntry struct {
 	Name     string `json:"name"`
 	Version  string `json:"version"`
-	SHA256   string `json:"sha256"`
+	SHA256   string `json:"sha256,omitempty"`
 	Source   string `json:"source"`
 	Preset   bool   `json:"preset,omitempty"`
 	Asset    string `json:"asset,omitempty"`
 	Provider string `json:"provider,omitempty"`
+	// AssetURL is where Asset was downloaded from. With AssetSHA256 it
+	// finds the asset in the download cache for `b install --offline`.
+	AssetURL string `json:"assetUrl,omitempty"`
 	// Digest is the upstream content identity at the time of the last
 	// successful install — for docker:// / oci:// binaries this is the
-	// image manifest digest (sha256:...) the tag resolved to. Empty for
+	// image manifest digest (sha256:...) the tag resolved to, for OCI
+	// artifacts the digest of the layer installed. Empty for
 	// providers that don't expose a stable digest. When non-empty, `b
 	// update` compares it against a freshly-resolved digest and skips
 	// the re-download if they match.
 	Digest string `json:"digest,omitempty"`
+	// AssetSHA256 is the sha256 of the downloaded release asset (archive
+	// or raw file) as verified against the checksum file the publisher
+	// ships with the release. SHA256 above covers the extracted binary;
+	// this one ties the install back to upstream. Empty when the release
+	// has no checksums.
+	AssetSHA256 string `json:"assetSha256,omitempty"`
+	// Signatures records the publisher signatures verified before the
+	// binary was written (see the b.yaml `verify:` block). `b verify`
+	// checks them against the current policy.
+	Signatures []signature.Result `json:"signatures,omitempty"`
+	// From is the binary whose release archive this file was extracted
+	// from, for the files of a b.yaml `extract:` list. Empty for binaries
+	// installed on their own.
+	From string `json:"from,omitempty"`
+	// TreeSHA256 is the SHA256Tree digest of the directory a b.yaml
+	// `layout: tree` binary was extracted into; SHA256 then covers the
+	// shim. Empty for single-file installs.
+	TreeSHA256 string `json:"treeSha256,omitempty"`
+	// Query is the branch or commit b.yaml asks a go:// binary for;
+	// Version is the module version it resolved to.
+	Query string `json:"query,omitempty"`
+	// Build is the b.yaml `build:` block a go:// binary was compiled with;
+	// a binary built another way is not the locked one. Nil for a plain
+	// `go install` and for other providers.
+	Build *provider.GoBuild `json:"build,omitempty"`
+	// Platforms holds the platform-specific fields above for every
+	// platform the binary is locked for, keyed "os/arch".
+	Platforms map[string]PlatformEntry `json:"platforms,omitempty"`
+}
+
+// PlatformEntry is what a BinEntry locks for one platform.
+type PlatformEntry struct {
+	SHA256      string             `json:"sha256"`
+	Asset       string             `json:"asset,omitempty"`
+	AssetURL    string             `json:"assetUrl,omitempty"`
+	AssetSHA256 string             `json:"assetSha256,omitempty"`
+	Signatures  []signature.Result `json:"signatures,omitempty"`
+	TreeSHA256  string             `json:"treeSha256,omitempty"`
 }
 
 // EnvEntry is a single env in the lockfile (Phase 2).
@@ -72,7 +133,7 @@ func ReadLock(dir string) (*Lock, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &Lock{Version: 1}, nil
+			return &Lock{Version: FormatVersion}, nil
 		}
 		return nil, err
 	}
@@ -80,12 +141,16 @@ func ReadLock(dir string) (*Lock, error) {
 	if err := json.Unmarshal(data, &lock); err != nil {
 		return nil, fmt.Errorf("parsing %s: %w", path, err)
 	}
+	// Rewriting a newer lock would drop what this b doesn't know about.
+	if lock.Version > FormatVersion {
+		return nil, fmt.Errorf("%s: format version %d is newer than this b reads (%d); upgrade b", path, lock.Version, FormatVersion)
+	}
 	return &lock, nil
 }
 
 // WriteLock writes the lockfile to the given directory.
 func WriteLock(dir string, lock *Lock, toolVersion string) error {
-	lock.Version = 1
+	lock.Version = FormatVersion
 	lock.Tool = ToolInfo{B: toolVersion}
 	lock.Timestamp = time.Now().UTC().Format(time.RFC3339)
 
@@ -118,6 +183,92 @@ func (l *Lock) UpsertBinary(entry BinEntry) {
 	l.Binaries = append(l.Binaries, entry)
 }
 
+// SetBinary records entry for platform ("os/arch"): its platform-specific
+// fields (see BinEntry) go under entry.Platforms[platform]. What the
+// previous entry locked for other platforms is kept while it pins the same
+// source, version and build. A different one leaves those platforms
+// unlocked; they are returned, in order, to be resolved again for the new
+// pin.
+func (l *Lock) SetBinary(platform string, entry BinEntry) (stale []string) {
+	platforms := map[string]PlatformEntry{platform: entry.platform()}
+	if prev := l.FindBinary(entry.Name); prev != nil {
+		same := prev.Source == entry.Source && prev.Version == entry.Version && prev.Build.Equal(entry.Build)
+		for p, e := range prev.Platforms {
+			switch {
+			case p == platform:
+			case same:
+				platforms[p] = e
+			default:
+				stale = append(stale, p)
+			}
+		}
+		if len(prev.Platforms) == 0 && prev.SHA256 != "" && platform != hostPlatform {
+			if same {
+				platforms[hostPlatform] = prev.platform()
+			} else {
+				stale = append(stale, hostPlatform)
+			}
+		}
+	}
+	entry.SHA256, entry.Asset, entry.AssetURL, entry.AssetSHA256 = "", "", "", ""
+	entry.Signatures, entry.TreeSHA256 = nil, ""
+	entry.Platforms = platforms
+	l.UpsertBinary(entry)
+	slices.Sort(stale)
+	return stale
+}
+
+// For returns e with the platform-specific fields (see BinEntry) set to
+// what it locks for platform ("os/arch"); they are empty when e isn't
+// locked for it.
+func (e BinEntry) For(platform string) BinEntry {
+	p, ok := e.Platforms[platform]
+	if !ok && len(e.Platforms) == 0 && platform == hostPlatform {
+		return e // format version 1
+	}
+	e.SHA256, e.Asset, e.AssetURL, e.AssetSHA256 = p.SHA256, p.Asset, p.AssetURL, p.AssetSHA256
+	e.Signatures, e.TreeSHA256 = p.Signatures, p.TreeSHA256
+	return e
+}
+
+// LockedPlatforms lists the platforms any binary in l is locked for, in
+// order.
+func (l *Lock) LockedPlatforms() []string {
+	var platforms []string
+	for _, e := range l.Binaries {
+		for p := range e.Platforms {
+			if !slices.Contains(platforms, p) {
+				platforms = append(platforms, p)
+			}
+		}
+	}
+	slices.Sort(platforms)
+	return platforms
+}
+
+func (e BinEntry) platform() PlatformEntry {
+	return PlatformEntry{
+		SHA256:      e.SHA256,
+		Asset:       e.Asset,
+		AssetURL:    e.AssetURL,
+		AssetSHA256: e.AssetSHA256,
+		Signatures:  e.Signatures,
+		TreeSHA256:  e.TreeSHA256,
+	}
+}
+
+// RemoveBinary drops the named binary from the lock. Returns true when an
+// entry was removed.
+func (l *Lock) RemoveBinary(name string) bool {
+	for i := range l.Binaries {
+		if l.Binaries[i].Name == name {
+			l.Binaries = append(l.Binaries[:i], l.Binaries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // FindEnv returns the lock entry for a given env ref (and optional label), or nil.
 func (l *Lock) FindEnv(ref, label string) *EnvEntry {
 	for i := range l.Envs {
@@ -175,3 +326,51 @@ func SHA256File(path string) (string, error) {
 	}
 	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
+
+// SHA256Tree computes a checksum over the directory tree at dir: the
+// relative path of every entry in lexical order, with the contents and
+// executable bit of files and the target of symlinks. Modification times
+// and owners don't count, so the same archive extracted twice hashes the
+// same.
+func SHA256Tree(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			fmt.Fprintf(h, "d %s\n", rel)
+		case mode&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "l %s %s\n", rel, filepath.ToSlash(target))
+		case mode.IsRegular():
+			sum, err := SHA256File(p)
+			if err != nil {
+				return err
+			}
+			kind := "f"
+			if mode&0111 != 0 {
+				kind = "x"
+			}
+			fmt.Fprintf(h, "%s %s %s\n", kind, rel, sum)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
@@ -25,8 +25,8 @@ func TestReadWriteLock(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ReadLock: %v", err)
 	}
-	if lk2.Version != 1 {
-		t.Errorf("version = %d, want 1", lk2.Version)
+	if lk2.Version != FormatVersion {
+		t.Errorf("version = %d, want %d", lk2.Version, FormatVersion)
 	}
 	if len(lk2.Binaries) != 1 {
 		t.Fatalf("got %d binaries, want 1", len(lk2.Binaries))
@@ -101,8 +101,8 @@ func TestReadLockMissing(t *testing.T) {
 	if err != nil {
 		t.Fatalf("ReadLock on missing file: %v", err)
 	}
-	if lk.Version != 1 {
-		t.Errorf("version = %d, want 1", lk.Version)
+	if lk.Version != FormatVersion {
+		t.Errorf("version = %d, want %d", lk.Version, FormatVersion)
 	}
 	if len(lk.Binaries) != 0 {
 		t.Errorf("got %d binaries, want 0", len(lk.Binaries))
@@ -132,6 +132,72 @@ func TestUpsertBinary(t *testing.T) {
 	}
 }
 
+func TestSetBinary_Platforms(t *testing.T) {
+	const other = "plan9/mips"
+	lk := &Lock{}
+	lk.SetBinary(hostPlatform, BinEntry{Name: "jq", Version: "1.7", Source: "github.com/jqlang/jq", SHA256: "host", Asset: "jq-host"})
+	lk.SetBinary(other, BinEntry{Name: "jq", Version: "1.7", Source: "github.com/jqlang/jq", SHA256: "other", AssetURL: "https://x/jq-other"})
+
+	e := lk.FindBinary("jq")
+	if e.SHA256 != "" || e.Asset != "" {
+		t.Errorf("platform fields at the top level: %+v", e)
+	}
+	if got := e.For(hostPlatform); got.SHA256 != "host" || got.Asset != "jq-host" {
+		t.Errorf("For(host) = %+v", got)
+	}
+	if got := e.For(other); got.SHA256 != "other" || got.AssetURL != "https://x/jq-other" {
+		t.Errorf("For(%s) = %+v", other, got)
+	}
+	if got := e.For("windows/arm64"); got.SHA256 != "" {
+		t.Errorf("unlocked platform = %+v, want empty", got)
+	}
+	if got := lk.LockedPlatforms(); len(got) != 2 {
+		t.Errorf("LockedPlatforms() = %v", got)
+	}
+
+	// Rewriting this platform keeps the other one...
+	lk.SetBinary(hostPlatform, BinEntry{Name: "jq", Version: "1.7", Source: "github.com/jqlang/jq", SHA256: "host2"})
+	if e := lk.FindBinary("jq"); e.For(hostPlatform).SHA256 != "host2" || e.For(other).SHA256 != "other" {
+		t.Errorf("after rewrite: %+v", e.Platforms)
+	}
+	// ...until the version moves on: then it is reported to be locked
+	// again for the new version.
+	stale := lk.SetBinary(hostPlatform, BinEntry{Name: "jq", Version: "1.8", Source: "github.com/jqlang/jq", SHA256: "host3"})
+	if e := lk.FindBinary("jq"); len(e.Platforms) != 1 {
+		t.Errorf("new version kept stale platforms: %+v", e.Platforms)
+	}
+	if len(stale) != 1 || stale[0] != other {
+		t.Errorf("stale = %v, want [%s]", stale, other)
+	}
+}
+
+func TestBinEntry_ForVersion1(t *testing.T) {
+	// Format version 1 wrote the host's download at the top level.
+	lk := &Lock{Binaries: []BinEntry{{Name: "jq", Version: "1.7", SHA256: "v1"}}}
+	if got := lk.FindBinary("jq").For(hostPlatform); got.SHA256 != "v1" {
+		t.Errorf("For(host) = %+v, want the top-level fields", got)
+	}
+	if got := lk.FindBinary("jq").For("plan9/mips"); got.SHA256 != "" {
+		t.Errorf("For(other) = %+v, want empty", got)
+	}
+
+	// Locking another platform files them under the host.
+	lk.SetBinary("plan9/mips", BinEntry{Name: "jq", Version: "1.7", SHA256: "other"})
+	if e := lk.FindBinary("jq"); e.Platforms[hostPlatform].SHA256 != "v1" || e.Platforms["plan9/mips"].SHA256 != "other" {
+		t.Errorf("Platforms = %+v", e.Platforms)
+	}
+}
+
+func TestReadLock_NewerFormat(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, lockFileName), []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadLock(dir); err == nil || !strings.Contains(err.Error(), "upgrade b") {
+		t.Errorf("ReadLock() error = %v, want upgrade hint", err)
+	}
+}
+
 func TestFindBinary(t *testing.T) {
 	lk := &Lock{
 		Binaries: []BinEntry{
@@ -0,0 +1,67 @@
+// Package offline holds b's --offline switch (also B_OFFLINE=1). While it
+// is on, nothing touches the network: versions come from b.lock, binaries
+// from the blob cache (pkg/blobcache) and env files from the git clones
+// already in the cache (pkg/gitcache). Whatever isn't there fails with an
+// *Error naming it, so the caller can list everything that is missing.
+package offline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// EnvVar enables offline mode when set to a true value ("1", "true").
+const EnvVar = "B_OFFLINE"
+
+var enabled atomic.Bool
+
+// Set turns offline mode on or off.
+func Set(on bool) {
+	enabled.Store(on)
+}
+
+// Enabled reports whether offline mode is on.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// FromEnv reports whether B_OFFLINE asks for offline mode.
+func FromEnv() (bool, error) {
+	v := strings.TrimSpace(os.Getenv(EnvVar))
+	if v == "" {
+		return false, nil
+	}
+	on, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s=%q is not a boolean", EnvVar, v)
+	}
+	return on, nil
+}
+
+// ErrOffline is matched by every *Error.
+var ErrOffline = errors.New("offline")
+
+// Error reports an artifact that isn't available locally.
+type Error struct {
+	// Artifact names what is missing: "kubectl v1.31.0", "github.com/org/infra@v2".
+	Artifact string
+	// Reason says where it was looked for, or what would need the network.
+	Reason string
+}
+
+// Missing returns an *Error for artifact.
+func Missing(artifact, format string, args ...any) error {
+	return &Error{Artifact: artifact, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("offline: %s: %s", e.Artifact, e.Reason)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrOffline
+}
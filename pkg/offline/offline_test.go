@@ -0,0 +1,63 @@
+package offline
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"1", true, false},
+		{"true", true, false},
+		{" TRUE ", true, false},
+		{"0", false, false},
+		{"false", false, false},
+		{"yes", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv(EnvVar, tt.value)
+			got, err := FromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Cleanup(func() { Set(false) })
+	if Enabled() {
+		t.Fatal("offline mode on by default")
+	}
+	Set(true)
+	if !Enabled() {
+		t.Error("Set(true) didn't enable offline mode")
+	}
+}
+
+func TestMissing(t *testing.T) {
+	err := fmt.Errorf("installing kubectl: %w", Missing("kubectl@v1.31.0", "not in the %s", "download cache"))
+	if !errors.Is(err, ErrOffline) {
+		t.Error("errors.Is(err, ErrOffline) = false")
+	}
+	var oe *Error
+	if !errors.As(err, &oe) {
+		t.Fatal("errors.As(err, *Error) = false")
+	}
+	if oe.Artifact != "kubectl@v1.31.0" || oe.Reason != "not in the download cache" {
+		t.Errorf("Error = %+v", oe)
+	}
+	if want := "installing kubectl: offline: kubectl@v1.31.0: not in the download cache"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
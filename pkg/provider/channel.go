@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fentas/b/pkg/semver"
+)
+
+// Channel selects which releases count as the latest one.
+type Channel string
+
+const (
+	// ChannelStable skips drafts and prereleases. It is the default.
+	ChannelStable Channel = "stable"
+	// ChannelPrerelease also takes prereleases ("v1.31.0-rc.1"), but only
+	// tags that are versions.
+	ChannelPrerelease Channel = "prerelease"
+	// ChannelNightly takes the most recent release of any kind, including
+	// rolling tags such as "nightly" or "edge".
+	ChannelNightly Channel = "nightly"
+)
+
+// Channels lists the valid channels, for flag help and errors.
+var Channels = []Channel{ChannelStable, ChannelPrerelease, ChannelNightly}
+
+// ParseChannel validates a b.yaml `channel:` value. An empty value is the
+// stable channel.
+func ParseChannel(s string) (Channel, error) {
+	switch ch := Channel(strings.ToLower(strings.TrimSpace(s))); ch {
+	case "":
+		return ChannelStable, nil
+	case ChannelStable, ChannelPrerelease, ChannelNightly:
+		return ch, nil
+	}
+	return "", fmt.Errorf("unknown channel %q (want stable, prerelease or nightly)", s)
+}
+
+// Accepts reports whether r belongs to the channel.
+func (ch Channel) Accepts(r ReleaseInfo) bool {
+	if r.Draft {
+		return false
+	}
+	switch ch {
+	case ChannelNightly:
+		return true
+	case ChannelPrerelease:
+		_, ok := semver.Parse(r.Version)
+		return ok
+	}
+	return !r.Prerelease
+}
+
+// LatestInChannel returns the latest version of ref on the channel. The
+// stable channel is the provider's own LatestVersion; the others walk the
+// release list newest first and need a ReleaseLister. Providers without
+// releases (go://, docker://, git://, templated URLs) have no channels and
+// always answer with LatestVersion.
+func LatestInChannel(p Provider, ref string, ch Channel) (string, error) {
+	if ch == "" || ch == ChannelStable || !IsReleaseProvider(p) {
+		return p.LatestVersion(ref)
+	}
+	l, ok := p.(ReleaseLister)
+	if !ok {
+		return p.LatestVersion(ref)
+	}
+	return latestFromList(l, ref, ch)
+}
+
+// latestFromList returns the first release on the channel from a
+// newest-first listing, reading at most maxListPages pages.
+func latestFromList(l ReleaseLister, ref string, ch Channel) (string, error) {
+	opts := ListOptions{Page: 1, PerPage: DefaultPerPage}
+	for i := 0; i < maxListPages; i++ {
+		page, err := l.ListReleases(ref, opts)
+		if err != nil {
+			return "", err
+		}
+		for _, r := range page.Releases {
+			if ch.Accepts(r) {
+				return r.Version, nil
+			}
+		}
+		if page.Next == 0 {
+			break
+		}
+		opts.Page = page.Next
+	}
+	if ch == ChannelStable {
+		return "", fmt.Errorf("no releases found for %s", ref)
+	}
+	return "", fmt.Errorf("no %s releases found for %s", ch, ref)
+}
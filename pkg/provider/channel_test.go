@@ -0,0 +1,134 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/hosts"
+)
+
+func TestParseChannel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Channel
+		wantErr bool
+	}{
+		{"", ChannelStable, false},
+		{"stable", ChannelStable, false},
+		{"Prerelease", ChannelPrerelease, false},
+		{" nightly ", ChannelNightly, false},
+		{"beta", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseChannel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseChannel(%q) = %q, %v; want %q, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestChannel_Accepts(t *testing.T) {
+	stable := ReleaseInfo{Version: "v1.2.0"}
+	rc := ReleaseInfo{Version: "v1.3.0-rc.1", Prerelease: true}
+	nightly := ReleaseInfo{Version: "nightly", Prerelease: true}
+	draft := ReleaseInfo{Version: "v1.4.0", Draft: true}
+
+	tests := []struct {
+		ch   Channel
+		r    ReleaseInfo
+		want bool
+	}{
+		{ChannelStable, stable, true},
+		{ChannelStable, rc, false},
+		{ChannelStable, draft, false},
+		{"", stable, true},
+		{ChannelPrerelease, stable, true},
+		{ChannelPrerelease, rc, true},
+		{ChannelPrerelease, nightly, false},
+		{ChannelPrerelease, draft, false},
+		{ChannelNightly, nightly, true},
+		{ChannelNightly, rc, true},
+		{ChannelNightly, draft, false},
+	}
+	for _, tt := range tests {
+		if got := tt.ch.Accepts(tt.r); got != tt.want {
+			t.Errorf("%q.Accepts(%s) = %v, want %v", tt.ch, tt.r.Version, got, tt.want)
+		}
+	}
+}
+
+// channelReleases serves a Gitea release list, newest first, mixing a
+// draft, a rolling nightly, a release candidate and stable releases.
+func channelReleases(t *testing.T) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/org/tool/releases", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"tag_name":"v2.0.0","draft":true},
+			{"tag_name":"nightly","prerelease":true},
+			{"tag_name":"v1.3.0-rc.1","prerelease":true},
+			{"tag_name":"v1.2.0"},
+			{"tag_name":"v1.1.0"}]`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	withHosts(t, map[string]*hosts.Host{
+		"gitea.example.corp": {Type: hosts.Gitea, API: srv.URL + "/api/v1"},
+	})
+	return "gitea.example.corp/org/tool"
+}
+
+func TestLatestInChannel(t *testing.T) {
+	ref := channelReleases(t)
+	tests := []struct {
+		ch   Channel
+		want string
+	}{
+		{"", "v1.2.0"},
+		{ChannelStable, "v1.2.0"},
+		{ChannelPrerelease, "v1.3.0-rc.1"},
+		{ChannelNightly, "nightly"},
+	}
+	for _, tt := range tests {
+		got, err := LatestInChannel(&Gitea{}, ref, tt.ch)
+		if err != nil {
+			t.Errorf("%q: %v", tt.ch, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.ch, got, tt.want)
+		}
+	}
+}
+
+func TestLatestInChannel_NoRelease(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/org%2Ftool/releases", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"tag_name":"v1.0.0-rc.1","released_at":"2024-01-01T00:00:00Z"}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	withHosts(t, map[string]*hosts.Host{
+		"gitlab.example.corp": {Type: hosts.GitLab, API: srv.URL + "/api/v4"},
+	})
+
+	// GitLab's latest skips release candidates, like GitHub's.
+	_, err := (&GitLab{}).LatestVersion("gitlab.example.corp/org/tool")
+	if err == nil || !strings.Contains(err.Error(), "no releases found") {
+		t.Errorf("err = %v, want no releases found", err)
+	}
+	v, err := LatestInChannel(&GitLab{}, "gitlab.example.corp/org/tool", ChannelPrerelease)
+	if err != nil || v != "v1.0.0-rc.1" {
+		t.Errorf("prerelease = %q, %v", v, err)
+	}
+}
+
+func TestLatestInChannel_NonRelease(t *testing.T) {
+	// go:// refs have no channels and keep their own notion of latest.
+	t.Setenv("GOPROXY", fileGoProxy(t, map[string]string{"x/@v/list": "v1.0.0\nv1.1.0-rc.1\n"}))
+	v, err := LatestInChannel(&GoInstall{}, "go://x", ChannelNightly)
+	if err != nil || v != "v1.0.0" {
+		t.Errorf("go:// = %q, %v", v, err)
+	}
+}
@@ -0,0 +1,208 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"path"
+	"strings"
+)
+
+// Per-asset checksum suffixes, in order of preference. A release that ships
+// "tool-linux-amd64.tar.gz.sha256" next to the archive is the most precise
+// source — it can't be confused with another asset's entry.
+var checksumSuffixes = []string{
+	".sha256", ".sha256sum", ".sha256.txt",
+	".sha512", ".sha512sum", ".sha512.txt",
+}
+
+// Aggregate checksum manifests. Matched against the lowercased asset name;
+// publishers commonly prefix these with the project name and version
+// (e.g. "k9s_0.32.5_checksums.txt"), so we match on suffix.
+var checksumManifestNames = []string{
+	"checksums.txt", "checksums.sha256", "checksums_sha256.txt",
+	"sha256sums", "sha256sums.txt", "sha256sum.txt",
+	"sha512sums", "sha512sums.txt",
+}
+
+// Signature/attestation suffixes that often sit next to checksum manifests
+// ("checksums.txt.sig", "SHA256SUMS.asc") — never parse those as checksums.
+var signatureSuffixes = []string{
+	".sig", ".asc", ".pem", ".pub", ".cert", ".bundle", ".minisig",
+}
+
+// FindChecksumAsset returns the release asset holding the upstream checksum
+// for assetName, or nil if the release ships none. A per-asset checksum file
+// ("<asset>.sha256") wins over an aggregate manifest ("checksums.txt",
+// "SHA256SUMS"), since the latter may not list every asset.
+func FindChecksumAsset(assets []Asset, assetName string) *Asset {
+	lowerAsset := strings.ToLower(assetName)
+	for _, suffix := range checksumSuffixes {
+		for i := range assets {
+			if strings.ToLower(assets[i].Name) == lowerAsset+suffix {
+				return &assets[i]
+			}
+		}
+	}
+	for i := range assets {
+		if isChecksumManifest(assets[i].Name) {
+			return &assets[i]
+		}
+	}
+	return nil
+}
+
+// ChecksumManifests returns every aggregate checksum manifest in assets.
+func ChecksumManifests(assets []Asset) []*Asset {
+	var manifests []*Asset
+	for i := range assets {
+		if isChecksumManifest(assets[i].Name) {
+			manifests = append(manifests, &assets[i])
+		}
+	}
+	return manifests
+}
+
+// isChecksumManifest reports whether name looks like an aggregate checksum
+// manifest (and not a signature over one).
+func isChecksumManifest(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range signatureSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return false
+		}
+	}
+	for _, m := range checksumManifestNames {
+		if lower == m || strings.HasSuffix(lower, "_"+m) ||
+			strings.HasSuffix(lower, "-"+m) || strings.HasSuffix(lower, "."+m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNotListed is returned by ParseChecksum for a checksum manifest that
+// doesn't list the asset.
+var ErrNotListed = errors.New("not listed")
+
+// ParseChecksum extracts the hex digest for assetName from the contents of
+// a checksum file. Understands the GNU coreutils format ("<hex>  name",
+// "<hex> *name"), the BSD format ("SHA256 (name) = <hex>"), and bare
+// per-asset files holding only "<hex>". Names are compared by basename so
+// manifests listing "./dist/tool.tar.gz" still match "tool.tar.gz".
+//
+// Returns an error wrapping ErrNotListed when the file is well-formed but
+// doesn't list the asset: a release publishing checksums must cover what is
+// installed from it. Other errors mean a bare single-digest file is
+// ambiguous or nothing parses at all.
+func ParseChecksum(data []byte, assetName string) (string, error) {
+	var (
+		bare    []string
+		entries int
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// BSD style: "SHA256 (name) = hex"
+		if open := strings.Index(line, " ("); open > 0 {
+			if closeIdx := strings.LastIndex(line, ") = "); closeIdx > open {
+				entries++
+				name := line[open+2 : closeIdx]
+				sum := strings.TrimSpace(line[closeIdx+4:])
+				if isHexDigest(sum) && sameAsset(name, assetName) {
+					return strings.ToLower(sum), nil
+				}
+				continue
+			}
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !isHexDigest(fields[0]) {
+			continue
+		}
+		if len(fields) == 1 {
+			bare = append(bare, fields[0])
+			continue
+		}
+		entries++
+		name := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+		if sameAsset(name, assetName) {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	if entries == 0 {
+		switch len(bare) {
+		case 0:
+			return "", fmt.Errorf("no checksum found")
+		case 1:
+			return strings.ToLower(bare[0]), nil
+		default:
+			return "", fmt.Errorf("ambiguous checksum file: %d digests without file names", len(bare))
+		}
+	}
+	return "", fmt.Errorf("%s: %w", assetName, ErrNotListed)
+}
+
+// VerifyChecksum compares data against the expected hex digest. The hash
+// algorithm is inferred from the digest length (sha256 or sha512).
+func VerifyChecksum(data []byte, want string) error {
+	var h hash.Hash
+	switch len(want) {
+	case sha256.Size * 2:
+		h = sha256.New()
+	case sha512.Size * 2:
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported checksum %q", want)
+	}
+	h.Write(data)
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("checksum mismatch: got %s, want %s", got, strings.ToLower(want))
+	}
+	return nil
+}
+
+// sameAsset compares a checksum-file entry name with the asset name,
+// tolerating leading "./" and directory prefixes.
+func sameAsset(entry, assetName string) bool {
+	return path.Base(strings.TrimPrefix(entry, "./")) == assetName
+}
+
+// isHexDigest reports whether s is a sha256 or sha512 hex digest.
+func isHexDigest(s string) bool {
+	if len(s) != sha256.Size*2 && len(s) != sha512.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// FindSignatureAssets returns the detached-signature companions of target
+// ("<target>.sig", "<target>.bundle", "SHA256SUMS.asc") that carry one of
+// the given suffixes, keyed by suffix. Returns nil when none are published.
+func FindSignatureAssets(assets []Asset, target string, suffixes []string) map[string]*Asset {
+	var found map[string]*Asset
+	lowerTarget := strings.ToLower(target)
+	for _, suffix := range suffixes {
+		for i := range assets {
+			if strings.ToLower(assets[i].Name) == lowerTarget+suffix {
+				if found == nil {
+					found = make(map[string]*Asset)
+				}
+				found[suffix] = &assets[i]
+				break
+			}
+		}
+	}
+	return found
+}
@@ -0,0 +1,118 @@
+package provider
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindChecksumAsset(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool_1.0_linux_amd64.tar.gz"},
+		{Name: "tool_1.0_checksums.txt"},
+		{Name: "tool_1.0_checksums.txt.sig"},
+		{Name: "tool_1.0_linux_amd64.tar.gz.sha256"},
+		{Name: "tool_1.0_darwin_arm64.tar.gz"},
+	}
+	tests := []struct {
+		asset string
+		want  string
+	}{
+		// Per-asset file wins over the aggregate manifest.
+		{"tool_1.0_linux_amd64.tar.gz", "tool_1.0_linux_amd64.tar.gz.sha256"},
+		// No per-asset file — fall back to the manifest, never the .sig.
+		{"tool_1.0_darwin_arm64.tar.gz", "tool_1.0_checksums.txt"},
+	}
+	for _, tt := range tests {
+		got := FindChecksumAsset(assets, tt.asset)
+		if got == nil || got.Name != tt.want {
+			t.Errorf("FindChecksumAsset(%q) = %v, want %q", tt.asset, got, tt.want)
+		}
+	}
+
+	if got := FindChecksumAsset([]Asset{{Name: "tool.tar.gz"}, {Name: "SHA256SUMS.asc"}}, "tool.tar.gz"); got != nil {
+		t.Errorf("expected no checksum asset, got %q", got.Name)
+	}
+	if got := FindChecksumAsset([]Asset{{Name: "tool.tar.gz"}, {Name: "SHA256SUMS"}}, "tool.tar.gz"); got == nil || got.Name != "SHA256SUMS" {
+		t.Errorf("expected SHA256SUMS, got %v", got)
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	sum := fmt.Sprintf("%x", sha256.Sum256([]byte("x")))
+	other := fmt.Sprintf("%x", sha256.Sum256([]byte("y")))
+	tests := []struct {
+		name    string
+		data    string
+		asset   string
+		want    string
+		wantErr bool
+	}{
+		{"gnu", other + "  a.tar.gz\n" + sum + "  tool.tar.gz\n", "tool.tar.gz", sum, false},
+		{"gnu binary marker", sum + " *tool.tar.gz\n", "tool.tar.gz", sum, false},
+		{"path prefix", sum + "  ./dist/tool.tar.gz\n", "tool.tar.gz", sum, false},
+		{"bsd", "SHA256 (tool.tar.gz) = " + sum + "\n", "tool.tar.gz", sum, false},
+		{"bare", sum + "\n", "tool.tar.gz", sum, false},
+		{"uppercase", "# comment\n" + fmt.Sprintf("%X", sha256.Sum256([]byte("x"))) + "  tool.tar.gz", "tool.tar.gz", sum, false},
+		{"not listed", other + "  a.tar.gz\n", "tool.tar.gz", "", true},
+		{"not listed bsd", "SHA256 (a.tar.gz) = " + other + "\n", "tool.tar.gz", "", true},
+		{"empty", "", "tool.tar.gz", "", true},
+		{"ambiguous bare", sum + "\n" + other + "\n", "tool.tar.gz", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChecksum([]byte(tt.data), tt.asset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if notListed := strings.HasPrefix(tt.name, "not listed"); errors.Is(err, ErrNotListed) != notListed {
+				t.Errorf("errors.Is(%v, ErrNotListed) = %v, want %v", err, !notListed, notListed)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("payload")
+	s256 := fmt.Sprintf("%x", sha256.Sum256(data))
+	s512 := fmt.Sprintf("%x", sha512.Sum512(data))
+	if err := VerifyChecksum(data, s256); err != nil {
+		t.Errorf("sha256: %v", err)
+	}
+	if err := VerifyChecksum(data, s512); err != nil {
+		t.Errorf("sha512: %v", err)
+	}
+	if err := VerifyChecksum([]byte("tampered"), s256); err == nil {
+		t.Error("expected mismatch error")
+	}
+	if err := VerifyChecksum(data, "abc"); err == nil {
+		t.Error("expected unsupported checksum error")
+	}
+}
+
+func TestFindSignatureAssets(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool.tar.gz"},
+		{Name: "tool.tar.gz.sig"},
+		{Name: "tool.tar.gz.pem"},
+		{Name: "SHA256SUMS"},
+		{Name: "SHA256SUMS.asc"},
+	}
+	got := FindSignatureAssets(assets, "tool.tar.gz", []string{".bundle", ".sig", ".pem"})
+	if len(got) != 2 || got[".sig"].Name != "tool.tar.gz.sig" || got[".pem"].Name != "tool.tar.gz.pem" {
+		t.Errorf("asset companions = %v", got)
+	}
+	got = FindSignatureAssets(assets, "SHA256SUMS", []string{".asc", ".sig"})
+	if len(got) != 1 || got[".asc"].Name != "SHA256SUMS.asc" {
+		t.Errorf("manifest companions = %v", got)
+	}
+	if got := FindSignatureAssets(assets, "tool.tar.gz", []string{".minisig"}); got != nil {
+		t.Errorf("expected no companions, got %v", got)
+	}
+}
@@ -37,8 +37,11 @@ var (
 	archiveExtensions = []string{
 		".tar.gz", ".tgz",
 		".tar.xz", ".txz",
-		".tar.bz2",
+		".tar.bz2", ".tbz2",
+		".tar.zst", ".tzst",
 		".zip",
+		// Single compressed files, e.g. tool-linux-amd64.gz.
+		".gz", ".xz", ".bz2", ".zst",
 	}
 )
 
@@ -58,7 +61,7 @@ type Scored struct {
 	Score int
 }
 
-// MatchAsset scores and selects the best release asset for the current
+// MatchAsset scores and selects the best release asset for the host
 // OS/arch. An optional assetFilter glob pattern (e.g. "argsh-so-*") narrows
 // the candidates before scoring. Returns an error if no suitable asset is found.
 func MatchAsset(assets []Asset, repoName, assetFilter string) (*Asset, error) {
@@ -76,11 +79,20 @@ func MatchAsset(assets []Asset, repoName, assetFilter string) (*Asset, error) {
 	return candidates[0].Asset, nil
 }
 
-// MatchAssets returns all matching assets scored and sorted (best first).
-// An optional assetFilter glob pattern narrows the candidates before scoring.
+// MatchAssets returns all matching assets for the host scored and sorted
+// (best first). An optional assetFilter glob pattern narrows the
+// candidates before scoring.
 func MatchAssets(assets []Asset, repoName, assetFilter string) []Scored {
-	goos := runtime.GOOS
-	goarch := runtime.GOARCH
+	return MatchAssetsFor(Platform{}, assets, repoName, assetFilter)
+}
+
+// MatchAssetsFor is MatchAssets for the target platform. Windows targets
+// prefer zip archives to tar.gz, and take a bare "<repo>.exe" as theirs.
+func MatchAssetsFor(target Platform, assets []Asset, repoName, assetFilter string) []Scored {
+	target = target.Resolve()
+	goos := target.OS
+	goarch := target.Arch
+	windows := goos == "windows"
 
 	osNames := osAliases[goos]
 	archNames := archAliases[goarch]
@@ -127,7 +139,7 @@ func MatchAssets(assets []Asset, repoName, assetFilter string) []Scored {
 		// same as a typical OS/arch-matched asset that also has the repo
 		// name in its filename, so it surfaces in the interactive picker
 		// alongside those candidates.
-		if repoLower != "" && isPortableName(lower, repoLower) {
+		if repoLower != "" && (isPortableName(lower, repoLower) || windows && lower == repoLower+".exe") {
 			candidates = append(candidates, Scored{Asset: a, Score: scoreOSArchMatch + scoreRepoNameHit})
 			continue
 		}
@@ -169,8 +181,10 @@ func MatchAssets(assets []Asset, repoName, assetFilter string) []Scored {
 			score += scoreRepoNameHit
 		}
 
-		// Prefer tar.gz over zip (more common in Go/Rust ecosystem)
-		if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		// Prefer tar.gz over zip (more common in Go/Rust ecosystem), but
+		// zip on Windows, where it is the native archive.
+		if windows && strings.HasSuffix(lower, ".zip") ||
+			!windows && (strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")) {
 			score += scoreTarGzBonus
 		}
 
@@ -185,7 +199,9 @@ func MatchAssets(assets []Asset, repoName, assetFilter string) []Scored {
 	return candidates
 }
 
-// DetectArchiveType returns the archive type based on filename.
+// DetectArchiveType returns the archive type based on filename: "tar.gz",
+// "tar.xz", "tar.bz2", "tar.zst" or "zip" for archives, "gz", "xz", "bz2" or
+// "zst" for a single compressed file.
 // Returns empty string if the file is not a recognized archive.
 func DetectArchiveType(name string) string {
 	lower := strings.ToLower(name)
@@ -194,10 +210,21 @@ func DetectArchiveType(name string) string {
 		return "tar.gz"
 	case strings.HasSuffix(lower, ".tar.xz"), strings.HasSuffix(lower, ".txz"):
 		return "tar.xz"
-	case strings.HasSuffix(lower, ".tar.bz2"):
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
 		return "tar.bz2"
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return "tar.zst"
 	case strings.HasSuffix(lower, ".zip"):
 		return "zip"
+	// Single compressed files; after the tar.* cases they'd also match.
+	case strings.HasSuffix(lower, ".gz"):
+		return "gz"
+	case strings.HasSuffix(lower, ".xz"):
+		return "xz"
+	case strings.HasSuffix(lower, ".bz2"):
+		return "bz2"
+	case strings.HasSuffix(lower, ".zst"):
+		return "zst"
 	}
 	return ""
 }
@@ -64,6 +64,13 @@ func TestDetectArchiveType(t *testing.T) {
 		{"foo.txz", "tar.xz"},
 		{"foo.zip", "zip"},
 		{"foo.tar.bz2", "tar.bz2"},
+		{"foo.tbz2", "tar.bz2"},
+		{"foo.tar.zst", "tar.zst"},
+		{"foo.TZST", "tar.zst"},
+		{"foo.gz", "gz"},
+		{"foo.xz", "xz"},
+		{"foo.bz2", "bz2"},
+		{"foo.zst", "zst"},
 		{"foo", ""},
 		{"foo.exe", ""},
 	}
@@ -281,6 +288,60 @@ func TestMatchAssets_SortedByScore(t *testing.T) {
 	}
 }
 
+func TestMatchAssets_CompressedScoredAsArchives(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("test requires linux/amd64")
+	}
+
+	assets := []Asset{
+		{Name: "tool-linux-amd64", URL: "https://example.com/bin", Size: 1000},
+		{Name: "tool-linux-amd64.zst", URL: "https://example.com/zst", Size: 400},
+		{Name: "tool-linux-amd64.tar.bz2", URL: "https://example.com/tbz2", Size: 500},
+	}
+
+	candidates := MatchAssets(assets, "tool", "")
+	if len(candidates) != 3 {
+		t.Fatalf("expected 3 candidates, got %d", len(candidates))
+	}
+	if last := candidates[2].Asset.Name; last != "tool-linux-amd64" {
+		t.Errorf("expected the raw binary last, got %q", last)
+	}
+	if candidates[0].Score != candidates[1].Score {
+		t.Errorf("compressed assets scored %d and %d, want the same archive bonus", candidates[0].Score, candidates[1].Score)
+	}
+}
+
+func TestMatchAssetsFor(t *testing.T) {
+	assets := []Asset{
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "tool_darwin_arm64.tar.gz"},
+		{Name: "tool_darwin_amd64.tar.gz"},
+		{Name: "tool_windows_amd64.tar.gz"},
+		{Name: "tool_windows_amd64.zip"},
+		{Name: "tool.exe"},
+	}
+	tests := []struct {
+		target Platform
+		want   []string
+	}{
+		{Platform{OS: "darwin", Arch: "arm64"}, []string{"tool_darwin_arm64.tar.gz"}},
+		{Platform{OS: "linux", Arch: "amd64"}, []string{"tool_linux_amd64.tar.gz"}},
+		// zip first on Windows; a bare tool.exe is a Windows build too
+		{Platform{OS: "windows", Arch: "amd64"}, []string{"tool_windows_amd64.zip", "tool_windows_amd64.tar.gz", "tool.exe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target.String(), func(t *testing.T) {
+			var got []string
+			for _, c := range MatchAssetsFor(tt.target, assets, "tool", "") {
+				got = append(got, c.Asset.Name)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("MatchAssetsFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestMatchAssetFilterErrorMessage tests error message includes filter.
 func TestMatchAssetFilterErrorMessage(t *testing.T) {
 	_, err := MatchAsset(nil, "tool", "custom-*")
@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
+	"slices"
 	"strings"
 
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/b/pkg/offline"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
-	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
@@ -32,6 +34,15 @@ func (d *Docker) LatestVersion(ref string) (string, error) {
 	return "latest", nil
 }
 
+// ListReleases lists the image's tags, read from the registry.
+func (d *Docker) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	tags, err := registryTags(dockerImage(ref))
+	if err != nil {
+		return nil, err
+	}
+	return tagPage(tags, opts), nil
+}
+
 // FetchRelease is not used for Docker — use Install instead.
 func (d *Docker) FetchRelease(ref, version string) (*Release, error) {
 	return nil, fmt.Errorf("docker provider does not use FetchRelease; use Install()")
@@ -63,10 +74,9 @@ func (d *Docker) ResolveDigest(ref, version string) (string, error) {
 	desc, err := remote.Head(nameRef,
 		remote.WithContext(ctx),
 		remote.WithAuthFromKeychain(authn.DefaultKeychain),
-		remote.WithPlatform(v1.Platform{
-			OS:           runtime.GOOS,
-			Architecture: runtime.GOARCH,
-		}),
+		remote.WithTransport(httpclient.Transport()),
+		remote.WithRetryStatusCodes(),
+		remote.WithPlatform(HostPlatform().image()),
 	)
 	if err != nil {
 		return "", nil
@@ -74,11 +84,16 @@ func (d *Docker) ResolveDigest(ref, version string) (string, error) {
 	return desc.Digest.String(), nil
 }
 
-// Install pulls the image, creates a container, copies the binary out, and cleans up.
+// Install pulls the image for target, creates a container, copies the binary out, and cleans up.
 // searchPaths are the paths to search for the binary inside the container.
 // If the ref includes ":/<path>", that path is used as the single search path.
-func (d *Docker) Install(ref, version, destDir string, searchPaths []string) (string, error) {
-	runtime, err := detectContainerRuntime()
+//
+// runtime names the container CLI (see containerRuntime); paths are
+// further files or directories copied out of the image next to the
+// binary (see SplitPaths). Without a runtime installed, and none asked
+// for, the image is read from the registry like oci:// does.
+func (d *Docker) Install(ref, version, destDir string, searchPaths []string, target Platform, runtime string, paths []string) (string, error) {
+	runtime, err := containerRuntime(runtime)
 	if err != nil {
 		return "", err
 	}
@@ -94,52 +109,143 @@ func (d *Docker) Install(ref, version, destDir string, searchPaths []string) (st
 		tag = "latest"
 	}
 	imageRef := image + ":" + tag
-	name := BinaryName(ref)
+	name := target.Exe(BinaryName(ref))
+	binPath, extra := SplitPaths(name, paths)
 
-	// Pull image
-	cmd := exec.Command(runtime, "pull", imageRef)
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("pulling image %s: %w", imageRef, err)
+	// Determine search paths: explicit ":/<path>" overrides everything.
+	if inContainerPath != "" {
+		searchPaths = []string{inContainerPath}
+	} else if binPath != "" {
+		searchPaths = []string{binPath}
+	} else if searchPaths == nil {
+		searchPaths = defaultSearchPaths(name)
+	}
+
+	if runtime == "" {
+		return installImage(image, tag, name, destDir, searchPaths, extra, target)
+	}
+
+	// Another platform's image has to be asked for; the runtime pulls its
+	// own otherwise.
+	var platform []string
+	if !target.IsHost() {
+		platform = []string{"--platform", target.String()}
+	}
+
+	// Pull image; offline, the runtime's local copy has to do.
+	if !offline.Enabled() {
+		cmd := exec.Command(runtime, append(append([]string{"pull"}, platform...), imageRef)...)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("pulling image %s: %w", imageRef, err)
+		}
 	}
 
 	// Create container (don't start it)
-	out, err := exec.Command(runtime, "create", imageRef).Output()
+	out, err := exec.Command(runtime, append(append([]string{"create"}, platform...), imageRef)...).Output()
 	if err != nil {
+		if offline.Enabled() {
+			return "", offline.Missing(imageRef, "not in the local %s images", runtime)
+		}
 		return "", fmt.Errorf("creating container from %s: %w", imageRef, err)
 	}
 	containerID := strings.TrimSpace(string(out))
 	defer exec.Command(runtime, "rm", containerID).Run()
 
-	// Determine search paths: explicit ":/<path>" overrides everything.
-	if inContainerPath != "" {
-		searchPaths = []string{inContainerPath}
-	} else if searchPaths == nil {
-		searchPaths = []string{
-			"/usr/local/bin/" + name,
-			"/usr/bin/" + name,
-			"/bin/" + name,
-			"/app/" + name,
-		}
-	}
-
 	dest := filepath.Join(destDir, name)
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return "", err
 	}
 
+	// Copy into a temp file next to dest and move it into place once the
+	// copy succeeded, so a failed cp never leaves a partial binary.
+	tmp, err := os.CreateTemp(destDir, "."+name+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("creating temp file: %w", err)
+	}
+	tmp.Close()
+	found := false
 	for _, p := range searchPaths {
-		cpCmd := exec.Command(runtime, "cp", containerID+":"+p, dest)
+		cpCmd := exec.Command(runtime, "cp", containerID+":"+p, tmp.Name())
 		if err := cpCmd.Run(); err == nil {
-			// Found it
-			if err := os.Chmod(dest, 0755); err != nil {
-				return "", err
-			}
-			return dest, nil
+			found = true
+			break
 		}
 	}
+	if !found {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("binary %q not found in image %s at paths: %v", name, imageRef, searchPaths)
+	}
+	for _, p := range extra {
+		if err := containerCopy(runtime, containerID, p, filepath.Join(destDir, path.Base(p))); err != nil {
+			_ = os.Remove(tmp.Name())
+			return "", fmt.Errorf("copying %s out of image %s: %w", p, imageRef, err)
+		}
+	}
+	if err := replaceWith(tmp.Name(), dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
 
-	return "", fmt.Errorf("binary %q not found in image %s at paths: %v", name, imageRef, searchPaths)
+// containerCopy copies the file or directory src out of the container to
+// dest, replacing what is there once the copy succeeded.
+func containerCopy(runtime, containerID, src, dest string) error {
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	// cp creates the target when it doesn't exist, a directory included.
+	copied := filepath.Join(tmp, filepath.Base(dest))
+	var stderr strings.Builder
+	cmd := exec.Command(runtime, "cp", containerID+":"+src, copied)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return replaceTree(copied, dest)
+}
+
+// defaultSearchPaths are where images usually keep a binary called name.
+func defaultSearchPaths(name string) []string {
+	return []string{
+		"/usr/local/bin/" + name,
+		"/usr/bin/" + name,
+		"/bin/" + name,
+		"/app/" + name,
+	}
+}
+
+// SplitPaths splits the in-image paths a binary called name is installed
+// from: the one named like the binary is the binary itself, the others
+// are installed next to it under their base names.
+func SplitPaths(name string, paths []string) (bin string, extra []string) {
+	for _, p := range paths {
+		if bin == "" && path.Base(p) == name {
+			bin = p
+			continue
+		}
+		extra = append(extra, p)
+	}
+	return bin, extra
+}
+
+// ValidatePaths checks the in-image paths of a `paths:` list: absolute,
+// naming a file or directory, each installed under a name of its own.
+func ValidatePaths(paths []string) error {
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		base := path.Base(path.Clean(p))
+		if !strings.HasPrefix(p, "/") || base == "/" {
+			return fmt.Errorf("paths: %q is not an absolute path below /", p)
+		}
+		if seen[base] {
+			return fmt.Errorf("paths: more than one path is named %q", base)
+		}
+		seen[base] = true
+	}
+	return nil
 }
 
 // dockerImage returns the image name (without tag/path) for legacy callers
@@ -157,8 +263,41 @@ func dockerImage(ref string) string {
 	return image
 }
 
+// ContainerRuntimes are the container CLIs docker:// refs can use, in the
+// order they are looked for.
+var ContainerRuntimes = []string{"docker", "podman", "nerdctl"}
+
+// ValidateRuntime checks a `runtime:` setting.
+func ValidateRuntime(runtime string) error {
+	if runtime != "" && !slices.Contains(ContainerRuntimes, runtime) {
+		return fmt.Errorf("unknown runtime %q (want one of %s)", runtime, strings.Join(ContainerRuntimes, ", "))
+	}
+	return nil
+}
+
+// containerRuntime picks the container CLI: want, else B_CONTAINER_RUNTIME,
+// else the first of ContainerRuntimes on PATH. A runtime asked for has to
+// be installed; when none was and none is, it returns "" so the image is
+// read from the registry instead.
+func containerRuntime(want string) (string, error) {
+	if want == "" {
+		want = os.Getenv("B_CONTAINER_RUNTIME")
+	}
+	if want == "" {
+		rt, _ := detectContainerRuntime()
+		return rt, nil
+	}
+	if err := ValidateRuntime(want); err != nil {
+		return "", err
+	}
+	if _, err := exec.LookPath(want); err != nil {
+		return "", fmt.Errorf("container runtime %s not found: %w", want, err)
+	}
+	return want, nil
+}
+
 func detectContainerRuntime() (string, error) {
-	for _, rt := range []string{"docker", "podman", "nerdctl"} {
+	for _, rt := range ContainerRuntimes {
 		if _, err := exec.LookPath(rt); err == nil {
 			return rt, nil
 		}
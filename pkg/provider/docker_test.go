@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"archive/tar"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// pluginLayers are the layers of a tool image with a plugin directory;
+// the newer one replaces plugin a and deletes plugin b.
+func pluginLayers(t *testing.T) []v1.Layer {
+	t.Helper()
+	return []v1.Layer{
+		fakeLayer(t, []tar.Header{
+			{Name: "usr/local/bin/tool", Typeflag: tar.TypeReg, Mode: 0755},
+			{Name: "usr/local/lib/tool/plugins/", Typeflag: tar.TypeDir, Mode: 0755},
+			{Name: "usr/local/lib/tool/plugins/a", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "usr/local/lib/tool/plugins/b", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "usr/local/lib/tool/plugins/sub/c", Typeflag: tar.TypeReg, Mode: 0644},
+		}, map[string][]byte{
+			"usr/local/bin/tool":               []byte("tool-binary"),
+			"usr/local/lib/tool/plugins/a":     []byte("old-a"),
+			"usr/local/lib/tool/plugins/b":     []byte("b"),
+			"usr/local/lib/tool/plugins/sub/c": []byte("c"),
+		}),
+		fakeLayer(t, []tar.Header{
+			{Name: "usr/local/lib/tool/plugins/a", Typeflag: tar.TypeReg, Mode: 0644},
+			{Name: "usr/local/lib/tool/plugins/.wh.b", Typeflag: tar.TypeReg},
+		}, map[string][]byte{
+			"usr/local/lib/tool/plugins/a": []byte("new-a"),
+		}),
+	}
+}
+
+// assertPlugins checks the plugin directory of pluginLayers was
+// installed into dir.
+func assertPlugins(t *testing.T, dir string) {
+	t.Helper()
+	for file, want := range map[string]string{"a": "new-a", "sub/c": "c"} {
+		if data, err := os.ReadFile(filepath.Join(dir, "plugins", file)); err != nil || string(data) != want {
+			t.Errorf("plugins/%s = %q, %v; want %q", file, data, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "plugins", "b")); !os.IsNotExist(err) {
+		t.Errorf("plugins/b, whited out, was installed: %v", err)
+	}
+}
+
+func TestExtractTreeFromLayers(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "plugins")
+	// What was there before is replaced, not merged into.
+	if err := os.MkdirAll(filepath.Join(dest, "stale"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	repo, _ := name.NewRepository("example.com/tool")
+	if err := extractTreeFromLayers(repo, pluginLayers(t), "/usr/local/lib/tool/plugins", dest); err != nil {
+		t.Fatalf("extractTreeFromLayers: %v", err)
+	}
+	assertPlugins(t, dir)
+	if _, err := os.Stat(filepath.Join(dest, "stale")); !os.IsNotExist(err) {
+		t.Errorf("stale was kept: %v", err)
+	}
+	if err := extractTreeFromLayers(repo, pluginLayers(t), "/usr/local/lib/tool/plugins/b", filepath.Join(dir, "b")); err == nil {
+		t.Error("extracted a whited out path")
+	}
+}
+
+func TestSplitPaths(t *testing.T) {
+	bin, extra := SplitPaths("tool", []string{"/usr/local/lib/tool/plugins", "/usr/local/bin/tool", "/etc/tool"})
+	if bin != "/usr/local/bin/tool" {
+		t.Errorf("bin = %q", bin)
+	}
+	if strings.Join(extra, ",") != "/usr/local/lib/tool/plugins,/etc/tool" {
+		t.Errorf("extra = %v", extra)
+	}
+}
+
+func TestValidatePaths(t *testing.T) {
+	tests := []struct {
+		paths   []string
+		wantErr string
+	}{
+		{paths: []string{"/usr/local/bin/tool", "/usr/local/lib/tool/plugins/"}},
+		{paths: []string{"usr/local/bin/tool"}, wantErr: "not an absolute path"},
+		{paths: []string{"/"}, wantErr: "not an absolute path"},
+		{paths: []string{"/a/tool", "/b/tool"}, wantErr: `more than one path is named "tool"`},
+	}
+	for _, tt := range tests {
+		err := ValidatePaths(tt.paths)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ValidatePaths(%v) = %v, want %q", tt.paths, err, tt.wantErr)
+		}
+	}
+	if err := ValidateRuntime("finch"); err == nil {
+		t.Error("ValidateRuntime(finch) = nil")
+	}
+}
+
+func TestDocker_Install_Runtime(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake runtime is a shell script")
+	}
+	// A fake podman whose containers are the image directory: cp copies
+	// out of it, anything else is recorded.
+	bin := t.TempDir()
+	image := t.TempDir()
+	record := filepath.Join(t.TempDir(), "calls")
+	for file, content := range map[string]string{
+		"usr/local/bin/tool":               "tool-binary",
+		"usr/local/lib/tool/plugins/a":     "new-a",
+		"usr/local/lib/tool/plugins/sub/c": "c",
+	} {
+		p := filepath.Join(image, file)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := "#!/bin/sh\necho \"$@\" >> " + record + "\n" +
+		"case \"$1\" in create) echo ctr ;; cp) cp -R " + image + "\"${2#ctr:}\" \"$3\" ;; esac\n"
+	if err := os.WriteFile(filepath.Join(bin, "podman"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("B_CONTAINER_RUNTIME", "podman")
+
+	dest := t.TempDir()
+	path, err := (&Docker{}).Install("docker://example.com/tool@v1", "", dest, nil, Platform{}, "",
+		[]string{"/usr/local/bin/tool", "/usr/local/lib/tool/plugins"})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "tool-binary" {
+		t.Errorf("binary = %q", data)
+	}
+	assertPlugins(t, dest)
+	calls, _ := os.ReadFile(record)
+	if !strings.HasPrefix(string(calls), "pull example.com/tool:v1\ncreate example.com/tool:v1\n") ||
+		!strings.HasSuffix(string(calls), "rm ctr\n") {
+		t.Errorf("runtime calls:\n%s", calls)
+	}
+}
+
+func TestDocker_Install_Daemonless(t *testing.T) {
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	repo := strings.TrimPrefix(srv.URL, "http://") + "/org/tool"
+	img, err := mutate.AppendLayers(empty.Image, pluginLayers(t)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(repo + ":v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("pushing image: %v", err)
+	}
+	// No runtime installed and none asked for.
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("B_CONTAINER_RUNTIME", "")
+
+	dest := t.TempDir()
+	path, err := (&Docker{}).Install("docker://"+repo+"@v1", "", dest, nil, Platform{}, "",
+		[]string{"/usr/local/lib/tool/plugins"})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if data, _ := os.ReadFile(path); path != filepath.Join(dest, "tool") || string(data) != "tool-binary" {
+		t.Errorf("binary %s = %q", path, data)
+	}
+	assertPlugins(t, dest)
+}
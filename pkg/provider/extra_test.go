@@ -7,8 +7,11 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+
+	"github.com/fentas/b/pkg/hosts"
 )
 
 func TestIsReleaseProvider(t *testing.T) {
@@ -120,7 +123,7 @@ func TestGoInstall_Install_MissingGo(t *testing.T) {
 	defer os.Setenv("PATH", oldPath)
 	os.Setenv("PATH", "/nonexistent")
 	g := &GoInstall{}
-	if _, err := g.Install("go://example.com/foo", "latest", t.TempDir()); err == nil {
+	if _, err := g.Install("go://example.com/foo", "latest", t.TempDir(), Platform{}, nil); err == nil {
 		t.Error("expected no-go error")
 	}
 }
@@ -136,8 +139,8 @@ func TestGoInstall_Metadata(t *testing.T) {
 	if g.Match("github.com/x/y") {
 		t.Error("should not match")
 	}
-	v, err := g.LatestVersion("go://x")
-	if err != nil || v != "latest" {
+	v, err := g.Resolve("go://x", "v1.2.3")
+	if err != nil || v != "v1.2.3" {
 		t.Errorf("v=%q err=%v", v, err)
 	}
 	if _, err := g.FetchRelease("", ""); err == nil {
@@ -173,13 +176,14 @@ func TestGiteaSetAuth(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewRequest: %v", err)
 	}
+	h := forgeHost("codeberg.org", hosts.Gitea)
 	t.Setenv("GITEA_TOKEN", "")
-	giteaSetAuth(r)
+	setAuth(r, h)
 	if r.Header.Get("Authorization") != "" {
 		t.Error("expected empty without env")
 	}
 	t.Setenv("GITEA_TOKEN", "secret")
-	giteaSetAuth(r)
+	setAuth(r, h)
 	if r.Header.Get("Authorization") != "token secret" {
 		t.Errorf("header = %q", r.Header.Get("Authorization"))
 	}
@@ -445,7 +449,8 @@ func TestDocker_Install_NoRuntime(t *testing.T) {
 	defer os.Setenv("PATH", oldPath)
 	os.Setenv("PATH", "/nonexistent")
 	d := &Docker{}
-	if _, err := d.Install("docker://x/y", "latest", t.TempDir(), nil); err == nil {
-		t.Error("expected no-runtime error")
+	_, err := d.Install("docker://x/y", "latest", t.TempDir(), nil, Platform{}, "podman", nil)
+	if err == nil || !strings.Contains(err.Error(), "container runtime podman not found") {
+		t.Errorf("Install() error = %v, want podman not found", err)
 	}
 }
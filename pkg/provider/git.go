@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"os/exec"
@@ -42,6 +43,24 @@ func (g *Git) LatestVersion(ref string) (string, error) {
 	return gitcache.ResolveRefAuth(resolved.URL, "HEAD", resolved.AuthHeader)
 }
 
+// ListReleases lists the repository's tags.
+func (g *Git) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	repo, _, err := parseGitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	url, auth := repo, ""
+	if !isLocalRepo(repo) {
+		resolved := gitcache.ResolveGitURL(repo, "")
+		url, auth = resolved.URL, resolved.AuthHeader
+	}
+	tags, err := gitcache.ListTagsAuth(url, auth)
+	if err != nil {
+		return nil, err
+	}
+	return tagPage(tags, opts), nil
+}
+
 // FetchRelease is not used for git — use Install instead.
 func (g *Git) FetchRelease(ref, version string) (*Release, error) {
 	return nil, fmt.Errorf("git provider does not use FetchRelease; use Install()")
@@ -80,7 +99,7 @@ func (g *Git) installFromLocal(repo, filePath, version, dest string) (string, er
 		return "", fmt.Errorf("git show %s in %s: %w", obj, repo, err)
 	}
 
-	if err := os.WriteFile(dest, data, 0755); err != nil {
+	if err := WriteExecutable(dest, bytes.NewReader(data)); err != nil {
 		return "", err
 	}
 	return dest, nil
@@ -115,7 +134,7 @@ func (g *Git) installFromRemote(repo, filePath, version, dest string) (string, e
 		return "", fmt.Errorf("reading %s at %s from %s: %w", filePath, commit, repo, err)
 	}
 
-	if err := os.WriteFile(dest, data, 0755); err != nil {
+	if err := WriteExecutable(dest, bytes.NewReader(data)); err != nil {
 		return "", err
 	}
 	return dest, nil
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
+
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
 )
 
 // Known Gitea/Forgejo instances.
@@ -19,13 +21,17 @@ func init() {
 	Register(&Gitea{})
 }
 
-// Gitea fetches releases from Gitea/Forgejo instances (including Codeberg).
+// Gitea fetches releases from Gitea/Forgejo instances: Codeberg, gitea.com
+// and hosts configured in b.yaml `hosts:`.
 type Gitea struct{}
 
 func (g *Gitea) Name() string { return "gitea" }
 
 func (g *Gitea) Match(ref string) bool {
 	ref, _ = ParseRef(ref)
+	if _, rest, ok := configuredHost(ref, hosts.Gitea); ok {
+		return strings.Contains(strings.Trim(rest, "/"), "/")
+	}
 	for _, host := range knownGiteaHosts {
 		if strings.HasPrefix(ref, host+"/") {
 			return true
@@ -34,37 +40,10 @@ func (g *Gitea) Match(ref string) bool {
 	return false
 }
 
+// LatestVersion returns the newest release that is neither a draft nor a
+// prerelease.
 func (g *Gitea) LatestVersion(ref string) (string, error) {
-	host, owner, repo := giteaParts(ref)
-	apiURL := fmt.Sprintf("https://%s/api/v1/repos/%s/%s/releases?limit=1", host, owner, repo)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return "", err
-	}
-	giteaSetAuth(req)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Gitea API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var releases []struct {
-		TagName string `json:"tag_name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return "", fmt.Errorf("decoding Gitea releases: %w", err)
-	}
-	if len(releases) == 0 {
-		return "", fmt.Errorf("no releases found for %s/%s on %s", owner, repo, host)
-	}
-	return releases[0].TagName, nil
+	return latestFromList(g, ref, ChannelStable)
 }
 
 func (g *Gitea) FetchRelease(ref, version string) (*Release, error) {
@@ -77,14 +56,15 @@ func (g *Gitea) FetchRelease(ref, version string) (*Release, error) {
 		}
 	}
 
-	apiURL := fmt.Sprintf("https://%s/api/v1/repos/%s/%s/releases/tags/%s", host, owner, repo, version)
+	h := forgeHost(host, hosts.Gitea)
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", h.APIBase(host), owner, repo, version)
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	giteaSetAuth(req)
+	setAuth(req, h)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -121,8 +101,25 @@ func (g *Gitea) FetchRelease(ref, version string) (*Release, error) {
 	return release, nil
 }
 
+// ListReleases lists the repository's releases. Gitea's default maximum
+// page size is 50.
+func (g *Gitea) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	host, owner, repo := giteaParts(ref)
+	h := forgeHost(host, hosts.Gitea)
+	page, perPage := pageBounds(opts, 50)
+	apiURL := fmt.Sprintf("%s/repos/%s/%s/releases?limit=%d&page=%d", h.APIBase(host), owner, repo, perPage, page)
+	return forgeReleasePage(h, "Gitea", apiURL, page, perPage)
+}
+
 func giteaParts(ref string) (host, owner, repo string) {
 	ref, _ = ParseRef(ref)
+	if name, rest, ok := configuredHost(ref, hosts.Gitea); ok {
+		parts := strings.SplitN(rest, "/", 3)
+		if len(parts) >= 2 && parts[0] != "" && parts[1] != "" {
+			return name, parts[0], parts[1]
+		}
+		return "", "", ""
+	}
 	for _, h := range knownGiteaHosts {
 		if strings.HasPrefix(ref, h+"/") {
 			rest := strings.TrimPrefix(ref, h+"/")
@@ -134,9 +131,3 @@ func giteaParts(ref string) (host, owner, repo string) {
 	}
 	return "", "", ""
 }
-
-func giteaSetAuth(req *http.Request) {
-	if token := os.Getenv("GITEA_TOKEN"); token != "" {
-		req.Header.Set("Authorization", "token "+token)
-	}
-}
@@ -5,15 +5,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
+
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
 )
 
 func init() {
 	Register(&GitHub{})
 }
 
-// GitHub fetches releases from the GitHub Releases API.
+// GitHub fetches releases from the GitHub Releases API, on github.com or on
+// a GitHub Enterprise host configured in b.yaml `hosts:`.
 type GitHub struct{}
 
 func (g *GitHub) Name() string { return "github" }
@@ -21,6 +24,10 @@ func (g *GitHub) Name() string { return "github" }
 func (g *GitHub) Match(ref string) bool {
 	// Matches "github.com/owner/repo" or bare "owner/repo" (no dots in owner).
 	ref, _ = ParseRef(ref)
+	if _, rest, ok := configuredHost(ref, hosts.GitHub); ok {
+		parts := strings.Split(rest, "/")
+		return len(parts) >= 2 && parts[0] != "" && parts[1] != ""
+	}
 	if strings.HasPrefix(ref, "github.com/") {
 		parts := strings.Split(strings.TrimPrefix(ref, "github.com/"), "/")
 		return len(parts) >= 2 && parts[0] != "" && parts[1] != ""
@@ -35,9 +42,12 @@ func (g *GitHub) Match(ref string) bool {
 
 func (g *GitHub) LatestVersion(ref string) (string, error) {
 	owner, repo := githubOwnerRepo(ref)
+	if name, _, ok := configuredHost(ref, hosts.GitHub); ok {
+		return githubAPILatest(forgeHost(name, hosts.GitHub), name, owner, repo)
+	}
 	// Use redirect-based approach (faster, no API rate limit).
 	url := fmt.Sprintf("https://github.com/%s/%s/releases/latest", owner, repo)
-	resp, err := http.Get(url)
+	resp, err := httpclient.Get(url)
 	if err != nil {
 		return "", err
 	}
@@ -50,6 +60,52 @@ func (g *GitHub) LatestVersion(ref string) (string, error) {
 	return parts[len(parts)-1], nil
 }
 
+// ListReleases lists the repository's releases, drafts included when the
+// token can see them. The API caps a page at 100.
+func (g *GitHub) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	owner, repo := githubOwnerRepo(ref)
+	name := "github.com"
+	if host, _, ok := configuredHost(ref, hosts.GitHub); ok {
+		name = host
+	}
+	h := forgeHost(name, hosts.GitHub)
+	page, perPage := pageBounds(opts, 100)
+	url := fmt.Sprintf("%s/repos/%s/%s/releases?per_page=%d&page=%d", h.APIBase(name), owner, repo, perPage, page)
+	return forgeReleasePage(h, "GitHub", url, page, perPage)
+}
+
+// githubAPILatest asks a GitHub Enterprise API for the latest release; the
+// github.com redirect trick isn't guaranteed behind enterprise SSO.
+func githubAPILatest(h *hosts.Host, name, owner, repo string) (string, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/latest", h.APIBase(name), owner, repo)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	setAuth(req, h)
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("no releases found for %s/%s on %s", owner, repo, name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("GitHub API error %d: %s", resp.StatusCode, string(body))
+	}
+	var latest struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&latest); err != nil {
+		return "", fmt.Errorf("decoding GitHub release: %w", err)
+	}
+	return latest.TagName, nil
+}
+
 func (g *GitHub) FetchRelease(ref, version string) (*Release, error) {
 	owner, repo := githubOwnerRepo(ref)
 	if version == "" {
@@ -60,17 +116,20 @@ func (g *GitHub) FetchRelease(ref, version string) (*Release, error) {
 		}
 	}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", owner, repo, version)
+	name := "github.com"
+	if host, _, ok := configuredHost(ref, hosts.GitHub); ok {
+		name = host
+	}
+	h := forgeHost(name, hosts.GitHub)
+	url := fmt.Sprintf("%s/repos/%s/%s/releases/tags/%s", h.APIBase(name), owner, repo, version)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/vnd.github+json")
-	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
+	setAuth(req, h)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +139,7 @@ func (g *GitHub) FetchRelease(ref, version string) (*Release, error) {
 		return nil, fmt.Errorf("release %s not found for %s/%s", version, owner, repo)
 	}
 	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
-		return nil, fmt.Errorf("GitHub API rate limited (set GITHUB_TOKEN for higher limits)")
+		return nil, fmt.Errorf("GitHub API rate limited (set %s for higher limits)", h.TokenVar())
 	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
@@ -113,6 +172,9 @@ func (g *GitHub) FetchRelease(ref, version string) (*Release, error) {
 // githubOwnerRepo extracts owner and repo from a ref.
 func githubOwnerRepo(ref string) (owner, repo string) {
 	ref, _ = ParseRef(ref)
+	if _, rest, ok := configuredHost(ref, hosts.GitHub); ok {
+		ref = rest
+	}
 	ref = strings.TrimPrefix(ref, "github.com/")
 	parts := strings.SplitN(ref, "/", 3)
 	if len(parts) >= 2 {
@@ -6,34 +6,34 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
 )
 
 func init() {
 	Register(&GitLab{})
 }
 
-// GitLab fetches releases from the GitLab Releases API.
+// GitLab fetches releases from the GitLab Releases API, on gitlab.com or on
+// a self-hosted instance configured in b.yaml `hosts:`.
 type GitLab struct{}
 
 func (g *GitLab) Name() string { return "gitlab" }
 
 func (g *GitLab) Match(ref string) bool {
 	ref, _ = ParseRef(ref)
+	if _, rest, ok := configuredHost(ref, hosts.GitLab); ok {
+		return strings.Contains(strings.Trim(rest, "/"), "/")
+	}
 	return strings.HasPrefix(ref, "gitlab.com/")
 }
 
+// LatestVersion returns the newest release that isn't a prerelease. The
+// release list also holds upcoming releases and release candidates.
 func (g *GitLab) LatestVersion(ref string) (string, error) {
-	projectPath := gitlabProjectPath(ref)
-	releases, err := gitlabGetReleases(projectPath, 1)
-	if err != nil {
-		return "", err
-	}
-	if len(releases) == 0 {
-		return "", fmt.Errorf("no releases found for %s", projectPath)
-	}
-	return releases[0].TagName, nil
+	return latestFromList(g, ref, ChannelStable)
 }
 
 func (g *GitLab) FetchRelease(ref, version string) (*Release, error) {
@@ -46,18 +46,18 @@ func (g *GitLab) FetchRelease(ref, version string) (*Release, error) {
 		}
 	}
 
-	apiURL := fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/releases/%s",
-		url.PathEscape(projectPath), url.PathEscape(version))
+	name := gitlabHost(ref)
+	h := forgeHost(name, hosts.GitLab)
+	apiURL := fmt.Sprintf("%s/projects/%s/releases/%s",
+		h.APIBase(name), url.PathEscape(projectPath), url.PathEscape(version))
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
-	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
-		req.Header.Set("PRIVATE-TOKEN", token)
-	}
+	setAuth(req, h)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpclient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -98,41 +98,29 @@ func (g *GitLab) FetchRelease(ref, version string) (*Release, error) {
 	return release, nil
 }
 
-type gitlabReleaseSummary struct {
-	TagName string `json:"tag_name"`
-}
-
-func gitlabGetReleases(projectPath string, perPage int) ([]gitlabReleaseSummary, error) {
-	apiURL := fmt.Sprintf("https://gitlab.com/api/v4/projects/%s/releases?per_page=%d",
-		url.PathEscape(projectPath), perPage)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	if token := os.Getenv("GITLAB_TOKEN"); token != "" {
-		req.Header.Set("PRIVATE-TOKEN", token)
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("GitLab API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var releases []gitlabReleaseSummary
-	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
-		return nil, fmt.Errorf("decoding GitLab releases: %w", err)
-	}
-	return releases, nil
+// ListReleases lists the project's releases. The API caps a page at 100.
+func (g *GitLab) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	projectPath := gitlabProjectPath(ref)
+	name := gitlabHost(ref)
+	h := forgeHost(name, hosts.GitLab)
+	page, perPage := pageBounds(opts, 100)
+	apiURL := fmt.Sprintf("%s/projects/%s/releases?per_page=%d&page=%d",
+		h.APIBase(name), url.PathEscape(projectPath), perPage, page)
+	return forgeReleasePage(h, "GitLab", apiURL, page, perPage)
 }
 
 func gitlabProjectPath(ref string) string {
 	ref, _ = ParseRef(ref)
+	if _, rest, ok := configuredHost(ref, hosts.GitLab); ok {
+		return strings.Trim(rest, "/")
+	}
 	return strings.TrimPrefix(ref, "gitlab.com/")
 }
+
+// gitlabHost returns the GitLab instance a ref lives on.
+func gitlabHost(ref string) string {
+	if name, _, ok := configuredHost(ref, hosts.GitLab); ok {
+		return name
+	}
+	return "gitlab.com"
+}
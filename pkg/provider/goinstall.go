@@ -2,10 +2,14 @@ package provider
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	"github.com/fentas/b/pkg/offline"
 )
 
 func init() {
@@ -21,9 +25,132 @@ func (g *GoInstall) Match(ref string) bool {
 	return strings.HasPrefix(ref, "go://")
 }
 
+// LatestVersion resolves "latest" to the module version it stands for;
+// see Resolve.
 func (g *GoInstall) LatestVersion(ref string) (string, error) {
-	// For Go install, "latest" is the version string.
-	return "latest", nil
+	return g.Resolve(ref, "latest")
+}
+
+// Resolve resolves query — "latest", a branch, a commit or a version — to
+// the module version `go install` builds for it, so b.lock pins the code
+// every machine builds. Module versions are returned as they are.
+func (g *GoInstall) Resolve(ref, query string) (string, error) {
+	if IsModuleVersion(query) {
+		return query, nil
+	}
+	if offline.Enabled() {
+		return "", offline.Missing(ref+"@"+query, "no module version recorded in b.lock")
+	}
+	return goResolve(goModule(ref), query)
+}
+
+// ListReleases lists the module's tagged versions from the module proxy.
+func (g *GoInstall) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	versions, err := goProxyList(goModule(ref))
+	if err != nil {
+		return nil, err
+	}
+	return tagPage(versions, opts), nil
+}
+
+// GoBuild is the per-binary `build:` block in b.yaml for go:// refs: what
+// `go install` is run with. The zero value is a plain `go install`.
+type GoBuild struct {
+	// Tags are passed as -tags.
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// LDFlags are passed as -ldflags, rendered as a template with the
+	// version, OS and Arch (e.g. "-X main.version={{.Version}}").
+	LDFlags string `json:"ldflags,omitempty" yaml:"ldflags,omitempty"`
+	// Flags are further `go install` flags (e.g. -buildmode=pie).
+	Flags []string `json:"flags,omitempty" yaml:"flags,omitempty"`
+	// Env is set for the build (e.g. CGO_ENABLED, GOEXPERIMENT).
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// Toolchain selects the Go toolchain through GOTOOLCHAIN (e.g.
+	// "go1.22.5").
+	Toolchain string `json:"toolchain,omitempty" yaml:"toolchain,omitempty"`
+	// Reproducible builds with -trimpath and -buildvcs=false, with
+	// CGO_ENABLED=0 unless Env sets it, and ignores the GOFLAGS of the
+	// environment, so the binary doesn't depend on the machine.
+	Reproducible bool `json:"reproducible,omitempty" yaml:"reproducible,omitempty"`
+}
+
+// goBuildReserved are the variables b sets for the build itself.
+var goBuildReserved = []string{"GOBIN", "GOPATH", "GOOS", "GOARCH"}
+
+// IsZero reports whether g builds like a plain `go install`.
+func (g *GoBuild) IsZero() bool {
+	return g == nil || g.Equal(&GoBuild{})
+}
+
+// Equal reports whether g and o build the same way; nil is the zero
+// value.
+func (g *GoBuild) Equal(o *GoBuild) bool {
+	if g == nil {
+		g = &GoBuild{}
+	}
+	if o == nil {
+		o = &GoBuild{}
+	}
+	return slices.Equal(g.Tags, o.Tags) && g.LDFlags == o.LDFlags && slices.Equal(g.Flags, o.Flags) &&
+		maps.Equal(g.Env, o.Env) && g.Toolchain == o.Toolchain && g.Reproducible == o.Reproducible
+}
+
+// args returns the `go install` flags for version on target.
+func (g *GoBuild) args(version string, target Platform) ([]string, error) {
+	if g == nil {
+		return nil, nil
+	}
+	var args []string
+	if g.Reproducible {
+		args = append(args, "-trimpath", "-buildvcs=false")
+	}
+	if len(g.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(g.Tags, ","))
+	}
+	if g.LDFlags != "" {
+		t := target.Resolve()
+		ldflags, err := RenderTemplate(g.LDFlags, HTTPVars{Version: version, OS: t.OS, Arch: t.Arch})
+		if err != nil {
+			return nil, fmt.Errorf("build.ldflags: %w", err)
+		}
+		args = append(args, "-ldflags", ldflags)
+	}
+	return append(args, g.Flags...), nil
+}
+
+// Validate reports settings of g b can't honour: Env can't set the
+// variables b sets for the build itself.
+func (g *GoBuild) Validate() error {
+	if g == nil {
+		return nil
+	}
+	for _, k := range goBuildReserved {
+		if _, ok := g.Env[k]; ok {
+			return fmt.Errorf("build.env can't set %s, b sets it for the build", k)
+		}
+	}
+	return nil
+}
+
+// env returns the variables g adds to the build environment.
+func (g *GoBuild) env() []string {
+	if g == nil {
+		return nil
+	}
+	var env []string
+	if g.Reproducible {
+		env = append(env, "GOFLAGS=")
+		if _, ok := g.Env["CGO_ENABLED"]; !ok {
+			env = append(env, "CGO_ENABLED=0")
+		}
+	}
+	if g.Toolchain != "" {
+		env = append(env, "GOTOOLCHAIN="+g.Toolchain)
+	}
+	for _, k := range slices.Sorted(maps.Keys(g.Env)) {
+		env = append(env, k+"="+g.Env[k])
+	}
+	return env
 }
 
 // FetchRelease is not used for Go install — use Install instead.
@@ -31,8 +158,9 @@ func (g *GoInstall) FetchRelease(ref, version string) (*Release, error) {
 	return nil, fmt.Errorf("go install provider does not use FetchRelease; use Install()")
 }
 
-// Install compiles the module and returns the path to the compiled binary.
-func (g *GoInstall) Install(ref, version, destDir string) (string, error) {
+// Install compiles the module for target as build configures, and
+// returns the path to the compiled binary.
+func (g *GoInstall) Install(ref, version, destDir string, target Platform, build *GoBuild) (string, error) {
 	if _, err := exec.LookPath("go"); err != nil {
 		return "", fmt.Errorf("go not found on PATH (required for go:// provider)")
 	}
@@ -50,21 +178,50 @@ func (g *GoInstall) Install(ref, version, destDir string) (string, error) {
 	defer os.RemoveAll(tmpDir)
 
 	installArg := module + "@" + version
-	cmd := exec.Command("go", "install", installArg)
+	if err := build.Validate(); err != nil {
+		return "", err
+	}
+	args, err := build.args(version, target)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("go", append(append([]string{"install"}, args...), installArg)...)
 	cmd.Env = append(os.Environ(), "GOBIN="+tmpDir)
+	binDir := tmpDir
+	if !target.IsHost() {
+		// go install refuses GOBIN for cross builds and puts them into
+		// $GOPATH/bin/<os>_<arch>; a throwaway GOPATH keeps them out of
+		// the user's, sharing the module cache.
+		modCache, err := exec.Command("go", "env", "GOMODCACHE").Output()
+		if err != nil {
+			return "", fmt.Errorf("go env GOMODCACHE: %w", err)
+		}
+		t := target.Resolve()
+		cmd.Env = append(os.Environ(), "GOBIN=", "GOPATH="+tmpDir,
+			"GOMODCACHE="+strings.TrimSpace(string(modCache)), "GOOS="+t.OS, "GOARCH="+t.Arch)
+		binDir = filepath.Join(tmpDir, "bin", t.OS+"_"+t.Arch)
+	}
+	cmd.Env = append(cmd.Env, build.env()...)
+	if offline.Enabled() {
+		// Build from the module cache only.
+		cmd.Env = append(cmd.Env, "GOPROXY=off", "GOFLAGS=-mod=mod")
+	}
 	cmd.Stderr = os.Stderr
 	if err := cmd.Run(); err != nil {
+		if offline.Enabled() {
+			return "", offline.Missing(installArg, "not in the Go module cache")
+		}
 		return "", fmt.Errorf("go install %s: %w", installArg, err)
 	}
 
 	// Find the compiled binary (last segment of module path)
-	name := BinaryName(ref)
-	compiled := filepath.Join(tmpDir, name)
+	name := target.Exe(BinaryName(ref))
+	compiled := filepath.Join(binDir, name)
 	if _, err := os.Stat(compiled); err != nil {
-		// Try to find any executable in tmpDir
-		entries, _ := os.ReadDir(tmpDir)
+		// Try to find any executable in binDir
+		entries, _ := os.ReadDir(binDir)
 		if len(entries) == 1 {
-			compiled = filepath.Join(tmpDir, entries[0].Name())
+			compiled = filepath.Join(binDir, entries[0].Name())
 			name = entries[0].Name()
 		} else {
 			return "", fmt.Errorf("compiled binary %q not found in GOBIN", name)
@@ -77,12 +234,13 @@ func (g *GoInstall) Install(ref, version, destDir string) (string, error) {
 		return "", err
 	}
 
-	// Read and write (rename doesn't work across filesystems)
-	data, err := os.ReadFile(compiled)
+	// Copy rather than rename: the build dir may be on another filesystem
+	f, err := os.Open(compiled)
 	if err != nil {
 		return "", err
 	}
-	if err := os.WriteFile(dest, data, 0755); err != nil {
+	defer f.Close()
+	if err := WriteExecutable(dest, f); err != nil {
 		return "", err
 	}
 
@@ -0,0 +1,259 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/offline"
+)
+
+func TestGoBuild_Args(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    *GoBuild
+		wantArgs string
+		wantEnv  string
+	}{
+		{name: "plain"},
+		{
+			name: "flags",
+			build: &GoBuild{
+				Tags:    []string{"netgo", "osusergo"},
+				LDFlags: "-s -w -X main.version={{.Version}} -X main.os={{.OS}}",
+				Flags:   []string{"-buildmode=pie"},
+			},
+			wantArgs: "-tags|netgo,osusergo|-ldflags|-s -w -X main.version=v1.2.3 -X main.os=linux|-buildmode=pie",
+		},
+		{
+			name:     "reproducible",
+			build:    &GoBuild{Reproducible: true, Toolchain: "go1.22.5", Env: map[string]string{"GOEXPERIMENT": "rangefunc", "GOAMD64": "v3"}},
+			wantArgs: "-trimpath|-buildvcs=false",
+			wantEnv:  "GOFLAGS= CGO_ENABLED=0 GOTOOLCHAIN=go1.22.5 GOAMD64=v3 GOEXPERIMENT=rangefunc",
+		},
+		{
+			name:     "reproducible with cgo",
+			build:    &GoBuild{Reproducible: true, Env: map[string]string{"CGO_ENABLED": "1"}},
+			wantArgs: "-trimpath|-buildvcs=false",
+			wantEnv:  "GOFLAGS= CGO_ENABLED=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tt.build.args("v1.2.3", Platform{OS: "linux", Arch: "amd64"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(args, "|"); got != tt.wantArgs {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+			if env := strings.Join(tt.build.env(), " "); env != tt.wantEnv {
+				t.Errorf("env = %q, want %q", env, tt.wantEnv)
+			}
+		})
+	}
+
+	if _, err := (&GoBuild{LDFlags: "{{.Nope}}"}).args("v1", Platform{}); err == nil {
+		t.Error("args() with a bad ldflags template: no error")
+	}
+}
+
+func TestGoBuild_Equal(t *testing.T) {
+	var none *GoBuild
+	if !none.Equal(&GoBuild{}) || !none.IsZero() || !(&GoBuild{Env: map[string]string{}}).IsZero() {
+		t.Error("nil and empty builds differ")
+	}
+	a := &GoBuild{Tags: []string{"netgo"}, Env: map[string]string{"CGO_ENABLED": "0"}}
+	if !a.Equal(&GoBuild{Tags: []string{"netgo"}, Env: map[string]string{"CGO_ENABLED": "0"}}) {
+		t.Error("equal builds differ")
+	}
+	for _, b := range []*GoBuild{
+		nil,
+		{Tags: []string{"netgo"}},
+		{Tags: []string{"netgo"}, Env: map[string]string{"CGO_ENABLED": "1"}},
+		{Tags: []string{"netgo"}, Env: map[string]string{"CGO_ENABLED": "0"}, Reproducible: true},
+	} {
+		if a.Equal(b) {
+			t.Errorf("%+v equals %+v", a, b)
+		}
+	}
+}
+
+func TestGoBuild_Validate(t *testing.T) {
+	if err := (&GoBuild{Env: map[string]string{"CGO_ENABLED": "0"}}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (&GoBuild{Env: map[string]string{"GOOS": "plan9"}}).Validate(); err == nil || !strings.Contains(err.Error(), "GOOS") {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestGoInstall_Install_Build(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go is a shell script")
+	}
+	// A fake go records how it was run and "builds" the binary into GOBIN.
+	bin := t.TempDir()
+	record := filepath.Join(t.TempDir(), "record")
+	script := "#!/bin/sh\necho \"$@\" > " + record + "\necho \"CGO_ENABLED=$CGO_ENABLED GOTOOLCHAIN=$GOTOOLCHAIN\" >> " + record +
+		"\necho built > \"$GOBIN/tool\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "go"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	dest := t.TempDir()
+	build := &GoBuild{
+		Tags:      []string{"netgo"},
+		LDFlags:   "-X main.version={{.Version}}",
+		Toolchain: "go1.22.5",
+		Env:       map[string]string{"CGO_ENABLED": "0"},
+	}
+	path, err := (&GoInstall{}).Install("go://example.com/org/tool", "v1.2.3", dest, Platform{}, build)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(dest, "tool") {
+		t.Errorf("path = %q", path)
+	}
+	got, _ := os.ReadFile(record)
+	want := "install -tags netgo -ldflags -X main.version=v1.2.3 example.com/org/tool@v1.2.3\nCGO_ENABLED=0 GOTOOLCHAIN=go1.22.5\n"
+	if string(got) != want {
+		t.Errorf("go ran as\n%s\nwant\n%s", got, want)
+	}
+
+	build.Env = map[string]string{"GOBIN": "/tmp"}
+	if _, err := (&GoInstall{}).Install("go://example.com/org/tool", "v1.2.3", dest, Platform{}, build); err == nil {
+		t.Error("Install() with build.env GOBIN: no error")
+	}
+}
+
+// fileGoProxy lays out files as a file:// module proxy.
+func fileGoProxy(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "file://" + filepath.ToSlash(dir)
+}
+
+func TestGoInstall_Resolve(t *testing.T) {
+	proxy := fileGoProxy(t, map[string]string{
+		"example.com/tool/@v/list":      "v1.0.0\nv1.2.0\nv1.10.0-rc.1\nv1.9.1\n",
+		"example.com/tool/@v/main.info": `{"Version":"v1.9.2-0.20240102150405-abcdefabcdef","Time":"2024-01-02T15:04:05Z"}`,
+		"example.com/!pre/@v/list":      "v0.1.0-alpha\nv0.2.0-beta\n",
+		"example.com/untagged/@v/list":  "",
+		"example.com/untagged/@latest":  `{"Version":"v0.0.0-20240102150405-abcdefabcdef"}`,
+	})
+	other := fileGoProxy(t, map[string]string{"example.com/other/@v/list": "v2.0.0\n"})
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	tests := []struct {
+		name    string
+		goproxy string
+		ref     string
+		query   string
+		want    string
+		wantErr string
+	}{
+		{name: "latest release", goproxy: proxy, ref: "go://example.com/tool/cmd/tool", query: "latest", want: "v1.9.1"},
+		{name: "latest prerelease", goproxy: proxy, ref: "go://example.com/Pre", query: "latest", want: "v0.2.0-beta"},
+		{name: "untagged", goproxy: proxy, ref: "go://example.com/untagged", query: "latest", want: "v0.0.0-20240102150405-abcdefabcdef"},
+		{name: "branch", goproxy: proxy, ref: "go://example.com/tool", query: "main", want: "v1.9.2-0.20240102150405-abcdefabcdef"},
+		{name: "version", goproxy: "off", ref: "go://example.com/tool", query: "v1.2.0", want: "v1.2.0"},
+		{name: "next proxy", goproxy: proxy + "," + other, ref: "go://example.com/other", query: "latest", want: "v2.0.0"},
+		{name: "after any error", goproxy: broken.URL + "|" + proxy, ref: "go://example.com/tool", query: "latest", want: "v1.9.1"},
+		{name: "stops on error", goproxy: broken.URL + "," + proxy, ref: "go://example.com/tool", query: "latest", wantErr: "503"},
+		{name: "unknown", goproxy: proxy, ref: "go://example.com/nope", query: "latest", wantErr: "no such module"},
+		{name: "unknown branch", goproxy: proxy, ref: "go://example.com/tool", query: "dev", wantErr: "no such module"},
+		{name: "off", goproxy: "off", ref: "go://example.com/tool", query: "latest", wantErr: "GOPROXY=off"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOPROXY", tt.goproxy)
+			got, err := (&GoInstall{}).Resolve(tt.ref, tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve() = %q, %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Resolve() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	offline.Set(true)
+	defer offline.Set(false)
+	if _, err := (&GoInstall{}).Resolve("go://example.com/tool", "latest"); !errors.Is(err, offline.ErrOffline) {
+		t.Errorf("offline: %v", err)
+	}
+}
+
+func TestGoInstall_Resolve_Direct(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake go is a shell script")
+	}
+	// Private modules, and a "direct" GOPROXY, are resolved by the go
+	// command; this one only knows example.com/private.
+	bin := t.TempDir()
+	script := "#!/bin/sh\ncase \"$5\" in example.com/private@main) echo v0.0.0-20240102150405-abcdefabcdef ;; *) echo \"not a module: $5\" >&2; exit 1 ;; esac\n"
+	if err := os.WriteFile(filepath.Join(bin, "go"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPRIVATE", "example.com/private,*.corp")
+	if v, err := (&GoInstall{}).Resolve("go://example.com/private/cmd/tool", "main"); err != nil || v != "v0.0.0-20240102150405-abcdefabcdef" {
+		t.Errorf("GOPRIVATE: %q, %v", v, err)
+	}
+	// GONOPROXY takes precedence over GOPRIVATE.
+	t.Setenv("GONOPROXY", "none.example")
+	if _, err := (&GoInstall{}).Resolve("go://example.com/private", "main"); err == nil || !strings.Contains(err.Error(), "GOPROXY=off") {
+		t.Errorf("GONOPROXY: %v", err)
+	}
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPROXY", "direct")
+	if _, err := (&GoInstall{}).Resolve("go://example.com/public", "main"); err == nil || !strings.Contains(err.Error(), "not a module") {
+		t.Errorf("direct: %v", err)
+	}
+}
+
+func TestGoNoProxy(t *testing.T) {
+	tests := []struct {
+		patterns string
+		pkg      string
+		want     bool
+	}{
+		{"", "example.com/tool", false},
+		{"example.com", "example.com/tool/cmd/tool", true},
+		{"example.com/tool", "example.com/tool/cmd", true},
+		{"example.com/tool", "example.com/toolbox", false},
+		{"*.corp,github.com/org", "git.corp/team/tool", true},
+		{"*.corp,github.com/org", "github.com/org/tool", true},
+		{"*.corp,github.com/org", "github.com/other/tool", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("GONOPROXY", tt.patterns)
+		if got := goNoProxy(tt.pkg); got != tt.want {
+			t.Errorf("goNoProxy(%q) with %q = %v, want %v", tt.pkg, tt.patterns, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/b/pkg/semver"
+)
+
+// defaultGoProxy is used when GOPROXY is unset, as by the go command.
+const defaultGoProxy = "https://proxy.golang.org"
+
+// errNoModule reports that a module proxy doesn't know a module, so the
+// next proxy in $GOPROXY is asked.
+var errNoModule = errors.New("no such module")
+
+// moduleVersion matches a canonical module version: a semver tag or a
+// pseudo-version.
+var moduleVersion = regexp.MustCompile(`^v\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+incompatible)?$`)
+
+// IsModuleVersion reports whether version is a canonical module version
+// rather than a query ("latest", a branch or a commit).
+func IsModuleVersion(version string) bool {
+	return moduleVersion.MatchString(version)
+}
+
+// goProxyEntry is an element of $GOPROXY. After an entry followed by "|"
+// the next one is tried on any error, after "," only when the module
+// wasn't found.
+type goProxyEntry struct {
+	url      string
+	anyError bool
+}
+
+// goProxies parses $GOPROXY.
+func goProxies() []goProxyEntry {
+	env := os.Getenv("GOPROXY")
+	if env == "" {
+		env = defaultGoProxy + ",direct"
+	}
+	var list []goProxyEntry
+	for env != "" {
+		i := strings.IndexAny(env, ",|")
+		e := goProxyEntry{url: env}
+		if i >= 0 {
+			e.url, e.anyError, env = env[:i], env[i] == '|', env[i+1:]
+		} else {
+			env = ""
+		}
+		if e.url = strings.TrimRight(strings.TrimSpace(e.url), "/"); e.url != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// goProxy returns the first module proxy listed in $GOPROXY.
+func goProxy() (string, error) {
+	list := goProxies()
+	if len(list) == 0 {
+		return defaultGoProxy, nil
+	}
+	first := list[0].url
+	if first == "direct" || first == "off" {
+		return "", fmt.Errorf("GOPROXY=%s: listing module versions needs a module proxy", os.Getenv("GOPROXY"))
+	}
+	return first, nil
+}
+
+// goProxyGet reads file from proxy, an http(s):// or file:// URL. A file
+// the proxy doesn't have is reported as http.StatusNotFound.
+func goProxyGet(proxy, file string) ([]byte, int, error) {
+	if dir, ok := strings.CutPrefix(proxy, "file://"); ok {
+		body, err := os.ReadFile(filepath.Join(filepath.FromSlash(dir), filepath.FromSlash(file)))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return nil, http.StatusNotFound, nil
+		case err != nil:
+			return nil, 0, err
+		}
+		return body, http.StatusOK, nil
+	}
+	resp, err := httpclient.Get(proxy + "/" + file)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
+
+// goProxyError reports an unexpected response of proxy.
+func goProxyError(proxy string, status int, body []byte) error {
+	return fmt.Errorf("module proxy %s: %d %s: %s", proxy, status, http.StatusText(status), strings.TrimSpace(string(body)))
+}
+
+// goProxyList returns the tagged versions of the module providing pkg. The
+// module root isn't known up front ("github.com/org/repo/cmd/tool"), so
+// parent paths are tried until the proxy knows one.
+func goProxyList(pkg string) ([]string, error) {
+	proxy, err := goProxy()
+	if err != nil {
+		return nil, err
+	}
+	for mod := pkg; strings.Contains(mod, "/"); mod = path.Dir(mod) {
+		body, status, err := goProxyGet(proxy, escapeModulePath(mod)+"/@v/list")
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case http.StatusOK:
+			return strings.Fields(string(body)), nil
+		case http.StatusNotFound, http.StatusGone:
+			continue
+		}
+		return nil, goProxyError(proxy, status, body)
+	}
+	return nil, fmt.Errorf("module proxy %s has no module for %s", proxy, pkg)
+}
+
+// goResolve resolves query — "latest", a branch, a commit or a version —
+// for the module providing pkg to the module version `go install
+// pkg@query` builds. The entries of $GOPROXY are tried in turn; "direct",
+// and modules matched by $GONOPROXY (or $GOPRIVATE), are resolved by the
+// go command, which honours $GONOSUMDB and the other settings itself.
+func goResolve(pkg, query string) (string, error) {
+	if IsModuleVersion(query) {
+		return query, nil
+	}
+	if goNoProxy(pkg) {
+		return goListVersion(pkg, query)
+	}
+	err := fmt.Errorf("GOPROXY=%s: no module proxy to resolve %s@%s", os.Getenv("GOPROXY"), pkg, query)
+	for _, e := range goProxies() {
+		switch e.url {
+		case "off":
+			return "", err
+		case "direct":
+			return goListVersion(pkg, query)
+		}
+		var version string
+		if version, err = goProxyQuery(e.url, pkg, query); err == nil {
+			return version, nil
+		}
+		if !e.anyError && !errors.Is(err, errNoModule) {
+			return "", err
+		}
+	}
+	return "", err
+}
+
+// goProxyQuery resolves query for the module providing pkg with proxy.
+// "latest" is the highest release, else the highest prerelease, else what
+// the proxy's @latest reports; any other query is looked up as
+// @v/<query>.info.
+func goProxyQuery(proxy, pkg, query string) (string, error) {
+	for _, mod := range modulePaths(pkg) {
+		base := escapeModulePath(mod) + "/@"
+		if query == "latest" {
+			body, status, err := goProxyGet(proxy, base+"v/list")
+			if err != nil {
+				return "", err
+			}
+			if status == http.StatusNotFound || status == http.StatusGone {
+				continue
+			}
+			if status != http.StatusOK {
+				return "", goProxyError(proxy, status, body)
+			}
+			if version, ok := highestModuleVersion(strings.Fields(string(body))); ok {
+				return version, nil
+			}
+			return goProxyInfo(proxy, mod, base+"latest")
+		}
+		version, err := goProxyInfo(proxy, mod, base+"v/"+query+".info")
+		if errors.Is(err, errNoModule) {
+			continue
+		}
+		return version, err
+	}
+	return "", fmt.Errorf("module proxy %s: %w for %s@%s", proxy, errNoModule, pkg, query)
+}
+
+// goProxyInfo reads the version from a proxy's .info (or @latest) file.
+func goProxyInfo(proxy, mod, file string) (string, error) {
+	body, status, err := goProxyGet(proxy, file)
+	switch {
+	case err != nil:
+		return "", err
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return "", fmt.Errorf("module proxy %s: %w %s", proxy, errNoModule, mod)
+	case status != http.StatusOK:
+		return "", goProxyError(proxy, status, body)
+	}
+	var info struct{ Version string }
+	if err := json.Unmarshal(body, &info); err != nil || !IsModuleVersion(info.Version) {
+		return "", fmt.Errorf("module proxy %s: bad version info for %s: %s", proxy, mod, strings.TrimSpace(string(body)))
+	}
+	return info.Version, nil
+}
+
+// highestModuleVersion returns the highest release among versions, or the
+// highest prerelease without one.
+func highestModuleVersion(versions []string) (string, bool) {
+	var best, bestPre semver.Version
+	var found, foundPre bool
+	for _, tag := range versions {
+		v, ok := semver.Parse(tag)
+		if !ok || !IsModuleVersion(tag) {
+			continue
+		}
+		if v.Pre == "" {
+			if !found || semver.Compare(v, best) > 0 {
+				best, found = v, true
+			}
+		} else if !foundPre || semver.Compare(v, bestPre) > 0 {
+			bestPre, foundPre = v, true
+		}
+	}
+	if found {
+		return best.Original, true
+	}
+	return bestPre.Original, foundPre
+}
+
+// goListVersion resolves query with `go list -m`, outside any module,
+// trying the parent paths of pkg until one is a module.
+func goListVersion(pkg, query string) (string, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return "", fmt.Errorf("go not found on PATH (required to resolve %s@%s)", pkg, query)
+	}
+	dir, err := os.MkdirTemp("", "b-golist-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	var out []byte
+	for _, mod := range modulePaths(pkg) {
+		cmd := exec.Command("go", "list", "-m", "-f", "{{.Version}}", mod+"@"+query)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+		out, err = cmd.CombinedOutput()
+		if version := strings.TrimSpace(string(out)); err == nil && IsModuleVersion(version) {
+			return version, nil
+		}
+	}
+	return "", fmt.Errorf("go list -m %s@%s: %s", pkg, query, strings.TrimSpace(string(out)))
+}
+
+// modulePaths lists the paths the module providing pkg may have: pkg and
+// its parents, longest first.
+func modulePaths(pkg string) []string {
+	paths := []string{pkg}
+	for mod := path.Dir(pkg); strings.Contains(mod, "/"); mod = path.Dir(mod) {
+		paths = append(paths, mod)
+	}
+	return paths
+}
+
+// goNoProxy reports whether pkg is matched by $GONOPROXY, or $GOPRIVATE
+// when that is empty: comma-separated globs matched against path
+// prefixes, as by the go command.
+func goNoProxy(pkg string) bool {
+	patterns := os.Getenv("GONOPROXY")
+	if patterns == "" {
+		patterns = os.Getenv("GOPRIVATE")
+	}
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		n := strings.Count(pattern, "/") + 1
+		prefix := strings.Split(pkg, "/")
+		if len(prefix) < n {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(prefix[:n], "/")); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeModulePath applies the module proxy's case encoding: an upper-case
+// letter becomes "!" and its lower-case form.
+func escapeModulePath(mod string) string {
+	var b strings.Builder
+	for _, r := range mod {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
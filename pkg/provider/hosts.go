@@ -0,0 +1,34 @@
+package provider
+
+import (
+	"net/http"
+
+	"github.com/fentas/b/pkg/hosts"
+)
+
+// forgeHost returns the host config for the forge called name: the b.yaml
+// `hosts:` entry when it runs typ, otherwise the public defaults.
+func forgeHost(name, typ string) *hosts.Host {
+	if h, ok := hosts.Lookup(name); ok && h.Type == typ {
+		return h
+	}
+	return &hosts.Host{Type: typ}
+}
+
+// configuredHost reports whether ref lives on a b.yaml `hosts:` entry of
+// type typ, returning the host name and the path below it.
+func configuredHost(ref, typ string) (name, rest string, ok bool) {
+	ref, _ = ParseRef(ref)
+	h, name, rest, ok := hosts.ForRef(ref)
+	if !ok || h.Type != typ {
+		return "", "", false
+	}
+	return name, rest, true
+}
+
+// setAuth adds the host's token to req, if one is set.
+func setAuth(req *http.Request, h *hosts.Host) {
+	if token := h.Token(); token != "" {
+		req.Header.Set(h.AuthHeader(token))
+	}
+}
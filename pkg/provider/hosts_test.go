@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fentas/b/pkg/hosts"
+)
+
+// withHosts configures b.yaml `hosts:` for the rest of the test.
+func withHosts(t *testing.T, configured map[string]*hosts.Host) {
+	t.Helper()
+	if err := hosts.Set(configured); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = hosts.Set(nil) })
+}
+
+func TestDetect_ConfiguredHosts(t *testing.T) {
+	withHosts(t, map[string]*hosts.Host{
+		"github.example.corp": {Type: hosts.GitHub},
+		"gitlab.example.corp": {Type: hosts.GitLab},
+		"gitea.example.corp":  {Type: hosts.Gitea},
+	})
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"github.example.corp/org/tool", "github"},
+		{"github.example.corp/org/tool@v1.2.0", "github"},
+		{"gitlab.example.corp/group/sub/tool", "gitlab"},
+		{"gitea.example.corp/org/tool", "gitea"},
+		{"github.com/org/tool", "github"},
+		{"gitlab.com/group/tool", "gitlab"},
+		{"codeberg.org/org/tool", "gitea"},
+	}
+	for _, tt := range tests {
+		p, err := Detect(tt.ref)
+		if err != nil {
+			t.Errorf("Detect(%q): %v", tt.ref, err)
+			continue
+		}
+		if p.Name() != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.ref, p.Name(), tt.want)
+		}
+	}
+
+	// Unconfigured hosts and incomplete refs still don't match.
+	for _, ref := range []string{"github.other.corp/org/tool", "github.example.corp/org", "gitlab.example.corp/tool"} {
+		if p, err := Detect(ref); err == nil {
+			t.Errorf("Detect(%q) = %s, want no provider", ref, p.Name())
+		}
+	}
+
+	if owner, repo := githubOwnerRepo("github.example.corp/org/tool@v1"); owner != "org" || repo != "tool" {
+		t.Errorf("githubOwnerRepo = %q, %q", owner, repo)
+	}
+	if got := gitlabProjectPath("gitlab.example.corp/group/sub/tool@v1"); got != "group/sub/tool" {
+		t.Errorf("gitlabProjectPath = %q", got)
+	}
+	if host, owner, repo := giteaParts("gitea.example.corp/org/tool"); host != "gitea.example.corp" || owner != "org" || repo != "tool" {
+		t.Errorf("giteaParts = %q, %q, %q", host, owner, repo)
+	}
+}
+
+func TestGitHub_Enterprise(t *testing.T) {
+	t.Setenv("GHE_TOKEN", "ghe-secret")
+	mux := http.NewServeMux()
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer ghe-secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("/api/v3/repos/org/tool/releases/latest", auth(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v2.1.0"}`))
+	}))
+	mux.HandleFunc("/api/v3/repos/org/tool/releases/tags/v2.1.0", auth(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v2.1.0","assets":[{"name":"tool-linux-amd64","browser_download_url":"https://github.example.corp/dl/tool","size":7}]}`))
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	withHosts(t, map[string]*hosts.Host{
+		"github.example.corp": {Type: hosts.GitHub, API: srv.URL + "/api/v3", TokenEnv: "GHE_TOKEN"},
+	})
+
+	g := &GitHub{}
+	latest, err := g.LatestVersion("github.example.corp/org/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != "v2.1.0" {
+		t.Errorf("LatestVersion = %q", latest)
+	}
+	rel, err := g.FetchRelease("github.example.corp/org/tool", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version != "v2.1.0" || len(rel.Assets) != 1 || rel.Assets[0].URL != "https://github.example.corp/dl/tool" {
+		t.Errorf("FetchRelease = %+v", rel)
+	}
+
+	if _, err := g.LatestVersion("github.example.corp/org/missing"); err == nil {
+		t.Error("expected error for repo without releases")
+	}
+	t.Setenv("GHE_TOKEN", "wrong")
+	if _, err := g.FetchRelease("github.example.corp/org/tool", "v2.1.0"); err == nil {
+		t.Error("expected error with a rejected token")
+	}
+}
+
+func TestGitLab_SelfHosted(t *testing.T) {
+	t.Setenv("GITLAB_TOKEN", "gl-secret")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/group%2Fsub%2Ftool/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "gl-secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"tag_name":"v0.9.0"}]`))
+	})
+	mux.HandleFunc("/api/v4/projects/group%2Fsub%2Ftool/releases/v0.9.0", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v0.9.0","assets":{"links":[{"name":"tool.tar.gz","direct_asset_url":"https://gitlab.example.corp/tool.tar.gz"}]}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	withHosts(t, map[string]*hosts.Host{
+		"gitlab.example.corp": {Type: hosts.GitLab, API: srv.URL + "/api/v4"},
+	})
+
+	g := &GitLab{}
+	rel, err := g.FetchRelease("gitlab.example.corp/group/sub/tool", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version != "v0.9.0" || len(rel.Assets) != 1 || rel.Assets[0].Name != "tool.tar.gz" {
+		t.Errorf("FetchRelease = %+v", rel)
+	}
+}
+
+func TestGitea_SelfHosted(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/org/tool/releases", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"tag_name":"v3.0.0"}]`))
+	})
+	mux.HandleFunc("/api/v1/repos/org/tool/releases/tags/v3.0.0", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tag_name":"v3.0.0","assets":[{"name":"tool","browser_download_url":"https://gitea.example.corp/tool"}]}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	withHosts(t, map[string]*hosts.Host{
+		"gitea.example.corp": {Type: hosts.Gitea, API: srv.URL + "/api/v1"},
+	})
+
+	rel, err := (&Gitea{}).FetchRelease("gitea.example.corp/org/tool", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version != "v3.0.0" || len(rel.Assets) != 1 {
+		t.Errorf("FetchRelease = %+v", rel)
+	}
+}
@@ -0,0 +1,273 @@
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/tidwall/gjson"
+)
+
+func init() {
+	Register(&HTTP{})
+}
+
+// HTTP downloads binaries from a templated URL, for tools published outside
+// a supported forge. The ref is the URL itself:
+//
+//	https://dl.example.com/tool/{{.Version}}/tool_{{.OS}}_{{.Arch}}.tar.gz
+//
+// Template data is {{.Version}}, {{.OS}} and {{.Arch}} (GOOS/GOARCH, after
+// the HTTPConfig aliases). The funcs trimPrefix, trimSuffix, replace, lower
+// and upper are available, e.g. {{trimPrefix "v" .Version}}.
+type HTTP struct{}
+
+// HTTPConfig is the per-binary `http:` block in b.yaml for templated URL
+// refs.
+//
+//	binaries:
+//	  https://dl.example.com/tool/{{.Version}}/tool_{{.OS}}_{{.Arch}}.tar.gz:
+//	    version: v1.4.2
+//	    http:
+//	      arch: {amd64: x86_64, arm64: aarch64}
+//	      latest:
+//	        url: https://dl.example.com/tool/latest.json
+//	        json: tag_name
+//	      member: tool_{{.OS}}_{{.Arch}}/tool
+type HTTPConfig struct {
+	// OS and Arch map GOOS/GOARCH to the names used in the URL
+	// (e.g. darwin → macOS, amd64 → x86_64). Unmapped values pass through.
+	OS   map[string]string `json:"os,omitempty" yaml:"os,omitempty"`
+	Arch map[string]string `json:"arch,omitempty" yaml:"arch,omitempty"`
+	// Latest resolves the newest version; without it the version must be
+	// pinned (unless the URL has no {{.Version}} at all).
+	Latest *HTTPLatest `json:"latest,omitempty" yaml:"latest,omitempty"`
+	// Member is the (templated) path of the binary inside the archive.
+	// When empty the binary is picked heuristically.
+	Member string `json:"member,omitempty" yaml:"member,omitempty"`
+}
+
+// HTTPLatest describes the latest-version endpoint. The response body is
+// read as plain text, unless JSON is set (a gjson path into the body) or
+// Redirect is true (the version is the last path segment of the URL the
+// endpoint redirects to). Regex, if set, extracts the version from that
+// value — the first capture group, or the whole match.
+type HTTPLatest struct {
+	URL      string `json:"url" yaml:"url"`
+	JSON     string `json:"json,omitempty" yaml:"json,omitempty"`
+	Redirect bool   `json:"redirect,omitempty" yaml:"redirect,omitempty"`
+	Regex    string `json:"regex,omitempty" yaml:"regex,omitempty"`
+}
+
+// HTTPVars is the data the URL and member templates are rendered with.
+type HTTPVars struct {
+	Version string
+	OS      string
+	Arch    string
+}
+
+// httpNameExtensions are stripped from a literal last URL segment when
+// deriving the binary name.
+var httpNameExtensions = append(append([]string{}, archiveExtensions...), ".exe", ".gz", ".xz", ".bz2")
+
+var httpTemplateFuncs = template.FuncMap{
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+}
+
+func (h *HTTP) Name() string { return "http" }
+
+func (h *HTTP) Match(ref string) bool {
+	return strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "http://")
+}
+
+// LatestVersion resolves the latest version without a b.yaml config; see
+// Latest.
+func (h *HTTP) LatestVersion(ref string) (string, error) {
+	return h.Latest(ref, nil)
+}
+
+// FetchRelease renders the URL for version without a b.yaml config and
+// returns it as a single-asset release; see Resolve.
+func (h *HTTP) FetchRelease(ref, version string) (*Release, error) {
+	asset, err := h.Resolve(ref, version, nil, Platform{})
+	if err != nil {
+		return nil, err
+	}
+	return &Release{Version: version, Assets: []Asset{*asset}}, nil
+}
+
+// Latest returns the latest version from cfg's latest endpoint. A URL
+// without {{.Version}} is unversioned and always reports "latest".
+func (h *HTTP) Latest(ref string, cfg *HTTPConfig) (string, error) {
+	ref, _ = ParseRef(ref)
+	if cfg == nil || cfg.Latest == nil || cfg.Latest.URL == "" {
+		if !strings.Contains(ref, ".Version") {
+			return "latest", nil
+		}
+		return "", fmt.Errorf("%s: no latest endpoint configured (pin a version or set http.latest)", ref)
+	}
+	return cfg.Latest.Resolve(cfg.vars("", Platform{}))
+}
+
+// Resolve fetches the latest version from the endpoint. vars fill in a
+// templated endpoint URL (e.g. one per platform).
+func (l *HTTPLatest) Resolve(vars HTTPVars) (string, error) {
+	endpoint, err := RenderTemplate(l.URL, vars)
+	if err != nil {
+		return "", fmt.Errorf("latest url: %w", err)
+	}
+	resp, err := httpclient.Get(endpoint)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d fetching latest version from %s", resp.StatusCode, endpoint)
+	}
+
+	var value string
+	switch {
+	case l.Redirect:
+		value = path.Base(resp.Request.URL.Path)
+	case l.JSON != "":
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		value = gjson.GetBytes(body, l.JSON).String()
+	default:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return "", err
+		}
+		value = string(body)
+	}
+	value = strings.TrimSpace(value)
+
+	if l.Regex != "" {
+		re, err := regexp.Compile(l.Regex)
+		if err != nil {
+			return "", fmt.Errorf("latest regex: %w", err)
+		}
+		m := re.FindStringSubmatch(value)
+		switch {
+		case m == nil:
+			value = ""
+		case len(m) > 1:
+			value = m[1]
+		default:
+			value = m[0]
+		}
+	}
+	if value == "" || strings.ContainsAny(value, "\n\r") {
+		return "", fmt.Errorf("no version found at %s", endpoint)
+	}
+	return value, nil
+}
+
+// Resolve renders the URL template for version on target.
+func (h *HTTP) Resolve(ref, version string, cfg *HTTPConfig, target Platform) (*Asset, error) {
+	ref, _ = ParseRef(ref)
+	raw, err := RenderTemplate(ref, cfg.vars(version, target))
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("rendered url %q: %w", raw, err)
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = u.Host
+	}
+	return &Asset{Name: name, URL: raw}, nil
+}
+
+// RenderMember renders cfg's archive member template for version on
+// target. Returns "" when no member is configured.
+func (c *HTTPConfig) RenderMember(version string, target Platform) (string, error) {
+	if c == nil || c.Member == "" {
+		return "", nil
+	}
+	return RenderTemplate(c.Member, c.vars(version, target))
+}
+
+// vars returns the template data for target.
+func (c *HTTPConfig) vars(version string, target Platform) HTTPVars {
+	target = target.Resolve()
+	return c.Vars(version, target.OS, target.Arch)
+}
+
+// Vars returns the template data for version on goos/goarch, with the OS
+// and Arch aliases applied.
+func (c *HTTPConfig) Vars(version, goos, goarch string) HTTPVars {
+	v := HTTPVars{Version: version, OS: goos, Arch: goarch}
+	if c != nil {
+		if alias, ok := c.OS[v.OS]; ok {
+			v.OS = alias
+		}
+		if alias, ok := c.Arch[v.Arch]; ok {
+			v.Arch = alias
+		}
+	}
+	return v
+}
+
+// RenderTemplate renders a URL or archive member template with vars.
+func RenderTemplate(text string, vars HTTPVars) (string, error) {
+	tmpl, err := template.New("url").Funcs(httpTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parsing template %q: %w", text, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("rendering template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
+
+// httpTemplateName derives a binary name from a templated URL: the literal
+// text of the last path segment that has any, up to its first template
+// action, without archive extensions or trailing separators.
+//
+//	https://dl.example.com/tool/{{.Version}}/tool_{{.OS}}_{{.Arch}}.tar.gz → "tool"
+//	https://dl.k8s.io/release/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl → "kubectl"
+func httpTemplateName(ref string) string {
+	r, _ := ParseRef(ref)
+	if i := strings.Index(r, "://"); i >= 0 {
+		r = r[i+3:]
+	}
+	if i := strings.IndexAny(r, "?#"); i >= 0 {
+		r = r[:i]
+	}
+	parts := strings.Split(strings.TrimRight(r, "/"), "/")
+	for i := len(parts) - 1; i > 0; i-- {
+		seg := parts[i]
+		if j := strings.Index(seg, "{{"); j >= 0 {
+			seg = seg[:j]
+		} else {
+			lower := strings.ToLower(seg)
+			for _, ext := range httpNameExtensions {
+				if strings.HasSuffix(lower, ext) {
+					seg = seg[:len(seg)-len(ext)]
+					break
+				}
+			}
+		}
+		if seg = strings.TrimRight(seg, "-_."); seg != "" {
+			return seg
+		}
+	}
+	return parts[0]
+}
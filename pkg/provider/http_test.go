@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHTTP_Match(t *testing.T) {
+	h := &HTTP{}
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"https://dl.example.com/tool/{{.Version}}/tool", true},
+		{"http://internal/tool", true},
+		{"github.com/org/tool", false},
+		{"oci://ghcr.io/org/img", false},
+		{"git://github.com/org/repo:bin/tool", false},
+	}
+	for _, tt := range tests {
+		if got := h.Match(tt.ref); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.ref, got, tt.want)
+		}
+	}
+
+	p, err := Detect("https://dl.example.com/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "http" {
+		t.Errorf("Detect = %s, want http", p.Name())
+	}
+	if IsReleaseProvider(p) {
+		t.Error("http should not be a release provider")
+	}
+}
+
+func TestHTTP_BinaryName(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"https://dl.example.com/tool/{{.Version}}/tool_{{.OS}}_{{.Arch}}.tar.gz", "tool"},
+		{"https://dl.k8s.io/release/{{.Version}}/bin/{{.OS}}/{{.Arch}}/kubectl", "kubectl"},
+		{"https://releases.example.com/kube-linter-{{.OS}}.zip@v0.6.0", "kube-linter"},
+		{`https://releases.hashicorp.com/terraform/{{trimPrefix "v" .Version}}/terraform_{{trimPrefix "v" .Version}}_{{.OS}}_{{.Arch}}.zip`, "terraform"},
+		{"https://dl.example.com/mytool/{{.Version}}/{{.OS}}-{{.Arch}}", "mytool"},
+		{"https://dl.example.com/bin/yq.tar.gz?token=x", "yq"},
+	}
+	for _, tt := range tests {
+		if got := BinaryName(tt.ref); got != tt.want {
+			t.Errorf("BinaryName(%q) = %q, want %q", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestHTTP_Resolve(t *testing.T) {
+	h := &HTTP{}
+	cfg := &HTTPConfig{
+		OS:   map[string]string{runtime.GOOS: "TestOS"},
+		Arch: map[string]string{runtime.GOARCH: "x86_64"},
+	}
+	ref := `https://dl.example.com/{{.Version}}/tool_{{trimPrefix "v" .Version}}_{{lower .OS}}_{{.Arch}}.tar.gz`
+	asset, err := h.Resolve(ref+"@v1.2.3", "v1.2.3", cfg, Platform{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "https://dl.example.com/v1.2.3/tool_1.2.3_testos_x86_64.tar.gz"
+	if asset.URL != want {
+		t.Errorf("URL = %q, want %q", asset.URL, want)
+	}
+	if asset.Name != "tool_1.2.3_testos_x86_64.tar.gz" {
+		t.Errorf("Name = %q", asset.Name)
+	}
+
+	// Without aliases GOOS/GOARCH pass through.
+	rel, err := h.FetchRelease("https://dl.example.com/{{.OS}}/{{.Arch}}/tool", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rel.Assets[0].URL; got != "https://dl.example.com/"+runtime.GOOS+"/"+runtime.GOARCH+"/tool" {
+		t.Errorf("URL = %q", got)
+	}
+
+	// Another platform renders with its own names and aliases.
+	asset, err = h.Resolve(ref, "v1.2.3", &HTTPConfig{Arch: map[string]string{"arm64": "aarch64"}}, Platform{OS: "darwin", Arch: "arm64"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.Name != "tool_1.2.3_darwin_aarch64.tar.gz" {
+		t.Errorf("Name for darwin/arm64 = %q", asset.Name)
+	}
+
+	if _, err := h.Resolve("https://dl.example.com/{{.Nope}}", "v1", nil, Platform{}); err == nil {
+		t.Error("expected error for unknown template field")
+	}
+	if _, err := h.Resolve("https://dl.example.com/{{.Version", "v1", nil, Platform{}); err == nil {
+		t.Error("expected error for malformed template")
+	}
+}
+
+func TestHTTPConfig_RenderMember(t *testing.T) {
+	var nilCfg *HTTPConfig
+	if m, err := nilCfg.RenderMember("v1", Platform{}); err != nil || m != "" {
+		t.Errorf("nil config: %q, %v", m, err)
+	}
+	cfg := &HTTPConfig{
+		Arch:   map[string]string{runtime.GOARCH: "x64"},
+		Member: "tool-{{.Version}}-{{.Arch}}/bin/tool",
+	}
+	m, err := cfg.RenderMember("v2", Platform{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != "tool-v2-x64/bin/tool" {
+		t.Errorf("member = %q", m)
+	}
+}
+
+func TestHTTP_Latest(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stable.txt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("v1.30.2\n"))
+	})
+	mux.HandleFunc("/release.json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"current":{"version":"2.4.0"}}`))
+	})
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/releases/v3.1.0", http.StatusFound)
+	})
+	mux.HandleFunc("/releases/", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<h1>Tool</h1>\n<p>Latest: tool 4.5.6 (stable)</p>"))
+	})
+	mux.HandleFunc("/platform/"+runtime.GOOS, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("v5"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ref := "https://dl.example.com/{{.Version}}/tool"
+	h := &HTTP{}
+	tests := []struct {
+		name    string
+		latest  *HTTPLatest
+		want    string
+		wantErr string
+	}{
+		{"text", &HTTPLatest{URL: srv.URL + "/stable.txt"}, "v1.30.2", ""},
+		{"json", &HTTPLatest{URL: srv.URL + "/release.json", JSON: "current.version"}, "2.4.0", ""},
+		{"redirect", &HTTPLatest{URL: srv.URL + "/latest", Redirect: true}, "v3.1.0", ""},
+		{"regex", &HTTPLatest{URL: srv.URL + "/page", Regex: `tool (\d+\.\d+\.\d+)`}, "4.5.6", ""},
+		{"templated url", &HTTPLatest{URL: srv.URL + "/platform/{{.OS}}"}, "v5", ""},
+		{"multi-line text", &HTTPLatest{URL: srv.URL + "/page"}, "", "no version found"},
+		{"json path missing", &HTTPLatest{URL: srv.URL + "/release.json", JSON: "nope"}, "", "no version found"},
+		{"regex no match", &HTTPLatest{URL: srv.URL + "/stable.txt", Regex: `^x`}, "", "no version found"},
+		{"bad regex", &HTTPLatest{URL: srv.URL + "/stable.txt", Regex: `(`}, "", "latest regex"},
+		{"not found", &HTTPLatest{URL: srv.URL + "/missing"}, "", "HTTP 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Latest(ref, &HTTPConfig{Latest: tt.latest})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Latest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTP_LatestWithoutEndpoint(t *testing.T) {
+	h := &HTTP{}
+	if _, err := h.LatestVersion("https://dl.example.com/{{.Version}}/tool"); err == nil {
+		t.Error("expected error for versioned URL without latest endpoint")
+	}
+	v, err := h.Latest("https://dl.example.com/{{.OS}}/tool", &HTTPConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "latest" {
+		t.Errorf("unversioned URL: got %q, want latest", v)
+	}
+}
@@ -3,19 +3,24 @@ package provider
 import (
 	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
 
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/fentas/b/pkg/httpclient"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
 )
 
 // digestResolveTimeout bounds the single-manifest HEAD call in
@@ -43,6 +48,9 @@ func init() {
 //
 // The in-container path must begin with "/" so it is unambiguous with
 // docker's own "image:tag" syntax (which we never use — tags go after "@").
+//
+// Refs naming an OCI artifact instead of an image install the artifact's
+// layer for the platform as the binary; see installArtifact.
 type OCI struct{}
 
 func (o *OCI) Name() string { return "oci" }
@@ -55,16 +63,27 @@ func (o *OCI) LatestVersion(ref string) (string, error) {
 	return "latest", nil
 }
 
+// ListReleases lists the image's tags.
+func (o *OCI) ListReleases(ref string, opts ListOptions) (*ReleasePage, error) {
+	image, _, _ := ParseImageRef(strings.TrimPrefix(ref, "oci://"))
+	tags, err := registryTags(image)
+	if err != nil {
+		return nil, err
+	}
+	return tagPage(tags, opts), nil
+}
+
 // FetchRelease is not used for OCI — use Install instead.
 func (o *OCI) FetchRelease(ref, version string) (*Release, error) {
 	return nil, fmt.Errorf("oci provider does not use FetchRelease; use Install()")
 }
 
 // ResolveDigest returns the current manifest digest for the tag. It is
-// resolved via a registry HEAD (no layers pulled), honouring the user's
-// docker-config auth and selecting the current platform's manifest when
-// the tag points at an index. Returns ("", nil) if the registry can't
-// be reached — callers treat empty as "unknown" and proceed to install.
+// resolved from the registry's manifest (no layers pulled), honouring the
+// user's docker-config auth. For an OCI artifact it is the digest of the
+// layer installed for the current platform instead. Returns ("", nil) if
+// the registry can't be reached — callers treat empty as "unknown" and
+// proceed to install.
 //
 // A digestResolveTimeout guards against hung registry connections; a
 // stalled HEAD would otherwise block `b update` indefinitely (one call
@@ -79,19 +98,18 @@ func (o *OCI) ResolveDigest(ref, version string) (string, error) {
 	if tag == "" {
 		tag = "latest"
 	}
-	nameRef, err := name.ParseReference(image + ":" + tag)
+	nameRef, err := imageReference(image, tag)
 	if err != nil {
-		return "", fmt.Errorf("parsing image ref %s:%s: %w", image, tag, err)
+		return "", err
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), digestResolveTimeout)
 	defer cancel()
-	desc, err := remote.Head(nameRef,
+	desc, err := remote.Get(nameRef,
 		remote.WithContext(ctx),
 		remote.WithAuthFromKeychain(authn.DefaultKeychain),
-		remote.WithPlatform(v1.Platform{
-			OS:           runtime.GOOS,
-			Architecture: runtime.GOARCH,
-		}),
+		remote.WithTransport(httpclient.Transport()),
+		remote.WithRetryStatusCodes(),
+		remote.WithPlatform(HostPlatform().image()),
 	)
 	if err != nil {
 		// Network / auth / 404 / timeout — treat as "unknown" rather
@@ -99,12 +117,17 @@ func (o *OCI) ResolveDigest(ref, version string) (string, error) {
 		// `b update`.
 		return "", nil
 	}
+	// An artifact's identity is the blob installed, not the manifest.
+	if layer, err := artifactDigest(desc, BinaryName(ref), HostPlatform()); err == nil && layer != "" {
+		return layer, nil
+	}
 	return desc.Digest.String(), nil
 }
 
-// Install pulls a platform-matching image manifest and extracts a single
-// binary file without invoking any container runtime.
-func (o *OCI) Install(ref, version, destDir string) (string, error) {
+// Install pulls the image manifest for target and extracts a single
+// binary file without invoking any container runtime. version may be a
+// manifest digest ("sha256:...") to pin the exact image that was verified.
+func (o *OCI) Install(ref, version, destDir string, target Platform, paths []string) (string, error) {
 	rest := strings.TrimPrefix(ref, "oci://")
 	image, refTag, inContainerPath := ParseImageRef(rest)
 
@@ -115,11 +138,29 @@ func (o *OCI) Install(ref, version, destDir string) (string, error) {
 	if tag == "" {
 		tag = "latest"
 	}
-	binName := BinaryName(ref)
+	binName := target.Exe(BinaryName(ref))
+	binPath, extra := SplitPaths(binName, paths)
 
-	nameRef, err := name.ParseReference(image + ":" + tag)
+	// Determine which paths to try inside the image.
+	var searchPaths []string
+	switch {
+	case inContainerPath != "":
+		searchPaths = []string{inContainerPath}
+	case binPath != "":
+		searchPaths = []string{binPath}
+	default:
+		searchPaths = defaultSearchPaths(binName)
+	}
+	return installImage(image, tag, binName, destDir, searchPaths, extra, target)
+}
+
+// installImage reads image at tag for target from the registry, with no
+// container runtime, and extracts the binary binName from the first of
+// searchPaths it holds, and each of extra next to it.
+func installImage(image, tag, binName, destDir string, searchPaths, extra []string, target Platform) (string, error) {
+	nameRef, err := imageReference(image, tag)
 	if err != nil {
-		return "", fmt.Errorf("parsing image ref %s:%s: %w", image, tag, err)
+		return "", err
 	}
 
 	// remote.Image handles manifest-list/index resolution internally using the
@@ -127,33 +168,33 @@ func (o *OCI) Install(ref, version, destDir string) (string, error) {
 	// platform matching here.
 	img, err := remote.Image(nameRef,
 		remote.WithAuthFromKeychain(authn.DefaultKeychain),
-		remote.WithPlatform(v1.Platform{
-			OS:           runtime.GOOS,
-			Architecture: runtime.GOARCH,
-		}),
+		remote.WithTransport(httpclient.Transport()),
+		remote.WithRetryStatusCodes(),
+		remote.WithPlatform(target.image()),
 	)
 	if err != nil {
 		return "", fmt.Errorf("fetching image %s: %w", nameRef, err)
 	}
 
-	// Determine which paths to try inside the image.
-	var searchPaths []string
-	if inContainerPath != "" {
-		searchPaths = []string{inContainerPath}
-	} else {
-		searchPaths = []string{
-			"/usr/local/bin/" + binName,
-			"/usr/bin/" + binName,
-			"/bin/" + binName,
-			"/app/" + binName,
-		}
-	}
-
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return "", err
 	}
 	dest := filepath.Join(destDir, binName)
 
+	artifact, err := isArtifact(img)
+	if err != nil {
+		return "", fmt.Errorf("reading manifest of %s: %w", nameRef, err)
+	}
+	if artifact {
+		if len(extra) > 0 {
+			return "", fmt.Errorf("%s is an OCI artifact, paths don't apply to it", nameRef)
+		}
+		if err := installArtifact(nameRef.Context(), img, binName, dest, target); err != nil {
+			return "", fmt.Errorf("installing artifact %s: %w", nameRef, err)
+		}
+		return dest, nil
+	}
+
 	layers, err := img.Layers()
 	if err != nil {
 		return "", fmt.Errorf("reading layers: %w", err)
@@ -162,21 +203,205 @@ func (o *OCI) Install(ref, version, destDir string) (string, error) {
 	// from newer layers so we don't resurrect a file deleted in the final image.
 	whiteouts := make(map[string]bool)
 	for i := len(layers) - 1; i >= 0; i-- {
-		found, err := extractBinaryFromLayer(layers[i], searchPaths, dest, whiteouts)
+		found, err := extractBinaryFromLayer(cachedLayer(nameRef.Context(), layers[i]), searchPaths, dest, whiteouts)
 		if err != nil {
 			return "", err
 		}
-		if found {
-			if err := os.Chmod(dest, 0755); err != nil {
-				return "", err
+		if !found {
+			continue
+		}
+		if err := os.Chmod(dest, 0755); err != nil {
+			return "", err
+		}
+		for _, p := range extra {
+			if err := extractTreeFromLayers(nameRef.Context(), layers, p, filepath.Join(destDir, path.Base(p))); err != nil {
+				return "", fmt.Errorf("extracting %s from image %s: %w", p, nameRef, err)
 			}
-			return dest, nil
 		}
+		return dest, nil
 	}
 
 	return "", fmt.Errorf("binary %q not found in image %s at paths: %v", binName, nameRef, searchPaths)
 }
 
+// cachedLayer serves l from the blob cache (see pkg/blobcache), adding it
+// first when it isn't there yet. Layers are content-addressed already, so
+// one pulled for any image of any project is reused. With the cache off,
+// or when storing fails, l is returned as is.
+func cachedLayer(repo name.Repository, l v1.Layer) v1.Layer {
+	blob, ok := cachedBlob(repo, l)
+	if !ok {
+		return l
+	}
+	cached, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) { return os.Open(blob) })
+	if err != nil {
+		return l
+	}
+	return cached
+}
+
+// cachedBlob returns the blob cache's copy of l's (compressed) blob,
+// adding it first when it isn't there yet. It reports false with the
+// cache off or when storing fails.
+func cachedBlob(repo name.Repository, l v1.Layer) (string, bool) {
+	if blobcache.Root() == "" {
+		return "", false
+	}
+	digest, err := l.Digest()
+	if err != nil || digest.Algorithm != "sha256" {
+		return "", false
+	}
+	key := repo.String() + "@" + digest.String()
+	blob, ok := blobcache.Lookup(key, digest.Hex)
+	if !ok {
+		rc, err := l.Compressed()
+		if err != nil {
+			return "", false
+		}
+		blob, err = blobcache.PutReader(key, rc, digest.Hex)
+		rc.Close()
+		if err != nil {
+			return "", false
+		}
+	}
+	return blob, true
+}
+
+// imageReference builds the reference for image at tag; a "sha256:..." tag
+// is treated as a digest so verified installs can pin the manifest.
+func imageReference(image, tag string) (name.Reference, error) {
+	sep := ":"
+	if strings.HasPrefix(tag, "sha256:") {
+		sep = "@"
+	}
+	nameRef, err := name.ParseReference(image + sep + tag)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image ref %s%s%s: %w", image, sep, tag, err)
+	}
+	return nameRef, nil
+}
+
+// Cosign stores image signatures as layers of a "sha256-<digest>.sig"
+// manifest, one simple-signing payload per layer, with the signature and
+// keyless material carried in layer annotations.
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignBundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// OCISignature is one cosign signature attached to an image.
+type OCISignature struct {
+	// Payload is the signed simple-signing JSON naming the manifest digest.
+	Payload []byte
+	// Signature is the base64 signature over Payload.
+	Signature string
+	// Certificate is the PEM signing certificate (keyless only).
+	Certificate []byte
+	// Bundle is the Rekor bundle recorded at signing time (keyless only).
+	Bundle []byte
+}
+
+// CosignSignatures fetches the cosign signatures attached to ref. The tag
+// is resolved once; signatures on the top-level digest (the index, for
+// multi-platform images) win over signatures on target's manifest.
+// Returns the signed digest — install from it, not the tag, so the bytes
+// extracted are the ones that were verified — and ("", nil, nil) when the
+// image carries no signatures.
+func (o *OCI) CosignSignatures(ref, version string, target Platform) (string, []OCISignature, error) {
+	rest := strings.TrimPrefix(ref, "oci://")
+	image, refTag, _ := ParseImageRef(rest)
+	tag := version
+	if tag == "" {
+		tag = refTag
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	nameRef, err := imageReference(image, tag)
+	if err != nil {
+		return "", nil, err
+	}
+	opts := []remote.Option{
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(httpclient.Transport()),
+		remote.WithRetryStatusCodes(),
+		remote.WithPlatform(target.image()),
+	}
+	desc, err := remote.Get(nameRef, opts...)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetching image %s: %w", nameRef, err)
+	}
+	digests := []v1.Hash{desc.Digest}
+	if desc.MediaType.IsIndex() {
+		img, err := desc.Image()
+		if err != nil {
+			return "", nil, fmt.Errorf("resolving platform manifest for %s: %w", nameRef, err)
+		}
+		d, err := img.Digest()
+		if err != nil {
+			return "", nil, err
+		}
+		digests = append(digests, d)
+	}
+
+	for _, d := range digests {
+		sigTag := nameRef.Context().Tag(fmt.Sprintf("%s-%s.sig", d.Algorithm, d.Hex))
+		sigImg, err := remote.Image(sigTag, opts...)
+		if err != nil {
+			var terr *transport.Error
+			if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return "", nil, fmt.Errorf("fetching signatures %s: %w", sigTag, err)
+		}
+		sigs, err := readCosignSignatures(sigImg)
+		if err != nil {
+			return "", nil, fmt.Errorf("reading signatures %s: %w", sigTag, err)
+		}
+		if len(sigs) > 0 {
+			return d.String(), sigs, nil
+		}
+	}
+	return "", nil, nil
+}
+
+func readCosignSignatures(img v1.Image) ([]OCISignature, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	var sigs []OCISignature
+	for _, desc := range manifest.Layers {
+		sig := desc.Annotations[cosignSignatureAnnotation]
+		if sig == "" {
+			continue
+		}
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := layer.Compressed()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		s := OCISignature{Payload: payload, Signature: sig}
+		if cert := desc.Annotations[cosignCertificateAnnotation]; cert != "" {
+			s.Certificate = []byte(cert)
+		}
+		if bundle := desc.Annotations[cosignBundleAnnotation]; bundle != "" {
+			s.Bundle = []byte(bundle)
+		}
+		sigs = append(sigs, s)
+	}
+	return sigs, nil
+}
+
 // extractBinaryFromLayer scans a layer's tar stream once, looking for any of
 // searchPaths. Returns true (and writes to dest) when a match is found.
 // Earlier entries in searchPaths take priority; once a higher-priority match
@@ -296,6 +521,104 @@ func extractBinaryFromLayer(l v1.Layer, searchPaths []string, dest string, white
 	return true, nil
 }
 
+// extractTreeFromLayers extracts the file or directory at src in the
+// image made of layers to dest, replacing what is there. Layers are read
+// newest-first: the newest copy of each path wins, and whiteouts hide
+// paths from the layers below them. Only regular files and directories
+// are extracted; links and devices are skipped.
+func extractTreeFromLayers(repo name.Repository, layers []v1.Layer, src, dest string) error {
+	src = path.Clean("/" + strings.TrimPrefix(src, "/"))
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	root := filepath.Join(tmp, filepath.Base(dest))
+
+	seen := make(map[string]bool)
+	whiteouts := make(map[string]bool)
+	for i := len(layers) - 1; i >= 0; i-- {
+		// Whiteouts hide paths from older layers only, not this one.
+		hidden, err := extractTreeFromLayer(cachedLayer(repo, layers[i]), src, root, seen, whiteouts)
+		if err != nil {
+			return err
+		}
+		for p := range hidden {
+			whiteouts[p] = true
+		}
+	}
+	if _, err := os.Stat(root); err != nil {
+		return fmt.Errorf("not found in image")
+	}
+	return replaceTree(root, dest)
+}
+
+// extractTreeFromLayer writes the paths below src in l that no newer
+// layer held or hid to root, and returns the whiteouts l adds.
+func extractTreeFromLayer(l v1.Layer, src, root string, seen, whiteouts map[string]bool) (map[string]bool, error) {
+	rc, err := l.Uncompressed()
+	if err != nil {
+		return nil, fmt.Errorf("reading layer contents: %w", err)
+	}
+	defer rc.Close()
+
+	hidden := make(map[string]bool)
+	tr := tar.NewReader(rc)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return hidden, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tar: %w", err)
+		}
+		name := path.Clean("/" + strings.TrimPrefix(hdr.Name, "/"))
+		base := path.Base(name)
+		if base == ".wh..wh..opq" {
+			if dir := path.Dir(name); dir == "/" {
+				hidden["/"] = true
+			} else {
+				hidden[dir+"/"] = true
+			}
+			continue
+		}
+		if strings.HasPrefix(base, ".wh.") {
+			hidden[path.Join(path.Dir(name), strings.TrimPrefix(base, ".wh."))] = true
+			continue
+		}
+		if name != src && !strings.HasPrefix(name, src+"/") {
+			continue
+		}
+		if seen[name] || isWhiteoutBlocked(name, whiteouts) {
+			continue
+		}
+		seen[name] = true
+		target := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, src)))
+		switch mode := hdr.FileInfo().Mode(); {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return nil, err
+			}
+		case mode.IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return nil, err
+			}
+			if err := writeFile(target, tr, mode.Perm()|0200); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// writeFile writes r to a new file at dest with mode perm.
+func writeFile(dest string, r io.Reader, perm os.FileMode) error {
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	return writeAndClose(f, r)
+}
+
 // isWhiteoutBlocked reports whether target (or any ancestor dir marked opaque)
 // has been whited out by a newer layer.
 func isWhiteoutBlocked(target string, whiteouts map[string]bool) bool {
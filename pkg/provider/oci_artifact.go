@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// OCI artifacts (as pushed by ORAS or `b publish`) carry files as layers
+// instead of an image filesystem: each layer is one file, named by the
+// title annotation, and either the index entry pointing at the manifest
+// or the layer descriptor itself names the platform.
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+// artifactManifest is the part of a manifest telling an artifact from an
+// image; v1.Manifest has no artifactType.
+type artifactManifest struct {
+	ArtifactType string `json:"artifactType,omitempty"`
+	Config       struct {
+		MediaType types.MediaType `json:"mediaType"`
+	} `json:"config"`
+}
+
+// artifact reports whether m is an artifact's: one with an artifactType,
+// or with a config that isn't an image's.
+func (m artifactManifest) artifact() bool {
+	return m.ArtifactType != "" || m.Config.MediaType != "" && !m.Config.MediaType.IsConfig()
+}
+
+// isArtifact reports whether img is an OCI artifact rather than an image.
+func isArtifact(img v1.Image) (bool, error) {
+	raw, err := img.RawManifest()
+	if err != nil {
+		return false, err
+	}
+	var m artifactManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return false, err
+	}
+	return m.artifact(), nil
+}
+
+// artifactLayer picks the layer of an artifact holding binName for target:
+// the one whose descriptor names the platform, else the one whose title
+// names it the way release assets do (tool-linux-amd64), else the only
+// layer there is. Archives are never picked; the blob is the binary.
+func artifactLayer(layers []v1.Descriptor, binName string, target Platform) (v1.Descriptor, error) {
+	want := target.image()
+	platformed := false
+	var assets []Asset
+	byTitle := make(map[string]v1.Descriptor)
+	for _, l := range layers {
+		if l.Platform != nil {
+			platformed = true
+			if l.Platform.Satisfies(want) {
+				return l, nil
+			}
+			continue
+		}
+		title := l.Annotations[ociTitleAnnotation]
+		if title == "" || DetectArchiveType(title) != "" {
+			continue
+		}
+		assets = append(assets, Asset{Name: title, Size: l.Size})
+		byTitle[title] = l
+	}
+	if c := MatchAssetsFor(target, assets, strings.TrimSuffix(binName, ".exe"), ""); len(c) > 0 {
+		return byTitle[c[0].Asset.Name], nil
+	}
+	if len(layers) == 1 && !platformed {
+		return layers[0], nil
+	}
+	return v1.Descriptor{}, fmt.Errorf("no layer for %s among %d", target, len(layers))
+}
+
+// installArtifact writes the blob of img's layer for target to dest as
+// is, through the blob cache like image layers.
+func installArtifact(repo name.Repository, img v1.Image, binName, dest string, target Platform) error {
+	m, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+	desc, err := artifactLayer(m.Layers, binName, target)
+	if err != nil {
+		return err
+	}
+	l, err := img.LayerByDigest(desc.Digest)
+	if err != nil {
+		return err
+	}
+	if blob, ok := cachedBlob(repo, l); ok {
+		f, err := os.Open(blob)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return WriteExecutable(dest, f)
+	}
+	rc, err := l.Compressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return WriteExecutable(dest, rc)
+}
+
+// artifactDigest returns the digest of the layer installed for target when
+// desc, as fetched from the registry, is an artifact or an index of them;
+// "" for images.
+func artifactDigest(desc *remote.Descriptor, binName string, target Platform) (string, error) {
+	if desc.MediaType.IsIndex() {
+		index, err := desc.ImageIndex()
+		if err != nil {
+			return "", err
+		}
+		im, err := index.IndexManifest()
+		if err != nil {
+			return "", err
+		}
+		// Only look further for artifacts, saving images the round trip.
+		child := indexChild(im, target)
+		if child == nil || child.ArtifactType == "" {
+			return "", nil
+		}
+		img, err := index.Image(child.Digest)
+		if err != nil {
+			return "", err
+		}
+		return imageArtifactDigest(img, binName, target)
+	}
+	if !desc.MediaType.IsImage() {
+		return "", nil
+	}
+	var m artifactManifest
+	if err := json.Unmarshal(desc.Manifest, &m); err != nil || !m.artifact() {
+		return "", err
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return "", err
+	}
+	return imageArtifactDigest(img, binName, target)
+}
+
+// imageArtifactDigest returns the digest of the layer of the artifact img
+// installed for target.
+func imageArtifactDigest(img v1.Image, binName string, target Platform) (string, error) {
+	m, err := img.Manifest()
+	if err != nil {
+		return "", err
+	}
+	l, err := artifactLayer(m.Layers, target.Exe(binName), target)
+	if err != nil {
+		return "", err
+	}
+	return l.Digest.String(), nil
+}
+
+// indexChild returns the entry of im for target.
+func indexChild(im *v1.IndexManifest, target Platform) *v1.Descriptor {
+	want := target.image()
+	for i, d := range im.Manifests {
+		if d.Platform != nil && d.Platform.Satisfies(want) {
+			return &im.Manifests[i]
+		}
+	}
+	return nil
+}
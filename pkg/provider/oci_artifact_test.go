@@ -0,0 +1,235 @@
+package provider
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+const testArtifactType = "application/vnd.example.tool.v1"
+
+// artifactImage is an artifact manifest with one layer per file, titled
+// by its name.
+func artifactImage(t *testing.T, files map[string]string) v1.Image {
+	t.Helper()
+	img := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, testArtifactType)
+	for title, content := range files {
+		var err error
+		img, err = mutate.Append(img, mutate.Addendum{
+			Layer:       static.NewLayer([]byte(content), "application/octet-stream"),
+			Annotations: map[string]string{ociTitleAnnotation: title},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return img
+}
+
+// serveRegistry starts an in-process registry and returns its host.
+func serveRegistry(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func pushArtifact(t *testing.T, ref string, img v1.Image) {
+	t.Helper()
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(r, img); err != nil {
+		t.Fatalf("pushing %s: %v", ref, err)
+	}
+}
+
+func TestOCI_InstallArtifact_Titles(t *testing.T) {
+	host := HostPlatform()
+	repo := serveRegistry(t) + "/org/tool"
+	pushArtifact(t, repo+":v1", artifactImage(t, map[string]string{
+		"tool-linux-amd64":                  "linux-amd64",
+		"tool-darwin-arm64":                 "darwin-arm64",
+		"tool-windows-amd64.exe":            "windows-amd64",
+		"tool-linux-amd64.tar.gz":           "archive",
+		"tool-" + host.OS + "-" + host.Arch: "host",
+	}))
+
+	for _, tt := range []struct {
+		target Platform
+		want   string
+	}{
+		{target: Platform{OS: "darwin", Arch: "arm64"}, want: "darwin-arm64"},
+		{target: Platform{OS: "windows", Arch: "amd64"}, want: "windows-amd64"},
+		{target: Platform{OS: "linux", Arch: "riscv64"}},
+	} {
+		dest := t.TempDir()
+		path, err := (&OCI{}).Install("oci://"+repo+"@v1", "", dest, tt.target, nil)
+		if tt.want == "" {
+			if err == nil || !strings.Contains(err.Error(), "no layer for linux/riscv64") {
+				t.Errorf("%s: Install() error = %v, want no layer", tt.target, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Install: %v", tt.target, err)
+		}
+		if path != filepath.Join(dest, tt.target.Exe("tool")) {
+			t.Errorf("%s: path = %q", tt.target, path)
+		}
+		if data, _ := os.ReadFile(path); string(data) != tt.want {
+			t.Errorf("%s: content = %q, want %q", tt.target, data, tt.want)
+		}
+	}
+
+	digest, err := (&OCI{}).ResolveDigest("oci://"+repo+"@v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := static.NewLayer([]byte("host"), "application/octet-stream"); digest != mustDigest(t, want) {
+		t.Errorf("ResolveDigest = %q, want the host layer's digest", digest)
+	}
+}
+
+func TestOCI_InstallArtifact_Index(t *testing.T) {
+	host := HostPlatform()
+	repo := serveRegistry(t) + "/org/tool"
+	var adds []mutate.IndexAddendum
+	for _, p := range []Platform{{OS: "darwin", Arch: "arm64"}, host} {
+		img := artifactImage(t, map[string]string{"tool": p.String()})
+		pushArtifact(t, repo+"@"+mustDigest(t, img), img)
+		adds = append(adds, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: p.OS, Architecture: p.Arch}}})
+	}
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), adds...)
+	r, _ := name.ParseReference(repo + ":v1")
+	if err := remote.WriteIndex(r, index); err != nil {
+		t.Fatalf("pushing index: %v", err)
+	}
+
+	dest := t.TempDir()
+	path, err := (&OCI{}).Install("oci://"+repo+"@v1", "", dest, Platform{OS: "darwin", Arch: "arm64"}, nil)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "darwin/arm64" {
+		t.Errorf("content = %q", data)
+	}
+
+	digest, err := (&OCI{}).ResolveDigest("oci://"+repo+"@v1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := static.NewLayer([]byte(host.String()), "application/octet-stream"); digest != mustDigest(t, want) {
+		t.Errorf("ResolveDigest = %q, want the host layer's digest", digest)
+	}
+}
+
+func TestOCI_ResolveDigest_Image(t *testing.T) {
+	repo, digest := pushTestImage(t, "tool", []byte("tool-binary"))
+	got, err := (&OCI{}).ResolveDigest("oci://"+repo+"@v1", "")
+	if err != nil || got != digest.String() {
+		t.Errorf("ResolveDigest = %q, %v; want the manifest digest %s", got, err, digest)
+	}
+}
+
+func TestArtifactLayer(t *testing.T) {
+	layer := func(title string, p *v1.Platform) v1.Descriptor {
+		return v1.Descriptor{Annotations: map[string]string{ociTitleAnnotation: title}, Platform: p}
+	}
+	linux := Platform{OS: "linux", Arch: "amd64"}
+	tests := []struct {
+		name    string
+		layers  []v1.Descriptor
+		want    string
+		wantErr bool
+	}{
+		{name: "descriptor platform", layers: []v1.Descriptor{
+			layer("a", &v1.Platform{OS: "darwin", Architecture: "arm64"}),
+			layer("b", &v1.Platform{OS: "linux", Architecture: "amd64"}),
+		}, want: "b"},
+		{name: "title", layers: []v1.Descriptor{layer("tool_Linux_x86_64", nil), layer("tool_Darwin_arm64", nil)}, want: "tool_Linux_x86_64"},
+		{name: "single layer", layers: []v1.Descriptor{layer("", nil)}, want: ""},
+		{name: "other platform only", layers: []v1.Descriptor{layer("a", &v1.Platform{OS: "darwin", Architecture: "arm64"})}, wantErr: true},
+		{name: "archives only", layers: []v1.Descriptor{layer("tool-linux-amd64.tar.gz", nil), layer("tool-linux-amd64.zip", nil)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := artifactLayer(tt.layers, "tool", linux)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("artifactLayer() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got.Annotations[ociTitleAnnotation] != tt.want {
+				t.Errorf("artifactLayer() = %+v, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func mustDigest(t *testing.T, d interface{ Digest() (v1.Hash, error) }) string {
+	t.Helper()
+	h, err := d.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h.String()
+}
+
+func TestOCI_Publish(t *testing.T) {
+	repo := serveRegistry(t) + "/org/tool"
+	dist := t.TempDir()
+	file := filepath.Join(dist, "tool-linux-arm64")
+	if err := os.WriteFile(file, []byte("linux-arm64"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	linux := Platform{OS: "linux", Arch: "arm64"}
+	if _, err := (&OCI{}).Publish("oci://"+repo, "v1", []ArtifactFile{{Platform: linux, Path: file}, {Platform: linux, Path: file}}); err == nil ||
+		!strings.Contains(err.Error(), "more than one file for linux/arm64") {
+		t.Errorf("Publish() error = %v, want the duplicate platform", err)
+	}
+	digest, err := (&OCI{}).Publish("oci://"+repo, "v1", []ArtifactFile{{Platform: linux, Path: file}})
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	ref, _ := name.ParseReference(repo + ":v1")
+	desc, err := remote.Get(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.Digest.String() != digest || desc.MediaType != types.OCIImageIndex {
+		t.Errorf("pushed %s %s, want the index %s", desc.MediaType, desc.Digest, digest)
+	}
+	index, _ := desc.ImageIndex()
+	im, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(im.Manifests) != 1 || im.Manifests[0].ArtifactType != ArtifactType || im.Manifests[0].Platform.OS != "linux" {
+		t.Errorf("index = %+v", im.Manifests)
+	}
+
+	dest := t.TempDir()
+	path, err := (&OCI{}).Install("oci://"+repo+"@v1", "", dest, linux, nil)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "linux-arm64" {
+		t.Errorf("content = %q", data)
+	}
+}
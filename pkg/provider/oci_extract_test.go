@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/fentas/b/pkg/blobcache"
+	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/google/go-containerregistry/pkg/v1/types"
@@ -249,3 +251,52 @@ func TestExtractBinaryFromLayer_OpaqueDirBlocksDescendant(t *testing.T) {
 		t.Error("opaque-dir whiteout should block extraction of descendants")
 	}
 }
+
+// countingLayer counts how often the layer's content is fetched.
+type countingLayer struct {
+	v1.Layer
+	fetches int
+}
+
+func (l *countingLayer) Compressed() (io.ReadCloser, error) {
+	l.fetches++
+	return l.Layer.Compressed()
+}
+
+func (l *countingLayer) Uncompressed() (io.ReadCloser, error) {
+	l.fetches++
+	return l.Layer.Uncompressed()
+}
+
+func TestCachedLayer(t *testing.T) {
+	blobcache.SetRoot(t.TempDir())
+	t.Cleanup(func() { blobcache.SetRoot("") })
+
+	repo, err := name.NewRepository("ghcr.io/org/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer := &countingLayer{Layer: fakeLayer(t,
+		[]tar.Header{{Name: "usr/bin/tool", Typeflag: tar.TypeReg, Mode: 0755}},
+		map[string][]byte{"usr/bin/tool": []byte("tool")},
+	)}
+
+	for i := range 2 {
+		dest := filepath.Join(t.TempDir(), "tool")
+		found, err := extractBinaryFromLayer(cachedLayer(repo, layer), []string{"/usr/bin/tool"}, dest, map[string]bool{})
+		if err != nil || !found {
+			t.Fatalf("pass %d: found = %v, err = %v", i, found, err)
+		}
+		if body, _ := os.ReadFile(dest); string(body) != "tool" {
+			t.Errorf("pass %d: extracted %q, want %q", i, body, "tool")
+		}
+	}
+	if layer.fetches != 1 {
+		t.Errorf("layer fetched %d times, want once", layer.fetches)
+	}
+
+	blobcache.SetRoot("")
+	if got := cachedLayer(repo, layer); got != v1.Layer(layer) {
+		t.Error("cachedLayer wrapped the layer with the cache off")
+	}
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// ArtifactType is the artifact type of binaries `b publish` pushes,
+// carried as the config media type of their manifests.
+const ArtifactType = "application/vnd.fentas.b.binary.v1"
+
+// ArtifactFile is the binary published for one platform.
+type ArtifactFile struct {
+	Platform Platform
+	Path     string
+}
+
+// Publish pushes files to the registry as an OCI artifact tagged version
+// (or the ref's tag): an index with one artifact manifest per platform,
+// each holding its file as the only layer, titled by the file's name.
+// Credentials come from the docker config like for installs. Returns
+// the index digest.
+func (o *OCI) Publish(ref, version string, files []ArtifactFile) (string, error) {
+	image, tag, inContainerPath := ParseImageRef(strings.TrimPrefix(ref, "oci://"))
+	if inContainerPath != "" {
+		return "", fmt.Errorf("%s: artifacts have no paths inside them", ref)
+	}
+	if version != "" {
+		tag = version
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	nameRef, err := imageReference(image, tag)
+	if err != nil {
+		return "", err
+	}
+
+	var adds []mutate.IndexAddendum
+	seen := make(map[Platform]bool, len(files))
+	for _, f := range files {
+		p := f.Platform.Resolve()
+		if seen[p] {
+			return "", fmt.Errorf("more than one file for %s", p)
+		}
+		seen[p] = true
+		img, err := artifactFileImage(f.Path)
+		if err != nil {
+			return "", err
+		}
+		adds = append(adds, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: p.OS, Architecture: p.Arch}},
+		})
+	}
+	if len(adds) == 0 {
+		return "", fmt.Errorf("nothing to publish")
+	}
+	index := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), adds...)
+	if err := remote.WriteIndex(nameRef, index,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(httpclient.Transport()),
+		remote.WithRetryStatusCodes(),
+	); err != nil {
+		return "", fmt.Errorf("pushing %s: %w", nameRef, err)
+	}
+	digest, err := index.Digest()
+	if err != nil {
+		return "", err
+	}
+	return digest.String(), nil
+}
+
+// artifactFileImage is the artifact manifest holding the file at path.
+func artifactFileImage(path string) (v1.Image, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), ArtifactType)
+	return mutate.Append(img, mutate.Addendum{
+		Layer:       static.NewLayer(data, "application/octet-stream"),
+		Annotations: map[string]string{ociTitleAnnotation: filepath.Base(path)},
+	})
+}
@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"archive/tar"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// pushTestImage serves an in-process registry and pushes a single-layer
+// image holding /usr/local/bin/<bin> to <host>/tools/<bin>:v1.
+func pushTestImage(t *testing.T, bin string, content []byte) (string, v1.Hash) {
+	t.Helper()
+	srv := httptest.NewServer(ggcrregistry.New())
+	t.Cleanup(srv.Close)
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	layer := fakeLayer(t,
+		[]tar.Header{{Name: "usr/local/bin/" + bin, Typeflag: tar.TypeReg, Mode: 0755}},
+		map[string][]byte{"usr/local/bin/" + bin: content})
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(host + "/tools/" + bin + ":v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("pushing image: %v", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host + "/tools/" + bin, digest
+}
+
+func TestOCI_CosignSignatures(t *testing.T) {
+	repo, digest := pushTestImage(t, "tool", []byte("tool-binary"))
+	o := &OCI{}
+
+	// Unsigned image: no signatures, no error.
+	got, sigs, err := o.CosignSignatures("oci://"+repo+"@v1", "", Platform{})
+	if err != nil || got != "" || sigs != nil {
+		t.Fatalf("unsigned image = (%q, %v, %v), want empty", got, sigs, err)
+	}
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"image":{"docker-manifest-digest":%q}}}`, digest))
+	sigImg, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(payload, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+		Annotations: map[string]string{
+			cosignSignatureAnnotation:   "c2lnbmF0dXJl",
+			cosignCertificateAnnotation: "-----BEGIN CERTIFICATE-----",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigRef, err := name.ParseReference(fmt.Sprintf("%s:sha256-%s.sig", repo, digest.Hex))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(sigRef, sigImg); err != nil {
+		t.Fatalf("pushing signature: %v", err)
+	}
+
+	got, sigs, err = o.CosignSignatures("oci://"+repo, "v1", Platform{})
+	if err != nil {
+		t.Fatalf("CosignSignatures: %v", err)
+	}
+	if got != digest.String() {
+		t.Errorf("digest = %q, want %q", got, digest)
+	}
+	if len(sigs) != 1 || string(sigs[0].Payload) != string(payload) ||
+		sigs[0].Signature != "c2lnbmF0dXJl" || string(sigs[0].Certificate) != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("signatures = %+v", sigs)
+	}
+}
+
+func TestOCI_InstallByDigest(t *testing.T) {
+	repo, digest := pushTestImage(t, "tool", []byte("tool-binary"))
+	dest := t.TempDir()
+	path, err := (&OCI{}).Install("oci://"+repo, digest.String(), dest, Platform{}, nil)
+	if err != nil {
+		t.Fatalf("Install by digest: %v", err)
+	}
+	if path != filepath.Join(dest, "tool") {
+		t.Errorf("path = %q", path)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "tool-binary" {
+		t.Errorf("content = %q", data)
+	}
+}
@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Platform is the OS and architecture, in GOOS/GOARCH terms, binaries are
+// installed for. The zero value, and either field left empty, mean the
+// host's.
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// Known GOOS and GOARCH values; anything else is most likely a typo.
+var (
+	knownOS = []string{
+		"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios",
+		"js", "linux", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows",
+	}
+	knownArch = []string{
+		"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le",
+		"mipsle", "ppc64", "ppc64le", "riscv64", "s390x", "wasm",
+	}
+)
+
+// HostPlatform is the platform b runs on.
+func HostPlatform() Platform {
+	return Platform{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParsePlatform parses "os/arch", e.g. "darwin/arm64", or a bare "os"
+// for the host's architecture.
+func ParsePlatform(s string) (Platform, error) {
+	goos, goarch, _ := strings.Cut(s, "/")
+	p := Platform{OS: goos, Arch: goarch}
+	if err := p.Validate(); err != nil {
+		return Platform{}, err
+	}
+	return p, nil
+}
+
+// Validate reports an OS or architecture Go doesn't know.
+func (p Platform) Validate() error {
+	if p.OS != "" && !slices.Contains(knownOS, p.OS) {
+		return fmt.Errorf("unknown os %q (want one of %s)", p.OS, strings.Join(knownOS, ", "))
+	}
+	if p.Arch != "" && !slices.Contains(knownArch, p.Arch) {
+		return fmt.Errorf("unknown arch %q (want one of %s)", p.Arch, strings.Join(knownArch, ", "))
+	}
+	return nil
+}
+
+// Resolve fills in what p leaves empty from the host.
+func (p Platform) Resolve() Platform {
+	host := HostPlatform()
+	if p.OS == "" {
+		p.OS = host.OS
+	}
+	if p.Arch == "" {
+		p.Arch = host.Arch
+	}
+	return p
+}
+
+// IsHost reports whether p is the platform b runs on.
+func (p Platform) IsHost() bool {
+	return p.Resolve() == HostPlatform()
+}
+
+// String returns "os/arch", with the host's values filled in.
+func (p Platform) String() string {
+	p = p.Resolve()
+	return p.OS + "/" + p.Arch
+}
+
+// Exe returns name as an executable's file name on p: with ".exe" on
+// Windows.
+func (p Platform) Exe(name string) string {
+	if p.Resolve().OS == "windows" && !strings.HasSuffix(strings.ToLower(name), ".exe") {
+		return name + ".exe"
+	}
+	return name
+}
+
+// image is p as an OCI image platform, selecting the manifest of an
+// image index.
+func (p Platform) image() v1.Platform {
+	p = p.Resolve()
+	return v1.Platform{OS: p.OS, Architecture: p.Arch}
+}
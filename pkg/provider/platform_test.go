@@ -0,0 +1,56 @@
+package provider
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Platform
+		wantErr string
+	}{
+		{in: "darwin/arm64", want: Platform{OS: "darwin", Arch: "arm64"}},
+		{in: "windows", want: Platform{OS: "windows"}},
+		{in: "macos/arm64", wantErr: `unknown os "macos"`},
+		{in: "linux/x86_64", wantErr: `unknown arch "x86_64"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePlatform(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParsePlatform() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParsePlatform() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	if !(Platform{}).IsHost() || (Platform{}).String() != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("zero Platform = %s, want the host", Platform{})
+	}
+	if got := (Platform{OS: "plan9"}).String(); got != "plan9/"+runtime.GOARCH {
+		t.Errorf("String() = %q, want the host arch filled in", got)
+	}
+	if (Platform{OS: "plan9"}).IsHost() {
+		t.Error("plan9 is not the host")
+	}
+
+	windows := Platform{OS: "windows", Arch: "amd64"}
+	for in, want := range map[string]string{"tool": "tool.exe", "tool.exe": "tool.exe", "TOOL.EXE": "TOOL.EXE"} {
+		if got := windows.Exe(in); got != want {
+			t.Errorf("Exe(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := (Platform{OS: "darwin"}).Exe("tool"); got != "tool" {
+		t.Errorf("Exe() on darwin = %q", got)
+	}
+}
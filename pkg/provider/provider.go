@@ -35,6 +35,40 @@ type DigestResolver interface {
 	ResolveDigest(ref, version string) (string, error)
 }
 
+// ReleaseLister is an optional interface for providers that can enumerate
+// the versions published for a ref: forge releases, image tags, git tags or
+// module proxy versions. It backs `b versions` and resolves version
+// constraints ("~1.7", ">=1.28 <1.31") to a concrete tag.
+type ReleaseLister interface {
+	// ListReleases returns one page of releases, newest first.
+	ListReleases(ref string, opts ListOptions) (*ReleasePage, error)
+}
+
+// ListOptions selects a page of releases.
+type ListOptions struct {
+	// Page is 1-based; 0 means the first page.
+	Page int
+	// PerPage is the page size; 0 means DefaultPerPage. Providers may
+	// cap it to what their API allows.
+	PerPage int
+}
+
+// ReleasePage is one page of a release listing.
+type ReleasePage struct {
+	Releases []ReleaseInfo
+	// Next is the number of the following page, 0 on the last one.
+	Next int
+}
+
+// ReleaseInfo describes one published version.
+type ReleaseInfo struct {
+	Version    string `json:"version"`
+	Prerelease bool   `json:"prerelease,omitempty" yaml:"prerelease,omitempty"`
+	Draft      bool   `json:"draft,omitempty" yaml:"draft,omitempty"`
+	// Published is the release date (RFC 3339) when the source records one.
+	Published string `json:"published,omitempty" yaml:"published,omitempty"`
+}
+
 // Release holds metadata about a release from any provider.
 type Release struct {
 	Version string
@@ -153,13 +187,14 @@ func ParseImageRef(ref string) (image, tag, inContainerPath string) {
 }
 
 // IsReleaseProvider returns true if the provider uses FetchRelease for downloads
-// (i.e. GitHub, GitLab, Gitea). Returns false for go://, docker://, oci://, git://.
+// (i.e. GitHub, GitLab, Gitea). Returns false for go://, docker://, oci://, git://
+// and templated http(s):// URLs.
 func IsReleaseProvider(p Provider) bool {
 	if p == nil {
 		return false
 	}
 	switch p.(type) {
-	case *GoInstall, *Docker, *OCI, *Git:
+	case *GoInstall, *Docker, *OCI, *Git, *HTTP:
 		return false
 	default:
 		return true
@@ -184,7 +219,12 @@ func IsProviderRef(s string) bool {
 //	"go://github.com/jrhouston/tfk8s" → "tfk8s",
 //	"docker://hashicorp/terraform" → "terraform"
 //	"docker://docker@cli:/usr/local/bin/docker" → "docker"
+//	"https://dl.example.com/{{.Version}}/tool_{{.OS}}.tar.gz" → "tool"
 func BinaryName(ref string) string {
+	// Templated http(s):// URLs name the binary in their last literal text.
+	if (&HTTP{}).Match(ref) {
+		return httpTemplateName(ref)
+	}
 	// git:// refs use the filepath part (after :) as the binary name
 	if strings.HasPrefix(ref, "git://") {
 		r := strings.TrimPrefix(ref, "git://")
@@ -187,13 +187,14 @@ func TestGoInstallName(t *testing.T) {
 }
 
 func TestGoInstallLatestVersion(t *testing.T) {
+	t.Setenv("GOPROXY", fileGoProxy(t, map[string]string{"github.com/org/tool/@v/list": "v1.0.0\nv1.1.0\n"}))
 	g := &GoInstall{}
 	v, err := g.LatestVersion("go://github.com/org/tool")
 	if err != nil {
 		t.Fatalf("LatestVersion() error = %v", err)
 	}
-	if v != "latest" {
-		t.Errorf("LatestVersion() = %q, want %q", v, "latest")
+	if v != "v1.1.0" {
+		t.Errorf("LatestVersion() = %q, want %q", v, "v1.1.0")
 	}
 }
 
@@ -271,7 +272,14 @@ func TestIsArchive(t *testing.T) {
 		{"tool.tar.xz", true},
 		{"tool.txz", true},
 		{"tool.tar.bz2", true},
+		{"tool.tbz2", true},
+		{"tool.tar.zst", true},
+		{"tool.tzst", true},
 		{"tool.zip", true},
+		{"tool-linux-amd64.gz", true},
+		{"tool-linux-amd64.xz", true},
+		{"tool-linux-amd64.bz2", true},
+		{"tool-linux-amd64.zst", true},
 		{"tool.exe", false},
 		{"tool", false},
 		{"tool.sha256", false},
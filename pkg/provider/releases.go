@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/fentas/b/pkg/semver"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// DefaultPerPage is the page size when ListOptions.PerPage is unset.
+const DefaultPerPage = 30
+
+// maxListPages caps how many pages ListAll reads, so a repository with
+// thousands of releases can't stall a resolve.
+const maxListPages = 10
+
+// listTimeout bounds listing the tags of an image repository.
+const listTimeout = 30 * time.Second
+
+// ListAll reads every page of a listing, up to 10 pages of 100 releases.
+func ListAll(l ReleaseLister, ref string) ([]ReleaseInfo, error) {
+	var all []ReleaseInfo
+	opts := ListOptions{Page: 1, PerPage: 100}
+	for i := 0; i < maxListPages; i++ {
+		page, err := l.ListReleases(ref, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Releases...)
+		if page.Next == 0 {
+			break
+		}
+		opts.Page = page.Next
+	}
+	return all, nil
+}
+
+// pageBounds returns the 1-based page and page size of opts, with the
+// size capped to limit.
+func pageBounds(opts ListOptions, limit int) (page, perPage int) {
+	page, perPage = opts.Page, opts.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+	if perPage > limit {
+		perPage = limit
+	}
+	return page, perPage
+}
+
+// forgeReleasePage fetches one page of a GitHub/GitLab/Gitea release list.
+// The three APIs share tag_name; GitLab has no draft or prerelease flag, so
+// its prereleases are told by the tag and its upcoming releases.
+func forgeReleasePage(h *hosts.Host, forge, url string, page, perPage int) (*ReleasePage, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setAuth(req, h)
+
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s API error %d: %s", forge, resp.StatusCode, string(body))
+	}
+
+	var releases []struct {
+		TagName     string `json:"tag_name"`
+		Draft       bool   `json:"draft"`
+		Prerelease  bool   `json:"prerelease"`
+		Upcoming    bool   `json:"upcoming_release"`
+		PublishedAt string `json:"published_at"`
+		ReleasedAt  string `json:"released_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("decoding %s releases: %w", forge, err)
+	}
+
+	out := &ReleasePage{}
+	for _, r := range releases {
+		if r.TagName == "" {
+			continue
+		}
+		published := r.PublishedAt
+		if published == "" {
+			published = r.ReleasedAt
+		}
+		out.Releases = append(out.Releases, ReleaseInfo{
+			Version:    r.TagName,
+			Prerelease: r.Prerelease || r.Upcoming || isPrerelease(r.TagName),
+			Draft:      r.Draft,
+			Published:  published,
+		})
+	}
+	if len(releases) == perPage {
+		out.Next = page + 1
+	}
+	return out, nil
+}
+
+// tagPage sorts plain tags newest first and returns the requested page.
+// Sources without release metadata (image tags, git tags, module versions)
+// list everything at once.
+func tagPage(tags []string, opts ListOptions) *ReleasePage {
+	tags = slices.Clone(tags)
+	sortNewestFirst(tags)
+	page, perPage := pageBounds(opts, len(tags)+1)
+	start := (page - 1) * perPage
+	out := &ReleasePage{}
+	if start >= len(tags) {
+		return out
+	}
+	end := min(start+perPage, len(tags))
+	for _, tag := range tags[start:end] {
+		out.Releases = append(out.Releases, ReleaseInfo{Version: tag, Prerelease: isPrerelease(tag)})
+	}
+	if end < len(tags) {
+		out.Next = page + 1
+	}
+	return out
+}
+
+// sortNewestFirst orders versions by descending semver precedence, with
+// tags that aren't versions ("latest", "edge") last in name order.
+func sortNewestFirst(tags []string) {
+	slices.SortStableFunc(tags, func(a, b string) int {
+		va, okA := semver.Parse(a)
+		vb, okB := semver.Parse(b)
+		switch {
+		case okA && okB:
+			if c := semver.Compare(vb, va); c != 0 {
+				return c
+			}
+			return strings.Compare(a, b)
+		case okA:
+			return -1
+		case okB:
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+}
+
+func isPrerelease(tag string) bool {
+	v, ok := semver.Parse(tag)
+	return ok && v.Pre != ""
+}
+
+// registryTags lists the tags of an image repository, with the user's
+// docker-config auth.
+func registryTags(image string) ([]string, error) {
+	repo, err := name.NewRepository(image)
+	if err != nil {
+		return nil, fmt.Errorf("parsing image %s: %w", image, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+	defer cancel()
+	tags, err := remote.List(repo,
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithTransport(httpclient.Transport()),
+		remote.WithRetryStatusCodes(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w", image, err)
+	}
+	return tags, nil
+}
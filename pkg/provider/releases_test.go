@@ -0,0 +1,268 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestReleaseLister_Implemented(t *testing.T) {
+	for _, p := range []Provider{&GitHub{}, &GitLab{}, &Gitea{}, &OCI{}, &Docker{}, &Git{}, &GoInstall{}} {
+		if _, ok := p.(ReleaseLister); !ok {
+			t.Errorf("%s does not implement ReleaseLister", p.Name())
+		}
+	}
+	if _, ok := Provider(&HTTP{}).(ReleaseLister); ok {
+		t.Error("http templates have no release list")
+	}
+}
+
+// releasePages serves n releases named v1.0.<i>, newest first, paged by
+// the query parameter sizeParam. v1.0.3 is a draft and v1.0.2 is flagged as
+// a prerelease.
+func releasePages(t *testing.T, n int, sizeParam string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get(sizeParam))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if size == 0 || page == 0 {
+			t.Errorf("unpaged request %s", r.URL)
+		}
+		var out []map[string]any
+		for i := (page - 1) * size; i < page*size && i < n; i++ {
+			v := n - 1 - i
+			out = append(out, map[string]any{
+				"tag_name":     fmt.Sprintf("v1.0.%d", v),
+				"draft":        v == 3,
+				"prerelease":   v == 2,
+				"published_at": "2024-01-02T03:04:05Z",
+			})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+func TestGitHub_ListReleases(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/tool/releases", releasePages(t, 150, "per_page"))
+	withFakeAPI(t, mux)
+	g := &GitHub{}
+
+	page, err := g.ListReleases("github.com/org/tool@v1.0.0", ListOptions{PerPage: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Releases) != 5 || page.Next != 2 || page.Releases[0].Version != "v1.0.149" {
+		t.Errorf("first page = %+v", page)
+	}
+	if page.Releases[0].Published != "2024-01-02T03:04:05Z" {
+		t.Errorf("published = %q", page.Releases[0].Published)
+	}
+
+	page, err = g.ListReleases("org/tool", ListOptions{Page: 30, PerPage: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReleaseInfo{
+		{Version: "v1.0.4", Published: "2024-01-02T03:04:05Z"},
+		{Version: "v1.0.3", Draft: true, Published: "2024-01-02T03:04:05Z"},
+		{Version: "v1.0.2", Prerelease: true, Published: "2024-01-02T03:04:05Z"},
+		{Version: "v1.0.1", Published: "2024-01-02T03:04:05Z"},
+		{Version: "v1.0.0", Published: "2024-01-02T03:04:05Z"},
+	}
+	if !slices.Equal(page.Releases, want) || page.Next != 31 {
+		t.Errorf("last page = %+v", page)
+	}
+
+	all, err := ListAll(g, "org/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 150 {
+		t.Errorf("ListAll = %d releases, want 150", len(all))
+	}
+}
+
+func TestGitHub_ListReleases_Error(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/org/tool/releases", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	withFakeAPI(t, mux)
+	noRetries := 0
+	if err := httpclient.Set(&httpclient.Config{Retries: &noRetries}, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = httpclient.Set(nil, "") })
+
+	if _, err := ListAll(&GitHub{}, "org/tool"); err == nil || !strings.Contains(err.Error(), "GitHub API error 500") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestGitLab_ListReleases(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/projects/group%2Fsub%2Ftool/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "100" {
+			t.Errorf("per_page = %q, want the API cap", r.URL.Query().Get("per_page"))
+		}
+		_, _ = w.Write([]byte(`[
+			{"tag_name":"v2.0.0","upcoming_release":true,"released_at":"2030-01-01T00:00:00Z"},
+			{"tag_name":"v1.1.0-rc.1","released_at":"2024-02-01T00:00:00Z"},
+			{"tag_name":"v1.0.0","released_at":"2024-01-01T00:00:00Z"}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	withHosts(t, map[string]*hosts.Host{
+		"gitlab.example.corp": {Type: hosts.GitLab, API: srv.URL + "/api/v4"},
+	})
+
+	page, err := (&GitLab{}).ListReleases("gitlab.example.corp/group/sub/tool", ListOptions{PerPage: 500})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReleaseInfo{
+		{Version: "v2.0.0", Prerelease: true, Published: "2030-01-01T00:00:00Z"},
+		{Version: "v1.1.0-rc.1", Prerelease: true, Published: "2024-02-01T00:00:00Z"},
+		{Version: "v1.0.0", Published: "2024-01-01T00:00:00Z"},
+	}
+	if !slices.Equal(page.Releases, want) || page.Next != 0 {
+		t.Errorf("page = %+v", page)
+	}
+}
+
+func TestGitea_ListReleases(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/repos/org/tool/releases", releasePages(t, 50, "limit"))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	withHosts(t, map[string]*hosts.Host{
+		"gitea.example.corp": {Type: hosts.Gitea, API: srv.URL + "/api/v1"},
+	})
+
+	// Exactly one full page of 50: the second, empty page ends ListAll.
+	all, err := ListAll(&Gitea{}, "gitea.example.corp/org/tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 50 {
+		t.Errorf("got %d releases, want 50", len(all))
+	}
+}
+
+func TestOCI_ListReleases(t *testing.T) {
+	repo, _ := pushTestImage(t, "tool", []byte("tool-binary"))
+	for _, tag := range []string{"v1.1", "v2.0-rc.1", "latest"} {
+		ref, err := name.ParseReference(repo + ":" + tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := remote.Write(ref, empty.Image); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := (&OCI{}).ListReleases("oci://"+repo+"@v1:/usr/local/bin/tool", ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReleaseInfo{
+		{Version: "v2.0-rc.1", Prerelease: true},
+		{Version: "v1.1"},
+		{Version: "v1"},
+		{Version: "latest"},
+	}
+	if !slices.Equal(page.Releases, want) {
+		t.Errorf("releases = %+v", page.Releases)
+	}
+
+	if _, err := (&OCI{}).ListReleases("oci://"+repo+"-missing", ListOptions{}); err == nil {
+		t.Error("expected error for unknown repository")
+	}
+}
+
+func TestGit_ListReleases(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repoDir, run := initTestRepo(t)
+	run("git", "commit", "--allow-empty", "-m", "initial")
+	run("git", "tag", "v0.9.0")
+	run("git", "tag", "v1.0.0")
+
+	page, err := (&Git{}).ListReleases("git://"+repoDir+":tool", ListOptions{PerPage: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Releases) != 1 || page.Releases[0].Version != "v1.0.0" || page.Next != 2 {
+		t.Errorf("page = %+v", page)
+	}
+}
+
+func TestGoInstall_ListReleases(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path == "/github.com/!burnt!sushi/toml/@v/list" {
+			_, _ = w.Write([]byte("v1.2.0\nv1.3.0-rc.1\nv0.4.1\n"))
+			return
+		}
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	t.Setenv("GOPROXY", srv.URL+"/,direct")
+
+	page, err := (&GoInstall{}).ListReleases("go://github.com/BurntSushi/toml/cmd/tomlv@latest", ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ReleaseInfo{{Version: "v1.3.0-rc.1", Prerelease: true}, {Version: "v1.2.0"}, {Version: "v0.4.1"}}
+	if !slices.Equal(page.Releases, want) {
+		t.Errorf("releases = %+v", page.Releases)
+	}
+	// The package path is walked up to the module root.
+	if len(requested) != 3 || requested[0] != "/github.com/!burnt!sushi/toml/cmd/tomlv/@v/list" {
+		t.Errorf("requested = %v", requested)
+	}
+
+	if _, err := (&GoInstall{}).ListReleases("go://example.com/unknown", ListOptions{}); err == nil {
+		t.Error("expected error for unknown module")
+	}
+	t.Setenv("GOPROXY", "direct")
+	if _, err := (&GoInstall{}).ListReleases("go://github.com/BurntSushi/toml", ListOptions{}); err == nil || !strings.Contains(err.Error(), "needs a module proxy") {
+		t.Errorf("GOPROXY=direct: err = %v", err)
+	}
+}
+
+func TestTagPage(t *testing.T) {
+	tags := []string{"edge", "v1.10.0", "v1.2.0", "latest", "v1.9.0-rc.1", "v1.9.0"}
+	var got []string
+	for p := 1; p != 0; {
+		page := tagPage(tags, ListOptions{Page: p, PerPage: 4})
+		for _, r := range page.Releases {
+			got = append(got, r.Version)
+		}
+		p = page.Next
+	}
+	want := []string{"v1.10.0", "v1.9.0", "v1.9.0-rc.1", "v1.2.0", "edge", "latest"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if page := tagPage(tags, ListOptions{Page: 9}); len(page.Releases) != 0 || page.Next != 0 {
+		t.Errorf("past the end = %+v", page)
+	}
+	if page := tagPage(nil, ListOptions{}); len(page.Releases) != 0 {
+		t.Errorf("empty = %+v", page)
+	}
+}
@@ -0,0 +1,58 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteExecutable writes r to dest with mode 0755. The content goes to a
+// temp file in dest's directory first and is renamed over dest only once
+// complete, so a failure part-way leaves any previous dest untouched
+// instead of a truncated executable.
+func WriteExecutable(dest string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	if err := writeAndClose(tmp, r); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return replaceWith(tmp.Name(), dest)
+}
+
+func writeAndClose(f *os.File, r io.Reader) error {
+	_, err := io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// replaceWith makes the complete file at tmp executable and moves it onto
+// dest. tmp is removed if that fails.
+func replaceWith(tmp, dest string) error {
+	if err := os.Chmod(tmp, 0755); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("moving %s into place: %w", filepath.Base(dest), err)
+	}
+	return nil
+}
+
+// replaceTree moves the file or directory at src onto dest, removing
+// what dest held before.
+func replaceTree(src, dest string) error {
+	if err := os.RemoveAll(dest); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dest); err != nil {
+		return fmt.Errorf("moving %s into place: %w", filepath.Base(dest), err)
+	}
+	return nil
+}
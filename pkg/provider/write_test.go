@@ -0,0 +1,39 @@
+package provider
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestWriteExecutable(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "tool")
+	if err := WriteExecutable(dest, strings.NewReader("v1")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+
+	// A reader failing part-way leaves the previous file as it was.
+	failing := io.MultiReader(strings.NewReader("v2-partial"), iotest.ErrReader(errors.New("boom")))
+	if err := WriteExecutable(dest, failing); err == nil {
+		t.Fatal("expected error")
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "v1" {
+		t.Errorf("dest = %q, want v1", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,291 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a version range:
+//
+//	~1.7          >=1.7.0 <1.8.0
+//	^3.14         >=3.14.0 <4.0.0   (^0.5 → <0.6.0)
+//	>=1.28 <1.31  both must hold; a comma works too
+//	1.2 - 1.4     >=1.2.0 <1.5.0
+//	1.x, 1.2.*    wildcards
+//	^1 || ^2      either range
+//
+// Prereleases only match when a bound of the same range names a
+// prerelease of the same major.minor.patch (">=2.0.0-rc.1").
+type Constraint struct {
+	raw  string
+	sets [][]comparator
+}
+
+type comparator struct {
+	op string // =, !=, >, >=, <, <=
+	v  Version
+}
+
+// partial is a version in a constraint, where trailing components may be
+// missing or wildcards.
+type partial struct {
+	v Version
+	n int // number of components given (0–3)
+}
+
+// IsConstraint reports whether s is a range rather than an exact version.
+// Plain tags ("v1.7.1", "1.7") are exact pins.
+func IsConstraint(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	if strings.ContainsAny(s[:1], "~^<>=!*") || strings.ContainsAny(s, " ,") || strings.Contains(s, "||") {
+		return true
+	}
+	base := strings.TrimPrefix(s, "v")
+	for _, p := range strings.Split(base, ".") {
+		if isWildcard(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseConstraint parses a version range; see Constraint.
+func ParseConstraint(s string) (*Constraint, error) {
+	c := &Constraint{raw: strings.TrimSpace(s)}
+	for _, alt := range strings.Split(s, "||") {
+		set, err := parseSet(alt)
+		if err != nil {
+			return nil, fmt.Errorf("version constraint %q: %w", c.raw, err)
+		}
+		c.sets = append(c.sets, set)
+	}
+	return c, nil
+}
+
+// String returns the constraint as written.
+func (c *Constraint) String() string { return c.raw }
+
+// Check reports whether v satisfies the constraint.
+func (c *Constraint) Check(v Version) bool {
+	for _, set := range c.sets {
+		if setAllows(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Highest returns the highest tag satisfying the constraint. Tags that
+// aren't versions are ignored.
+func (c *Constraint) Highest(tags []string) (string, bool) {
+	var best Version
+	found := false
+	for _, tag := range tags {
+		v, ok := Parse(tag)
+		if !ok || !c.Check(v) {
+			continue
+		}
+		if !found || Compare(v, best) > 0 {
+			best, found = v, true
+		}
+	}
+	return best.Original, found
+}
+
+func setAllows(set []comparator, v Version) bool {
+	for _, cmp := range set {
+		if !cmp.allows(v) {
+			return false
+		}
+	}
+	if v.Pre == "" {
+		return true
+	}
+	for _, cmp := range set {
+		if cmp.v.Pre != "" && cmp.v.Major == v.Major && cmp.v.Minor == v.Minor && cmp.v.Patch == v.Patch {
+			return true
+		}
+	}
+	return false
+}
+
+func (c comparator) allows(v Version) bool {
+	r := Compare(v, c.v)
+	switch c.op {
+	case "=":
+		return r == 0
+	case "!=":
+		return r != 0
+	case ">":
+		return r > 0
+	case ">=":
+		return r >= 0
+	case "<":
+		return r < 0
+	default: // "<="
+		return r <= 0
+	}
+}
+
+func parseSet(s string) ([]comparator, error) {
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty range")
+	}
+	var set []comparator
+	for i := 0; i < len(fields); i++ {
+		term := fields[i]
+		// Hyphen range: "1.2 - 1.4"
+		if i+2 < len(fields) && fields[i+1] == "-" {
+			lo, err := parsePartial(term)
+			if err != nil {
+				return nil, err
+			}
+			hi, err := parsePartial(fields[i+2])
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, comparator{">=", lo.v})
+			set = append(set, upTo(hi)...)
+			i += 2
+			continue
+		}
+		// An operator separated from its version: ">= 1.28"
+		if strings.Trim(term, "~^<>=!") == "" && i+1 < len(fields) {
+			term += fields[i+1]
+			i++
+		}
+		cmps, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cmps...)
+	}
+	return set, nil
+}
+
+func parseTerm(term string) ([]comparator, error) {
+	op := term[:len(term)-len(strings.TrimLeft(term, "~^<>=!"))]
+	p, err := parsePartial(term[len(op):])
+	if err != nil {
+		return nil, err
+	}
+	v := p.v
+	switch op {
+	case "", "=", "==":
+		if p.n == 3 {
+			return []comparator{{"=", v}}, nil
+		}
+		return between(p), nil
+	case "!=":
+		if p.n < 3 {
+			return nil, fmt.Errorf("%q: != needs a full version", term)
+		}
+		return []comparator{{"!=", v}}, nil
+	case ">":
+		if p.n == 3 {
+			return []comparator{{">", v}}, nil
+		}
+		if p.n == 0 {
+			return nil, fmt.Errorf("%q matches nothing", term)
+		}
+		return []comparator{{">=", bump(p)}}, nil
+	case ">=":
+		return []comparator{{">=", v}}, nil
+	case "<":
+		return []comparator{{"<", v}}, nil
+	case "<=":
+		return upTo(p), nil
+	case "~", "~>":
+		if p.n == 1 {
+			return between(p), nil
+		}
+		return between(partial{v: v, n: min(p.n, 2)}), nil
+	case "^":
+		switch {
+		case p.n == 0:
+			return between(p), nil
+		case v.Major > 0 || p.n == 1:
+			return []comparator{{">=", v}, {"<", Version{Major: v.Major + 1}}}, nil
+		case v.Minor > 0 || p.n == 2:
+			return []comparator{{">=", v}, {"<", Version{Minor: v.Minor + 1}}}, nil
+		default:
+			return []comparator{{">=", v}, {"<", Version{Patch: v.Patch + 1}}}, nil
+		}
+	}
+	return nil, fmt.Errorf("%q: unknown operator %q", term, op)
+}
+
+// between is the range a partial version covers: 1.2 → >=1.2.0 <1.3.0.
+func between(p partial) []comparator {
+	if p.n == 0 {
+		return []comparator{{">=", Version{}}}
+	}
+	return []comparator{{">=", p.v}, {"<", bump(p)}}
+}
+
+// upTo is the inclusive upper bound of a partial: <=1.4 → <1.5.0.
+func upTo(p partial) []comparator {
+	switch p.n {
+	case 0:
+		return nil
+	case 3:
+		return []comparator{{"<=", p.v}}
+	}
+	return []comparator{{"<", bump(p)}}
+}
+
+// bump returns the first version above the range of a partial.
+func bump(p partial) Version {
+	switch p.n {
+	case 1:
+		return Version{Major: p.v.Major + 1}
+	case 2:
+		return Version{Major: p.v.Major, Minor: p.v.Minor + 1}
+	}
+	return Version{Major: p.v.Major, Minor: p.v.Minor, Patch: p.v.Patch + 1}
+}
+
+func parsePartial(s string) (partial, error) {
+	var p partial
+	raw := s
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	if s == "" {
+		return p, fmt.Errorf("missing version")
+	}
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		p.v.Pre = s[i+1:]
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return p, fmt.Errorf("%q: too many components", raw)
+	}
+	nums := []*int{&p.v.Major, &p.v.Minor, &p.v.Patch}
+	for i, part := range parts {
+		if isWildcard(part) {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("%q is not a version", raw)
+		}
+		*nums[i] = n
+		p.n = i + 1
+	}
+	if p.v.Pre != "" && p.n < 3 {
+		return p, fmt.Errorf("%q: a prerelease needs a full version", raw)
+	}
+	return p, nil
+}
+
+func isWildcard(s string) bool {
+	return s == "x" || s == "X" || s == "*"
+}
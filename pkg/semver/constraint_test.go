@@ -0,0 +1,132 @@
+package semver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsConstraint(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"~1.7", true},
+		{"^3.14", true},
+		{">=1.28 <1.31", true},
+		{">=1.28,<1.31", true},
+		{"1.x", true},
+		{"v1.2.*", true},
+		{"*", true},
+		{"^1 || ^2", true},
+		{"1.2 - 1.4", true},
+		{"v1.7.1", false},
+		{"1.7", false},
+		{"latest", false},
+		{"", false},
+		{"jq-1.7.1", false},
+	}
+	for _, tt := range tests {
+		if got := IsConstraint(tt.s); got != tt.want {
+			t.Errorf("IsConstraint(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		match      []string
+		noMatch    []string
+	}{
+		{"~1.7", []string{"1.7.0", "v1.7.9"}, []string{"1.6.9", "1.8.0", "1.7.1-rc.1"}},
+		{"~1", []string{"1.0.0", "1.9.3"}, []string{"2.0.0", "0.9.0"}},
+		{"~1.7.2", []string{"1.7.2", "1.7.5"}, []string{"1.7.1", "1.8.0"}},
+		{"^3.14", []string{"3.14.0", "3.99.1"}, []string{"3.13.9", "4.0.0"}},
+		{"^0.5", []string{"0.5.0", "0.5.7"}, []string{"0.6.0", "0.4.9"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4", "0.0.2"}},
+		{"^0", []string{"0.0.1", "0.9.9"}, []string{"1.0.0"}},
+		{">=1.28 <1.31", []string{"1.28.0", "1.30.14"}, []string{"1.27.9", "1.31.0", "1.31.0-rc.1"}},
+		{">=1.28, <1.31", []string{"1.29.0"}, []string{"1.31.0"}},
+		{">= 1.28 < 1.31", []string{"1.29.0"}, []string{"1.31.0"}},
+		{">1.2", []string{"1.3.0"}, []string{"1.2.9"}},
+		{">1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"<=1.2", []string{"1.2.9", "0.1.0"}, []string{"1.3.0"}},
+		{"<=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"=1.2", []string{"1.2.0", "1.2.5"}, []string{"1.3.0"}},
+		{"1.2.x", []string{"1.2.0", "1.2.5"}, []string{"1.3.0"}},
+		{"1.x", []string{"1.0.0", "1.99.0"}, []string{"2.0.0"}},
+		{"*", []string{"0.0.1", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{"1.2 - 1.4", []string{"1.2.0", "1.4.9"}, []string{"1.5.0", "1.1.9"}},
+		{"1.2.0 - 1.4.0", []string{"1.4.0"}, []string{"1.4.1"}},
+		{"^1 || ^3", []string{"1.5.0", "3.0.0"}, []string{"2.0.0"}},
+		{">=2.0.0-rc.1", []string{"2.0.0-rc.1", "2.0.0-rc.2", "2.0.0", "2.1.0"}, []string{"2.0.0-beta", "2.1.0-rc.1"}},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Errorf("ParseConstraint(%q): %v", tt.constraint, err)
+			continue
+		}
+		for _, s := range tt.match {
+			v, _ := Parse(s)
+			if !c.Check(v) {
+				t.Errorf("%q should match %s", tt.constraint, s)
+			}
+		}
+		for _, s := range tt.noMatch {
+			v, _ := Parse(s)
+			if c.Check(v) {
+				t.Errorf("%q should not match %s", tt.constraint, s)
+			}
+		}
+	}
+}
+
+func TestParseConstraint_Errors(t *testing.T) {
+	tests := []struct {
+		constraint string
+		wantErr    string
+	}{
+		{"", "empty range"},
+		{"^1 ||", "empty range"},
+		{">=abc", "not a version"},
+		{"~1.2.3.4", "too many components"},
+		{"!=1.2", "needs a full version"},
+		{">*", "matches nothing"},
+		{"=>1.2", "unknown operator"},
+		{"^1.2-rc.1", "needs a full version"},
+		{">=", "missing version"},
+	}
+	for _, tt := range tests {
+		_, err := ParseConstraint(tt.constraint)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseConstraint(%q) err = %v, want %q", tt.constraint, err, tt.wantErr)
+		}
+	}
+}
+
+func TestConstraint_Highest(t *testing.T) {
+	tags := []string{"v1.6.3", "v1.7.0", "v1.7.1", "v1.8.0-rc.1", "v1.8.0", "nightly", "v2.0.0"}
+	tests := []struct {
+		constraint string
+		want       string
+		ok         bool
+	}{
+		{"~1.7", "v1.7.1", true},
+		{"^1.6", "v1.8.0", true},
+		{"<1.8", "v1.7.1", true},
+		{"*", "v2.0.0", true},
+		{"^3", "", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := c.Highest(tags)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Highest(%q) = %q, %v; want %q, %v", tt.constraint, got, ok, tt.want, tt.ok)
+		}
+	}
+}
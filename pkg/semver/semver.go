@@ -0,0 +1,139 @@
+// Package semver parses release tags as semantic versions and matches them
+// against range constraints such as "~1.7", "^3.14" or ">=1.28 <1.31".
+//
+// Tags are parsed leniently: a leading "v", a component prefix
+// ("kustomize/v5.4.1") or a name prefix ("jq-1.7.1") is ignored, and missing
+// minor/patch components count as zero.
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre is the prerelease part without the "-" (e.g. "rc.1").
+	Pre string
+	// Original is the string the version was parsed from.
+	Original string
+}
+
+// Parse parses a release tag. The second result is false when the tag isn't
+// a version.
+func Parse(tag string) (Version, bool) {
+	v := Version{Original: tag}
+	s := tag
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+	// Skip a name prefix ("v", "jq-", "release-") up to the first digit.
+	i := strings.IndexAny(s, "0123456789")
+	if i < 0 {
+		return v, false
+	}
+	for _, c := range s[:i] {
+		if !isLetter(c) && c != '-' && c != '_' {
+			return v, false
+		}
+	}
+	s = s[i:]
+	if j := strings.IndexByte(s, '+'); j >= 0 {
+		s = s[:j]
+	}
+	if j := strings.IndexByte(s, '-'); j >= 0 {
+		v.Pre = s[j+1:]
+		if v.Pre == "" {
+			return v, false
+		}
+		s = s[:j]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return v, false
+	}
+	// A bare number after a name ("nightly-2024") is a date or build, not a
+	// major version.
+	if len(parts) == 1 && i > 1 {
+		return v, false
+	}
+	nums := []*int{&v.Major, &v.Minor, &v.Patch}
+	for k, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		*nums[k] = n
+	}
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 as a is lower than, equal to or higher than b,
+// following semver precedence (a prerelease sorts before its release).
+func Compare(a, b Version) int {
+	if c := cmpInt(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := cmpInt(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := cmpInt(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+	return comparePre(a.Pre, b.Pre)
+}
+
+// String returns the version as "major.minor.patch[-pre]".
+func (v Version) String() string {
+	s := strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+	if v.Pre != "" {
+		s += "-" + v.Pre
+	}
+	return s
+}
+
+func comparePre(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := cmpInt(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1 // numeric identifiers sort first
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmpInt(len(as), len(bs))
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isLetter(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
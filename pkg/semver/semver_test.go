@@ -0,0 +1,74 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+		ok   bool
+	}{
+		{"v1.7.1", "1.7.1", true},
+		{"1.7", "1.7.0", true},
+		{"v3", "3.0.0", true},
+		{"jq-1.7.1", "1.7.1", true},
+		{"kustomize/v5.4.1", "5.4.1", true},
+		{"v2.0.0-rc.1", "2.0.0-rc.1", true},
+		{"v1.2.3+build.5", "1.2.3", true},
+		{"release_4.2", "4.2.0", true},
+		{"latest", "", false},
+		{"nightly-2024", "", false},
+		{"1.2.3.4", "", false},
+		{"v1.2.x", "", false},
+		{"v1.2.3-", "", false},
+		{"sha256:abc1", "", false},
+	}
+	for _, tt := range tests {
+		v, ok := Parse(tt.tag)
+		if ok != tt.ok {
+			t.Errorf("Parse(%q) ok = %v, want %v", tt.tag, ok, tt.ok)
+			continue
+		}
+		if ok && v.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.tag, v, tt.want)
+		}
+		if ok && v.Original != tt.tag {
+			t.Errorf("Parse(%q).Original = %q", tt.tag, v.Original)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Ascending order.
+	ordered := []string{
+		"0.0.1",
+		"0.1.0",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := 0; i < len(ordered); i++ {
+		for j := 0; j < len(ordered); j++ {
+			a, _ := Parse(ordered[i])
+			b, _ := Parse(ordered[j])
+			want := cmpInt(i, j)
+			if got := Compare(a, b); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	a, _ := Parse("v1.2")
+	b, _ := Parse("1.2.0")
+	if Compare(a, b) != 0 {
+		t.Error("v1.2 and 1.2.0 should be equal")
+	}
+}
@@ -0,0 +1,264 @@
+// Package signature verifies publisher signatures on downloaded release
+// assets — cosign (key-based or keyless), minisign, and GPG — fully offline.
+package signature
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Verifier names as recorded in b.lock.
+const (
+	VerifierCosign   = "cosign"
+	VerifierMinisign = "minisign"
+	VerifierGPG      = "gpg"
+)
+
+// Config is the per-binary `verify:` block in b.yaml. Every configured
+// verifier must succeed; a binary with an empty Config is not verified.
+//
+//	binaries:
+//	  github.com/sigstore/cosign:
+//	    verify:
+//	      cosign:
+//	        identity: keyless@projectsigstore.iam.gserviceaccount.com
+//	        issuer: https://accounts.google.com
+//	        trustedRoot: .keys/fulcio.pem
+//	        rekorKey: .keys/rekor.pub
+type Config struct {
+	Cosign   *Cosign   `json:"cosign,omitempty" yaml:"cosign,omitempty"`
+	Minisign *Minisign `json:"minisign,omitempty" yaml:"minisign,omitempty"`
+	GPG      *GPG      `json:"gpg,omitempty" yaml:"gpg,omitempty"`
+}
+
+// Cosign verifies cosign blob signatures. Set Key for key-based signing, or
+// Identity + Issuer + TrustedRoot + RekorKey for keyless (Fulcio) signing.
+type Cosign struct {
+	// Key is a PEM public key, or a path to one (relative to b.yaml).
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Identity is the expected certificate subject (SAN email or URI).
+	Identity string `json:"identity,omitempty" yaml:"identity,omitempty"`
+	// Issuer is the expected OIDC issuer recorded in the certificate.
+	Issuer string `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	// TrustedRoot is a PEM bundle (path) of the Fulcio root and
+	// intermediates the signing certificate must chain to.
+	TrustedRoot string `json:"trustedRoot,omitempty" yaml:"trustedRoot,omitempty"`
+	// RekorKey is the PEM public key (path) of the Rekor transparency log
+	// whose signed entry timestamps prove when a keyless signature was
+	// made, inside the certificate's short validity.
+	RekorKey string `json:"rekorKey,omitempty" yaml:"rekorKey,omitempty"`
+}
+
+// Minisign verifies minisign signatures.
+type Minisign struct {
+	// Key is the base64 public key ("RW..."), or a path to a minisign.pub.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// GPG verifies detached OpenPGP signatures.
+type GPG struct {
+	// Keyring is an armored or binary public keyring, or a path to one.
+	Keyring string `json:"keyring,omitempty" yaml:"keyring,omitempty"`
+}
+
+// IsZero reports whether no verifier is configured.
+func (c *Config) IsZero() bool {
+	return c == nil || (c.Cosign == nil && c.Minisign == nil && c.GPG == nil)
+}
+
+// Verifiers returns the names of the configured verifiers.
+func (c *Config) Verifiers() []string {
+	if c == nil {
+		return nil
+	}
+	var names []string
+	if c.Cosign != nil {
+		names = append(names, VerifierCosign)
+	}
+	if c.Minisign != nil {
+		names = append(names, VerifierMinisign)
+	}
+	if c.GPG != nil {
+		names = append(names, VerifierGPG)
+	}
+	return names
+}
+
+// Companions returns the file suffixes the configured verifiers look for
+// next to the signed file ("tool.tar.gz.sig", "SHA256SUMS.asc", ...).
+func (c *Config) Companions() []string {
+	if c == nil {
+		return nil
+	}
+	var suffixes []string
+	if c.Cosign != nil {
+		suffixes = append(suffixes, ".bundle", ".sig", ".pem", ".cert")
+	}
+	if c.Minisign != nil {
+		suffixes = append(suffixes, ".minisig")
+	}
+	if c.GPG != nil {
+		suffixes = append(suffixes, c.gpgSuffixes()...)
+	}
+	return dedupe(suffixes)
+}
+
+// gpgSuffixes returns the companions GPG reads its signature from, in
+// order of preference. With cosign configured too a ".sig" is cosign's,
+// so GPG only takes ".asc" and ".gpg".
+func (c *Config) gpgSuffixes() []string {
+	if c.Cosign != nil {
+		return []string{".asc", ".gpg"}
+	}
+	return []string{".asc", ".sig", ".gpg"}
+}
+
+// Resolve returns a copy of c with relative key paths joined against dir
+// (the directory holding b.yaml). Inline keys are left untouched.
+func (c *Config) Resolve(dir string) *Config {
+	if c == nil {
+		return nil
+	}
+	out := &Config{}
+	if c.Cosign != nil {
+		cs := *c.Cosign
+		if !isInlinePEM(cs.Key) {
+			cs.Key = resolvePath(dir, cs.Key)
+		}
+		cs.TrustedRoot = resolvePath(dir, cs.TrustedRoot)
+		if !isInlinePEM(cs.RekorKey) {
+			cs.RekorKey = resolvePath(dir, cs.RekorKey)
+		}
+		out.Cosign = &cs
+	}
+	if c.Minisign != nil {
+		ms := *c.Minisign
+		if _, err := parseMinisignKey(ms.Key); err != nil {
+			ms.Key = resolvePath(dir, ms.Key)
+		}
+		out.Minisign = &ms
+	}
+	if c.GPG != nil {
+		g := *c.GPG
+		if !isInlinePEM(g.Keyring) {
+			g.Keyring = resolvePath(dir, g.Keyring)
+		}
+		out.GPG = &g
+	}
+	return out
+}
+
+// Material is the signature material published next to a signed file,
+// keyed by companion suffix (".sig", ".bundle", ".minisig", ...).
+type Material map[string][]byte
+
+// Result describes one successful verification. It is recorded in b.lock
+// so `b verify` can check it against the current b.yaml policy.
+type Result struct {
+	Verifier string `json:"verifier"`
+	// Signer identifies the trusted key: a key fingerprint, minisign key
+	// id, or the keyless certificate identity.
+	Signer string `json:"signer"`
+	// Signed is the file the signature covers — the asset itself, or the
+	// checksum manifest that lists it.
+	Signed string `json:"signed,omitempty"`
+}
+
+// Verify checks payload against the material with every configured
+// verifier and returns one Result per verifier. Any failure is fatal.
+func (c *Config) Verify(payload []byte, m Material) ([]Result, error) {
+	if c.IsZero() {
+		return nil, nil
+	}
+	var results []Result
+	if c.Cosign != nil {
+		signer, err := c.Cosign.verify(payload, m)
+		if err != nil {
+			return nil, fmt.Errorf("cosign: %w", err)
+		}
+		results = append(results, Result{Verifier: VerifierCosign, Signer: signer})
+	}
+	if c.Minisign != nil {
+		signer, err := c.Minisign.verify(payload, m)
+		if err != nil {
+			return nil, fmt.Errorf("minisign: %w", err)
+		}
+		results = append(results, Result{Verifier: VerifierMinisign, Signer: signer})
+	}
+	if c.GPG != nil {
+		signer, err := c.GPG.verify(payload, m, c.gpgSuffixes())
+		if err != nil {
+			return nil, fmt.Errorf("gpg: %w", err)
+		}
+		results = append(results, Result{Verifier: VerifierGPG, Signer: signer})
+	}
+	return results, nil
+}
+
+// Check reports whether recorded results (from b.lock) still satisfy the
+// policy: every configured verifier has a result, and its signer is still
+// trusted by the configured key, identity, or keyring. Runs offline.
+func (c *Config) Check(results []Result) error {
+	for _, name := range c.Verifiers() {
+		var rec *Result
+		for i := range results {
+			if results[i].Verifier == name {
+				rec = &results[i]
+				break
+			}
+		}
+		if rec == nil {
+			return fmt.Errorf("no %s signature recorded", name)
+		}
+		var err error
+		switch name {
+		case VerifierCosign:
+			err = c.Cosign.trusts(rec.Signer)
+		case VerifierMinisign:
+			err = c.Minisign.trusts(rec.Signer)
+		case VerifierGPG:
+			err = c.GPG.trusts(rec.Signer)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// readKeyMaterial returns inline PEM/armored material as-is, or the
+// contents of the file it names.
+func readKeyMaterial(s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("no key configured")
+	}
+	if isInlinePEM(s) {
+		return []byte(s), nil
+	}
+	return os.ReadFile(s)
+}
+
+func isInlinePEM(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN ")
+}
+
+func resolvePath(dir, p string) string {
+	if p == "" || filepath.IsAbs(p) || dir == "" {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+func dedupe(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := in[:0]
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,565 @@
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fulcio certificate extensions carrying the OIDC issuer. 57264.1.1 is the
+// legacy raw-string form; 57264.1.8 is the DER-encoded UTF8String form.
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// cosignBundle covers both the legacy `cosign sign-blob --bundle` format and
+// the protobuf-JSON sigstore bundle (v0.1–v0.3).
+type cosignBundle struct {
+	// legacy
+	Base64Signature string `json:"base64Signature"`
+	Cert            string `json:"cert"`
+	RekorBundle     *struct {
+		SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+		Payload              struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogIndex       int64  `json:"logIndex"`
+			LogID          string `json:"logID"`
+		} `json:"Payload"`
+	} `json:"rekorBundle"`
+
+	// sigstore bundle
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial *struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		TlogEntries []struct {
+			LogIndex json.RawMessage `json:"logIndex"`
+			LogID    *struct {
+				KeyID []byte `json:"keyId"`
+			} `json:"logId"`
+			IntegratedTime   json.RawMessage `json:"integratedTime"`
+			InclusionPromise *struct {
+				SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+			} `json:"inclusionPromise"`
+			CanonicalizedBody []byte `json:"canonicalizedBody"`
+		} `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	MessageSignature *struct {
+		MessageDigest *struct {
+			Algorithm string `json:"algorithm"`
+			Digest    []byte `json:"digest"`
+		} `json:"messageDigest"`
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// cosignMaterial is the normalized signature material for one blob.
+type cosignMaterial struct {
+	sig   []byte
+	certs [][]byte // DER; leaf first
+	tlog  []tlogEntry
+}
+
+// tlogEntry is a Rekor transparency log entry as bundles carry it: the
+// canonicalized entry body and the log's signed entry timestamp (SET),
+// its promise that the body was logged at integratedTime.
+type tlogEntry struct {
+	body           []byte
+	integratedTime int64
+	logIndex       int64
+	logID          string // hex
+	set            []byte
+}
+
+func (c *Cosign) verify(payload []byte, m Material) (string, error) {
+	cm, err := cosignMaterialFrom(payload, m)
+	if err != nil {
+		return "", err
+	}
+	if c.Key != "" {
+		pub, err := c.publicKey()
+		if err != nil {
+			return "", err
+		}
+		if err := verifyBlob(pub, payload, cm.sig); err != nil {
+			return "", err
+		}
+		return keyFingerprint(pub)
+	}
+	return c.verifyKeyless(payload, cm)
+}
+
+func (c *Cosign) verifyKeyless(payload []byte, cm *cosignMaterial) (string, error) {
+	if c.Identity == "" || c.Issuer == "" {
+		return "", fmt.Errorf("keyless verification needs identity and issuer")
+	}
+	if c.TrustedRoot == "" {
+		return "", fmt.Errorf("keyless verification needs trustedRoot (Fulcio root PEM)")
+	}
+	if c.RekorKey == "" {
+		return "", fmt.Errorf("keyless verification needs rekorKey (Rekor public key PEM)")
+	}
+	if len(cm.certs) == 0 {
+		return "", fmt.Errorf("no signing certificate published")
+	}
+	leaf, err := x509.ParseCertificate(cm.certs[0])
+	if err != nil {
+		return "", fmt.Errorf("parsing signing certificate: %w", err)
+	}
+
+	rootPEM, err := readKeyMaterial(c.TrustedRoot)
+	if err != nil {
+		return "", fmt.Errorf("reading trustedRoot: %w", err)
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, cert := range parsePEMCertificates(rootPEM) {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			roots.AddCert(cert)
+		} else {
+			intermediates.AddCert(cert)
+		}
+	}
+	for _, der := range cm.certs[1:] {
+		if cert, err := x509.ParseCertificate(der); err == nil {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	// Fulcio certificates live for ~10 minutes; they are checked at the time
+	// the signature entered the transparency log. That time is only taken
+	// from an entry the log signed, recording this signature and
+	// certificate; anyone holding an expired certificate's key could claim
+	// any other.
+	at, err := c.verifyTlog(payload, cm, leaf)
+	if err != nil {
+		return "", err
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return "", fmt.Errorf("certificate not trusted: %w", err)
+	}
+
+	if !certHasIdentity(leaf, c.Identity) {
+		return "", fmt.Errorf("certificate identity does not match %q", c.Identity)
+	}
+	if issuer := certIssuer(leaf); issuer != c.Issuer {
+		return "", fmt.Errorf("certificate issuer %q does not match %q", issuer, c.Issuer)
+	}
+	if err := verifyBlob(leaf.PublicKey, payload, cm.sig); err != nil {
+		return "", err
+	}
+	return c.Identity, nil
+}
+
+// verifyTlog finds a transparency log entry the configured Rekor key
+// signed that records sig by leaf over payload, and returns when it was
+// logged.
+func (c *Cosign) verifyTlog(payload []byte, cm *cosignMaterial, leaf *x509.Certificate) (time.Time, error) {
+	data, err := readKeyMaterial(c.RekorKey)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading rekorKey: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("rekorKey is not PEM encoded")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing rekorKey: %w", err)
+	}
+	if len(cm.tlog) == 0 {
+		return time.Time{}, fmt.Errorf("no transparency log entry published; keyless signatures need a bundle with one")
+	}
+	logID := fmt.Sprintf("%x", sha256.Sum256(block.Bytes))
+	var last error
+	for _, e := range cm.tlog {
+		if last = e.verify(pub, logID, payload, cm.sig, leaf); last == nil {
+			return time.Unix(e.integratedTime, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("transparency log entry: %w", last)
+}
+
+// verify checks that the log with key pub and id logID signed e, and that
+// e is a hashedrekord entry of sig by leaf over payload.
+func (e tlogEntry) verify(pub crypto.PublicKey, logID string, payload, sig []byte, leaf *x509.Certificate) error {
+	if e.logID != logID {
+		return fmt.Errorf("logged by %s, not the configured Rekor log", e.logID)
+	}
+	if len(e.set) == 0 {
+		return fmt.Errorf("no signed entry timestamp")
+	}
+	// The SET signs the canonical JSON of these fields, keys sorted.
+	signed, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{base64.StdEncoding.EncodeToString(e.body), e.integratedTime, e.logID, e.logIndex})
+	if err != nil {
+		return err
+	}
+	if err := verifyBlob(pub, signed, e.set); err != nil {
+		return fmt.Errorf("signed entry timestamp: %w", err)
+	}
+
+	var body struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   []byte `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(e.body, &body); err != nil {
+		return fmt.Errorf("parsing entry: %w", err)
+	}
+	if body.Kind != "hashedrekord" {
+		return fmt.Errorf("entry is a %q, not a hashedrekord", body.Kind)
+	}
+	hash := body.Spec.Data.Hash
+	if sum := sha256.Sum256(payload); hash.Algorithm != "sha256" || hash.Value != fmt.Sprintf("%x", sum) {
+		return fmt.Errorf("entry is for another file")
+	}
+	if !bytes.Equal(body.Spec.Signature.Content, sig) {
+		return fmt.Errorf("entry is for another signature")
+	}
+	certs := parseCertificates(body.Spec.Signature.PublicKey.Content)
+	if len(certs) == 0 || !bytes.Equal(certs[0], leaf.Raw) {
+		return fmt.Errorf("entry is for another certificate")
+	}
+	return nil
+}
+
+// trusts reports whether a signer recorded in b.lock matches the configured
+// key or keyless identity.
+func (c *Cosign) trusts(signer string) error {
+	if c.Key != "" {
+		pub, err := c.publicKey()
+		if err != nil {
+			return err
+		}
+		fp, err := keyFingerprint(pub)
+		if err != nil {
+			return err
+		}
+		if fp != signer {
+			return fmt.Errorf("signed by %s, configured key is %s", signer, fp)
+		}
+		return nil
+	}
+	if signer != c.Identity {
+		return fmt.Errorf("signed by %q, configured identity is %q", signer, c.Identity)
+	}
+	return nil
+}
+
+func (c *Cosign) publicKey() (crypto.PublicKey, error) {
+	data, err := readKeyMaterial(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key is not PEM encoded")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// cosignMaterialFrom prefers a bundle over a bare ".sig" (+ ".pem"/".cert").
+func cosignMaterialFrom(payload []byte, m Material) (*cosignMaterial, error) {
+	if raw, ok := m[".bundle"]; ok {
+		return parseCosignBundle(payload, raw)
+	}
+	raw, ok := m[".sig"]
+	if !ok {
+		return nil, fmt.Errorf("no .sig or .bundle published")
+	}
+	cm := &cosignMaterial{sig: decodeBase64OrRaw(raw)}
+	for _, suffix := range []string{".pem", ".cert"} {
+		if cert, ok := m[suffix]; ok {
+			cm.certs = parseCertificates(cert)
+			break
+		}
+	}
+	return cm, nil
+}
+
+func parseCosignBundle(payload, raw []byte) (*cosignMaterial, error) {
+	var b cosignBundle
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, fmt.Errorf("parsing bundle: %w", err)
+	}
+	cm := &cosignMaterial{}
+	switch {
+	case b.Base64Signature != "":
+		sig, err := base64.StdEncoding.DecodeString(b.Base64Signature)
+		if err != nil {
+			return nil, fmt.Errorf("decoding bundle signature: %w", err)
+		}
+		cm.sig = sig
+		if b.Cert != "" {
+			cm.certs = parseCertificates([]byte(b.Cert))
+		}
+		if rb := b.RekorBundle; rb != nil {
+			body, err := base64.StdEncoding.DecodeString(rb.Payload.Body)
+			if err != nil {
+				return nil, fmt.Errorf("decoding bundle log entry: %w", err)
+			}
+			cm.tlog = append(cm.tlog, tlogEntry{
+				body:           body,
+				integratedTime: rb.Payload.IntegratedTime,
+				logIndex:       rb.Payload.LogIndex,
+				logID:          rb.Payload.LogID,
+				set:            rb.SignedEntryTimestamp,
+			})
+		}
+	case b.MessageSignature != nil:
+		cm.sig = b.MessageSignature.Signature
+		if d := b.MessageSignature.MessageDigest; d != nil && d.Algorithm == "SHA2_256" {
+			sum := sha256.Sum256(payload)
+			if !bytes.Equal(sum[:], d.Digest) {
+				return nil, fmt.Errorf("bundle digest does not match the signed file")
+			}
+		}
+		if vm := b.VerificationMaterial; vm != nil {
+			if vm.Certificate != nil {
+				cm.certs = [][]byte{vm.Certificate.RawBytes}
+			} else if vm.X509CertificateChain != nil {
+				for _, c := range vm.X509CertificateChain.Certificates {
+					cm.certs = append(cm.certs, c.RawBytes)
+				}
+			}
+			for _, e := range vm.TlogEntries {
+				entry := tlogEntry{
+					body:           e.CanonicalizedBody,
+					integratedTime: jsonInt(e.IntegratedTime),
+					logIndex:       jsonInt(e.LogIndex),
+				}
+				if e.LogID != nil {
+					entry.logID = fmt.Sprintf("%x", e.LogID.KeyID)
+				}
+				if e.InclusionPromise != nil {
+					entry.set = e.InclusionPromise.SignedEntryTimestamp
+				}
+				cm.tlog = append(cm.tlog, entry)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("bundle holds no message signature")
+	}
+	if len(cm.sig) == 0 {
+		return nil, fmt.Errorf("bundle holds an empty signature")
+	}
+	return cm, nil
+}
+
+// jsonInt reads an int64 the protobuf JSON encoding writes as a string.
+func jsonInt(raw json.RawMessage) int64 {
+	n, _ := strconv.ParseInt(strings.Trim(string(raw), `"`), 10, 64)
+	return n
+}
+
+// verifyBlob checks a cosign blob signature with pub. ECDSA and RSA sign the
+// digest of the blob; ed25519 signs the blob itself.
+func verifyBlob(pub crypto.PublicKey, payload, sig []byte) error {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		var digest []byte
+		switch k.Curve {
+		case elliptic.P384():
+			sum := sha512.Sum384(payload)
+			digest = sum[:]
+		case elliptic.P521():
+			sum := sha512.Sum512(payload)
+			digest = sum[:]
+		default:
+			sum := sha256.Sum256(payload)
+			digest = sum[:]
+		}
+		if !ecdsa.VerifyASN1(k, digest, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, sig) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(payload)
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", pub)
+	}
+	return nil
+}
+
+// keyFingerprint identifies a public key by the sha256 of its PKIX encoding.
+func keyFingerprint(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(der)), nil
+}
+
+func certHasIdentity(cert *x509.Certificate, identity string) bool {
+	for _, email := range cert.EmailAddresses {
+		if email == identity {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == identity {
+			return true
+		}
+	}
+	return false
+}
+
+func certIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var s string
+			if _, err := asn1.Unmarshal(ext.Value, &s); err == nil {
+				return s
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// parseCertificates accepts PEM, base64-encoded PEM (as cosign writes
+// ".pem" files and bundle "cert" fields), or a single DER certificate.
+func parseCertificates(data []byte) [][]byte {
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		if decoded, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+			data = decoded
+		}
+	}
+	var ders [][]byte
+	for _, cert := range parsePEMCertificates(data) {
+		ders = append(ders, cert.Raw)
+	}
+	if len(ders) == 0 {
+		if _, err := x509.ParseCertificate(data); err == nil {
+			ders = append(ders, data)
+		}
+	}
+	return ders
+}
+
+func parsePEMCertificates(data []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// decodeBase64OrRaw decodes cosign's base64 ".sig" files; anything that
+// isn't base64 is taken as a raw signature.
+func decodeBase64OrRaw(data []byte) []byte {
+	if decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data))); err == nil {
+		return decoded
+	}
+	return data
+}
+
+// VerifyImage checks one cosign image signature for digest: the signature
+// over the simple-signing payload, and that the payload names digest (so a
+// signature for another image can't be replayed). Only cosign signs images.
+func (c *Config) VerifyImage(digest string, payload []byte, sig string, cert, rekorBundle []byte) ([]Result, error) {
+	if c.Minisign != nil || c.GPG != nil {
+		return nil, fmt.Errorf("only cosign signatures are supported for images")
+	}
+	if c.Cosign == nil {
+		return nil, nil
+	}
+	var simple struct {
+		Critical struct {
+			Image struct {
+				DockerManifestDigest string `json:"docker-manifest-digest"`
+			} `json:"image"`
+		} `json:"critical"`
+	}
+	if err := json.Unmarshal(payload, &simple); err != nil {
+		return nil, fmt.Errorf("cosign: parsing signature payload: %w", err)
+	}
+	if got := simple.Critical.Image.DockerManifestDigest; got != digest {
+		return nil, fmt.Errorf("cosign: signature is for %s, not %s", got, digest)
+	}
+
+	m := Material{".sig": []byte(sig)}
+	if len(cert) > 0 {
+		m[".pem"] = cert
+		if len(rekorBundle) > 0 {
+			// Same shape as a `cosign sign-blob --bundle` file, so the
+			// Rekor entry is verified and its time used to check the
+			// certificate.
+			bundle, err := json.Marshal(map[string]interface{}{
+				"base64Signature": sig,
+				"cert":            base64.StdEncoding.EncodeToString(cert),
+				"rekorBundle":     json.RawMessage(rekorBundle),
+			})
+			if err != nil {
+				return nil, err
+			}
+			m = Material{".bundle": bundle}
+		}
+	}
+	signer, err := c.Cosign.verify(payload, m)
+	if err != nil {
+		return nil, fmt.Errorf("cosign: %w", err)
+	}
+	return []Result{{Verifier: VerifierCosign, Signer: signer, Signed: digest}}, nil
+}
@@ -0,0 +1,67 @@
+package signature
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func (g *GPG) verify(payload []byte, m Material, suffixes []string) (string, error) {
+	keyring, err := g.keyring()
+	if err != nil {
+		return "", err
+	}
+	var sig []byte
+	for _, suffix := range suffixes {
+		if s, ok := m[suffix]; ok {
+			sig = s
+			break
+		}
+	}
+	if sig == nil {
+		return "", fmt.Errorf("no %s published", strings.Join(suffixes, " or "))
+	}
+	var signer *openpgp.Entity
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN ")) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(payload), bytes.NewReader(sig), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(payload), bytes.NewReader(sig), nil)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fingerprint(signer), nil
+}
+
+// trusts reports whether the recorded signer fingerprint is still in the
+// configured keyring.
+func (g *GPG) trusts(signer string) error {
+	keyring, err := g.keyring()
+	if err != nil {
+		return err
+	}
+	for _, e := range keyring {
+		if strings.EqualFold(fingerprint(e), signer) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signing key %s is not in the keyring", signer)
+}
+
+func (g *GPG) keyring() (openpgp.EntityList, error) {
+	data, err := readKeyMaterial(g.Keyring)
+	if err != nil {
+		return nil, fmt.Errorf("reading keyring: %w", err)
+	}
+	if _, err := armor.Decode(bytes.NewReader(data)); err == nil {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+func fingerprint(e *openpgp.Entity) string {
+	return fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+}
@@ -0,0 +1,140 @@
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// minisignKey is a decoded minisign public key: "Ed" || key id || ed25519 key.
+type minisignKey struct {
+	id  [8]byte
+	pub ed25519.PublicKey
+}
+
+// ID renders the key id the way `minisign` prints it.
+func (k *minisignKey) ID() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(k.id[:]))
+}
+
+func (m *Minisign) verify(payload []byte, material Material) (string, error) {
+	raw, ok := material[".minisig"]
+	if !ok {
+		return "", fmt.Errorf("no .minisig published")
+	}
+	key, err := m.publicKey()
+	if err != nil {
+		return "", err
+	}
+	if err := verifyMinisign(key, payload, raw); err != nil {
+		return "", err
+	}
+	return key.ID(), nil
+}
+
+func (m *Minisign) trusts(signer string) error {
+	key, err := m.publicKey()
+	if err != nil {
+		return err
+	}
+	if key.ID() != signer {
+		return fmt.Errorf("signed by key %s, configured key is %s", signer, key.ID())
+	}
+	return nil
+}
+
+// publicKey accepts the base64 key itself or a path to a minisign.pub file
+// ("untrusted comment: ..." followed by the base64 key).
+func (m *Minisign) publicKey() (*minisignKey, error) {
+	if key, err := parseMinisignKey(m.Key); err == nil {
+		return key, nil
+	}
+	data, err := os.ReadFile(m.Key)
+	if err != nil {
+		return nil, fmt.Errorf("reading key: %w", err)
+	}
+	lines := minisignLines(data)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty key file %s", m.Key)
+	}
+	return parseMinisignKey(lines[len(lines)-1])
+}
+
+func parseMinisignKey(s string) (*minisignKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, fmt.Errorf("invalid minisign public key")
+	}
+	key := &minisignKey{pub: ed25519.PublicKey(raw[10:])}
+	copy(key.id[:], raw[2:10])
+	return key, nil
+}
+
+// verifyMinisign checks a .minisig file: the signature over the payload
+// ("Ed" legacy, or "ED" over its BLAKE2b-512 prehash) and the global
+// signature binding the trusted comment to it.
+func verifyMinisign(key *minisignKey, payload, sigFile []byte) error {
+	lines := minisignLines(sigFile)
+	if len(lines) < 2 {
+		return fmt.Errorf("malformed .minisig")
+	}
+	sig, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed .minisig signature line")
+	}
+	if !bytes.Equal(sig[2:10], key.id[:]) {
+		return fmt.Errorf("signed by key %016X, configured key is %s",
+			binary.LittleEndian.Uint64(sig[2:10]), key.ID())
+	}
+	message := payload
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(payload)
+		message = sum[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", sig[:2])
+	}
+	if !ed25519.Verify(key.pub, message, sig[10:]) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	// Trusted comment + global signature are optional in very old files.
+	if len(lines) < 3 {
+		return nil
+	}
+	comment, ok := strings.CutPrefix(lines[1], "trusted comment: ")
+	if !ok {
+		return fmt.Errorf("malformed .minisig trusted comment")
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil || len(global) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed .minisig global signature")
+	}
+	if !ed25519.Verify(key.pub, append(append([]byte{}, sig[10:]...), comment...), global) {
+		return fmt.Errorf("invalid trusted comment signature")
+	}
+	return nil
+}
+
+// minisignLines returns the non-empty lines of a minisign file, minus the
+// leading "untrusted comment:" line.
+func minisignLines(data []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,479 @@
+package signature
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"golang.org/x/crypto/blake2b"
+)
+
+var payload = []byte("release asset bytes")
+
+func ecdsaKeyPEM(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func cosignSign(t *testing.T, priv *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, priv, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+func TestCosignKey(t *testing.T) {
+	priv, pubPEM := ecdsaKeyPEM(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cosign.pub"), []byte(pubPEM), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := (&Config{Cosign: &Cosign{Key: "cosign.pub"}}).Resolve(dir)
+
+	results, err := cfg.Verify(payload, Material{".sig": cosignSign(t, priv, payload)})
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(results) != 1 || results[0].Verifier != VerifierCosign || !strings.HasPrefix(results[0].Signer, "sha256:") {
+		t.Fatalf("results = %+v", results)
+	}
+	if err := cfg.Check(results); err != nil {
+		t.Errorf("Check: %v", err)
+	}
+
+	if _, err := cfg.Verify([]byte("tampered"), Material{".sig": cosignSign(t, priv, payload)}); err == nil {
+		t.Error("expected tampered payload to fail")
+	}
+	if _, err := cfg.Verify(payload, Material{}); err == nil {
+		t.Error("expected missing signature to fail")
+	}
+
+	// A different key must neither verify nor satisfy Check.
+	_, otherPEM := ecdsaKeyPEM(t)
+	other := &Config{Cosign: &Cosign{Key: otherPEM}}
+	if _, err := other.Verify(payload, Material{".sig": cosignSign(t, priv, payload)}); err == nil {
+		t.Error("expected wrong key to fail")
+	}
+	if err := other.Check(results); err == nil {
+		t.Error("expected Check to reject a signer that isn't the configured key")
+	}
+}
+
+// fulcio issues a short-lived code-signing certificate for identity, the
+// way Fulcio does for keyless signing.
+type fulcio struct {
+	root     *x509.Certificate
+	rootKey  *ecdsa.PrivateKey
+	rootPEM  string
+	notAfter time.Time
+}
+
+func newFulcio(t *testing.T) *fulcio {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(der)
+	return &fulcio{
+		root:    root,
+		rootKey: key,
+		rootPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	}
+}
+
+func (f *fulcio) issue(t *testing.T, identity, issuer string, notBefore time.Time) (*ecdsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuerDER, _ := asn1.Marshal(issuer)
+	u, _ := url.Parse(identity)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    notBefore,
+		NotAfter:     notBefore.Add(10 * time.Minute),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:         []*url.URL{u},
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidIssuerV2, Value: issuerDER},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, f.root, &key.PublicKey, f.rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, der
+}
+
+// rekorLog is a test transparency log that signs entry timestamps.
+type rekorLog struct {
+	key    *ecdsa.PrivateKey
+	keyPEM string
+	logID  string
+}
+
+func newRekorLog(t *testing.T) *rekorLog {
+	t.Helper()
+	key, keyPEM := ecdsaKeyPEM(t)
+	block, _ := pem.Decode([]byte(keyPEM))
+	return &rekorLog{key: key, keyPEM: keyPEM, logID: fmt.Sprintf("%x", sha256.Sum256(block.Bytes))}
+}
+
+// entry logs a hashedrekord of rawSig by certPEM over data at time at and
+// returns the canonicalized body and its signed entry timestamp.
+func (r *rekorLog) entry(t *testing.T, data, rawSig, certPEM []byte, at int64) (body, set []byte) {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	body, _ = json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data": map[string]interface{}{"hash": map[string]string{"algorithm": "sha256", "value": fmt.Sprintf("%x", sum)}},
+			"signature": map[string]interface{}{
+				"content":   rawSig,
+				"publicKey": map[string]interface{}{"content": certPEM},
+			},
+		},
+	})
+	signed, _ := json.Marshal(map[string]interface{}{
+		"body":           base64.StdEncoding.EncodeToString(body),
+		"integratedTime": at,
+		"logID":          r.logID,
+		"logIndex":       42,
+	})
+	set, _ = base64.StdEncoding.DecodeString(string(cosignSign(t, r.key, signed)))
+	return body, set
+}
+
+// legacyBundle is a `cosign sign-blob --bundle` file for the entry.
+func (r *rekorLog) legacyBundle(sig, certPEM, body, set []byte, at int64) []byte {
+	b, _ := json.Marshal(map[string]interface{}{
+		"base64Signature": string(sig),
+		"cert":            base64.StdEncoding.EncodeToString(certPEM),
+		"rekorBundle": map[string]interface{}{
+			"SignedEntryTimestamp": set,
+			"Payload": map[string]interface{}{
+				"body":           base64.StdEncoding.EncodeToString(body),
+				"integratedTime": at,
+				"logIndex":       42,
+				"logID":          r.logID,
+			},
+		},
+	})
+	return b
+}
+
+func TestCosignKeyless(t *testing.T) {
+	const (
+		identity = "https://github.com/org/tool/.github/workflows/release.yml@refs/tags/v1.0.0"
+		issuer   = "https://token.actions.githubusercontent.com"
+	)
+	f := newFulcio(t)
+	// The certificate already expired; the log timestamp proves it was
+	// valid when the signature was made.
+	signedAt := time.Now().Add(-30 * time.Minute)
+	key, certDER := f.issue(t, identity, issuer, signedAt.Add(-time.Minute))
+	sig := cosignSign(t, key, payload)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+
+	rawSig, _ := base64.StdEncoding.DecodeString(string(sig))
+	rekor := newRekorLog(t)
+	body, set := rekor.entry(t, payload, rawSig, certPEM, signedAt.Unix())
+
+	dir := t.TempDir()
+	for file, data := range map[string]string{"fulcio.pem": f.rootPEM, "rekor.pub": rekor.keyPEM} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := (&Config{Cosign: &Cosign{Identity: identity, Issuer: issuer, TrustedRoot: "fulcio.pem", RekorKey: "rekor.pub"}}).Resolve(dir)
+
+	legacy := rekor.legacyBundle(sig, certPEM, body, set, signedAt.Unix())
+	logID, _ := hex.DecodeString(rekor.logID)
+	sum := sha256.Sum256(payload)
+	sigstore, _ := json.Marshal(map[string]interface{}{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]interface{}{
+			"certificate": map[string]interface{}{"rawBytes": certDER},
+			"tlogEntries": []map[string]interface{}{{
+				"logIndex":          "42",
+				"logId":             map[string]interface{}{"keyId": logID},
+				"integratedTime":    fmt.Sprint(signedAt.Unix()),
+				"inclusionPromise":  map[string]interface{}{"signedEntryTimestamp": set},
+				"canonicalizedBody": body,
+			}},
+		},
+		"messageSignature": map[string]interface{}{
+			"messageDigest": map[string]interface{}{"algorithm": "SHA2_256", "digest": sum[:]},
+			"signature":     rawSig,
+		},
+	})
+
+	for name, m := range map[string]Material{
+		"legacy bundle":   {".bundle": legacy},
+		"sigstore bundle": {".bundle": sigstore},
+	} {
+		t.Run(name, func(t *testing.T) {
+			results, err := cfg.Verify(payload, m)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if results[0].Signer != identity {
+				t.Errorf("signer = %q, want %q", results[0].Signer, identity)
+			}
+			if err := cfg.Check(results); err != nil {
+				t.Errorf("Check: %v", err)
+			}
+		})
+	}
+
+	wrong := []struct {
+		name string
+		cfg  *Cosign
+	}{
+		{"identity", &Cosign{Identity: "https://github.com/evil/tool", Issuer: issuer, TrustedRoot: f.rootPEM, RekorKey: rekor.keyPEM}},
+		{"issuer", &Cosign{Identity: identity, Issuer: "https://accounts.google.com", TrustedRoot: f.rootPEM, RekorKey: rekor.keyPEM}},
+		{"root", &Cosign{Identity: identity, Issuer: issuer, TrustedRoot: newFulcio(t).rootPEM, RekorKey: rekor.keyPEM}},
+		{"no root", &Cosign{Identity: identity, Issuer: issuer, RekorKey: rekor.keyPEM}},
+		{"rekor key", &Cosign{Identity: identity, Issuer: issuer, TrustedRoot: f.rootPEM, RekorKey: newRekorLog(t).keyPEM}},
+		{"no rekor key", &Cosign{Identity: identity, Issuer: issuer, TrustedRoot: f.rootPEM}},
+	}
+	for _, tt := range wrong {
+		t.Run("wrong "+tt.name, func(t *testing.T) {
+			if _, err := (&Config{Cosign: tt.cfg}).Verify(payload, Material{".bundle": legacy}); err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+
+	// The certificate is expired now, so only a log entry the log signed
+	// for this very signature may vouch for when it was made.
+	otherBody, otherSET := rekor.entry(t, []byte("other file"), rawSig, certPEM, signedAt.Unix())
+	lateBody, lateSET := rekor.entry(t, payload, rawSig, certPEM, time.Now().Unix())
+	forged := []struct {
+		name string
+		m    Material
+	}{
+		{"late log entry", Material{".bundle": rekor.legacyBundle(sig, certPEM, lateBody, lateSET, time.Now().Unix())}},
+		{"no log entry", Material{".sig": sig, ".pem": []byte(base64.StdEncoding.EncodeToString(certPEM))}},
+		{"tampered integratedTime", Material{".bundle": rekor.legacyBundle(sig, certPEM, lateBody, lateSET, signedAt.Unix())}},
+		{"no signed entry timestamp", Material{".bundle": rekor.legacyBundle(sig, certPEM, body, nil, signedAt.Unix())}},
+		{"entry for another file", Material{".bundle": rekor.legacyBundle(sig, certPEM, otherBody, otherSET, signedAt.Unix())}},
+	}
+	for _, tt := range forged {
+		t.Run("forged "+tt.name, func(t *testing.T) {
+			if _, err := cfg.Verify(payload, tt.m); err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+func TestVerifyImage(t *testing.T) {
+	priv, pubPEM := ecdsaKeyPEM(t)
+	cfg := &Config{Cosign: &Cosign{Key: pubPEM}}
+	digest := "sha256:" + strings.Repeat("a", 64)
+	simple := []byte(`{"critical":{"identity":{"docker-reference":"ghcr.io/org/img"},"image":{"docker-manifest-digest":"` + digest + `"},"type":"cosign container image signature"},"optional":null}`)
+	sig := string(cosignSign(t, priv, simple))
+
+	results, err := cfg.VerifyImage(digest, simple, sig, nil, nil)
+	if err != nil {
+		t.Fatalf("VerifyImage: %v", err)
+	}
+	if results[0].Signed != digest {
+		t.Errorf("signed = %q, want %q", results[0].Signed, digest)
+	}
+	// A valid signature for another digest must not be accepted.
+	if _, err := cfg.VerifyImage("sha256:"+strings.Repeat("b", 64), simple, sig, nil, nil); err == nil {
+		t.Error("expected digest mismatch to fail")
+	}
+	if _, err := (&Config{GPG: &GPG{Keyring: "x"}}).VerifyImage(digest, simple, sig, nil, nil); err == nil {
+		t.Error("expected gpg on images to be rejected")
+	}
+}
+
+func TestMinisign(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	pubKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+
+	minisig := func(alg string, data []byte, comment string) []byte {
+		msg := data
+		if alg == "ED" {
+			sum := blake2b.Sum512(data)
+			msg = sum[:]
+		}
+		sig := ed25519.Sign(priv, msg)
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+		return []byte("untrusted comment: signature from minisign secret key\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte(alg), keyID...), sig...)) + "\n" +
+			"trusted comment: " + comment + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "minisign.pub"),
+		[]byte("untrusted comment: minisign public key 0807060504030201\n"+pubKey+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, cfg := range map[string]*Config{
+		"inline": {Minisign: &Minisign{Key: pubKey}},
+		"file":   (&Config{Minisign: &Minisign{Key: "minisign.pub"}}).Resolve(dir),
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, alg := range []string{"ED", "Ed"} {
+				results, err := cfg.Verify(payload, Material{".minisig": minisig(alg, payload, "timestamp:1 file:tool")})
+				if err != nil {
+					t.Fatalf("%s: %v", alg, err)
+				}
+				if results[0].Signer != "0807060504030201" {
+					t.Errorf("signer = %q", results[0].Signer)
+				}
+				if err := cfg.Check(results); err != nil {
+					t.Errorf("Check: %v", err)
+				}
+			}
+		})
+	}
+
+	cfg := &Config{Minisign: &Minisign{Key: pubKey}}
+	if _, err := cfg.Verify([]byte("tampered"), Material{".minisig": minisig("ED", payload, "c")}); err == nil {
+		t.Error("expected tampered payload to fail")
+	}
+	forged := bytes.Replace(minisig("ED", payload, "c"), []byte("trusted comment: c"), []byte("trusted comment: d"), 1)
+	if _, err := cfg.Verify(payload, Material{".minisig": forged}); err == nil {
+		t.Error("expected forged trusted comment to fail")
+	}
+	if err := cfg.Check([]Result{{Verifier: VerifierMinisign, Signer: "FFFFFFFFFFFFFFFF"}}); err == nil {
+		t.Error("expected Check to reject another key id")
+	}
+}
+
+func TestGPG(t *testing.T) {
+	entity, err := openpgp.NewEntity("Release Signer", "", "release@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keyring bytes.Buffer
+	w, err := armor.Encode(&keyring, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	var armored, binarySig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&armored, entity, bytes.NewReader(payload), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := openpgp.DetachSign(&binarySig, entity, bytes.NewReader(payload), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &Config{GPG: &GPG{Keyring: keyring.String()}}
+	want := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+	for name, m := range map[string]Material{
+		"armored": {".asc": armored.Bytes()},
+		"binary":  {".sig": binarySig.Bytes()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			results, err := cfg.Verify(payload, m)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if results[0].Signer != want {
+				t.Errorf("signer = %q, want %q", results[0].Signer, want)
+			}
+			if err := cfg.Check(results); err != nil {
+				t.Errorf("Check: %v", err)
+			}
+		})
+	}
+	if _, err := cfg.Verify([]byte("tampered"), Material{".asc": armored.Bytes()}); err == nil {
+		t.Error("expected tampered payload to fail")
+	}
+
+	// Next to cosign, a ".sig" is cosign's: GPG reads its own companions only.
+	both := &Config{Cosign: &Cosign{}, GPG: cfg.GPG}
+	m := Material{".sig": []byte("cosign signature"), ".gpg": binarySig.Bytes()}
+	if signer, err := both.GPG.verify(payload, m, both.gpgSuffixes()); err != nil || signer != want {
+		t.Errorf("verify() with cosign = %q, %v; want the .gpg signature verified", signer, err)
+	}
+	if _, err := both.GPG.verify(payload, Material{".sig": binarySig.Bytes()}, both.gpgSuffixes()); err == nil || !strings.Contains(err.Error(), "no .asc or .gpg published") {
+		t.Errorf("verify() with cosign and only a .sig: %v", err)
+	}
+}
+
+func TestConfig_Check(t *testing.T) {
+	cfg := &Config{Minisign: &Minisign{Key: "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3"}}
+	if err := cfg.Check(nil); err == nil || !strings.Contains(err.Error(), "no minisign signature recorded") {
+		t.Errorf("expected missing-record error, got %v", err)
+	}
+	var empty *Config
+	if !empty.IsZero() || len(empty.Verifiers()) != 0 {
+		t.Error("nil config must be empty")
+	}
+	if err := empty.Check(nil); err != nil {
+		t.Errorf("nil config Check: %v", err)
+	}
+}
+
+func TestConfig_Companions(t *testing.T) {
+	cfg := &Config{Cosign: &Cosign{}, GPG: &GPG{}}
+	got := strings.Join(cfg.Companions(), " ")
+	if got != ".bundle .sig .pem .cert .asc .gpg" {
+		t.Errorf("Companions() = %q", got)
+	}
+}
@@ -1,12 +1,14 @@
 package state
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/path"
+	"github.com/fentas/b/pkg/provider"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,6 +34,22 @@ func LoadConfigFromPath(configPath string) (*State, error) {
 		if b.File != "" && !filepath.IsAbs(b.File) {
 			b.File = filepath.Join(configDir, b.File)
 		}
+		if b.Channel != "" {
+			ch, err := provider.ParseChannel(b.Channel)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", configPath, b.Name, err)
+			}
+			b.Channel = string(ch)
+		}
+		if err := b.ValidateLayout(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", configPath, b.Name, err)
+		}
+		if err := b.Build.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", configPath, b.Name, err)
+		}
+		if err := b.ValidateImage(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", configPath, b.Name, err)
+		}
 	}
 
 	return &state, nil
@@ -196,3 +196,115 @@ func mustFile(s *State, name string) string {
 	}
 	return ""
 }
+
+func TestLoadConfig_Channel(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	if err := os.WriteFile(configPath, []byte("binaries:\n  github.com/derailed/k9s:\n    channel: Prerelease\n  jq: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Binaries.Get("github.com/derailed/k9s").Channel; got != "prerelease" {
+		t.Errorf("channel = %q, want prerelease", got)
+	}
+	if err := SaveConfig(config, configPath); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "channel: prerelease") || strings.Count(string(data), "channel:") != 1 {
+		t.Errorf("saved config:\n%s", data)
+	}
+
+	if err := os.WriteFile(configPath, []byte("binaries:\n  jq:\n    channel: beta\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFromPath(configPath); err == nil || !strings.Contains(err.Error(), `unknown channel "beta"`) {
+		t.Errorf("err = %v, want unknown channel", err)
+	}
+}
+
+func TestLoadConfig_Extract(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	if err := os.WriteFile(configPath, []byte("binaries:\n  github.com/cilium/cilium-cli:\n    extract:\n      - hubble\n      - member: bin/cilium-dbg\n        as: dbg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := config.Binaries.Get("github.com/cilium/cilium-cli").Extract
+	if len(got) != 2 || got[0].Name() != "hubble" || got[1].Name() != "dbg" {
+		t.Fatalf("extract = %v", got)
+	}
+	if err := SaveConfig(config, configPath); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "- hubble\n") || !strings.Contains(string(data), "as: dbg") {
+		t.Errorf("saved config:\n%s", data)
+	}
+}
+
+func TestLoadConfig_Layout(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	if err := os.WriteFile(configPath, []byte("binaries:\n  github.com/hashicorp/packer:\n    layout: tree\n    entrypoint: bin/packer\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := config.Binaries.Get("github.com/hashicorp/packer")
+	if got.Layout != "tree" || got.Entrypoint != "bin/packer" {
+		t.Fatalf("layout = %q, entrypoint = %q", got.Layout, got.Entrypoint)
+	}
+	if err := SaveConfig(config, configPath); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "layout: tree") || !strings.Contains(string(data), "entrypoint: bin/packer") {
+		t.Errorf("saved config:\n%s", data)
+	}
+
+	if err := os.WriteFile(configPath, []byte("binaries:\n  packer:\n    layout: tree\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFromPath(configPath); err == nil || !strings.Contains(err.Error(), "packer: layout: tree needs an entrypoint") {
+		t.Errorf("LoadConfigFromPath() error = %v, want the missing entrypoint", err)
+	}
+}
+
+func TestLoadConfig_Image(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "b.yaml")
+	if err := os.WriteFile(configPath, []byte("binaries:\n  docker://ghcr.io/org/tool@v1:\n    runtime: podman\n    paths:\n      - /usr/local/bin/tool\n      - /usr/local/lib/tool/plugins\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := config.Binaries.Get("docker://ghcr.io/org/tool@v1")
+	if got.Runtime != "podman" || len(got.Paths) != 2 {
+		t.Fatalf("runtime = %q, paths = %v", got.Runtime, got.Paths)
+	}
+	if err := SaveConfig(config, configPath); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(configPath)
+	if !strings.Contains(string(data), "runtime: podman") || !strings.Contains(string(data), "- /usr/local/lib/tool/plugins") {
+		t.Errorf("saved config:\n%s", data)
+	}
+
+	if err := os.WriteFile(configPath, []byte("binaries:\n  docker://tool:\n    runtime: lxc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfigFromPath(configPath); err == nil || !strings.Contains(err.Error(), `docker://tool: unknown runtime "lxc"`) {
+		t.Errorf("LoadConfigFromPath() error = %v, want the unknown runtime", err)
+	}
+}
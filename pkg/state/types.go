@@ -7,12 +7,24 @@ import (
 
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/envmatch"
+	"github.com/fentas/b/pkg/hosts"
+	"github.com/fentas/b/pkg/httpclient"
 )
 
 type State struct {
 	Binaries BinaryList `yaml:"binaries"`
 	Envs     EnvList    `yaml:"envs,omitempty"`
 	Profiles EnvList    `yaml:"profiles,omitempty"` // short-name profiles for upstream repos
+	Presets  []string   `yaml:"presets,omitempty"`  // preset files or directories, relative to b.yaml
+	// Aqua lists the aqua registries aqua: refs are looked up in: a
+	// registry.yaml, a directory relative to b.yaml, or a git repository.
+	Aqua []string `yaml:"aqua,omitempty"`
+	// Hosts maps self-hosted forge hostnames (GitHub Enterprise, GitLab,
+	// Gitea) to their type, API base URL and token env var.
+	Hosts map[string]*hosts.Host `yaml:"hosts,omitempty"`
+	// Network configures timeouts, retries, proxy and CA bundles for
+	// every HTTP request b makes.
+	Network *httpclient.Config `yaml:"network,omitempty"`
 }
 
 // EnvEntry is a single env in b.yaml.
@@ -299,6 +311,22 @@ func (s *State) MarshalYAML() (interface{}, error) {
 		result["profiles"] = profiles
 	}
 
+	if len(s.Presets) > 0 {
+		result["presets"] = s.Presets
+	}
+
+	if len(s.Aqua) > 0 {
+		result["aqua"] = s.Aqua
+	}
+
+	if len(s.Hosts) > 0 {
+		result["hosts"] = s.Hosts
+	}
+
+	if s.Network != nil {
+		result["network"] = s.Network
+	}
+
 	return result, nil
 }
 
@@ -343,7 +371,7 @@ func (list *BinaryList) MarshalYAML() (interface{}, error) {
 	for _, b := range *list {
 		if b.Name != "" {
 			// Build the binary configuration
-			config := make(map[string]string)
+			config := make(map[string]interface{})
 
 			// Emit 'version:' — the requested version (loaded from YAML
 			// 'version:' or set by 'b install --add <ref>@<tag>').
@@ -377,6 +405,47 @@ func (list *BinaryList) MarshalYAML() (interface{}, error) {
 				config["onPost"] = b.OnPost
 			}
 
+			// Signature policy is user-authored; round-trip it as-is
+			if !b.Verify.IsZero() {
+				config["verify"] = b.Verify
+			}
+
+			// Templated URL settings are user-authored; round-trip as-is
+			if b.HTTP != nil {
+				config["http"] = b.HTTP
+			}
+
+			// Release channel followed when no version is pinned
+			if b.Channel != "" {
+				config["channel"] = b.Channel
+			}
+
+			// Further files installed from the release archive
+			if len(b.Extract) > 0 {
+				config["extract"] = b.Extract
+			}
+
+			// Install layout and the entrypoint of a tree
+			if b.Layout != "" {
+				config["layout"] = b.Layout
+			}
+			if b.Entrypoint != "" {
+				config["entrypoint"] = b.Entrypoint
+			}
+
+			// Build options of go:// refs are user-authored; round-trip as-is
+			if !b.Build.IsZero() {
+				config["build"] = b.Build
+			}
+
+			// Container runtime and the paths copied out of an image
+			if b.Runtime != "" {
+				config["runtime"] = b.Runtime
+			}
+			if len(b.Paths) > 0 {
+				config["paths"] = b.Paths
+			}
+
 			// If we have any configuration, use it; otherwise use empty struct
 			if len(config) > 0 {
 				result[b.Name] = config
@@ -2,6 +2,7 @@ package state
 
 import (
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -337,30 +338,30 @@ func TestBinaryListMarshalYAML(t *testing.T) {
 	}
 
 	// jq should have enforced (not version — those are distinct now)
-	jqCfg, ok := m["jq"].(map[string]string)
+	jqCfg, ok := m["jq"].(map[string]interface{})
 	if !ok {
-		t.Fatal("jq config should be map[string]string")
+		t.Fatal("jq config should be map[string]interface{}")
 	}
 	if jqCfg["enforced"] != "jq-1.7" {
 		t.Errorf("jq enforced = %q, want %q", jqCfg["enforced"], "jq-1.7")
 	}
-	if jqCfg["version"] != "" {
+	if jqCfg["version"] != nil {
 		t.Errorf("jq version = %q, want empty (Enforced shouldn't leak into version)", jqCfg["version"])
 	}
 
 	// envsubst should have alias
-	esCfg, ok := m["envsubst"].(map[string]string)
+	esCfg, ok := m["envsubst"].(map[string]interface{})
 	if !ok {
-		t.Fatal("envsubst config should be map[string]string")
+		t.Fatal("envsubst config should be map[string]interface{}")
 	}
 	if esCfg["alias"] != "renvsubst" {
 		t.Errorf("envsubst alias = %q, want %q", esCfg["alias"], "renvsubst")
 	}
 
 	// kubectl should have file
-	kCfg, ok := m["kubectl"].(map[string]string)
+	kCfg, ok := m["kubectl"].(map[string]interface{})
 	if !ok {
-		t.Fatal("kubectl config should be map[string]string")
+		t.Fatal("kubectl config should be map[string]interface{}")
 	}
 	if kCfg["file"] != "/usr/local/bin/kubectl" {
 		t.Errorf("kubectl file = %q", kCfg["file"])
@@ -421,11 +422,12 @@ func TestBinaryListMarshalYAML_VersionRoundTrip(t *testing.T) {
 			if !ok {
 				t.Fatalf("entry %q missing from MarshalYAML output: %v", tc.in.Name, root)
 			}
-			cfg, ok := raw.(map[string]string)
+			cfg, ok := raw.(map[string]interface{})
 			if !ok {
-				t.Fatalf("entry %q has type %T, want map[string]string (value=%v)", tc.in.Name, raw, raw)
+				t.Fatalf("entry %q has type %T, want map[string]interface{} (value=%v)", tc.in.Name, raw, raw)
 			}
-			gotVersion, gotEnforced := cfg["version"], cfg["enforced"]
+			gotVersion, _ := cfg["version"].(string)
+			gotEnforced, _ := cfg["enforced"].(string)
 			if gotVersion != tc.wantVersion {
 				t.Errorf("'version:' = %q, want %q (cfg=%v)", gotVersion, tc.wantVersion, cfg)
 			}
@@ -589,6 +591,80 @@ func TestBinaryListMarshalYAML_OnPostRoundTrip(t *testing.T) {
 	}
 }
 
+func TestBinaryListMarshalYAML_VerifyRoundTrip(t *testing.T) {
+	input := `github.com/sigstore/cosign:
+  verify:
+    cosign:
+      identity: keyless@projectsigstore.iam.gserviceaccount.com
+      issuer: https://accounts.google.com
+      trustedRoot: .keys/fulcio.pem
+jedisct1/minisign:
+  verify:
+    minisign:
+      key: RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3
+`
+	var list BinaryList
+	if err := yaml.Unmarshal([]byte(input), &list); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, err := yaml.Marshal(&list)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var list2 BinaryList
+	if err := yaml.Unmarshal(data, &list2); err != nil {
+		t.Fatalf("unmarshal round-trip: %v", err)
+	}
+	cosign := list2.Get("github.com/sigstore/cosign")
+	if cosign == nil || cosign.Verify == nil || cosign.Verify.Cosign == nil {
+		t.Fatalf("cosign verify block lost:\n%s", data)
+	}
+	if got := cosign.Verify.Cosign.Issuer; got != "https://accounts.google.com" {
+		t.Errorf("issuer = %q", got)
+	}
+	minisign := list2.Get("jedisct1/minisign")
+	if minisign == nil || minisign.Verify == nil || minisign.Verify.Minisign == nil ||
+		minisign.Verify.Minisign.Key != "RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3" {
+		t.Errorf("minisign verify block lost:\n%s", data)
+	}
+}
+
+func TestBinaryListMarshalYAML_HTTPRoundTrip(t *testing.T) {
+	key := "https://dl.example.com/tool/{{.Version}}/tool_{{.OS}}_{{.Arch}}.tar.gz"
+	input := `"` + key + `":
+  version: v1.4.2
+  http:
+    arch:
+      amd64: x86_64
+    latest:
+      url: https://dl.example.com/tool/latest.json
+      json: tag_name
+    member: tool_{{.OS}}_{{.Arch}}/tool
+`
+	var list BinaryList
+	if err := yaml.Unmarshal([]byte(input), &list); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if b := list.Get(key); b == nil || !b.IsProviderRef {
+		t.Fatalf("templated URL should be a provider ref: %+v", b)
+	}
+	data, err := yaml.Marshal(&list)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var list2 BinaryList
+	if err := yaml.Unmarshal(data, &list2); err != nil {
+		t.Fatalf("unmarshal round-trip: %v", err)
+	}
+	b := list2.Get(key)
+	if b == nil || b.HTTP == nil || b.HTTP.Latest == nil {
+		t.Fatalf("http block lost:\n%s", data)
+	}
+	if b.HTTP.Arch["amd64"] != "x86_64" || b.HTTP.Latest.JSON != "tag_name" || b.HTTP.Member != "tool_{{.OS}}_{{.Arch}}/tool" {
+		t.Errorf("http block changed: %+v\n%s", b.HTTP, data)
+	}
+}
+
 func TestBinaryListUnmarshalYAML_NilBinary(t *testing.T) {
 	input := `
 terraform:
@@ -670,6 +746,92 @@ envs:
 	}
 }
 
+func TestStatePresets_RoundTrip(t *testing.T) {
+	input := `
+presets:
+  - presets/
+  - tools.yaml
+binaries:
+  mytool: {}
+`
+	var s State
+	if err := yaml.Unmarshal([]byte(input), &s); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(s.Presets) != 2 || s.Presets[0] != "presets/" || s.Presets[1] != "tools.yaml" {
+		t.Fatalf("presets = %v", s.Presets)
+	}
+	data, err := yaml.Marshal(&s)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var s2 State
+	if err := yaml.Unmarshal(data, &s2); err != nil {
+		t.Fatalf("unmarshal round-trip: %v", err)
+	}
+	if len(s2.Presets) != 2 {
+		t.Errorf("presets lost:\n%s", data)
+	}
+	if !managedKey(nil, "presets") {
+		t.Error("managedKey([], \"presets\") = false — removed presets would linger on save")
+	}
+
+	empty, err := (&State{}).MarshalYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := empty.(map[string]interface{})["presets"]; ok {
+		t.Error("should not have presets key when empty")
+	}
+}
+
+func TestStateHosts_RoundTrip(t *testing.T) {
+	input := `
+hosts:
+  github.example.corp:
+    type: github
+  gitlab.example.corp:
+    type: gitlab
+    api: https://gitlab.example.corp/api/v4
+    tokenEnv: CORP_GITLAB_TOKEN
+binaries:
+  github.example.corp/org/tool: {}
+`
+	var s State
+	if err := yaml.Unmarshal([]byte(input), &s); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	gl := s.Hosts["gitlab.example.corp"]
+	if len(s.Hosts) != 2 || gl == nil || gl.Type != "gitlab" || gl.TokenEnv != "CORP_GITLAB_TOKEN" {
+		t.Fatalf("hosts = %+v", s.Hosts)
+	}
+	data, err := yaml.Marshal(&s)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var s2 State
+	if err := yaml.Unmarshal(data, &s2); err != nil {
+		t.Fatalf("unmarshal round-trip: %v", err)
+	}
+	if gl2 := s2.Hosts["gitlab.example.corp"]; gl2 == nil || *gl2 != *gl {
+		t.Errorf("hosts changed:\n%s", data)
+	}
+
+	// Every key the marshaler emits must be managed, so removed hosts and
+	// fields don't linger on save.
+	if !managedKey(nil, "hosts") || !managedKey([]string{"hosts"}, "gitlab.example.corp") {
+		t.Error("hosts section not managed")
+	}
+	for _, key := range []string{"type", "api", "tokenEnv"} {
+		if !managedKey([]string{"hosts", "gitlab.example.corp"}, key) {
+			t.Errorf("managedKey([hosts <name>], %q) = false", key)
+		}
+	}
+	if managedKey([]string{"hosts", "gitlab.example.corp"}, "owner") {
+		t.Error("user custom field under hosts must be preserved")
+	}
+}
+
 func TestEnvListMarshalYAML_AllForms(t *testing.T) {
 	list := EnvList{
 		{Key: "github.com/org/empty"},                                 // bare entry
@@ -776,3 +938,45 @@ func containsStr(s, sub string) bool {
 	}
 	return false
 }
+
+func TestStateNetwork_RoundTrip(t *testing.T) {
+	input := `
+network:
+  timeout: 10s
+  retries: 0
+  proxy: http://proxy.example.corp:3128
+  noProxy: [.example.corp]
+  caFiles: [certs/corp.pem]
+  userAgent: corp-ci/1.0
+binaries:
+  jq: {}
+`
+	var s State
+	if err := yaml.Unmarshal([]byte(input), &s); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	n := s.Network
+	if n == nil || n.Timeout != "10s" || n.Retries == nil || *n.Retries != 0 || n.Proxy != "http://proxy.example.corp:3128" {
+		t.Fatalf("network = %+v", n)
+	}
+	data, err := yaml.Marshal(&s)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var s2 State
+	if err := yaml.Unmarshal(data, &s2); err != nil {
+		t.Fatalf("unmarshal round-trip: %v", err)
+	}
+	if !reflect.DeepEqual(s2.Network, n) {
+		t.Errorf("network changed:\n%s", data)
+	}
+
+	for _, key := range []string{"timeout", "retries", "proxy", "noProxy", "caFiles", "userAgent"} {
+		if !managedKey([]string{"network"}, key) {
+			t.Errorf("managedKey([network], %q) = false", key)
+		}
+	}
+	if !managedKey(nil, "network") || managedKey([]string{"network"}, "comment") {
+		t.Error("network section managed wrongly")
+	}
+}
@@ -17,15 +17,26 @@ func managedKey(path []string, key string) bool {
 	switch len(path) {
 	case 0:
 		// File root — b owns these top-level sections.
-		return key == "binaries" || key == "envs" || key == "profiles"
+		switch key {
+		case "binaries", "envs", "profiles", "presets", "aqua", "hosts", "network":
+			return true
+		}
+		return false
 	case 1:
 		// One level in; the previous level decides the schema:
 		//   binaries.<name>   — always managed (map entries are b's list)
 		//   envs.<name>       — always managed
 		//   profiles.<name>   — always managed
+		//   hosts.<name>      — always managed
+		//   network.<field>   — the httpclient.Config fields
 		switch path[0] {
-		case "binaries", "envs", "profiles":
+		case "binaries", "envs", "profiles", "hosts":
 			return true
+		case "network":
+			switch key {
+			case "timeout", "retries", "proxy", "noProxy", "caFiles", "userAgent":
+				return true
+			}
 		}
 		return false
 	case 2:
@@ -37,7 +48,15 @@ func managedKey(path []string, key string) bool {
 		case "binaries":
 			// Matches BinaryList.MarshalYAML.
 			switch key {
-			case "version", "enforced", "alias", "file", "asset", "onPost":
+			case "version", "enforced", "alias", "file", "asset", "onPost", "verify", "http", "channel",
+				"extract", "layout", "entrypoint", "build", "runtime", "paths":
+				return true
+			}
+			return false
+		case "hosts":
+			// Matches hosts.Host serialization.
+			switch key {
+			case "type", "api", "tokenEnv":
 				return true
 			}
 			return false
@@ -53,24 +72,36 @@ func managedKey(path []string, key string) bool {
 		}
 		return false
 	case 3:
-		// Three levels in — the only nested schema b owns here is
-		// envs.<name>.files.<glob> (same under profiles). The glob key
-		// itself is managed (envmatch operates on it) so deletions
-		// propagate, but deeper keys fall through to the default below.
+		// Three levels in — the nested schemas b owns here are
+		// envs.<name>.files.<glob> (same under profiles) and
+		// binaries.<name>.build. The glob key itself is managed (envmatch
+		// operates on it) so deletions propagate, but deeper keys fall
+		// through to the default below.
 		if (path[0] == "envs" || path[0] == "profiles") && path[2] == "files" {
 			return true
 		}
+		if path[0] == "binaries" && path[2] == "build" {
+			// Matches provider.GoBuild.
+			switch key {
+			case "tags", "ldflags", "flags", "env", "toolchain", "reproducible":
+				return true
+			}
+		}
 		return false
 	case 4:
 		// Four levels in — envs.<name>.files.<glob>.<field>. Only the
 		// fields the marshaler emits (dest/ignore/select) are managed;
-		// any other key under a file entry is preserved.
+		// any other key under a file entry is preserved. Every variable
+		// of binaries.<name>.build.env is b's.
 		if (path[0] == "envs" || path[0] == "profiles") && path[2] == "files" {
 			switch key {
 			case "dest", "ignore", "select":
 				return true
 			}
 		}
+		if path[0] == "binaries" && path[2] == "build" && path[3] == "env" {
+			return true
+		}
 		return false
 	}
 	// Anything deeper than the known schema is forward-compat territory —
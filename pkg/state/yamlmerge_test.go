@@ -8,6 +8,8 @@ import (
 
 	"github.com/fentas/b/pkg/binary"
 	"github.com/fentas/b/pkg/envmatch"
+	"github.com/fentas/b/pkg/provider"
+	"github.com/fentas/b/pkg/signature"
 	"gopkg.in/yaml.v3"
 )
 
@@ -427,11 +429,20 @@ func TestManagedKey_MatchesMarshalOutput(t *testing.T) {
 	// emitted key sets.
 	binSample := &BinaryList{
 		&binary.LocalBinary{
-			Name:     "tool",
-			File:     "/tmp/tool",
-			Enforced: "v1.0",
-			Alias:    "alias",
-			Asset:    "tool-*.tar.gz",
+			Name:       "tool",
+			File:       "/tmp/tool",
+			Enforced:   "v1.0",
+			Alias:      "alias",
+			Asset:      "tool-*.tar.gz",
+			OnPost:     "true",
+			Verify:     &signature.Config{Minisign: &signature.Minisign{Key: "minisign.pub"}},
+			Channel:    "prerelease",
+			Extract:    []binary.Extract{{Member: "completions/tool.bash"}},
+			Layout:     binary.LayoutTree,
+			Entrypoint: "bin/tool",
+			Build:      &provider.GoBuild{Tags: []string{"netgo"}},
+			Runtime:    "podman",
+			Paths:      []string{"/usr/bin/tool", "/usr/share/tool-data"},
 		},
 	}
 	binMarshal, err := binSample.MarshalYAML()
@@ -448,9 +459,9 @@ func TestManagedKey_MatchesMarshalOutput(t *testing.T) {
 	if !ok {
 		t.Fatalf("BinaryList.MarshalYAML did not emit 'tool' entry: %v", binRoot)
 	}
-	binEntry, ok := binEntryAny.(map[string]string)
+	binEntry, ok := binEntryAny.(map[string]interface{})
 	if !ok {
-		t.Fatalf("BinaryList.MarshalYAML 'tool' entry is %T, want map[string]string", binEntryAny)
+		t.Fatalf("BinaryList.MarshalYAML 'tool' entry is %T, want map[string]interface{}", binEntryAny)
 	}
 	if len(binEntry) == 0 {
 		t.Fatal("BinaryList.MarshalYAML emitted an empty 'tool' entry — drift guard would silently pass")
@@ -626,3 +637,159 @@ func TestSaveConfig_PreservesUnknownBinaryFields(t *testing.T) {
 		t.Errorf("per-binary 'kubectl.groups' was wiped, got:\n%s", got)
 	}
 }
+
+// saveBinaryRoundTrip loads initial, lets edit change the single binary it
+// configures, saves it back and returns that binary's saved entry.
+func saveBinaryRoundTrip(t *testing.T, initial string, edit func(*binary.LocalBinary)) map[string]interface{} {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "b.yaml")
+	if err := os.WriteFile(configPath, []byte(initial), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfigFromPath(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfigFromPath: %v", err)
+	}
+	if len(config.Binaries) != 1 {
+		t.Fatalf("loaded %d binaries, want 1", len(config.Binaries))
+	}
+	b := config.Binaries[0]
+	edit(b)
+	if err := SaveConfig(config, configPath); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+
+	result, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(result), "# keep me") {
+		t.Errorf("comment lost, got:\n%s", result)
+	}
+	var saved struct {
+		Binaries map[string]map[string]interface{} `yaml:"binaries"`
+	}
+	if err := yaml.Unmarshal(result, &saved); err != nil {
+		t.Fatalf("unmarshal saved yaml: %v\n%s", err, result)
+	}
+	entry, ok := saved.Binaries[b.Name]
+	if !ok {
+		t.Fatalf("%s missing from saved file:\n%s", b.Name, result)
+	}
+	return entry
+}
+
+func TestSaveConfig_RoundTripsExtract(t *testing.T) {
+	const initial = `binaries:
+  # keep me
+  github.com/org/tool:
+    owner: me
+    extract:
+      - completions/tool.bash
+`
+	entry := saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) {
+		b.Extract = append(b.Extract, binary.Extract{Member: "man/tool.1", As: "tool.1"})
+	})
+	if extract, _ := entry["extract"].([]interface{}); len(extract) != 2 {
+		t.Errorf("extract = %v, want both entries", entry["extract"])
+	}
+
+	entry = saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) { b.Extract = nil })
+	if _, ok := entry["extract"]; ok {
+		t.Errorf("removed extract survived the save: %v", entry)
+	}
+	if entry["owner"] != "me" {
+		t.Errorf("user field lost: %v", entry)
+	}
+}
+
+func TestSaveConfig_RoundTripsLayout(t *testing.T) {
+	const initial = `binaries:
+  # keep me
+  github.com/org/tool:
+    owner: me
+    layout: tree
+    entrypoint: bin/tool
+`
+	entry := saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) { b.Entrypoint = "libexec/tool" })
+	if entry["layout"] != binary.LayoutTree || entry["entrypoint"] != "libexec/tool" {
+		t.Errorf("layout/entrypoint = %v/%v", entry["layout"], entry["entrypoint"])
+	}
+
+	entry = saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) { b.Layout, b.Entrypoint = "", "" })
+	if _, ok := entry["layout"]; ok {
+		t.Errorf("removed layout survived the save: %v", entry)
+	}
+	if _, ok := entry["entrypoint"]; ok {
+		t.Errorf("removed entrypoint survived the save: %v", entry)
+	}
+	if entry["owner"] != "me" {
+		t.Errorf("user field lost: %v", entry)
+	}
+}
+
+func TestSaveConfig_RoundTripsBuild(t *testing.T) {
+	const initial = `binaries:
+  # keep me
+  go://github.com/org/tool:
+    owner: me
+    build:
+      tags: [netgo]
+      ldflags: -s -w
+      env:
+        CGO_ENABLED: "1"
+        GOEXPERIMENT: loopvar
+`
+	entry := saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) {
+		b.Build.LDFlags = ""
+		delete(b.Build.Env, "GOEXPERIMENT")
+	})
+	build, _ := entry["build"].(map[string]interface{})
+	if _, ok := build["ldflags"]; ok {
+		t.Errorf("removed build.ldflags survived the save: %v", build)
+	}
+	if env, _ := build["env"].(map[string]interface{}); len(env) != 1 || env["CGO_ENABLED"] != "1" {
+		t.Errorf("build.env = %v, want CGO_ENABLED only", build["env"])
+	}
+	if tags, _ := build["tags"].([]interface{}); len(tags) != 1 {
+		t.Errorf("build.tags = %v", build["tags"])
+	}
+
+	entry = saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) { b.Build = nil })
+	if _, ok := entry["build"]; ok {
+		t.Errorf("removed build survived the save: %v", entry)
+	}
+	if entry["owner"] != "me" {
+		t.Errorf("user field lost: %v", entry)
+	}
+}
+
+func TestSaveConfig_RoundTripsImage(t *testing.T) {
+	const initial = `binaries:
+  # keep me
+  docker://ghcr.io/org/tool:
+    owner: me
+    runtime: podman
+    paths:
+      - /usr/bin/tool
+      - /usr/share/tool-data
+`
+	entry := saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) { b.Paths = b.Paths[:1] })
+	if entry["runtime"] != "podman" {
+		t.Errorf("runtime = %v", entry["runtime"])
+	}
+	if paths, _ := entry["paths"].([]interface{}); len(paths) != 1 || paths[0] != "/usr/bin/tool" {
+		t.Errorf("paths = %v, want [/usr/bin/tool]", entry["paths"])
+	}
+
+	entry = saveBinaryRoundTrip(t, initial, func(b *binary.LocalBinary) { b.Runtime, b.Paths = "", nil })
+	if _, ok := entry["runtime"]; ok {
+		t.Errorf("removed runtime survived the save: %v", entry)
+	}
+	if _, ok := entry["paths"]; ok {
+		t.Errorf("removed paths survived the save: %v", entry)
+	}
+	if entry["owner"] != "me" {
+		t.Errorf("user field lost: %v", entry)
+	}
+}